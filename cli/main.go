@@ -2,21 +2,113 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"regexp"
+	"strings"
+	"text/tabwriter"
 
 	"github.com/cici0602/pml-to-selinux/compiler"
+	"github.com/cici0602/pml-to-selinux/mapping"
+	"github.com/cici0602/pml-to-selinux/models"
 	"github.com/cici0602/pml-to-selinux/selinux"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 )
 
+// version is the tool version reported by the version command and recorded in manifests
+const version = "0.1.0"
+
 var (
 	modelPath  string
 	policyPath string
+	policyDir  string
 	outputDir  string
 	moduleName string
 	validate   bool
 	optimize   bool
 	verbose    bool
+	quiet      bool
+	enableMap  bool
+	userdom    bool
+	strict     bool
+	annotate   bool
+
+	constraints   bool
+	allowCritical bool
+	strictActions bool
+	basePolicy    bool
+	policyVersion int
+	booleanStyle  string
+	fcStyle       string
+	homeDirTmpl   bool
+	denyMode      string
+	ifacePrefix   string
+	baseModule    string
+	emitMetrics   string
+	relabelScript bool
+	baseStats     string
+	maxGrowth     float64
+	install       bool
+	dryRun        bool
+	toStdout      bool
+	manifestPath  string
+	outputFormat  string
+	compileMode   string
+	goPackage     string
+
+	countOnly     bool
+	failOnWarning bool
+	failOn        string
+	compatMode    string
+	warnBroad     bool
+	strictPaths   bool
+	lintOnly      bool
+	onlyTypes     bool
+	onlyRules     bool
+	onlyContexts  bool
+	enableLints   []string
+	disableLints  []string
+	minRisk       int
+
+	explainConflict    bool
+	noOptimizeContexts bool
+	collapseClasses    bool
+	regexRoundtrip     bool
+
+	classMapPath  string
+	permsPath     string
+	actionMapPath string
+	contextCheck  bool
+	expandAttrs   string
+	sortAttrs     bool
+	groupFiles    bool
+	noFCFor       []string
+	levelNames    []string
+	exclSubjects  []string
+	exclObjects   []string
+	transforms    []string
+
+	equivA string
+	equivB string
+
+	initTemplate string
+
+	importInput  string
+	importOutput string
+	importCounts bool
+	importSpec   string
+
+	decompileInput  string
+	decompileOutput string
+
+	exportOutput string
+
+	querySubject string
+	queryObject  string
+	queryAction  string
+
+	againstInstalled bool
 )
 
 func main() {
@@ -39,15 +131,68 @@ language and automatically generate SELinux policies.`,
 	}
 
 	compileCmd.Flags().StringVarP(&modelPath, "model", "m", "", "Path to PML model file (required)")
-	compileCmd.Flags().StringVarP(&policyPath, "policy", "p", "", "Path to PML policy file (required)")
+	compileCmd.Flags().StringVarP(&policyPath, "policy", "p", "", "Path to PML policy file (required unless --policy-dir)")
+	compileCmd.Flags().StringVar(&policyDir, "policy-dir", "", "Directory of *.csv, *.json and *.yaml policy files compiled as one module")
 	compileCmd.Flags().StringVarP(&outputDir, "output", "o", "./output", "Output directory for generated files")
 	compileCmd.Flags().StringVarP(&moduleName, "name", "n", "", "Module name (default: inferred from policy)")
+	compileCmd.Flags().StringVar(&outputFormat, "format", "te", "Output format: te or refpolicy (.te/.fc/.if files), cil (.cil module with its file contexts) or gosrc (Go source embedding the policy)")
+	compileCmd.Flags().StringVar(&compileMode, "mode", "module", "Policy layout: module (a loadable policy module) or monolithic (a policy.conf for checkpolicy, with a file_contexts file)")
+	compileCmd.Flags().StringVar(&goPackage, "go-package", "policy", "Package name of the generated Go source (with --format gosrc)")
 	compileCmd.Flags().BoolVarP(&validate, "validate", "v", false, "Validate generated policy")
 	compileCmd.Flags().BoolVar(&optimize, "optimize", true, "Optimize generated policy")
 	compileCmd.Flags().BoolVar(&verbose, "verbose", false, "Enable verbose output")
+	compileCmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "Only print warnings and errors")
+	compileCmd.Flags().BoolVar(&onlyTypes, "only-types", false, "Print the type declarations instead of writing output files")
+	compileCmd.Flags().BoolVar(&onlyRules, "only-rules", false, "Write only the .te file")
+	compileCmd.Flags().BoolVar(&onlyContexts, "only-contexts", false, "Write only the .fc file")
+	compileCmd.Flags().BoolVar(&noOptimizeContexts, "no-optimize-contexts", false, "Keep file contexts in source order without deduplication")
+	compileCmd.Flags().BoolVar(&collapseClasses, "collapse-classes", false, "Collapse rules differing only in class into a class set")
+	compileCmd.Flags().BoolVar(&enableMap, "enable-map", false, "Grant the map permission with read/execute and mmap (requires a policy that defines map, and --policy-version 31 or later when set)")
+	compileCmd.Flags().StringArrayVar(&exclSubjects, "exclude-subject", nil, "Drop policy lines with this subject before compiling (repeatable)")
+	compileCmd.Flags().StringArrayVar(&exclObjects, "exclude-object", nil, "Drop policy lines whose object matches this path pattern before compiling (repeatable)")
+	compileCmd.Flags().StringArrayVar(&noFCFor, "no-fc-for", nil, "Do not generate file contexts for objects under this path prefix (repeatable)")
+	compileCmd.Flags().StringArrayVar(&levelNames, "level-name", nil, "Name an MLS sensitivity or category for @level= and ::level objects, e.g. restricted=s5 or hr=c0 (repeatable)")
+	compileCmd.Flags().StringArrayVar(&transforms, "transform", nil, "Run a registered policy transformer after generation (repeatable, applied in order)")
+	compileCmd.Flags().BoolVar(&userdom, "userdom", false, "Label objects under /home/*/ with userdom_user_home_content calls instead of /home file contexts")
+	compileCmd.Flags().BoolVar(&groupFiles, "group-file-types", false, "Group the module's file types under a <module>_file_types attribute and generate <module>_manage_all_files")
+	compileCmd.Flags().BoolVar(&sortAttrs, "deterministic-attributes", true, "Sort type attributes so typeattribute lines are diff-stable")
+	compileCmd.Flags().StringVar(&expandAttrs, "expand-attributes-decl", "", "Emit expandattribute for g2 attributes with the given value (true or false)")
+	compileCmd.Flags().BoolVar(&contextCheck, "context-check", false, "Warn on file context types not defined in the installed SELinux policy (requires seinfo)")
+	compileCmd.Flags().StringVar(&classMapPath, "class-map", "", "Path to a file of object-prefix to class mappings (e.g. 'dbus: dbus')")
+	compileCmd.Flags().StringVar(&permsPath, "class-perms", "", "Path to a file of object classes and their permissions (e.g. 'dbus send_msg acquire_svc'), extending those permissions are checked against")
+	compileCmd.Flags().StringVar(&actionMapPath, "action-map", "", "Path to a JSON or YAML map of custom actions to their class and permissions (e.g. 'deploy: {class: file, permissions: [write, create, setattr]}')")
+	compileCmd.Flags().BoolVar(&install, "install", false, "Build <module>.pp with checkmodule and semodule_package, then install it with 'sudo semodule -i'")
+	compileCmd.Flags().BoolVar(&dryRun, "dry-run", false, "With --install, print the build and install commands instead of running them")
+	compileCmd.Flags().BoolVar(&toStdout, "stdout", false, "Print the generated files to stdout, each under a '=== <name> ===' header, instead of writing them to the output directory")
+	compileCmd.Flags().BoolVar(&relabelScript, "relabel-script", false, "Write relabel.sh to restorecon the directories covered by the file contexts")
+	compileCmd.Flags().StringVar(&manifestPath, "output-manifest", "", "Write a JSON manifest of the inputs and generated files with SHA-256 checksums")
+	compileCmd.Flags().StringVar(&emitMetrics, "emit-metrics", "", "Write policy metrics to <output>/<module>.prom (format: prometheus)")
+	compileCmd.Flags().StringVar(&baseStats, "baseline-stats", "", "Fail when rule or type counts grew past the JSON complexity baseline at this path; written if missing")
+	compileCmd.Flags().Float64Var(&maxGrowth, "baseline-max-growth", 10, "Percentage by which --baseline-stats counts may grow")
+	compileCmd.Flags().StringVar(&baseModule, "base-module", "", "Path to a shared base policy whose types are required instead of declared")
+	compileCmd.Flags().IntVar(&policyVersion, "policy-version", 0, "Target policydb version; 30 or later enables ioctl extended permissions (@xperm), 31 or later the map permission (--enable-map)")
+	compileCmd.Flags().StringVar(&ifacePrefix, "interface-prefix", "", "Prefix the generated .if interface names (e.g. acme gives acme_<module>_read_files); type names are unchanged")
+	compileCmd.Flags().StringVar(&booleanStyle, "boolean-style", "bool", "How ?cond= booleans are declared: bool (gen_bool, if blocks) or tunable (gen_tunable, tunable_policy)")
+	compileCmd.Flags().BoolVar(&annotate, "annotate", false, "Precede each allow rule in the .te with comments saying what its PML lines grant")
+	compileCmd.Flags().StringVar(&denyMode, "deny-mode", compiler.DenyModeNeverallow, "How deny rules are written: neverallow (asserting the access is never allowed), dontaudit (silencing its denials) or skip")
+	compileCmd.Flags().StringVar(&fcStyle, "fc-context-style", selinux.FCContextStyleGenContext, "How .fc contexts are written: gen_context (refpolicy M4 macro) or literal (system_u:object_r:type:s0)")
+	compileCmd.Flags().BoolVar(&homeDirTmpl, "home-dir-template", false, "Write .fc patterns under /home/*/ and /home as HOME_DIR and HOME_ROOT templates expanded per user by genhomedircon")
+	compileCmd.Flags().BoolVar(&basePolicy, "base-policy", false, "Generate a base policy (no policy_module; allows sid rules, constraints and object defaults)")
+	compileCmd.Flags().BoolVar(&allowCritical, "allow-critical", false, "Warn instead of failing when a file context matches a system-critical path")
+	compileCmd.Flags().BoolVar(&warnBroad, "warn-on-broad-perms", false, "Warn on rules granting broad permission sets to broad targets")
+	compileCmd.Flags().BoolVar(&strict, "strict", false, "Fail instead of warning when the module would have no allow rules or type transitions")
+	compileCmd.Flags().BoolVar(&strictActions, "strict-actions", false, "Fail on actions that are neither mapped nor raw SELinux permissions instead of passing them through")
+	compileCmd.Flags().BoolVar(&strictPaths, "strict-paths", false, "Require objects to be clean absolute paths (no '..', '.', '//' or trailing '/') or special forms")
+	compileCmd.Flags().BoolVar(&regexRoundtrip, "validate-regex-roundtrip", false, "Check that every path object, with wildcards filled in, matches its generated file-context regex")
+	compileCmd.Flags().BoolVar(&lintOnly, "lint-only", false, "Only validate the policies and run the enabled lints; exits non-zero on lint warnings")
+	compileCmd.Flags().StringSliceVar(&enableLints, "enable-lint", nil, "Enable lints by name (comma-separated: "+strings.Join(compiler.LintNames(), ", ")+")")
+	compileCmd.Flags().StringSliceVar(&disableLints, "disable-lint", nil, "Disable lints by name (comma-separated)")
+	compileCmd.Flags().BoolVar(&constraints, "constraints", false, "Emit user-role and role-type constrain statements from role relations (requires --base-policy or --mode monolithic)")
 
 	compileCmd.MarkFlagRequired("model")
-	compileCmd.MarkFlagRequired("policy")
+	compileCmd.MarkFlagsOneRequired("policy", "policy-dir")
+	compileCmd.MarkFlagsMutuallyExclusive("policy", "policy-dir")
+	compileCmd.MarkFlagsMutuallyExclusive("only-types", "only-rules", "only-contexts")
 
 	// Validate command
 	validateCmd := &cobra.Command{
@@ -60,10 +205,163 @@ language and automatically generate SELinux policies.`,
 	validateCmd.Flags().StringVarP(&modelPath, "model", "m", "", "Path to PML model file (required)")
 	validateCmd.Flags().StringVarP(&policyPath, "policy", "p", "", "Path to PML policy file (required)")
 	validateCmd.Flags().BoolVar(&verbose, "verbose", false, "Enable verbose output")
+	validateCmd.Flags().StringVar(&permsPath, "class-perms", "", "Path to a file of object classes and their permissions (e.g. 'dbus send_msg acquire_svc'), extending those permissions are checked against")
+	validateCmd.Flags().StringVar(&actionMapPath, "action-map", "", "Path to a JSON or YAML map of custom actions to their class and permissions")
+	validateCmd.Flags().BoolVar(&countOnly, "count-only", false, "Print only error, warning and conflict counts")
+	validateCmd.Flags().BoolVar(&warnBroad, "warn-on-broad-perms", false, "Warn on rules granting broad permission sets to broad targets")
+	validateCmd.Flags().BoolVar(&strictPaths, "strict-paths", false, "Require objects to be clean absolute paths (no '..', '.', '//' or trailing '/') or special forms")
+	validateCmd.Flags().StringSliceVar(&enableLints, "enable-lint", nil, "Enable lints by name (comma-separated: "+strings.Join(compiler.LintNames(), ", ")+")")
+	validateCmd.Flags().StringSliceVar(&disableLints, "disable-lint", nil, "Disable lints by name (comma-separated)")
+	validateCmd.Flags().IntVar(&minRisk, "min-risk", 0, "Only report lint findings with at least this risk score (0-100)")
+	validateCmd.Flags().StringVar(&compatMode, "compat", "", "Warn on extensions that do not round-trip to another enforcer (supported: casbin)")
+	validateCmd.Flags().BoolVar(&explainConflict, "explain-conflict", false, "Show each conflict's object regexes and a sample path matching both")
+	validateCmd.Flags().BoolVar(&failOnWarning, "fail-on-warning", false, "Exit with non-zero status if any warnings are found")
 
 	validateCmd.MarkFlagRequired("model")
 	validateCmd.MarkFlagRequired("policy")
 
+	// Equiv command
+	equivCmd := &cobra.Command{
+		Use:   "equiv",
+		Short: "Check whether two .te files grant the same access",
+		Long:  "Parse two .te files, canonicalize their allow rules and report whether they grant exactly the same access",
+		Run:   runEquiv,
+	}
+
+	equivCmd.Flags().StringVarP(&equivA, "a", "a", "", "Path to the first .te file (required)")
+	equivCmd.Flags().StringVarP(&equivB, "b", "b", "", "Path to the second .te file (required)")
+
+	equivCmd.MarkFlagRequired("a")
+	equivCmd.MarkFlagRequired("b")
+
+	// Query command
+	queryCmd := &cobra.Command{
+		Use:   "query",
+		Short: "Check whether the compiled policy allows an access",
+		Long:  "Compile the PML policy and report whether a subject may perform an action on an object path, explaining any denial",
+		Run:   runQuery,
+	}
+
+	queryCmd.Flags().StringVarP(&modelPath, "model", "m", "", "Path to PML model file (required)")
+	queryCmd.Flags().StringVarP(&policyPath, "policy", "p", "", "Path to PML policy file (required)")
+	queryCmd.Flags().StringVarP(&moduleName, "name", "n", "", "Module name (default: inferred from policy)")
+	queryCmd.Flags().StringVar(&querySubject, "subject", "", "Subject to query, e.g. httpd (required)")
+	queryCmd.Flags().StringVar(&queryObject, "object", "", "Object path to query, e.g. /var/www/index.html (required)")
+	queryCmd.Flags().StringVar(&queryAction, "action", "", "Action to query, e.g. read (required)")
+
+	queryCmd.MarkFlagRequired("model")
+	queryCmd.MarkFlagRequired("policy")
+	queryCmd.MarkFlagRequired("subject")
+	queryCmd.MarkFlagRequired("object")
+	queryCmd.MarkFlagRequired("action")
+
+	// Transitions command
+	transitionsCmd := &cobra.Command{
+		Use:   "transitions",
+		Short: "Report the type transitions of the compiled policy",
+		Long:  "Compile the PML policy and list every type_transition, checking that each domain transition has its execute, transition and entrypoint rules; exits non-zero if any are missing",
+		Run:   runTransitions,
+	}
+
+	transitionsCmd.Flags().StringVarP(&modelPath, "model", "m", "", "Path to PML model file (required)")
+	transitionsCmd.Flags().StringVarP(&policyPath, "policy", "p", "", "Path to PML policy file (required)")
+	transitionsCmd.Flags().StringVarP(&moduleName, "name", "n", "", "Module name (default: inferred from policy)")
+
+	transitionsCmd.MarkFlagRequired("model")
+	transitionsCmd.MarkFlagRequired("policy")
+
+	// Diff command
+	diffCmd := &cobra.Command{
+		Use:   "diff",
+		Short: "Compare the compiled policy with the installed policy",
+		Long:  "Compile the PML policy and compare its types and allow rules with the policy loaded in the kernel (queried with seinfo and sesearch), reporting rules that still need applying and installed rules the source does not grant; exits non-zero on any difference",
+		Run:   runDiff,
+	}
+
+	diffCmd.Flags().StringVarP(&modelPath, "model", "m", "", "Path to PML model file (required)")
+	diffCmd.Flags().StringVarP(&policyPath, "policy", "p", "", "Path to PML policy file (required)")
+	diffCmd.Flags().StringVarP(&moduleName, "name", "n", "", "Module name (default: inferred from policy)")
+	diffCmd.Flags().BoolVar(&againstInstalled, "against-installed", false, "Compare against the installed policy (required; requires setools)")
+
+	diffCmd.MarkFlagRequired("model")
+	diffCmd.MarkFlagRequired("policy")
+	diffCmd.MarkFlagRequired("against-installed")
+
+	// Lint-source command
+	lintSourceCmd := &cobra.Command{
+		Use:   "lint-source",
+		Short: "Report duplicate and redundant rules in the policy source",
+		Long:  "Parse the policy rules and report exact-duplicate lines and rules whose permissions another rule on the same object already grants; exits non-zero if any are found",
+		Run:   runLintSource,
+	}
+
+	lintSourceCmd.Flags().StringVarP(&policyPath, "policy", "p", "", "Path to PML policy file")
+	lintSourceCmd.Flags().StringVar(&policyDir, "policy-dir", "", "Directory of *.csv, *.json and *.yaml policy files, read in sorted order")
+
+	lintSourceCmd.MarkFlagsOneRequired("policy", "policy-dir")
+	lintSourceCmd.MarkFlagsMutuallyExclusive("policy", "policy-dir")
+
+	// Lint command
+	lintCmd := &cobra.Command{
+		Use:   "lint",
+		Short: "Run every policy check and report findings with severities",
+		Long:  "Run the syntax checks, all lints, the analyzer's conflict detection and the conflict analysis of the generated policy, reporting each finding at its source line with a severity",
+		Run:   runLint,
+	}
+
+	lintCmd.Flags().StringVarP(&modelPath, "model", "m", "", "Path to PML model file (required)")
+	lintCmd.Flags().StringVarP(&policyPath, "policy", "p", "", "Path to PML policy file")
+	lintCmd.Flags().StringVar(&policyDir, "policy-dir", "", "Directory of *.csv, *.json and *.yaml policy files, read in sorted order")
+	lintCmd.Flags().StringSliceVar(&disableLints, "disable-lint", nil, "Disable lints by name (comma-separated: "+strings.Join(compiler.LintNames(), ", ")+")")
+	lintCmd.Flags().BoolVar(&strictPaths, "strict-paths", false, "Require objects to be clean absolute paths (no '..', '.', '//' or trailing '/') or special forms")
+	lintCmd.Flags().IntVar(&minRisk, "min-risk", 0, "Only report findings with at least this risk score (0-100)")
+	lintCmd.Flags().StringVar(&failOn, "fail-on", "error", "Lowest severity that makes the exit status non-zero: error or warning")
+
+	lintCmd.MarkFlagRequired("model")
+	lintCmd.MarkFlagsOneRequired("policy", "policy-dir")
+	lintCmd.MarkFlagsMutuallyExclusive("policy", "policy-dir")
+
+	// Import command
+	importCmd := &cobra.Command{
+		Use:   "import",
+		Short: "Generate PML rules from AVC denials or a pod spec",
+		Long:  "Aggregate AVC denials from an audit log or an aggregated denial report ('... N times') into PML allow rules, or scaffold rules from the volume mounts of a Kubernetes pod spec (experimental)",
+		Run:   runImport,
+	}
+
+	importCmd.Flags().StringVarP(&importInput, "input", "i", "", "Path to the audit log or denial report")
+	importCmd.Flags().StringVar(&importSpec, "from-podspec", "", "Path to a pod or workload manifest (YAML or JSON) whose volume mounts become rules (experimental)")
+	importCmd.Flags().StringVarP(&importOutput, "output", "o", "", "Path of the PML policy to write (default: stdout)")
+	importCmd.Flags().BoolVar(&importCounts, "counts", false, "Precede each rule with a comment giving its denial count")
+
+	importCmd.MarkFlagsOneRequired("input", "from-podspec")
+	importCmd.MarkFlagsMutuallyExclusive("input", "from-podspec")
+	importCmd.MarkFlagsMutuallyExclusive("counts", "from-podspec")
+
+	// Decompile command
+	decompileCmd := &cobra.Command{
+		Use:   "decompile",
+		Short: "Convert a .te module into PML rules",
+		Long:  "Parse a hand-written .te module and lower its allow rules and type transitions into PML rules, collapsing permission sets into actions; constructs PML cannot represent are listed as comments and warnings",
+		Run:   runDecompile,
+	}
+
+	decompileCmd.Flags().StringVarP(&decompileInput, "input", "i", "", "Path to the .te module")
+	decompileCmd.Flags().StringVarP(&decompileOutput, "output", "o", "", "Path of the PML policy to write (default: stdout)")
+
+	decompileCmd.MarkFlagRequired("input")
+
+	// Export-mappings command
+	exportMappingsCmd := &cobra.Command{
+		Use:   "export-mappings",
+		Short: "Print the action to permission mappings",
+		Long:  "Print the default action mappings, with those of --action-map added, as a YAML action map to start a custom one from",
+		Run:   runExportMappings,
+	}
+
+	exportMappingsCmd.Flags().StringVar(&actionMapPath, "action-map", "", "Path to a JSON or YAML map of custom actions to include")
+	exportMappingsCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "Path of the action map to write (default: stdout)")
+
 	// Init command
 	initCmd := &cobra.Command{
 		Use:   "init [project-name]",
@@ -73,17 +371,28 @@ language and automatically generate SELinux policies.`,
 		Run:   runInit,
 	}
 
+	initCmd.Flags().StringVar(&initTemplate, "template", "", "Directory of *.tmpl files (text/template) used instead of the built-in templates")
+
 	// Version command
 	versionCmd := &cobra.Command{
 		Use:   "version",
 		Short: "Print version information",
 		Run: func(cmd *cobra.Command, args []string) {
-			fmt.Println("pml2selinux version 0.1.0")
+			fmt.Printf("pml2selinux version %s\n", version)
 		},
 	}
 
 	rootCmd.AddCommand(compileCmd)
 	rootCmd.AddCommand(validateCmd)
+	rootCmd.AddCommand(equivCmd)
+	rootCmd.AddCommand(queryCmd)
+	rootCmd.AddCommand(transitionsCmd)
+	rootCmd.AddCommand(diffCmd)
+	rootCmd.AddCommand(lintSourceCmd)
+	rootCmd.AddCommand(lintCmd)
+	rootCmd.AddCommand(importCmd)
+	rootCmd.AddCommand(decompileCmd)
+	rootCmd.AddCommand(exportMappingsCmd)
 	rootCmd.AddCommand(initCmd)
 	rootCmd.AddCommand(versionCmd)
 
@@ -93,32 +402,119 @@ language and automatically generate SELinux policies.`,
 	}
 }
 
+// interfacePrefixPattern matches prefixes that keep interface names valid m4 macro names
+var interfacePrefixPattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// progress receives compile diagnostics and progress lines. It is stderr with
+// --stdout, so stdout holds only the generated files.
+var progress io.Writer = os.Stdout
+
 func runCompile(cmd *cobra.Command, args []string) {
+	if expandAttrs != "" && expandAttrs != "true" && expandAttrs != "false" {
+		fmt.Fprintf(os.Stderr, "✗ Invalid --expand-attributes-decl value '%s' (must be true or false)\n", expandAttrs)
+		os.Exit(1)
+	}
+	if outputFormat == "refpolicy" {
+		outputFormat = "te"
+	}
+	if outputFormat != "te" && outputFormat != "cil" && outputFormat != "gosrc" {
+		fmt.Fprintf(os.Stderr, "✗ Unsupported output format '%s' (supported: te, refpolicy, cil, gosrc)\n", outputFormat)
+		os.Exit(1)
+	}
+	if compileMode != "module" && compileMode != "monolithic" {
+		fmt.Fprintf(os.Stderr, "✗ Unsupported mode '%s' (supported: module, monolithic)\n", compileMode)
+		os.Exit(1)
+	}
+	if compileMode == "monolithic" && (outputFormat != "te" || install || onlyTypes || onlyRules || onlyContexts) {
+		fmt.Fprintf(os.Stderr, "✗ --mode monolithic requires the te output format, no --install and no --only-* flags\n")
+		os.Exit(1)
+	}
+	if booleanStyle != selinux.BooleanStyleBool && booleanStyle != selinux.BooleanStyleTunable {
+		fmt.Fprintf(os.Stderr, "✗ Unsupported boolean style '%s' (supported: bool, tunable)\n", booleanStyle)
+		os.Exit(1)
+	}
+	if denyMode != compiler.DenyModeNeverallow && denyMode != compiler.DenyModeDontaudit && denyMode != compiler.DenyModeSkip {
+		fmt.Fprintf(os.Stderr, "✗ Unsupported deny mode '%s' (supported: neverallow, dontaudit, skip)\n", denyMode)
+		os.Exit(1)
+	}
+	loadClassPermissions()
+	loadActionMappings()
+	if fcStyle != selinux.FCContextStyleGenContext && fcStyle != selinux.FCContextStyleLiteral {
+		fmt.Fprintf(os.Stderr, "✗ Unsupported .fc context style '%s' (supported: gen_context, literal)\n", fcStyle)
+		os.Exit(1)
+	}
+	if ifacePrefix != "" && !interfacePrefixPattern.MatchString(ifacePrefix) {
+		fmt.Fprintf(os.Stderr, "✗ Invalid --interface-prefix '%s' (must match [a-z][a-z0-9_]*)\n", ifacePrefix)
+		os.Exit(1)
+	}
+	if emitMetrics != "" && emitMetrics != "prometheus" {
+		fmt.Fprintf(os.Stderr, "✗ Unsupported metrics format '%s' (supported: prometheus)\n", emitMetrics)
+		os.Exit(1)
+	}
+	if maxGrowth < 0 {
+		fmt.Fprintf(os.Stderr, "✗ Invalid --baseline-max-growth %g (must not be negative)\n", maxGrowth)
+		os.Exit(1)
+	}
+	if install && outputFormat != "te" {
+		fmt.Fprintf(os.Stderr, "✗ --install requires the te output format\n")
+		os.Exit(1)
+	}
+	if (onlyTypes || onlyRules || onlyContexts) && (outputFormat != "te" || install) {
+		fmt.Fprintf(os.Stderr, "✗ --only-types, --only-rules and --only-contexts require the te output format and no --install\n")
+		os.Exit(1)
+	}
+	if dryRun && !install {
+		fmt.Fprintf(os.Stderr, "✗ --dry-run only applies to --install\n")
+		os.Exit(1)
+	}
+	if toStdout && (install || manifestPath != "") {
+		fmt.Fprintf(os.Stderr, "✗ --stdout cannot be combined with --install or --output-manifest\n")
+		os.Exit(1)
+	}
+	if toStdout {
+		progress = os.Stderr
+	}
+
 	if verbose {
-		fmt.Printf("Compiling PML to SELinux policy...\n")
-		fmt.Printf("  Model:  %s\n", modelPath)
-		fmt.Printf("  Policy: %s\n", policyPath)
-		fmt.Printf("  Output: %s\n", outputDir)
-		fmt.Println()
+		fmt.Fprintf(progress, "Compiling PML to SELinux policy...\n")
+		fmt.Fprintf(progress, "  Model:  %s\n", modelPath)
+		if policyDir != "" {
+			fmt.Fprintf(progress, "  Policy: %s/*.{csv,json,yaml,yml}\n", policyDir)
+		} else {
+			fmt.Fprintf(progress, "  Policy: %s\n", policyPath)
+		}
+		fmt.Fprintf(progress, "  Output: %s\n", outputDir)
+		fmt.Fprintln(progress)
 	}
 
 	// 1. Parse PML files
 	if verbose {
-		fmt.Println("⟳ Parsing PML files...")
+		fmt.Fprintln(progress, "⟳ Parsing PML files...")
+	}
+	parser := newCompileParser(policyPath)
+	if policyDir != "" {
+		parser.SetPolicyDir(policyDir)
 	}
-	parser := compiler.NewParser(modelPath, policyPath)
 	pml, err := parser.Parse()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "✗ Parse error: %v\n", err)
 		os.Exit(1)
 	}
 	if verbose {
-		fmt.Printf("✓ Successfully parsed model and %d policies\n", len(pml.Policies))
+		fmt.Fprintf(progress, "✓ Successfully parsed model and %d policies\n", len(pml.Policies))
+	}
+	excluded, err := compiler.ExcludePolicies(pml, exclSubjects, exclObjects)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+		os.Exit(1)
+	}
+	if verbose && excluded > 0 {
+		fmt.Fprintf(progress, "✓ Excluded %d policies\n", excluded)
 	}
 
 	// 2. Decode standard PML to SELinux structures
 	if verbose {
-		fmt.Println("⟳ Decoding PML to SELinux structures...")
+		fmt.Fprintln(progress, "⟳ Decoding PML to SELinux structures...")
 	}
 	decoded, err := parser.Decode(pml)
 	if err != nil {
@@ -126,15 +522,37 @@ func runCompile(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 	if verbose {
-		fmt.Printf("✓ Decoded %d policies, %d transitions\n",
+		fmt.Fprintf(progress, "✓ Decoded %d policies, %d transitions\n",
 			len(decoded.Policies), len(decoded.Transitions))
 	}
 
 	// 3. Analyze and validate
 	if verbose {
-		fmt.Println("⟳ Analyzing policy...")
+		fmt.Fprintln(progress, "⟳ Analyzing policy...")
+	}
+	levelMapper := mapping.NewLevelMapper()
+	for _, definition := range levelNames {
+		if err := levelMapper.Define(definition); err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+			os.Exit(1)
+		}
 	}
 	analyzer := compiler.NewAnalyzer(decoded)
+	analyzer.SetOutput(progress)
+	analyzer.SetLevelMapper(levelMapper)
+	configureAnalyzer(analyzer)
+	if lintOnly {
+		if err := analyzer.Lint(); err != nil {
+			fmt.Fprintf(os.Stderr, "✗ Analysis error: %v\n", err)
+			os.Exit(1)
+		}
+		if warnings := len(analyzer.GetWarnings()); warnings > 0 {
+			fmt.Fprintf(progress, "✗ %d lint warnings\n", warnings)
+			os.Exit(1)
+		}
+		fmt.Fprintln(progress, "✓ No lint warnings")
+		return
+	}
 	err = analyzer.Analyze()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "✗ Analysis error: %v\n", err)
@@ -142,53 +560,377 @@ func runCompile(cmd *cobra.Command, args []string) {
 	}
 	stats := analyzer.GetStats()
 	if verbose {
-		fmt.Printf("✓ Analysis complete: %d rules, %d subjects, %d objects\n",
+		fmt.Fprintf(progress, "✓ Analysis complete: %d rules, %d subjects, %d objects\n",
 			stats.TotalPolicies, stats.UniqueSubjects, stats.UniqueObjects)
 		if stats.Conflicts > 0 {
-			fmt.Printf("⚠ Warning: Found %d potential conflicts\n", stats.Conflicts)
+			fmt.Fprintf(progress, "⚠ Warning: Found %d potential conflicts\n", stats.Conflicts)
 		}
 	}
 
 	// 4. Generate SELinux policy
 	if verbose {
-		fmt.Println("⟳ Generating SELinux policy...")
+		fmt.Fprintln(progress, "⟳ Generating SELinux policy...")
+	}
+	generator := compiler.NewGenerator(analyzer.Resolved(), moduleName)
+	generator.SetOutput(progress)
+	generator.SetEnableMap(enableMap)
+	generator.SetConstraints(constraints)
+	generator.SetAllowCritical(allowCritical)
+	generator.SetStrictActions(strictActions)
+	generator.SetStrict(strict)
+	generator.SetDenyMode(denyMode)
+	generator.SetBasePolicy(basePolicy || compileMode == "monolithic")
+	generator.SetPolicyVersion(policyVersion)
+	generator.SetDeterministicAttributes(sortAttrs)
+	generator.SetGroupFileTypes(groupFiles)
+	generator.SetUserdom(userdom)
+	generator.SetNoFileContextPrefixes(noFCFor)
+	generator.SetLevelMapper(levelMapper)
+	if expandAttrs != "" {
+		generator.SetAttributeExpansion(expandAttrs == "true")
+	}
+	if baseModule != "" {
+		baseParser := newCompileParser(baseModule)
+		basePML, err := baseParser.Parse()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✗ Base module parse error: %v\n", err)
+			os.Exit(1)
+		}
+		baseDecoded, err := baseParser.Decode(basePML)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✗ Base module decoding error: %v\n", err)
+			os.Exit(1)
+		}
+		generator.SetBaseModule(baseDecoded, "")
 	}
-	generator := compiler.NewGenerator(decoded, moduleName)
 	selinuxPolicy, err := generator.Generate()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "✗ Generation error: %v\n", err)
 		os.Exit(1)
 	}
+	if verbose && moduleName == "" {
+		fmt.Fprintf(progress, "✓ Inferred module name: %s (use --name to override)\n", selinuxPolicy.ModuleName)
+	}
 	if verbose {
-		fmt.Printf("✓ Generated %d types, %d allow rules, %d file contexts\n",
+		fmt.Fprintf(progress, "✓ Generated %d types, %d allow rules, %d file contexts\n",
 			len(selinuxPolicy.Types), len(selinuxPolicy.Rules),
 			len(selinuxPolicy.FileContexts))
 	}
+	if err := compiler.RunTransformers(selinuxPolicy, transforms); err != nil {
+		fmt.Fprintf(os.Stderr, "✗ Transform error: %v\n", err)
+		os.Exit(1)
+	}
+	if verbose && len(transforms) > 0 {
+		fmt.Fprintf(progress, "✓ Applied transformers: %s\n", strings.Join(transforms, ", "))
+	}
 
 	// 4. Optimize if requested
 	if optimize {
 		if verbose {
-			fmt.Println("⟳ Optimizing policy...")
+			fmt.Fprintln(progress, "⟳ Optimizing policy...")
 		}
 		optimizer := compiler.NewOptimizer(selinuxPolicy)
+		optimizer.SetOutput(progress)
+		optimizer.SetOptimizeContexts(!noOptimizeContexts)
+		optimizer.SetCollapseClasses(collapseClasses)
 		err = optimizer.Optimize()
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "✗ Optimization error: %v\n", err)
 			os.Exit(1)
 		}
 		if verbose {
-			fmt.Printf("✓ Optimized: %d types, %d rules\n",
+			fmt.Fprintf(progress, "✓ Optimized: %d types, %d rules\n",
 				len(selinuxPolicy.Types), len(selinuxPolicy.Rules))
+			if removed := optimizer.DuplicateContextsRemoved(); removed > 0 {
+				fmt.Fprintf(progress, "  Removed %d duplicate file contexts\n", removed)
+			}
+		}
+	}
+
+	// Check context types against the installed policy
+	if contextCheck {
+		installed, err := selinux.LoadInstalledTypes()
+		if err != nil {
+			fmt.Fprintf(progress, "⚠ Context check skipped: %v\n", err)
+		} else {
+			for _, typeName := range selinux.CheckContextTypes(selinuxPolicy, installed) {
+				fmt.Fprintf(progress, "⚠ Warning: file context type '%s' is not defined in the installed policy\n", typeName)
+			}
+		}
+	}
+
+	// Self-check the path conversion against the source objects
+	if regexRoundtrip {
+		failures := compiler.CheckRegexRoundtrip(decoded)
+		for _, failure := range failures {
+			if failure.Err != nil {
+				fmt.Fprintf(os.Stderr, "✗ Regex roundtrip: '%s': %v\n", failure.Object, failure.Err)
+				continue
+			}
+			fmt.Fprintf(os.Stderr, "✗ Regex roundtrip: '%s' does not match its sample path %s (patterns: %s)\n",
+				failure.Object, failure.Sample, strings.Join(failure.Patterns, ", "))
+		}
+		if len(failures) > 0 {
+			os.Exit(1)
+		}
+		if verbose {
+			fmt.Fprintln(progress, "✓ Regex roundtrip check passed")
+		}
+	}
+
+	// Warn on transition targets that cannot run
+	for _, trapped := range compiler.DetectConflicts(selinuxPolicy).TrappedDomains {
+		fmt.Fprintf(progress, "⚠ Warning: %s\n", trapped)
+	}
+
+	// Guard against policy growth past the committed complexity baseline
+	if baseStats != "" {
+		checkComplexityBaseline(selinuxPolicy)
+	}
+
+	// Print only the type declarations, e.g. for documentation
+	if onlyTypes {
+		types, err := selinux.NewTEGenerator(selinuxPolicy).GenerateTypes()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✗ TE generation error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Print(types)
+		return
+	}
+
+	// 5. Generate output files
+	files := generateOutputFiles(selinuxPolicy, stats)
+
+	// Print the files instead of writing them, e.g. to inspect the output in CI
+	if toStdout {
+		for _, file := range files {
+			fmt.Printf("=== %s ===\n%s", file.name, file.content)
+			if !strings.HasSuffix(file.content, "\n") {
+				fmt.Println()
+			}
 		}
+		if !quiet {
+			fmt.Fprintln(progress, compiler.RenderModuleSummary(selinuxPolicy))
+		}
+		return
 	}
 
-	// 5. Write output files
 	if verbose {
-		fmt.Printf("⟳ Writing files to %s...\n", outputDir)
+		fmt.Fprintf(progress, "⟳ Writing files to %s...\n", outputDir)
+	}
+	generated := writeOutputFiles(files)
+
+	if !quiet {
+		fmt.Fprintf(progress, "✓ Compilation successful!\n")
+		for _, path := range generated {
+			fmt.Fprintf(progress, "  Generated: %s\n", path)
+		}
+	}
+
+	if manifestPath != "" {
+		inputs, err := parser.SourceFiles()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✗ Failed to write manifest: %v\n", err)
+			os.Exit(1)
+		}
+		for _, path := range []string{classMapPath, permsPath, baseModule} {
+			if path != "" {
+				inputs = append(inputs, path)
+			}
+		}
+		if err := writeManifest(manifestPath, inputs, generated); err != nil {
+			fmt.Fprintf(os.Stderr, "✗ Failed to write manifest: %v\n", err)
+			os.Exit(1)
+		}
+		if !quiet {
+			fmt.Fprintf(progress, "  Manifest:  %s\n", manifestPath)
+		}
+	}
+
+	if !quiet {
+		fmt.Fprintf(progress, "\n%s\n", compiler.RenderModuleSummary(selinuxPolicy))
+	}
+
+	if install {
+		installModule(selinuxPolicy.ModuleName, generated[0], generated[1])
+		return
+	}
+
+	if validate && compileMode == "monolithic" && !quiet {
+		fmt.Fprintln(progress, "\nℹ To validate the policy, run:")
+		fmt.Fprintf(progress, "  checkpolicy -M -o policy.bin %s\n", generated[0])
+		fmt.Fprintf(progress, "  setfiles -c policy.bin %s\n", generated[1])
+	} else if validate && outputFormat == "te" && !onlyRules && !onlyContexts && !quiet {
+		tePath, fcPath := generated[0], generated[1]
+		fmt.Fprintln(progress, "\nℹ To validate and install the policy, run:")
+		fmt.Fprintf(progress, "  checkmodule -M -m -o %s.mod %s\n", selinuxPolicy.ModuleName, tePath)
+		fmt.Fprintf(progress, "  semodule_package -o %s.pp -m %s.mod -fc %s\n",
+			selinuxPolicy.ModuleName, selinuxPolicy.ModuleName, fcPath)
+		fmt.Fprintf(progress, "  sudo semodule -i %s.pp\n", selinuxPolicy.ModuleName)
+	}
+}
+
+// installModule builds the generated module into a .pp and installs it,
+// or only prints the commands with --dry-run
+func installModule(name, tePath, fcPath string) {
+	steps := selinux.InstallSteps(name, tePath, fcPath, outputDir, os.Geteuid() == 0)
+
+	if dryRun {
+		fmt.Println("\nℹ Dry run, would run:")
+		for _, step := range steps {
+			fmt.Printf("  %s\n", step)
+		}
+		return
+	}
+
+	if missing := selinux.MissingInstallTools(steps); len(missing) > 0 {
+		fmt.Fprintf(os.Stderr, "✗ Cannot install: %s not found (install checkpolicy and policycoreutils)\n",
+			strings.Join(missing, ", "))
+		os.Exit(1)
+	}
+
+	fmt.Printf("\n⟳ Installing module %s (sudo may ask for your password)...\n", name)
+	for _, step := range steps {
+		fmt.Printf("  $ %s\n", step)
+		if err := selinux.RunInstallStep(step); err != nil {
+			fmt.Fprintf(os.Stderr, "✗ Install error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	fmt.Printf("✓ Installed module %s\n", name)
+}
+
+// writeManifest records the hashes of the inputs and outputs of a compilation
+func writeManifest(path string, inputs, outputs []string) error {
+	manifest := compiler.NewManifest(version)
+	for _, input := range inputs {
+		if err := manifest.AddInput(input); err != nil {
+			return err
+		}
+	}
+	for _, output := range outputs {
+		if err := manifest.AddOutput(output); err != nil {
+			return err
+		}
+	}
+	return manifest.Write(path)
+}
+
+// checkComplexityBaseline compares the policy's complexity with the --baseline-stats
+// file and exits when counts grew too much; a missing baseline is written instead
+func checkComplexityBaseline(selinuxPolicy *models.SELinuxPolicy) {
+	complexity := compiler.NewOptimizer(selinuxPolicy).AnalyzeComplexity()
+
+	if _, err := os.Stat(baseStats); os.IsNotExist(err) {
+		if err := compiler.WriteComplexityBaseline(baseStats, complexity); err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+			os.Exit(1)
+		}
+		if !quiet {
+			fmt.Fprintf(progress, "✓ Wrote complexity baseline %s (%d rules, %d types)\n", baseStats, complexity.TotalRules, complexity.TotalTypes)
+		}
+		return
+	}
+
+	baseline, err := compiler.ReadComplexityBaseline(baseStats)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+		os.Exit(1)
+	}
+	grown := compiler.CompareComplexity(baseline, complexity, maxGrowth)
+	if len(grown) > 0 {
+		fmt.Fprintf(os.Stderr, "✗ Policy grew more than %g%% over baseline %s:\n", maxGrowth, baseStats)
+		for _, growth := range grown {
+			fmt.Fprintf(os.Stderr, "  %s\n", growth)
+		}
+		os.Exit(1)
+	}
+	if verbose {
+		fmt.Fprintf(progress, "✓ Complexity within %g%% of baseline %s\n", maxGrowth, baseStats)
+	}
+}
+
+// outputFile is a generated file, named relative to the output directory
+type outputFile struct {
+	name    string
+	content string
+	mode    os.FileMode
+	desc    string // Names the file in write errors, e.g. ".te file"
+}
+
+// generateOutputFiles generates the files of the selected output format,
+// followed by the metrics file and relabel script when requested. Policy
+// files come first, in the order install and the validation hints expect.
+func generateOutputFiles(selinuxPolicy *models.SELinuxPolicy, stats *compiler.AnalysisStats) []outputFile {
+	var files []outputFile
+	if outputFormat == "gosrc" {
+		// Generate Go source embedding the policy
+		goGenerator := selinux.NewGoSourceGenerator(selinuxPolicy)
+		goGenerator.SetPackageName(goPackage)
+		goContent, err := goGenerator.Generate()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✗ Go source generation error: %v\n", err)
+			os.Exit(1)
+		}
+		files = append(files, outputFile{selinuxPolicy.ModuleName + "_policy.go", goContent, 0644, "Go source file"})
+	} else if outputFormat == "cil" {
+		// Generate a CIL module holding rules and file contexts
+		cilContent, err := selinux.NewCILGenerator(selinuxPolicy).Generate()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✗ CIL generation error: %v\n", err)
+			os.Exit(1)
+		}
+		files = append(files, outputFile{selinuxPolicy.ModuleName + ".cil", cilContent, 0644, ".cil file"})
+	} else if compileMode == "monolithic" {
+		files = append(files, generateMonolithicFiles(selinuxPolicy)...)
+	} else {
+		files = append(files, generatePolicyFiles(selinuxPolicy)...)
 	}
 
+	// Generate metrics file
+	if emitMetrics != "" {
+		complexity := compiler.NewOptimizer(selinuxPolicy).AnalyzeComplexity()
+		metrics := compiler.RenderPrometheusMetrics(selinuxPolicy.ModuleName, stats, complexity)
+		files = append(files, outputFile{selinuxPolicy.ModuleName + ".prom", metrics, 0644, "metrics file"})
+	}
+
+	// Generate relabel script
+	if relabelScript {
+		script := selinux.NewSemanageGenerator(selinuxPolicy).GenerateRelabelScript()
+		files = append(files, outputFile{"relabel.sh", script, 0755, "relabel script"})
+	}
+
+	return files
+}
+
+// writeOutputFiles writes the files to the output directory and returns their paths
+func writeOutputFiles(files []outputFile) []string {
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "✗ Failed to create output directory: %v\n", err)
+		os.Exit(1)
+	}
+
+	paths := make([]string, 0, len(files))
+	for _, file := range files {
+		path := fmt.Sprintf("%s/%s", outputDir, file.name)
+		if err := os.WriteFile(path, []byte(file.content), file.mode); err != nil {
+			fmt.Fprintf(os.Stderr, "✗ Failed to write %s: %v\n", file.desc, err)
+			os.Exit(1)
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// generatePolicyFiles generates the .te, .fc and .if files, or only the one
+// selected by --only-rules or --only-contexts
+func generatePolicyFiles(selinuxPolicy *models.SELinuxPolicy) []outputFile {
 	// Generate .te file
 	teGenerator := selinux.NewTEGenerator(selinuxPolicy)
+	teGenerator.SetBasePolicy(basePolicy)
+	teGenerator.SetBooleanStyle(booleanStyle)
+	teGenerator.SetAnnotate(annotate)
 	teContent, err := teGenerator.Generate()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "✗ TE generation error: %v\n", err)
@@ -197,6 +939,9 @@ func runCompile(cmd *cobra.Command, args []string) {
 
 	// Generate .fc file
 	fcGenerator := selinux.NewFCGenerator(selinuxPolicy)
+	fcGenerator.SetPreserveOrder(noOptimizeContexts)
+	fcGenerator.SetContextStyle(fcStyle)
+	fcGenerator.SetHomeDirTemplate(homeDirTmpl)
 	fcContent, err := fcGenerator.Generate()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "✗ FC generation error: %v\n", err)
@@ -205,54 +950,444 @@ func runCompile(cmd *cobra.Command, args []string) {
 
 	// Generate .if file
 	ifGenerator := selinux.NewIFGenerator(selinuxPolicy)
+	ifGenerator.SetBooleanStyle(booleanStyle)
+	ifGenerator.SetInterfacePrefix(ifacePrefix)
 	ifContent, err := ifGenerator.Generate()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "✗ IF generation error: %v\n", err)
 		os.Exit(1)
 	}
 
-	// Create output directory
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		fmt.Fprintf(os.Stderr, "✗ Failed to create output directory: %v\n", err)
+	te := outputFile{selinuxPolicy.ModuleName + ".te", teContent, 0644, ".te file"}
+	fc := outputFile{selinuxPolicy.ModuleName + ".fc", fcContent, 0644, ".fc file"}
+	switch {
+	case onlyRules:
+		return []outputFile{te}
+	case onlyContexts:
+		return []outputFile{fc}
+	}
+	return []outputFile{te, fc, {selinuxPolicy.ModuleName + ".if", ifContent, 0644, ".if file"}}
+}
+
+// generateMonolithicFiles generates policy.conf and, since policy.conf has no
+// file context syntax, the file contexts as a file_contexts file with literal contexts
+func generateMonolithicFiles(selinuxPolicy *models.SELinuxPolicy) []outputFile {
+	monoGenerator := selinux.NewMonolithicGenerator(selinuxPolicy)
+	monoGenerator.SetAnnotate(annotate)
+	confContent, err := monoGenerator.Generate()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✗ policy.conf generation error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fcGenerator := selinux.NewFCGenerator(selinuxPolicy)
+	fcGenerator.SetPreserveOrder(noOptimizeContexts)
+	fcGenerator.SetContextStyle(selinux.FCContextStyleLiteral)
+	fcContent, err := fcGenerator.Generate()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✗ FC generation error: %v\n", err)
+		os.Exit(1)
+	}
+
+	return []outputFile{
+		{"policy.conf", confContent, 0644, "policy.conf"},
+		{"file_contexts", fcContent, 0644, "file_contexts"},
+	}
+}
+
+func runEquiv(cmd *cobra.Command, args []string) {
+	policyA, skippedA, err := compiler.ParseTEModuleFile(equivA)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✗ Parse error: %v\n", err)
+		os.Exit(1)
+	}
+	policyB, skippedB, err := compiler.ParseTEModuleFile(equivB)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✗ Parse error: %v\n", err)
+		os.Exit(1)
+	}
+	for _, stmt := range skippedA {
+		fmt.Fprintf(os.Stderr, "Warning: %s: not compared: %s\n", equivA, stmt)
+	}
+	for _, stmt := range skippedB {
+		fmt.Fprintf(os.Stderr, "Warning: %s: not compared: %s\n", equivB, stmt)
+	}
+
+	result := compiler.CheckEquivalence(policyA, policyB)
+	fmt.Print(compiler.FormatEquivalence(result))
+	if !result.Equivalent {
+		os.Exit(1)
+	}
+}
+
+func runQuery(cmd *cobra.Command, args []string) {
+	parser := compiler.NewParser(modelPath, policyPath)
+	pml, err := parser.Parse()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✗ Parse error: %v\n", err)
+		os.Exit(1)
+	}
+	decoded, err := parser.Decode(pml)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✗ Decoding error: %v\n", err)
+		os.Exit(1)
+	}
+	analyzer := compiler.NewAnalyzer(decoded)
+	if err := analyzer.Analyze(); err != nil {
+		fmt.Fprintf(os.Stderr, "✗ Analysis error: %v\n", err)
+		os.Exit(1)
+	}
+	decoded = analyzer.Resolved()
+	policy, err := compiler.NewGenerator(decoded, moduleName).Generate()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✗ Generation error: %v\n", err)
 		os.Exit(1)
 	}
 
-	// Write .te file
-	tePath := fmt.Sprintf("%s/%s.te", outputDir, selinuxPolicy.ModuleName)
-	if err := os.WriteFile(tePath, []byte(teContent), 0644); err != nil {
-		fmt.Fprintf(os.Stderr, "✗ Failed to write .te file: %v\n", err)
+	result := compiler.Query(policy, decoded, querySubject, queryObject, queryAction)
+	if !result.Allowed {
+		fmt.Printf("✗ %s\n", result.Reason)
 		os.Exit(1)
 	}
+	fmt.Printf("✓ %s\n", result.Reason)
+}
 
-	// Write .fc file
-	fcPath := fmt.Sprintf("%s/%s.fc", outputDir, selinuxPolicy.ModuleName)
-	if err := os.WriteFile(fcPath, []byte(fcContent), 0644); err != nil {
-		fmt.Fprintf(os.Stderr, "✗ Failed to write .fc file: %v\n", err)
+func runDiff(cmd *cobra.Command, args []string) {
+	if !againstInstalled {
+		fmt.Fprintf(os.Stderr, "✗ diff needs --against-installed\n")
 		os.Exit(1)
 	}
 
-	// Write .if file
-	ifPath := fmt.Sprintf("%s/%s.if", outputDir, selinuxPolicy.ModuleName)
-	if err := os.WriteFile(ifPath, []byte(ifContent), 0644); err != nil {
-		fmt.Fprintf(os.Stderr, "✗ Failed to write .if file: %v\n", err)
+	parser := compiler.NewParser(modelPath, policyPath)
+	pml, err := parser.Parse()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✗ Parse error: %v\n", err)
+		os.Exit(1)
+	}
+	decoded, err := parser.Decode(pml)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✗ Decoding error: %v\n", err)
+		os.Exit(1)
+	}
+	policy, err := compiler.NewGenerator(decoded, moduleName).Generate()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✗ Generation error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := compiler.NewOptimizer(policy).Optimize(); err != nil {
+		fmt.Fprintf(os.Stderr, "✗ Optimization error: %v\n", err)
+		os.Exit(1)
+	}
+
+	installedTypes, err := selinux.LoadInstalledTypes()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+		os.Exit(1)
+	}
+	// Only module types the kernel knows can be queried for rules
+	sourceTypes := make([]string, 0, len(policy.Types))
+	for _, typeDecl := range policy.Types {
+		if installedTypes[typeDecl.TypeName] {
+			sourceTypes = append(sourceTypes, typeDecl.TypeName)
+		}
+	}
+	installedRules, err := selinux.LoadInstalledRules(sourceTypes)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✗ %v\n", err)
 		os.Exit(1)
 	}
 
-	fmt.Printf("✓ Compilation successful!\n")
-	fmt.Printf("  Generated: %s\n", tePath)
-	fmt.Printf("  Generated: %s\n", fcPath)
-	fmt.Printf("  Generated: %s\n", ifPath)
+	diff := selinux.DiffInstalled(policy, installedTypes, installedRules)
+	if diff.Empty() {
+		fmt.Printf("✓ Installed policy matches the source of module %s\n", policy.ModuleName)
+		return
+	}
+	for _, typeName := range diff.MissingTypes {
+		fmt.Printf("+ type %s;\n", typeName)
+	}
+	for _, rule := range diff.NotInstalled {
+		fmt.Printf("+ %s\n", rule)
+	}
+	for _, rule := range diff.Drift {
+		fmt.Printf("- %s\n", rule)
+	}
+	fmt.Printf("\n%d types and %d rules need applying, %d installed rules are not in the source (drift)\n",
+		len(diff.MissingTypes), len(diff.NotInstalled), len(diff.Drift))
+	os.Exit(1)
+}
 
-	if validate {
-		fmt.Println("\nℹ To validate and install the policy, run:")
-		fmt.Printf("  checkmodule -M -m -o %s.mod %s\n", selinuxPolicy.ModuleName, tePath)
-		fmt.Printf("  semodule_package -o %s.pp -m %s.mod -fc %s\n",
-			selinuxPolicy.ModuleName, selinuxPolicy.ModuleName, fcPath)
-		fmt.Printf("  sudo semodule -i %s.pp\n", selinuxPolicy.ModuleName)
+func runTransitions(cmd *cobra.Command, args []string) {
+	parser := compiler.NewParser(modelPath, policyPath)
+	pml, err := parser.Parse()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✗ Parse error: %v\n", err)
+		os.Exit(1)
+	}
+	decoded, err := parser.Decode(pml)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✗ Decoding error: %v\n", err)
+		os.Exit(1)
+	}
+	policy, err := compiler.NewGenerator(decoded, moduleName).Generate()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✗ Generation error: %v\n", err)
+		os.Exit(1)
+	}
+
+	reports := compiler.ReportTransitions(policy)
+	if len(reports) == 0 {
+		fmt.Println("No type transitions")
+		return
+	}
+
+	mark := func(report compiler.TransitionReport, ok bool) string {
+		switch {
+		case !report.IsDomainTransition():
+			return "-"
+		case ok:
+			return "✓"
+		default:
+			return "✗"
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SOURCE\t\tENTRY POINT\t\tNEW TYPE\tCLASS\tEXECUTE\tTRANSITION\tENTRYPOINT")
+	missing := 0
+	for _, report := range reports {
+		fmt.Fprintf(w, "%s\t→\t%s\t→\t%s\t%s\t%s\t%s\t%s\n",
+			report.SourceType, report.EntryPoint, report.NewType, report.Class,
+			mark(report, report.Execute), mark(report, report.Transition), mark(report, report.Entrypoint))
+		missing += len(report.Missing())
+	}
+	w.Flush()
+
+	if missing > 0 {
+		fmt.Println()
+		for _, report := range reports {
+			for _, rule := range report.Missing() {
+				fmt.Printf("⚠ Warning: %s → %s is missing '%s'\n", report.SourceType, report.NewType, rule)
+			}
+		}
+		os.Exit(1)
+	}
+}
+
+func runLintSource(cmd *cobra.Command, args []string) {
+	parser := compiler.NewParser("", policyPath)
+	if policyDir != "" {
+		parser.SetPolicyDir(policyDir)
+	}
+	policies, err := parser.ParsePolicies()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✗ Parse error: %v\n", err)
+		os.Exit(1)
+	}
+
+	issues := compiler.LintSource(policies)
+	if len(issues) == 0 {
+		fmt.Printf("✓ No duplicate or redundant rules in %d policies\n", len(policies))
+		return
+	}
+
+	fmt.Printf("⚠ Warning: Found %d duplicate or redundant rules\n", len(issues))
+	for _, issue := range issues {
+		fmt.Printf("  %s\n", issue)
+	}
+	os.Exit(1)
+}
+
+func runLint(cmd *cobra.Command, args []string) {
+	threshold := compiler.Severity(failOn)
+	if threshold != compiler.SeverityError && threshold != compiler.SeverityWarning {
+		fmt.Fprintf(os.Stderr, "✗ Unsupported --fail-on severity '%s' (supported: error, warning)\n", failOn)
+		os.Exit(1)
+	}
+
+	parser := compiler.NewParser(modelPath, policyPath)
+	if policyDir != "" {
+		parser.SetPolicyDir(policyDir)
+	}
+	pml, err := parser.Parse()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✗ Parse error: %v\n", err)
+		os.Exit(1)
+	}
+
+	opts := compiler.LintOptions{
+		Disable:     disableLints,
+		StrictPaths: strictPaths,
+	}
+	findings := compiler.FilterFindingsByRisk(compiler.LintPolicy(pml, opts), minRisk)
+	if len(findings) == 0 {
+		fmt.Printf("✓ No findings in %d policies\n", len(pml.Policies))
+		return
+	}
+
+	fmt.Printf("⚠ Found %d findings (total risk %d)\n", len(findings), compiler.TotalRisk(findings))
+	for _, finding := range findings {
+		fmt.Printf("  %s\n", finding)
+	}
+	if compiler.FailsAt(findings, threshold) {
+		os.Exit(1)
+	}
+}
+
+func runImport(cmd *cobra.Command, args []string) {
+	if importSpec != "" {
+		importFromPodSpec()
+		return
+	}
+
+	denials, err := compiler.ParseAVCFile(importInput)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✗ Parse error: %v\n", err)
+		os.Exit(1)
 	}
+	if len(denials) == 0 {
+		fmt.Fprintf(os.Stderr, "✗ No AVC denials found in %s\n", importInput)
+		os.Exit(1)
+	}
+
+	policy := compiler.RenderAVCPolicy(denials, importCounts)
+	if importOutput == "" {
+		fmt.Print(policy)
+		return
+	}
+	if err := os.WriteFile(importOutput, []byte(policy), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "✗ Failed to write policy: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✓ Wrote %d rules to %s\n", len(denials), importOutput)
+}
+
+// importFromPodSpec writes a starter policy for the volume mounts of a pod spec
+func importFromPodSpec() {
+	mounts, err := compiler.ParsePodSpecFile(importSpec)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✗ Parse error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(mounts) == 0 {
+		fmt.Fprintf(os.Stderr, "✗ No volume mounts found in %s\n", importSpec)
+		os.Exit(1)
+	}
+
+	policy := compiler.RenderPodSpecPolicy(mounts)
+	if importOutput == "" {
+		fmt.Print(policy)
+		return
+	}
+	if err := os.WriteFile(importOutput, []byte(policy), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "✗ Failed to write policy: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✓ Wrote rules for %d volume mounts to %s\n", len(mounts), importOutput)
+}
+
+func runExportMappings(cmd *cobra.Command, args []string) {
+	loadActionMappings()
+	content, err := yaml.Marshal(mapping.NewActionMapper().ExportMappings())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✗ Failed to export mappings: %v\n", err)
+		os.Exit(1)
+	}
+
+	if exportOutput == "" {
+		fmt.Print(string(content))
+		return
+	}
+	if err := os.WriteFile(exportOutput, content, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "✗ Failed to write action map: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✓ Wrote action map to %s\n", exportOutput)
+}
+
+func runDecompile(cmd *cobra.Command, args []string) {
+	content, err := os.ReadFile(decompileInput)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✗ Failed to read %s: %v\n", decompileInput, err)
+		os.Exit(1)
+	}
+	te, skipped, err := compiler.ParseTEModule(string(content))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✗ Parse error: %s: %v\n", decompileInput, err)
+		os.Exit(1)
+	}
+
+	result := compiler.Reverse(te)
+	for _, stmt := range skipped {
+		result.Warnings = append(result.Warnings, "skipped "+stmt)
+	}
+	for _, warning := range result.Warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+
+	policy := compiler.RenderReversePolicy(result)
+	if decompileOutput == "" {
+		fmt.Print(policy)
+		return
+	}
+	if err := os.WriteFile(decompileOutput, []byte(policy), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "✗ Failed to write policy: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✓ Wrote %d rules to %s\n", len(result.Policies), decompileOutput)
+}
+
+// newCompileParser returns a parser for policyPath with the --class-map
+// mappings applied, so the module and its --base-module decode alike
+func newCompileParser(policyPath string) *compiler.Parser {
+	parser := compiler.NewParser(modelPath, policyPath)
+	if classMapPath != "" {
+		classMap, err := compiler.LoadClassMap(classMapPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✗ Class map error: %v\n", err)
+			os.Exit(1)
+		}
+		parser.SetClassMap(classMap)
+	}
+	return parser
+}
+
+// loadClassPermissions adds the --class-perms classes to the permission table
+func loadClassPermissions() {
+	if permsPath == "" {
+		return
+	}
+	if err := compiler.LoadClassPermissions(permsPath); err != nil {
+		fmt.Fprintf(os.Stderr, "✗ Class permissions error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// loadActionMappings registers the --action-map actions with every action mapper
+func loadActionMappings() {
+	if actionMapPath == "" {
+		return
+	}
+	config, err := compiler.LoadActionMappings(actionMapPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✗ Action map error: %v\n", err)
+		os.Exit(1)
+	}
+	mapping.RegisterActionMappings(config)
 }
 
 func runValidate(cmd *cobra.Command, args []string) {
+	if compatMode != "" && compatMode != "casbin" {
+		fmt.Fprintf(os.Stderr, "✗ Invalid --compat value '%s', must be 'casbin'\n", compatMode)
+		os.Exit(1)
+	}
+	loadClassPermissions()
+	loadActionMappings()
+
+	if countOnly {
+		runValidateCountOnly()
+		return
+	}
+
 	if verbose {
 		fmt.Println("Validating PML files...")
 	}
@@ -272,27 +1407,141 @@ func runValidate(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
-	// Analyze
+	// Analyze, leaving the lints to the ranked findings below
 	analyzer := compiler.NewAnalyzer(decoded)
+	configureAnalyzer(analyzer)
+	for _, name := range compiler.LintNames() {
+		analyzer.DisableLint(name)
+	}
 	err = analyzer.Analyze()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "✗ Validation failed: %v\n", err)
 		os.Exit(1)
 	}
+	findings := rankedFindings(pml.Policies)
 
 	stats := analyzer.GetStats()
 	fmt.Println("✓ Validation successful!")
 	fmt.Printf("  Total policies: %d\n", stats.TotalPolicies)
 	fmt.Printf("  Allow rules:    %d\n", stats.AllowRules)
 	fmt.Printf("  Deny rules:     %d\n", stats.DenyRules)
+	if stats.DontauditRules > 0 {
+		fmt.Printf("  Dontaudit:      %d\n", stats.DontauditRules)
+	}
+	if stats.Booleans > 0 {
+		fmt.Printf("  Booleans:       %d\n", stats.Booleans)
+	}
 
 	if stats.Conflicts > 0 {
 		fmt.Printf("\n⚠ Warning: Found %d potential conflicts\n", stats.Conflicts)
 		conflicts := analyzer.GetConflicts()
 		for i, conflict := range conflicts {
 			fmt.Printf("  %d. %s\n", i+1, conflict.Reason)
+			if explainConflict {
+				explanation := compiler.ExplainConflict(conflict)
+				fmt.Printf("     allow: %-30s → %s\n", explanation.AllowObject, explanation.AllowPattern)
+				fmt.Printf("     deny:  %-30s → %s\n", explanation.DenyObject, explanation.DenyPattern)
+				if explanation.SamplePath != "" {
+					fmt.Printf("     both match: %s\n", explanation.SamplePath)
+				} else {
+					fmt.Printf("     both match: no sample path found (overlap is by shared directory)\n")
+				}
+			}
 		}
 	}
+
+	if len(findings) > 0 {
+		fmt.Printf("\n⚠ Warning: Found %d lint findings (total risk %d)\n", len(findings), compiler.TotalRisk(findings))
+		for _, finding := range findings {
+			fmt.Printf("  [%3d] %s\n", finding.Risk, finding)
+		}
+	}
+
+	compatIssues := compatibilityIssues(pml)
+	if len(compatIssues) > 0 {
+		fmt.Printf("\n⚠ Warning: Found %d uses of extensions to %s semantics\n", len(compatIssues), compatMode)
+		for _, issue := range compatIssues {
+			fmt.Printf("  %s\n", issue)
+		}
+	}
+
+	if failOnWarning && (len(analyzer.GetWarnings()) > 0 || len(findings) > 0 || len(compatIssues) > 0) {
+		os.Exit(1)
+	}
+}
+
+// compatibilityIssues checks the policies against the --compat target, if any
+func compatibilityIssues(pml *models.ParsedPML) []compiler.CompatIssue {
+	if compatMode == "casbin" {
+		return compiler.CheckCasbinCompat(pml)
+	}
+	return nil
+}
+
+// configureAnalyzer applies the lint and path options shared by compile and validate
+func configureAnalyzer(analyzer *compiler.Analyzer) {
+	if warnBroad {
+		analyzer.SetBroadPermsThreshold(compiler.DefaultBroadPermsThreshold)
+	}
+	analyzer.SetStrictPaths(strictPaths)
+	for _, name := range enableLints {
+		if err := analyzer.EnableLint(name); err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+			os.Exit(1)
+		}
+	}
+	for _, name := range disableLints {
+		if err := analyzer.DisableLint(name); err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
+// rankedFindings lints the policies with the validate flags and returns the
+// findings of at least --min-risk, highest risk first
+func rankedFindings(policies []models.Policy) []compiler.Finding {
+	opts := compiler.LintOptions{
+		Enable:      enableLints,
+		Disable:     disableLints,
+		StrictPaths: strictPaths,
+	}
+	if warnBroad {
+		opts.BroadPermsThreshold = compiler.DefaultBroadPermsThreshold
+	}
+	findings := compiler.FilterFindingsByRisk(compiler.Lint(policies, opts), minRisk)
+	compiler.SortFindingsByRisk(findings)
+	return findings
+}
+
+// runValidateCountOnly runs the full validation but prints a single summary line
+func runValidateCountOnly() {
+	errorCount, warningCount, conflictCount := 0, 0, 0
+
+	parser := compiler.NewParser(modelPath, policyPath)
+	pml, err := parser.Parse()
+	if err == nil {
+		var decoded *models.DecodedPML
+		decoded, err = parser.Decode(pml)
+		if err == nil {
+			analyzer := compiler.NewAnalyzer(decoded)
+			configureAnalyzer(analyzer)
+			analyzer.SetQuiet(true)
+			analyzer.Analyze()
+			errorCount = len(analyzer.GetErrors())
+			warningCount = len(analyzer.GetWarnings()) + len(compatibilityIssues(pml))
+			conflictCount = len(analyzer.GetConflicts())
+		}
+	}
+	if err != nil && errorCount == 0 {
+		errorCount = 1
+	}
+
+	fmt.Printf("%d errors, %d warnings, %d conflicts\n", errorCount, warningCount, conflictCount)
+
+	if errorCount > 0 || (failOnWarning && warningCount > 0) {
+		os.Exit(1)
+	}
 }
 
 func runInit(cmd *cobra.Command, args []string) {
@@ -305,6 +1554,20 @@ func runInit(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
+	if initTemplate != "" {
+		generated, err := compiler.RenderProjectTemplates(initTemplate, projectName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✗ Template error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("✓ Project created from %s!\n\n", initTemplate)
+		for _, path := range generated {
+			fmt.Printf("  Generated: %s\n", path)
+		}
+		return
+	}
+
 	// Template model file
 	modelTemplate := `[request_definition]
 r = sub, obj, act, class
@@ -347,6 +1610,13 @@ p, ` + projectName + `_t, /etc/passwd, write, file, deny
 # Role relations example (optional)
 # g, user_u, user_r
 # g2, ` + projectName + `_t, domain
+
+# Role declaration and role change example (optional)
+# role, ` + projectName + `_r
+# ra, user_r, ` + projectName + `_r
+
+# Pseudo-filesystem label example (optional)
+# genfs, proc, /sys/kernel/` + projectName + `, ` + projectName + `_proc_t
 `
 
 	// Template README
@@ -2,9 +2,13 @@ package compiler
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 
+	"github.com/cici0602/pml-to-selinux/mapping"
 	"github.com/cici0602/pml-to-selinux/models"
 )
 
@@ -12,15 +16,40 @@ import (
 type Analyzer struct {
 	decoded   *models.DecodedPML
 	errors    []error
+	warnings  []string
 	stats     *AnalysisStats
 	conflicts []ConflictInfo
+	resolved  []models.DecodedPolicy // policies left after conflict resolution
+	quiet     bool                   // suppress printing warnings as they are found
+	out       io.Writer              // receives printed warnings, os.Stdout by default
+	effect    PolicyEffect           // conflict resolution declared by the model's policy_effect
+
+	// broadPermsThreshold enables the broad-permission lint when positive
+	broadPermsThreshold int
+
+	// lintOverrides enables or disables lints by name, overriding their defaults
+	lintOverrides map[string]bool
+
+	// strictPaths requires objects to be clean absolute paths or recognized special forms
+	strictPaths bool
+
+	// levelMapper resolves named security levels and categories
+	levelMapper *mapping.LevelMapper
 }
 
+// DefaultBroadPermsThreshold is the breadth score above which a rule is reported as over-privileged.
+// It flags e.g. the full file permission set on a directory tree three levels below root.
+const DefaultBroadPermsThreshold = 64
+
+// broadDepthLimit is the literal path depth at which a wildcard stops widening a target
+const broadDepthLimit = 5
+
 // AnalysisStats contains statistics about the analyzed policy
 type AnalysisStats struct {
 	TotalPolicies  int
 	AllowRules     int
 	DenyRules      int // Deprecated in MVP, kept for backward compatibility
+	DontauditRules int // Rules silencing denials, which grant nothing
 	UniqueSubjects int
 	UniqueObjects  int
 	UniqueActions  int
@@ -40,11 +69,54 @@ type ConflictInfo struct {
 	Reason    string
 }
 
+// ReasonDenyShadowed starts the reason of a conflict whose deny rule is
+// unreachable: every path it matches is also matched by a broader allow
+const ReasonDenyShadowed = "deny shadowed by allow"
+
+// PolicyEffect is the conflict resolution declared by the model's policy_effect
+type PolicyEffect int
+
+const (
+	// EffectAllowOverride keeps an allow even when a deny matches: some(where (p.eft == allow))
+	EffectAllowOverride PolicyEffect = iota
+	// EffectDenyOverride lets any matching deny win: ... && !some(where (p.eft == deny))
+	EffectDenyOverride
+	// EffectPriority lets the rule listed first win: priority(p.eft) || deny
+	EffectPriority
+)
+
+// String returns the conventional Casbin name of the effect
+func (e PolicyEffect) String() string {
+	switch e {
+	case EffectDenyOverride:
+		return "deny-override"
+	case EffectPriority:
+		return "priority"
+	default:
+		return "allow-override"
+	}
+}
+
+// ParsePolicyEffect recognizes the standard Casbin policy_effect expressions
+func ParsePolicyEffect(expr string) (PolicyEffect, error) {
+	switch strings.Join(strings.Fields(expr), "") {
+	case "some(where(p.eft==allow))":
+		return EffectAllowOverride, nil
+	case "some(where(p.eft==allow))&&!some(where(p.eft==deny))", "!some(where(p.eft==deny))":
+		return EffectDenyOverride, nil
+	case "priority(p.eft)||deny":
+		return EffectPriority, nil
+	}
+	return EffectAllowOverride, fmt.Errorf("unsupported policy_effect '%s'", expr)
+}
+
 // NewAnalyzer creates a new analyzer instance
 func NewAnalyzer(decoded *models.DecodedPML) *Analyzer {
 	return &Analyzer{
-		decoded: decoded,
-		errors:  make([]error, 0),
+		decoded:     decoded,
+		errors:      make([]error, 0),
+		out:         os.Stdout,
+		levelMapper: mapping.NewLevelMapper(),
 		stats: &AnalysisStats{
 			SubjectTypes:   make(map[string]int),
 			ObjectPatterns: make(map[string]int),
@@ -57,6 +129,7 @@ func NewAnalyzer(decoded *models.DecodedPML) *Analyzer {
 func (a *Analyzer) Analyze() error {
 	// Validate model completeness
 	if err := a.validateModel(); err != nil {
+		a.errors = append(a.errors, err)
 		return err
 	}
 
@@ -65,6 +138,15 @@ func (a *Analyzer) Analyze() error {
 		return err
 	}
 
+	// Check MLS dominance between subject clearances and object levels
+	if err := a.validateMLSDominance(); err != nil {
+		return err
+	}
+
+	// Determine how allow/deny conflicts are resolved; an unrecognized
+	// effect keeps the allow-override behavior
+	a.effect, _ = ParsePolicyEffect(a.decoded.Model.Effect)
+
 	// Detect policy conflicts
 	a.conflicts = a.detectConflicts()
 	if len(a.conflicts) > 0 {
@@ -75,9 +157,15 @@ func (a *Analyzer) Analyze() error {
 		}
 	}
 
+	// Drop allow rules that lose to a deny under the declared effect
+	a.resolveConflicts()
+
 	// Generate statistics
 	a.generateStats()
 
+	// Run the enabled lints
+	a.runLints()
+
 	return nil
 }
 
@@ -127,39 +215,82 @@ func (a *Analyzer) validateModel() error {
 }
 
 // validatePolicies checks if all policy rules are valid
+// Every invalid rule is recorded in a.errors; the first one is returned.
 func (a *Analyzer) validatePolicies() error {
-	validEffects := map[string]bool{"allow": true, "deny": true}
+	var firstErr error
 
 	for i, policy := range a.decoded.Policies {
-		// Check if subject is not empty
-		if policy.Subject == "" {
-			return fmt.Errorf("policy rule %d: subject cannot be empty", i+1)
+		if err := a.validatePolicy(i, policy); err != nil {
+			err = sourceError(policy.Policy, err)
+			a.errors = append(a.errors, err)
+			if firstErr == nil {
+				firstErr = err
+			}
 		}
+	}
 
-		// Check if object is not empty
-		if policy.Object == "" {
-			return fmt.Errorf("policy rule %d: object cannot be empty", i+1)
-		}
+	return firstErr
+}
 
-		// Check if action is not empty
-		if policy.Action == "" {
-			return fmt.Errorf("policy rule %d: action cannot be empty", i+1)
-		}
+// validatePolicy checks a single policy rule
+func (a *Analyzer) validatePolicy(i int, policy models.DecodedPolicy) error {
+	validEffects := map[string]bool{"allow": true, "deny": true, "dontaudit": true}
+
+	// Check if subject is not empty
+	if policy.Subject == "" {
+		return fmt.Errorf("policy rule %d: subject cannot be empty", i+1)
+	}
+
+	// Check if object is not empty
+	if policy.Object == "" {
+		return fmt.Errorf("policy rule %d: object cannot be empty", i+1)
+	}
+
+	// Check if action is not empty
+	if policy.Action == "" {
+		return fmt.Errorf("policy rule %d: action cannot be empty", i+1)
+	}
 
-		// Check if class is not empty
-		if policy.Class == "" {
-			return fmt.Errorf("policy rule %d: class cannot be empty", i+1)
+	// Check if class is not empty
+	if policy.Class == "" {
+		return fmt.Errorf("policy rule %d: class cannot be empty", i+1)
+	}
+
+	// Check if effect is valid (skip validation for transition rules)
+	if policy.Type == "p2" && policy.Action == "transition" {
+		// For transition rules, effect is actually the new_type, so don't validate it as allow/deny
+	} else if !validEffects[policy.Effect] {
+		return fmt.Errorf("policy rule %d: invalid effect '%s', must be 'allow', 'deny' or 'dontaudit'", i+1, policy.Effect)
+	}
+
+	// A transition back into the source domain is always a modeling error
+	if policy.IsTransition && policy.TransitionInfo != nil {
+		typeMapper := mapping.NewTypeMapper("")
+		trans := policy.TransitionInfo
+		if typeMapper.SubjectToType(trans.SourceType) == typeMapper.SubjectToType(trans.NewType) {
+			return fmt.Errorf("policy rule %d: self-transition: %s transitions to itself on %s:%s",
+				i+1, trans.SourceType, trans.TargetType, trans.Class)
 		}
+	}
 
-		// Check if effect is valid (skip validation for transition rules)
-		if policy.Type == "p2" && policy.Action == "transition" {
-			// For transition rules, effect is actually the new_type, so don't validate it as allow/deny
-		} else if !validEffects[policy.Effect] {
-			return fmt.Errorf("policy rule %d: invalid effect '%s', must be 'allow' or 'deny'", i+1, policy.Effect)
+	// A raw permission must exist in its class; mapped actions only grant permissions
+	// that do, and "*" and "manage" stand for the full set (see permissionCount)
+	if !policy.IsTransition && policy.Action != "*" && policy.Action != "manage" {
+		actionMapper := mapping.NewActionMapper()
+		if !actionMapper.IsMappedAction(policy.Action) {
+			_, perms := actionMapper.MapAction(policy.Action, policy.Class)
+			if unknown := mapping.ValidatePermissions(policy.Class, perms); len(unknown) > 0 {
+				return fmt.Errorf("policy rule %d: class %s has no permission '%s'", i+1, policy.Class, strings.Join(unknown, "', '"))
+			}
 		}
+	}
 
-		// Validate path patterns
-		if err := a.validatePathPattern(policy.Object); err != nil {
+	// Validate path patterns
+	if err := a.validatePathPattern(policy.Object); err != nil {
+		return fmt.Errorf("policy rule %d: invalid object pattern '%s': %w", i+1, policy.Object, err)
+	}
+	if a.strictPaths {
+		if err := a.validateStrictPath(policy.Object); err != nil {
 			return fmt.Errorf("policy rule %d: invalid object pattern '%s': %w", i+1, policy.Object, err)
 		}
 	}
@@ -202,6 +333,105 @@ func (a *Analyzer) validatePathPattern(pattern string) error {
 	return nil
 }
 
+// validateStrictPath requires a special form (self, a port, a type or a g2 attribute)
+// or an absolute path with no '.' or '..' components that is already normalized
+func (a *Analyzer) validateStrictPath(pattern string) error {
+	if pattern == "self" || strings.HasPrefix(pattern, "tcp:") || strings.HasPrefix(pattern, "udp:") ||
+		strings.HasPrefix(pattern, "packet:") || isAllDigits(pattern) {
+		return nil
+	}
+
+	if !strings.HasPrefix(pattern, "/") {
+		if strings.HasSuffix(pattern, "_t") || a.isTypeAttribute(pattern) {
+			return nil
+		}
+		return fmt.Errorf("strict paths: object must be an absolute path, a port, a packet type, self, a type or an attribute")
+	}
+
+	for _, component := range strings.Split(pattern, "/") {
+		if component == "." || component == ".." {
+			return fmt.Errorf("strict paths: '%s' path components are not allowed", component)
+		}
+	}
+	if normalized := mapping.NormalizePath(pattern); normalized != pattern {
+		return fmt.Errorf("strict paths: path is not normalized, use '%s'", normalized)
+	}
+
+	return nil
+}
+
+// isTypeAttribute reports whether name is an attribute declared by a g2 relation
+func (a *Analyzer) isTypeAttribute(name string) bool {
+	for _, attr := range a.decoded.TypeAttributes {
+		if attr.Role == name {
+			return true
+		}
+	}
+	return false
+}
+
+// mlsReadPermissions observe an object: the subject must dominate it (no read-up)
+var mlsReadPermissions = map[string]bool{
+	"read": true, "getattr": true, "search": true, "execute": true, "map": true, "ioctl": true,
+}
+
+// mlsWritePermissions modify an object: the object must dominate the subject (no write-down)
+var mlsWritePermissions = map[string]bool{
+	"write": true, "append": true, "create": true, "setattr": true, "unlink": true, "link": true,
+	"rename": true, "add_name": true, "remove_name": true, "rmdir": true, "reparent": true,
+}
+
+// validateMLSDominance checks each allow rule whose subject and object both carry a level:
+// for read-type permissions the subject's clearance (high level) must dominate the
+// object's level, and for write-type permissions the object's level must dominate
+// the subject's current (low) level
+func (a *Analyzer) validateMLSDominance() error {
+	actionMapper := mapping.NewActionMapper()
+	levelMapper := a.levelMapper
+	var firstErr error
+
+	for i, policy := range a.decoded.Policies {
+		if policy.Effect != "allow" || policy.IsTransition || policy.SubjectLevel == "" || policy.Level == "" {
+			continue
+		}
+		// Unparsable levels are reported by the generator
+		subjectRange, err := levelMapper.MapRange(policy.SubjectLevel)
+		if err != nil {
+			continue
+		}
+		objectRange, err := levelMapper.MapRange(policy.Level)
+		if err != nil {
+			continue
+		}
+		objectLevel := objectRange.Low
+
+		reads, writes := false, false
+		_, perms := actionMapper.MapAction(policy.Action, policy.Class)
+		for _, perm := range perms {
+			reads = reads || mlsReadPermissions[perm]
+			writes = writes || mlsWritePermissions[perm]
+		}
+
+		var violation error
+		if reads && !subjectRange.High.Dominates(objectLevel) {
+			violation = fmt.Errorf("policy rule %d: MLS read-up: subject '%s' clearance %s does not dominate object '%s' level %s",
+				i+1, policy.Subject, subjectRange.High, policy.Object, objectLevel)
+		} else if writes && !objectLevel.Dominates(subjectRange.Low) {
+			violation = fmt.Errorf("policy rule %d: MLS write-down: object '%s' level %s does not dominate subject '%s' level %s",
+				i+1, policy.Object, objectLevel, policy.Subject, subjectRange.Low)
+		}
+		if violation != nil {
+			violation = sourceError(policy.Policy, violation)
+			a.errors = append(a.errors, violation)
+			if firstErr == nil {
+				firstErr = violation
+			}
+		}
+	}
+
+	return firstErr
+}
+
 // isAllDigits checks if a string contains only digits
 func isAllDigits(s string) bool {
 	if s == "" {
@@ -221,14 +451,17 @@ func isValidPathChar(ch rune) bool {
 		(ch >= 'A' && ch <= 'Z') ||
 		(ch >= '0' && ch <= '9') ||
 		ch == '/' || ch == '*' || ch == '.' || ch == '-' || ch == '_' ||
-		ch == '(' || ch == ')' || ch == '?' || ch == '=' || ch == ':' // Allow regex chars and port patterns
+		ch == '(' || ch == ')' || ch == '?' || ch == '=' || ch == ':' || // Allow regex chars and port patterns
+		ch == '{' || ch == '}' || ch == ',' || // Brace alternatives, e.g. /dev/{sda,null}
+		ch == '[' || ch == ']' || ch == '\\' // Character classes and escaped literals, e.g. /srv/file\[1\]
 }
 
 // detectConflicts finds conflicting allow and deny rules
 func (a *Analyzer) detectConflicts() []ConflictInfo {
 	var conflicts []ConflictInfo
 
-	// Group policies by subject for efficient comparison
+	// Group policies by subject for efficient comparison; dontaudit rules
+	// grant nothing and never conflict
 	allowRules := make(map[string][]models.DecodedPolicy)
 	denyRules := make(map[string][]models.DecodedPolicy)
 
@@ -250,14 +483,20 @@ func (a *Analyzer) detectConflicts() []ConflictInfo {
 
 		for _, allowRule := range allows {
 			for _, denyRule := range denies {
-				if a.rulesConflict(allowRule, denyRule) {
-					conflicts = append(conflicts, ConflictInfo{
-						AllowRule: allowRule,
-						DenyRule:  denyRule,
-						Reason: fmt.Sprintf("Allow and deny rules conflict for subject '%s', object '%s', action '%s', class '%s'",
-							subject, allowRule.Object, allowRule.Action, allowRule.Class),
-					})
+				if !a.rulesConflict(allowRule, denyRule) {
+					continue
+				}
+				reason := fmt.Sprintf("Allow and deny rules conflict for subject '%s', object '%s', action '%s', class '%s'",
+					subject, allowRule.Object, allowRule.Action, allowRule.Class)
+				if allowRule.Object != denyRule.Object && a.denyShadowed(allowRule.Object, denyRule.Object) {
+					reason = fmt.Sprintf("%s: subject '%s', action '%s', class '%s': allow on '%s' covers every path of deny on '%s'",
+						ReasonDenyShadowed, subject, allowRule.Action, allowRule.Class, allowRule.Object, denyRule.Object)
 				}
+				conflicts = append(conflicts, ConflictInfo{
+					AllowRule: allowRule,
+					DenyRule:  denyRule,
+					Reason:    reason,
+				})
 			}
 		}
 	}
@@ -265,6 +504,122 @@ func (a *Analyzer) detectConflicts() []ConflictInfo {
 	return conflicts
 }
 
+// resolveConflicts collects the policies left once allow rules overridden by a
+// deny rule are removed. Under allow-override every allow is kept, which is what
+// the generator emits. The decoded policies themselves are left untouched.
+func (a *Analyzer) resolveConflicts() {
+	a.resolved = a.decoded.Policies
+	if a.effect == EffectAllowOverride || len(a.conflicts) == 0 {
+		return
+	}
+
+	kept := make([]models.DecodedPolicy, 0, len(a.decoded.Policies))
+	for i, policy := range a.decoded.Policies {
+		if policy.Effect == "allow" && a.overriddenByDeny(i) {
+			a.addWarning(fmt.Sprintf("Allow rule dropped by %s policy effect: subject '%s', object '%s', action '%s'",
+				a.effect, policy.Subject, policy.Object, policy.Action))
+			continue
+		}
+		kept = append(kept, policy)
+	}
+	a.resolved = kept
+}
+
+// lintBroadPermissions warns on allow rules whose breadth score exceeds the threshold
+func (a *Analyzer) lintBroadPermissions() {
+	actionMapper := mapping.NewActionMapper()
+	threshold := a.broadPermsThreshold
+	if threshold <= 0 {
+		threshold = DefaultBroadPermsThreshold
+	}
+
+	for i, policy := range a.decoded.Policies {
+		if policy.Effect != "allow" || policy.IsTransition {
+			continue
+		}
+
+		perms := a.permissionCount(actionMapper, policy)
+		breadth := a.targetBreadth(policy.Object)
+		score := perms * breadth
+		if score <= threshold {
+			continue
+		}
+		a.addWarning(fmt.Sprintf("policy rule %d: broad rule (score %d > %d): subject '%s', object '%s', action '%s' grants %d %s permissions on a target spanning ~%d types",
+			i+1, score, threshold, policy.Subject, policy.Object, policy.Action, perms, policy.Class, breadth))
+	}
+}
+
+// permissionCount returns how many permissions a rule grants; "*" and "manage"
+// grant the full permission set of the class
+func (a *Analyzer) permissionCount(actionMapper *mapping.ActionMapper, policy models.DecodedPolicy) int {
+	_, perms := actionMapper.MapAction(policy.Action, policy.Class)
+	if policy.Action == "*" || policy.Action == "manage" {
+		if full := mapping.ClassPermissionCount(policy.Class); full > 0 {
+			return full
+		}
+	}
+	return len(perms)
+}
+
+// targetBreadth estimates how many types an object covers: the member count of a
+// g2 attribute, or for a path pattern a count that doubles per level the wildcard
+// sits closer to the root
+func (a *Analyzer) targetBreadth(object string) int {
+	members := 0
+	for _, attr := range a.decoded.TypeAttributes {
+		if attr.Role == object {
+			members++
+		}
+	}
+	if members > 0 {
+		return members
+	}
+
+	wildcard := strings.IndexAny(object, "*?([{")
+	if wildcard == -1 {
+		return 1
+	}
+
+	depth := 0
+	for _, segment := range strings.Split(object[:wildcard], "/") {
+		if segment != "" {
+			depth++
+		}
+	}
+	shift := broadDepthLimit - depth
+	if shift < 1 {
+		shift = 1
+	}
+	return 1 << shift
+}
+
+// overriddenByDeny reports whether the allow rule at index i loses to a conflicting deny rule.
+// Only a deny covering the allow overrides it: a narrower deny gets its own type and
+// neverallow, so the rest of the allow still applies.
+func (a *Analyzer) overriddenByDeny(i int) bool {
+	allow := a.decoded.Policies[i]
+	for j, deny := range a.decoded.Policies {
+		if deny.Effect != "deny" || !a.rulesConflict(allow, deny) || !a.denyCovers(allow.Object, deny.Object) {
+			continue
+		}
+		// With priority the rule listed first wins
+		if a.effect == EffectDenyOverride || j < i {
+			return true
+		}
+	}
+	return false
+}
+
+// denyCovers reports whether a deny object covers everything the allow object grants:
+// both map to the same type, or every path of the allow is matched by the deny
+func (a *Analyzer) denyCovers(allowObject, denyObject string) bool {
+	typeMapper := mapping.NewTypeMapper("")
+	if typeMapper.PathToType(allowObject) == typeMapper.PathToType(denyObject) {
+		return true
+	}
+	return a.denyShadowed(denyObject, allowObject)
+}
+
 // rulesConflict checks if two rules conflict
 func (a *Analyzer) rulesConflict(allow, deny models.DecodedPolicy) bool {
 	// Rules conflict if they have the same subject, overlapping objects, same action, and same class
@@ -278,8 +633,46 @@ func (a *Analyzer) rulesConflict(allow, deny models.DecodedPolicy) bool {
 		return false
 	}
 
-	// Check if objects overlap
-	return a.pathsOverlap(allow.Object, deny.Object)
+	// Check if objects overlap; the prefix checks are cheap, containment is not
+	return a.pathsOverlap(allow.Object, deny.Object) || a.denyShadowed(allow.Object, deny.Object)
+}
+
+// denyShadowed reports whether every path the deny object matches is matched
+// by the allow object too, so the deny can never take effect under allow-override.
+// Both objects are compiled through PathMapper; the deny object is instantiated
+// into representative paths (each brace alternative with its wildcards filled
+// in, plus a child, a grandchild and the parent of each) and the deny is
+// shadowed when all of those it matches are matched by the allow.
+func (a *Analyzer) denyShadowed(allowObject, denyObject string) bool {
+	if !strings.HasPrefix(allowObject, "/") || !strings.HasPrefix(denyObject, "/") {
+		return false
+	}
+
+	pathMapper := mapping.NewPathMapper()
+	allowRegex, err := regexp.Compile("^" + pathMapper.ConvertToSELinuxPattern(allowObject) + "$")
+	if err != nil {
+		return false
+	}
+	denyRegex, err := regexp.Compile("^" + pathMapper.ConvertToSELinuxPattern(denyObject) + "$")
+	if err != nil {
+		return false
+	}
+
+	sampled := 0
+	for _, alternative := range mapping.ExpandBraces(denyObject) {
+		for _, sample := range samplePaths(alternative) {
+			for _, path := range []string{sample, sample + "/nested"} {
+				if !denyRegex.MatchString(path) {
+					continue
+				}
+				if !allowRegex.MatchString(path) {
+					return false
+				}
+				sampled++
+			}
+		}
+	}
+	return sampled > 0
 }
 
 // pathsOverlap checks if two path patterns overlap
@@ -313,20 +706,30 @@ func (a *Analyzer) pathsOverlap(path1, path2 string) bool {
 	return false
 }
 
-// generateStats generates statistics about the policies
+// generateStats generates statistics about the policies left after conflict resolution
 func (a *Analyzer) generateStats() {
-	a.stats.TotalPolicies = len(a.decoded.Policies)
+	policies := a.Resolved().Policies
+	a.stats.TotalPolicies = len(policies)
 
 	uniqueSubjects := make(map[string]bool)
 	uniqueObjects := make(map[string]bool)
 	uniqueActions := make(map[string]bool)
+	booleans := make(map[string]bool)
 
-	for _, policy := range a.decoded.Policies {
-		// Count allow and deny rules
-		if policy.Effect == "allow" || policy.Effect == "" {
+	for _, policy := range policies {
+		// A boolean guards rules whether the condition is negated or not
+		if policy.Condition != "" {
+			booleans[strings.TrimPrefix(policy.Condition, "!")] = true
+		}
+
+		// Count allow, deny and dontaudit rules
+		switch policy.Effect {
+		case "allow", "":
 			a.stats.AllowRules++
-		} else if policy.Effect == "deny" {
+		case "deny":
 			a.stats.DenyRules++
+		case "dontaudit":
+			a.stats.DontauditRules++
 		}
 
 		// Track unique values
@@ -349,9 +752,25 @@ func (a *Analyzer) generateStats() {
 
 	// Count transitions
 	a.stats.Transitions = len(a.decoded.Transitions)
+
+	// Count distinct booleans
+	a.stats.Booleans = len(booleans)
 }
 
-// GetConflicts returns detected policy conflicts
+// Effect returns the policy effect used to resolve conflicts
+func (a *Analyzer) Effect() PolicyEffect {
+	return a.effect
+}
+
+// Resolved returns a copy of the decoded PML holding only the policies left after
+// conflict resolution, for the generator to compile. Before Analyze it holds every policy.
+func (a *Analyzer) Resolved() *models.DecodedPML {
+	resolved := *a.decoded
+	if a.resolved != nil {
+		resolved.Policies = a.resolved
+	}
+	return &resolved
+}
 
 // GetStats returns the analysis statistics
 func (a *Analyzer) GetStats() *AnalysisStats {
@@ -363,11 +782,46 @@ func (a *Analyzer) GetConflicts() []ConflictInfo {
 	return a.conflicts
 }
 
+// SetBroadPermsThreshold enables the broad-permission lint, warning on allow rules whose
+// breadth score (permission count × target breadth) exceeds threshold. With zero the lint
+// only runs when enabled by name, using DefaultBroadPermsThreshold.
+func (a *Analyzer) SetBroadPermsThreshold(threshold int) {
+	a.broadPermsThreshold = threshold
+}
+
+// SetStrictPaths rejects objects that are neither a recognized special form nor a
+// clean absolute path, e.g. /var/www/../etc or /var/www/
+func (a *Analyzer) SetStrictPaths(strict bool) {
+	a.strictPaths = strict
+}
+
+// SetQuiet controls whether warnings are printed as they are found.
+// Warnings are collected either way and available through GetWarnings.
+func (a *Analyzer) SetQuiet(quiet bool) {
+	a.quiet = quiet
+}
+
+// SetOutput sets where warnings are printed as they are found, os.Stdout by default
+func (a *Analyzer) SetOutput(w io.Writer) {
+	a.out = w
+}
+
+// SetLevelMapper sets the mapper resolving named security levels and categories
+func (a *Analyzer) SetLevelMapper(levelMapper *mapping.LevelMapper) {
+	a.levelMapper = levelMapper
+}
+
 // addWarning adds a warning message (non-fatal)
 func (a *Analyzer) addWarning(msg string) {
-	// For now, just collect as errors, but mark them as warnings
-	// In the future, we could have separate warning tracking
-	fmt.Printf("WARNING: %s\n", msg)
+	a.warnings = append(a.warnings, msg)
+	if !a.quiet {
+		fmt.Fprintf(a.out, "WARNING: %s\n", msg)
+	}
+}
+
+// GetWarnings returns all warnings collected during analysis
+func (a *Analyzer) GetWarnings() []string {
+	return a.warnings
 }
 
 // GetErrors returns all errors encountered during analysis
@@ -1,6 +1,9 @@
 package compiler
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/cici0602/pml-to-selinux/models"
@@ -204,6 +207,21 @@ func TestValidatePolicies(t *testing.T) {
 			wantErr: true,
 			errMsg:  "invalid object pattern",
 		},
+		{
+			name: "domain transition",
+			policies: []models.Policy{
+				{Type: "p2", Subject: "httpd_t", Object: "/usr/lib/cgi-bin/*::process", Action: "transition", Effect: "httpd_cgi_t"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "self-transition",
+			policies: []models.Policy{
+				{Type: "p2", Subject: "httpd", Object: "/usr/sbin/httpd::process", Action: "transition", Effect: "httpd_t"},
+			},
+			wantErr: true,
+			errMsg:  "self-transition: httpd transitions to itself on /usr/sbin/httpd:process",
+		},
 		{
 			name: "valid special object type",
 			policies: []models.Policy{
@@ -548,3 +566,530 @@ func TestPathsOverlap(t *testing.T) {
 		})
 	}
 }
+
+// newTestDecodedPML builds a DecodedPML with a minimal valid model around the given policies
+func newTestDecodedPML(policies ...models.Policy) *models.DecodedPML {
+	parser := &Parser{}
+	decodedPolicies := make([]models.DecodedPolicy, 0, len(policies))
+	for i := range policies {
+		decoded, _ := parser.decodePolicy(&policies[i])
+		decodedPolicies = append(decodedPolicies, *decoded)
+	}
+
+	return &models.DecodedPML{
+		Model: &models.PMLModel{
+			RequestDefinition: map[string][]string{"r": {"sub", "obj", "act"}},
+			PolicyDefinition:  map[string][]string{"p": {"sub", "obj", "act", "eft"}},
+			Matchers:          "m",
+			Effect:            "some(where (p.eft == allow))",
+		},
+		Policies:       decodedPolicies,
+		Roles:          []models.RoleRelation{},
+		TypeAttributes: []models.RoleRelation{},
+		Transitions:    []models.TransitionInfo{},
+	}
+}
+
+// TestAnalyzer_BooleanStats tests that stats count each boolean once, however it guards rules
+func TestAnalyzer_BooleanStats(t *testing.T) {
+	decoded := newTestDecodedPML(
+		models.Policy{Type: "p", Subject: "httpd_t", Object: "/home/*?bool=httpd_enable_homedirs", Action: "read", Effect: "allow"},
+		models.Policy{Type: "p", Subject: "httpd_t", Object: "/srv/nfs/*?cond=httpd_use_nfs", Action: "read", Effect: "allow"},
+		models.Policy{Type: "p", Subject: "httpd_t", Object: "/srv/tmp/*?cond=!httpd_use_nfs", Action: "write", Effect: "allow"},
+		models.Policy{Type: "p", Subject: "httpd_t", Object: "/var/www/*", Action: "read", Effect: "allow"},
+	)
+	if got := decoded.Policies[0]; got.Object != "/home/*" || got.Condition != "httpd_enable_homedirs" {
+		t.Errorf("?bool= should set the condition, got object %q condition %q", got.Object, got.Condition)
+	}
+
+	analyzer := NewAnalyzer(decoded)
+	analyzer.generateStats()
+	if stats := analyzer.GetStats(); stats.Booleans != 2 {
+		t.Errorf("Expected 2 booleans, got %d", stats.Booleans)
+	}
+}
+
+func TestAnalyzer_DontauditRules(t *testing.T) {
+	decoded := newTestDecodedPML(
+		models.Policy{Type: "p", Subject: "httpd_t", Object: "/tmp/*", Action: "read", Effect: "allow"},
+		models.Policy{Type: "p", Subject: "httpd_t", Object: "/tmp/*", Action: "read", Effect: "dontaudit"},
+		models.Policy{Type: "p", Subject: "httpd_t", Object: "/etc/shadow", Action: "read", Effect: "deny"},
+	)
+
+	analyzer := NewAnalyzer(decoded)
+	analyzer.SetQuiet(true)
+	if err := analyzer.Analyze(); err != nil {
+		t.Fatalf("Analyze() error = %v", err)
+	}
+	stats := analyzer.GetStats()
+	if stats.AllowRules != 1 || stats.DenyRules != 1 || stats.DontauditRules != 1 {
+		t.Errorf("Expected 1 allow, 1 deny and 1 dontaudit rule, got %d, %d and %d",
+			stats.AllowRules, stats.DenyRules, stats.DontauditRules)
+	}
+	// A dontaudit on allowed access grants nothing and is no conflict
+	if conflicts := analyzer.GetConflicts(); len(conflicts) != 0 {
+		t.Errorf("Expected no conflicts, got %+v", conflicts)
+	}
+}
+
+// TestAnalyzer_UnknownPermissions tests that raw permissions a class does not define are errors
+func TestAnalyzer_UnknownPermissions(t *testing.T) {
+	tests := []struct {
+		action  string
+		object  string
+		wantErr string
+	}{
+		{action: "reed", object: "/etc/app.conf", wantErr: "class file has no permission 'reed'"},
+		{action: "name_bind", object: "/etc/app.conf::dir", wantErr: "class dir has no permission 'name_bind'"},
+		{action: "read", object: "/etc/app.conf"},
+		{action: "execute_no_trans", object: "/usr/bin/app"},
+		{action: "net_bind_service", object: "self::capability"},
+		{action: "manage", object: "/var/lib/app/*"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			analyzer := NewAnalyzer(newTestDecodedPML(
+				models.Policy{Type: "p", Subject: "app_t", Object: tt.object, Action: tt.action, Effect: "allow"},
+			))
+			analyzer.SetQuiet(true)
+			err := analyzer.Analyze()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Analyze() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Analyze() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestAnalyzerCollectsErrorsAndWarnings tests that every invalid rule and warning is recorded
+func TestAnalyzerCollectsErrorsAndWarnings(t *testing.T) {
+	analyzer := NewAnalyzer(newTestDecodedPML(
+		models.Policy{Subject: "", Object: "/var/www/*", Action: "read", Effect: "allow"},
+		models.Policy{Subject: "httpd_t", Object: "var/www", Action: "read", Effect: "allow"},
+		models.Policy{Subject: "httpd_t", Object: "/var/www/*", Action: "read", Effect: "allow"},
+	))
+	analyzer.SetQuiet(true)
+
+	err := analyzer.Analyze()
+	if err == nil || !contains(err.Error(), "subject cannot be empty") {
+		t.Errorf("Analyze() should return the first error, got %v", err)
+	}
+	if len(analyzer.GetErrors()) != 2 {
+		t.Errorf("expected 2 collected errors, got %d: %v", len(analyzer.GetErrors()), analyzer.GetErrors())
+	}
+
+	analyzer = NewAnalyzer(newTestDecodedPML(
+		models.Policy{Subject: "httpd_t", Object: "/var/www/*", Action: "read", Effect: "allow"},
+		models.Policy{Subject: "httpd_t", Object: "/var/www/*", Action: "read", Effect: "deny"},
+	))
+	analyzer.SetQuiet(true)
+
+	if err := analyzer.Analyze(); err != nil {
+		t.Fatalf("Analyze() error = %v", err)
+	}
+	if len(analyzer.GetWarnings()) != 1 {
+		t.Errorf("expected 1 warning for the conflict, got %d", len(analyzer.GetWarnings()))
+	}
+}
+
+// TestParsePolicyEffect tests recognition of the standard policy_effect expressions
+func TestParsePolicyEffect(t *testing.T) {
+	tests := []struct {
+		expr      string
+		expected  PolicyEffect
+		expectErr bool
+	}{
+		{expr: "some(where (p.eft == allow))", expected: EffectAllowOverride},
+		{expr: "some(where (p.eft == allow)) && !some(where (p.eft == deny))", expected: EffectDenyOverride},
+		{expr: "!some(where (p.eft == deny))", expected: EffectDenyOverride},
+		{expr: "priority(p.eft) || deny", expected: EffectPriority},
+		{expr: "max(p.weight)", expected: EffectAllowOverride, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			effect, err := ParsePolicyEffect(tt.expr)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("ParsePolicyEffect(%q) error = %v, expectErr %v", tt.expr, err, tt.expectErr)
+			}
+			if effect != tt.expected {
+				t.Errorf("ParsePolicyEffect(%q) = %s, want %s", tt.expr, effect, tt.expected)
+			}
+		})
+	}
+}
+
+// TestResolveConflictsByEffect tests that conflicting allows are dropped according to the effect
+func TestResolveConflictsByEffect(t *testing.T) {
+	tests := []struct {
+		name       string
+		effect     string
+		policies   []models.Policy
+		keptAllows int
+	}{
+		{
+			name:   "allow-override keeps the allow",
+			effect: "some(where (p.eft == allow))",
+			policies: []models.Policy{
+				{Subject: "httpd_t", Object: "/etc/shadow", Action: "read", Effect: "allow"},
+				{Subject: "httpd_t", Object: "/etc/shadow", Action: "read", Effect: "deny"},
+			},
+			keptAllows: 1,
+		},
+		{
+			name:   "deny-override drops the allow",
+			effect: "some(where (p.eft == allow)) && !some(where (p.eft == deny))",
+			policies: []models.Policy{
+				{Subject: "httpd_t", Object: "/etc/shadow", Action: "read", Effect: "allow"},
+				{Subject: "httpd_t", Object: "/etc/shadow", Action: "read", Effect: "deny"},
+				{Subject: "httpd_t", Object: "/var/www/*", Action: "read", Effect: "allow"},
+			},
+			keptAllows: 1,
+		},
+		{
+			name:   "priority keeps an allow listed first",
+			effect: "priority(p.eft) || deny",
+			policies: []models.Policy{
+				{Subject: "httpd_t", Object: "/etc/shadow", Action: "read", Effect: "allow"},
+				{Subject: "httpd_t", Object: "/etc/shadow", Action: "read", Effect: "deny"},
+			},
+			keptAllows: 1,
+		},
+		{
+			name:   "priority drops an allow listed after the deny",
+			effect: "priority(p.eft) || deny",
+			policies: []models.Policy{
+				{Subject: "httpd_t", Object: "/etc/shadow", Action: "read", Effect: "deny"},
+				{Subject: "httpd_t", Object: "/etc/shadow", Action: "read", Effect: "allow"},
+			},
+			keptAllows: 0,
+		},
+		{
+			name:   "deny-override keeps a broader allow around a narrower deny",
+			effect: "some(where (p.eft == allow)) && !some(where (p.eft == deny))",
+			policies: []models.Policy{
+				{Subject: "httpd_t", Object: "/var/www/*", Action: "read", Effect: "allow"},
+				{Subject: "httpd_t", Object: "/var/www/html/secret.conf", Action: "read", Effect: "deny"},
+			},
+			keptAllows: 1,
+		},
+		{
+			name:   "deny-override drops an allow inside a broader deny",
+			effect: "some(where (p.eft == allow)) && !some(where (p.eft == deny))",
+			policies: []models.Policy{
+				{Subject: "httpd_t", Object: "/var/www/html/index.html", Action: "read", Effect: "allow"},
+				{Subject: "httpd_t", Object: "/var/www/*", Action: "read", Effect: "deny"},
+			},
+			keptAllows: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoded := newTestDecodedPML(tt.policies...)
+			decoded.Model.Effect = tt.effect
+
+			analyzer := NewAnalyzer(decoded)
+			analyzer.SetQuiet(true)
+			if err := analyzer.Analyze(); err != nil {
+				t.Fatalf("Analyze() error = %v", err)
+			}
+			if len(decoded.Policies) != len(tt.policies) {
+				t.Errorf("Analyze() must not rewrite the decoded policies, got %d of %d", len(decoded.Policies), len(tt.policies))
+			}
+
+			allows := 0
+			for _, policy := range analyzer.Resolved().Policies {
+				if policy.Effect == "allow" {
+					allows++
+				}
+			}
+			if allows != tt.keptAllows {
+				t.Errorf("expected %d allow rules after resolution, got %d", tt.keptAllows, allows)
+			}
+			if stats := analyzer.GetStats(); stats.AllowRules != tt.keptAllows {
+				t.Errorf("stats count %d allow rules, want %d", stats.AllowRules, tt.keptAllows)
+			}
+		})
+	}
+}
+
+// TestAnalyzer_UnknownEffectIsSilent tests that an unrecognized policy_effect
+// falls back to allow-override without a warning
+func TestAnalyzer_UnknownEffectIsSilent(t *testing.T) {
+	for _, effect := range []string{"max(p.weight)", "e"} {
+		decoded := newTestDecodedPML(
+			models.Policy{Type: "p", Subject: "httpd_t", Object: "/var/www/*", Action: "read", Effect: "allow"},
+		)
+		decoded.Model.Effect = effect
+
+		analyzer := NewAnalyzer(decoded)
+		analyzer.SetQuiet(true)
+		if err := analyzer.Analyze(); err != nil {
+			t.Fatalf("Analyze() error = %v", err)
+		}
+		if warnings := analyzer.GetWarnings(); len(warnings) != 0 {
+			t.Errorf("effect %q: expected no warnings, got %v", effect, warnings)
+		}
+		if analyzer.Effect() != EffectAllowOverride {
+			t.Errorf("effect %q resolved as %s, want allow-override", effect, analyzer.Effect())
+		}
+	}
+}
+
+func TestBroadPermissionsLint(t *testing.T) {
+	decoded := newTestDecodedPML(
+		models.Policy{Type: "p", Subject: "app_t", Object: "/var/lib/app(/.*)?", Action: "manage", Effect: "allow"},
+		models.Policy{Type: "p", Subject: "app_t", Object: "/var/www/*", Action: "read", Effect: "allow"},
+		models.Policy{Type: "p", Subject: "app_t", Object: "/**", Action: "write", Effect: "allow"},
+		models.Policy{Type: "p", Subject: "app_t", Object: "web_content", Action: "*", Effect: "allow"},
+		models.Policy{Type: "p", Subject: "app_t", Object: "/etc/app.conf", Action: "manage", Effect: "allow"},
+	)
+	decoded.TypeAttributes = []models.RoleRelation{
+		{Type: "g2", Member: "httpd_content_t", Role: "web_content"},
+		{Type: "g2", Member: "httpd_cache_t", Role: "web_content"},
+		{Type: "g2", Member: "httpd_upload_t", Role: "web_content"},
+	}
+
+	analyzer := NewAnalyzer(decoded)
+	analyzer.SetQuiet(true)
+	analyzer.SetBroadPermsThreshold(DefaultBroadPermsThreshold)
+	if err := analyzer.Analyze(); err != nil {
+		t.Fatalf("Analyze() error = %v", err)
+	}
+
+	var broad []string
+	for _, warning := range analyzer.GetWarnings() {
+		if contains(warning, "broad rule") {
+			broad = append(broad, warning)
+		}
+	}
+	want := []string{"policy rule 1:", "policy rule 3:", "policy rule 4:"}
+	if len(broad) != len(want) {
+		t.Fatalf("Expected %d broad rule warnings, got %d: %v", len(want), len(broad), broad)
+	}
+	for i, prefix := range want {
+		if !strings.HasPrefix(broad[i], prefix) {
+			t.Errorf("warning %d = %q, want prefix %q", i, broad[i], prefix)
+		}
+	}
+
+	// Disabled by default
+	analyzer = NewAnalyzer(newTestDecodedPML(decoded.Policies[0].Policy))
+	analyzer.SetQuiet(true)
+	analyzer.Analyze()
+	if len(analyzer.GetWarnings()) != 0 {
+		t.Errorf("Expected no warnings without threshold, got %v", analyzer.GetWarnings())
+	}
+}
+
+func TestStrictPaths(t *testing.T) {
+	tests := []struct {
+		object  string
+		wantErr string
+	}{
+		{object: "/var/www/html"},
+		{object: "/var/www(/.*)?"},
+		{object: "/var/log/*.log"},
+		{object: "self"},
+		{object: "tcp:8080"},
+		{object: "packet:httpd_packet_t"},
+		{object: "httpd_content_t"},
+		{object: "web_content"},
+		{object: "/var/www/../etc", wantErr: "'..' path components"},
+		{object: "/var/./www", wantErr: "'.' path components"},
+		{object: "/var/www/", wantErr: "use '/var/www'"},
+		{object: "/var//www", wantErr: "use '/var/www'"},
+		{object: "some_dir", wantErr: "must be an absolute path"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.object, func(t *testing.T) {
+			decoded := newTestDecodedPML(
+				models.Policy{Type: "p", Subject: "app_t", Object: tt.object, Action: "read", Effect: "allow"},
+			)
+			decoded.TypeAttributes = []models.RoleRelation{
+				{Type: "g2", Member: "httpd_content_t", Role: "web_content"},
+			}
+
+			// Lenient by default
+			analyzer := NewAnalyzer(decoded)
+			analyzer.SetQuiet(true)
+			if err := analyzer.Analyze(); err != nil {
+				t.Fatalf("Analyze() without strict paths error = %v", err)
+			}
+
+			analyzer = NewAnalyzer(decoded)
+			analyzer.SetQuiet(true)
+			analyzer.SetStrictPaths(true)
+			err := analyzer.Analyze()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Analyze() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !contains(err.Error(), tt.wantErr) {
+				t.Errorf("Analyze() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMLSDominance(t *testing.T) {
+	tests := []struct {
+		name    string
+		subject string
+		object  string
+		action  string
+		wantErr string
+	}{
+		{name: "read down", subject: "app_t@level=s0-s2", object: "/srv/a@level=s1", action: "read"},
+		{name: "read up", subject: "app_t@level=s0-s1", object: "/srv/a@level=secret", action: "read", wantErr: "MLS read-up: subject 'app_t' clearance s1 does not dominate object '/srv/a' level s3"},
+		{name: "read missing category", subject: "app_t@level=s2:c0", object: "/srv/a@level=s1:c1", action: "read", wantErr: "MLS read-up"},
+		{name: "write up", subject: "app_t@level=s1", object: "/srv/a@level=s2", action: "write"},
+		{name: "write down", subject: "app_t@level=s2-s3", object: "/srv/a@level=s1", action: "write", wantErr: "MLS write-down: object '/srv/a' level s1 does not dominate subject 'app_t' level s2"},
+		{name: "no subject level", subject: "app_t", object: "/srv/a@level=s3", action: "read"},
+		{name: "bad subject annotation", subject: "app_t@audit=true", object: "/srv/a", action: "read", wantErr: "only '@level=' annotations"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := &Parser{}
+			decodedPolicy, err := parser.decodePolicy(&models.Policy{
+				Type: "p", Subject: tt.subject, Object: tt.object, Action: tt.action, Effect: "allow",
+			})
+			if err == nil {
+				decoded := newTestDecodedPML()
+				decoded.Policies = []models.DecodedPolicy{*decodedPolicy}
+				analyzer := NewAnalyzer(decoded)
+				analyzer.SetQuiet(true)
+				err = analyzer.Analyze()
+			}
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestDenyShadowed tests pattern containment between an allow and a deny object
+func TestDenyShadowed(t *testing.T) {
+	tests := []struct {
+		name   string
+		allow  string
+		deny   string
+		expect bool
+	}{
+		{"file under recursive allow", "/var/www/*", "/var/www/html/secret.conf", true},
+		{"wildcard directory", "/var/*/secret.conf", "/var/www/secret.conf", true},
+		{"recursive allow covers recursive deny", "/var/www/**", "/var/www/html/*", true},
+		{"every brace alternative covered", "/etc/app/*", "/etc/app/{a,b}.conf", true},
+		{"one brace alternative escapes", "/etc/app/*", "/etc/{app,other}/x.conf", false},
+		{"deny broader than allow", "/var/www/html/*", "/var/www/*", false},
+		{"extension filter", "/var/www/*.conf", "/var/www/*", false},
+		{"disjoint directories", "/var/www/*", "/srv/www/secret.conf", false},
+		{"non-path objects", "http_port_t", "http_port_t", false},
+	}
+
+	analyzer := NewAnalyzer(newTestDecodedPML())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := analyzer.denyShadowed(tt.allow, tt.deny); got != tt.expect {
+				t.Errorf("denyShadowed(%q, %q) = %v, expected %v", tt.allow, tt.deny, got, tt.expect)
+			}
+		})
+	}
+}
+
+// TestDetectConflicts_ShadowedDeny tests that a deny covered by a broader allow
+// gets its own reason, while identical objects keep the plain conflict reason
+func TestDetectConflicts_ShadowedDeny(t *testing.T) {
+	decoded := newTestDecodedPML(
+		models.Policy{Type: "p", Subject: "httpd_t", Object: "/var/www/*/*.conf", Action: "read", Effect: "allow"},
+		models.Policy{Type: "p", Subject: "httpd_t", Object: "/var/www/html/secret.conf", Action: "read", Effect: "deny"},
+		models.Policy{Type: "p", Subject: "httpd_t", Object: "/srv/app/*", Action: "write", Effect: "allow"},
+		models.Policy{Type: "p", Subject: "httpd_t", Object: "/srv/app/*", Action: "write", Effect: "deny"},
+	)
+	analyzer := NewAnalyzer(decoded)
+	conflicts := analyzer.detectConflicts()
+	if len(conflicts) != 2 {
+		t.Fatalf("expected 2 conflicts, got %d: %+v", len(conflicts), conflicts)
+	}
+
+	shadowed := 0
+	for _, conflict := range conflicts {
+		if strings.HasPrefix(conflict.Reason, ReasonDenyShadowed) {
+			shadowed++
+			if conflict.DenyRule.Object != "/var/www/html/secret.conf" {
+				t.Errorf("unexpected shadowed deny %q", conflict.DenyRule.Object)
+			}
+		}
+	}
+	if shadowed != 1 {
+		t.Errorf("expected 1 shadowed deny, got %d", shadowed)
+	}
+}
+
+// TestAnalyzer_ErrorsCiteSourceLine tests that validation errors name the policy
+// file line of the rule rather than its index among the decoded rules
+func TestAnalyzer_ErrorsCiteSourceLine(t *testing.T) {
+	tmpDir := t.TempDir()
+	modelPath := filepath.Join(tmpDir, "model.conf")
+	policyPath := filepath.Join(tmpDir, "policy.csv")
+	modelData := `[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act, eft
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = r.sub == p.sub && r.obj == p.obj && r.act == p.act
+`
+	policyData := `# Web server
+
+p, httpd_t, /var/www/*, read, allow
+
+# Logs
+p, , /var/log/httpd/*, append, allow
+`
+	if err := os.WriteFile(modelPath, []byte(modelData), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(policyPath, []byte(policyData), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	parser := NewParser(modelPath, policyPath)
+	pml, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	decoded, err := parser.Decode(pml)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+
+	analyzer := NewAnalyzer(decoded)
+	analyzer.SetQuiet(true)
+	err = analyzer.Analyze()
+	if want := policyPath + ":6: subject cannot be empty"; err == nil || err.Error() != want {
+		t.Errorf("Analyze() error = %v, want %q", err, want)
+	}
+}
@@ -0,0 +1,201 @@
+package compiler
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AVCDenial is the aggregated count of denials for one source, target, class and permission
+type AVCDenial struct {
+	SourceType string
+	TargetType string
+	Class      string
+	Permission string
+	Path       string // First path seen for the target, if the records carried one
+	Count      int
+}
+
+var (
+	avcPermsPattern = regexp.MustCompile(`denied\s+\{([^}]*)\}`)
+	avcFieldPattern = regexp.MustCompile(`\b(scontext|tcontext|tclass|path|name)=("[^"]*"|\S+)`)
+	avcCountPattern = regexp.MustCompile(`\b(\d+)\s+times\b`)
+)
+
+// ParseAVCFile reads AVC denials from an audit log or an aggregated denial report
+func ParseAVCFile(path string) ([]AVCDenial, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open AVC file: %w", err)
+	}
+	defer file.Close()
+
+	return ParseAVC(path, file)
+}
+
+// ParseAVC parses AVC denial records and aggregates them by (source, target, class, permission).
+// Besides raw audit records, aggregated lines ending in "N times" count N denials,
+// as do CSV stats lines of source type, target type, class, permission and count:
+//
+//	avc: denied { read } for scontext=system_u:system_r:httpd_t:s0 tcontext=system_u:object_r:var_t:s0 tclass=file 1243 times
+//	httpd_t,var_t,file,read,1243
+//
+// Lines that are not AVC denials are skipped. The result is sorted by count, highest first.
+func ParseAVC(name string, r io.Reader) ([]AVCDenial, error) {
+	aggregated := make(map[string]*AVCDenial)
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+
+		// CSV stats: source_type,target_type,class,permission,count
+		if stat, ok := parseAVCStat(line); ok {
+			addAVCDenial(aggregated, stat)
+			continue
+		}
+
+		perms := avcPermsPattern.FindStringSubmatch(line)
+		if perms == nil || !strings.Contains(line, "avc:") {
+			continue
+		}
+
+		fields := make(map[string]string)
+		for _, match := range avcFieldPattern.FindAllStringSubmatch(line, -1) {
+			fields[match[1]] = strings.Trim(match[2], `"`)
+		}
+		source, target := contextType(fields["scontext"]), contextType(fields["tcontext"])
+		if source == "" || target == "" || fields["tclass"] == "" {
+			return nil, &ParseError{
+				File:    name,
+				Line:    lineNum,
+				Message: "AVC denial needs scontext, tcontext and tclass",
+			}
+		}
+
+		count := 1
+		if match := avcCountPattern.FindStringSubmatch(line); match != nil {
+			count, _ = strconv.Atoi(match[1])
+		}
+
+		path := fields["path"]
+		if path == "" && strings.HasPrefix(fields["name"], "/") {
+			path = fields["name"]
+		}
+
+		for _, perm := range strings.Fields(perms[1]) {
+			addAVCDenial(aggregated, AVCDenial{
+				SourceType: source,
+				TargetType: target,
+				Class:      fields["tclass"],
+				Permission: perm,
+				Path:       path,
+				Count:      count,
+			})
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading AVC file: %w", err)
+	}
+
+	denials := make([]AVCDenial, 0, len(aggregated))
+	for _, denial := range aggregated {
+		denials = append(denials, *denial)
+	}
+	sort.Slice(denials, func(i, j int) bool {
+		if denials[i].Count != denials[j].Count {
+			return denials[i].Count > denials[j].Count
+		}
+		return avcKey(denials[i]) < avcKey(denials[j])
+	})
+
+	return denials, nil
+}
+
+// addAVCDenial merges a denial into the aggregate for its source, target, class and permission
+func addAVCDenial(aggregated map[string]*AVCDenial, denial AVCDenial) {
+	key := avcKey(denial)
+	existing, ok := aggregated[key]
+	if !ok {
+		aggregated[key] = &denial
+		return
+	}
+	if existing.Path == "" {
+		existing.Path = denial.Path
+	}
+	existing.Count += denial.Count
+}
+
+// parseAVCStat parses a CSV stats line; headers and other lines are not stats
+func parseAVCStat(line string) (AVCDenial, bool) {
+	fields := strings.Split(line, ",")
+	if len(fields) != 5 {
+		return AVCDenial{}, false
+	}
+	for i := range fields {
+		fields[i] = strings.TrimSpace(fields[i])
+		if fields[i] == "" {
+			return AVCDenial{}, false
+		}
+	}
+	count, err := strconv.Atoi(fields[4])
+	if err != nil || count < 1 {
+		return AVCDenial{}, false
+	}
+	return AVCDenial{
+		SourceType: fields[0],
+		TargetType: fields[1],
+		Class:      fields[2],
+		Permission: fields[3],
+		Count:      count,
+	}, true
+}
+
+// contextType returns the type field of a user:role:type:level context
+func contextType(context string) string {
+	parts := strings.Split(context, ":")
+	if len(parts) < 3 {
+		return ""
+	}
+	return parts[2]
+}
+
+// avcKey orders denials with equal counts
+func avcKey(d AVCDenial) string {
+	return strings.Join([]string{d.SourceType, d.TargetType, d.Class, d.Permission}, "|")
+}
+
+// RenderAVCPolicy renders aggregated denials as PML allow rules. The object is the
+// denied path when known, otherwise the target type. With counts, each rule is
+// preceded by a comment giving the number of denials that motivated it.
+func RenderAVCPolicy(denials []AVCDenial, counts bool) string {
+	var builder strings.Builder
+
+	builder.WriteString("# Generated from AVC denials by pml2selinux import\n")
+	builder.WriteString("# Review every rule before use: each one grants access that was denied\n\n")
+
+	for _, denial := range denials {
+		object := denial.TargetType
+		if denial.Path != "" {
+			object = denial.Path
+		}
+		if counts {
+			noun := "denials"
+			if denial.Count == 1 {
+				noun = "denial"
+			}
+			builder.WriteString(fmt.Sprintf("# %d %s\n", denial.Count, noun))
+		}
+		builder.WriteString(fmt.Sprintf("p, %s, %s, %s::%s, allow\n",
+			denial.SourceType, object, denial.Permission, denial.Class))
+	}
+
+	return builder.String()
+}
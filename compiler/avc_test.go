@@ -0,0 +1,49 @@
+package compiler
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseAVC(t *testing.T) {
+	input := `type=AVC msg=audit(1700000000.123:42): avc:  denied  { read open } for  pid=812 comm="httpd" path="/srv/www/index.html" scontext=system_u:system_r:httpd_t:s0 tcontext=system_u:object_r:var_t:s0 tclass=file permissive=0
+type=SYSCALL msg=audit(1700000000.123:42): arch=c000003e syscall=2 success=no
+avc: denied { read } for scontext=system_u:system_r:httpd_t:s0 tcontext=system_u:object_r:var_t:s0 tclass=file 1243 times
+source,target,class,perm,count
+sshd_t,shadow_t,file,read,5
+`
+	denials, err := ParseAVC("audit.log", strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseAVC() error = %v", err)
+	}
+
+	want := []AVCDenial{
+		{SourceType: "httpd_t", TargetType: "var_t", Class: "file", Permission: "read", Path: "/srv/www/index.html", Count: 1244},
+		{SourceType: "sshd_t", TargetType: "shadow_t", Class: "file", Permission: "read", Count: 5},
+		{SourceType: "httpd_t", TargetType: "var_t", Class: "file", Permission: "open", Path: "/srv/www/index.html", Count: 1},
+	}
+	if len(denials) != len(want) {
+		t.Fatalf("Expected %d denials, got %d: %+v", len(want), len(denials), denials)
+	}
+	for i := range want {
+		if denials[i] != want[i] {
+			t.Errorf("denial %d = %+v, want %+v", i, denials[i], want[i])
+		}
+	}
+
+	policy := RenderAVCPolicy(denials, true)
+	if !strings.Contains(policy, "# 1244 denials\np, httpd_t, /srv/www/index.html, read::file, allow\n") {
+		t.Errorf("Missing counted rule, got:\n%s", policy)
+	}
+	if !strings.Contains(policy, "# 1 denial\n") {
+		t.Errorf("Expected singular count comment, got:\n%s", policy)
+	}
+	if strings.Contains(RenderAVCPolicy(denials, false), "denials\n") {
+		t.Error("count comments should only be emitted when requested")
+	}
+
+	_, err = ParseAVC("audit.log", strings.NewReader("avc: denied { read } for tclass=file\n"))
+	if parseErr, ok := err.(*ParseError); !ok || parseErr.Line != 1 {
+		t.Errorf("Expected ParseError on line 1, got %v", err)
+	}
+}
@@ -0,0 +1,67 @@
+package compiler
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// ComplexityGrowth is a complexity count that grew past the allowed percentage
+type ComplexityGrowth struct {
+	Metric   string
+	Baseline int
+	Current  int
+}
+
+// String renders the growth as "rules: 10 -> 12 (+20.0%)"
+func (g ComplexityGrowth) String() string {
+	if g.Baseline == 0 {
+		return fmt.Sprintf("%s: %d -> %d", g.Metric, g.Baseline, g.Current)
+	}
+	percent := float64(g.Current-g.Baseline) * 100 / float64(g.Baseline)
+	return fmt.Sprintf("%s: %d -> %d (+%.1f%%)", g.Metric, g.Baseline, g.Current, percent)
+}
+
+// ReadComplexityBaseline reads complexity stats written by WriteComplexityBaseline
+func ReadComplexityBaseline(path string) (ComplexityAnalysis, error) {
+	var baseline ComplexityAnalysis
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return baseline, fmt.Errorf("failed to read baseline stats: %w", err)
+	}
+	if err := json.Unmarshal(data, &baseline); err != nil {
+		return baseline, fmt.Errorf("invalid baseline stats %s: %w", path, err)
+	}
+	return baseline, nil
+}
+
+// WriteComplexityBaseline writes complexity stats as indented JSON
+func WriteComplexityBaseline(path string, complexity ComplexityAnalysis) error {
+	data, err := json.MarshalIndent(complexity, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode baseline stats: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write baseline stats: %w", err)
+	}
+	return nil
+}
+
+// CompareComplexity returns the rule and type counts that grew by more than
+// maxGrowth percent over the baseline. A count the baseline has at zero may
+// not grow at all.
+func CompareComplexity(baseline, current ComplexityAnalysis, maxGrowth float64) []ComplexityGrowth {
+	counts := []ComplexityGrowth{
+		{Metric: "rules", Baseline: baseline.TotalRules, Current: current.TotalRules},
+		{Metric: "types", Baseline: baseline.TotalTypes, Current: current.TotalTypes},
+	}
+
+	grown := make([]ComplexityGrowth, 0)
+	for _, count := range counts {
+		limit := float64(count.Baseline) * (1 + maxGrowth/100)
+		if float64(count.Current) > limit {
+			grown = append(grown, count)
+		}
+	}
+	return grown
+}
@@ -0,0 +1,65 @@
+package compiler
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompareComplexity(t *testing.T) {
+	baseline := ComplexityAnalysis{TotalRules: 100, TotalTypes: 10}
+
+	tests := []struct {
+		name      string
+		current   ComplexityAnalysis
+		maxGrowth float64
+		want      []string
+	}{
+		{name: "unchanged", current: baseline, maxGrowth: 0},
+		{name: "within limit", current: ComplexityAnalysis{TotalRules: 110, TotalTypes: 11}, maxGrowth: 10},
+		{name: "shrunk", current: ComplexityAnalysis{TotalRules: 50, TotalTypes: 5}, maxGrowth: 0},
+		{name: "rules grew", current: ComplexityAnalysis{TotalRules: 111, TotalTypes: 10}, maxGrowth: 10, want: []string{"rules: 100 -> 111 (+11.0%)"}},
+		{name: "both grew", current: ComplexityAnalysis{TotalRules: 101, TotalTypes: 12}, maxGrowth: 0, want: []string{"rules: 100 -> 101 (+1.0%)", "types: 10 -> 12 (+20.0%)"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CompareComplexity(baseline, tt.current, tt.maxGrowth)
+			if len(got) != len(tt.want) {
+				t.Fatalf("CompareComplexity() = %v, want %v", got, tt.want)
+			}
+			for i, want := range tt.want {
+				if got[i].String() != want {
+					t.Errorf("growth %d = %q, want %q", i, got[i].String(), want)
+				}
+			}
+		})
+	}
+
+	if got := CompareComplexity(ComplexityAnalysis{}, ComplexityAnalysis{TotalTypes: 1}, 50); len(got) != 1 || got[0].String() != "types: 0 -> 1" {
+		t.Errorf("growth from an empty baseline = %v", got)
+	}
+}
+
+func TestComplexityBaselineRoundtrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats.json")
+	stats := ComplexityAnalysis{TotalRules: 12, TotalTypes: 4, AverageRulesPerType: 6, MaxRulesPerType: 8, ComplexityScore: 20}
+
+	if err := WriteComplexityBaseline(path, stats); err != nil {
+		t.Fatalf("WriteComplexityBaseline() error = %v", err)
+	}
+	got, err := ReadComplexityBaseline(path)
+	if err != nil {
+		t.Fatalf("ReadComplexityBaseline() error = %v", err)
+	}
+	if got != stats {
+		t.Errorf("baseline = %+v, want %+v", got, stats)
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadComplexityBaseline(path); err == nil {
+		t.Error("expected an error reading invalid JSON")
+	}
+}
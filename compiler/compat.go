@@ -0,0 +1,133 @@
+package compiler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cici0602/pml-to-selinux/models"
+)
+
+// CompatIssue is a use of a PML extension that a standard Casbin enforcer
+// would not interpret the same way
+type CompatIssue struct {
+	File      string // Empty for rules without a source position (role relations, genfs, n, f, sid, ft)
+	Line      int
+	Extension string // Short name of the extension, e.g. "object class" or "@level"
+	Message   string
+}
+
+// String renders the issue with its source position, if known
+func (i CompatIssue) String() string {
+	if i.File == "" {
+		return i.Message
+	}
+	return fmt.Sprintf("%s:%d: %s", i.File, i.Line, i.Message)
+}
+
+// casbinPolicyFields is the number of fields the tool reads from a policy rule: sub, obj, act, eft
+const casbinPolicyFields = 4
+
+// CheckCasbinCompat reports every place the parsed policies rely on extensions to
+// Casbin semantics (object and action classes, ?cond=, @ annotations, inline comments,
+// transition rules and non-Casbin rule types), i.e. what will not round-trip to a
+// Casbin enforcer
+func CheckCasbinCompat(pml *models.ParsedPML) []CompatIssue {
+	var issues []CompatIssue
+
+	for _, policy := range pml.Policies {
+		add := func(extension, format string, args ...interface{}) {
+			issues = append(issues, CompatIssue{
+				File:      policy.File,
+				Line:      policy.Line,
+				Extension: extension,
+				Message:   fmt.Sprintf(format, args...),
+			})
+		}
+
+		definition, declared := pml.Model.PolicyDefinition[policy.Type]
+		if !declared {
+			add("rule type", "rule type '%s' is not declared in [policy_definition]", policy.Type)
+		} else if len(definition) != casbinPolicyFields {
+			add("field count", "'%s' declares %d fields in [policy_definition] but policy rules have %d",
+				policy.Type, len(definition), casbinPolicyFields)
+		}
+
+		if policy.Type == "p2" && strings.HasPrefix(policy.Action, "transition") {
+			add("transition", "type transition rule uses the effect field for the new type '%s'", policy.Effect)
+		}
+
+		object, objectAnnotations, _ := strings.Cut(policy.Object, "@")
+		if strings.Contains(object, "?cond=") {
+			add("?cond=", "object '%s' uses a ?cond= boolean condition", policy.Object)
+		}
+		if strings.Contains(object, "::") {
+			add("object class", "object '%s' encodes a class with '::'", policy.Object)
+		}
+		if strings.Contains(policy.Action, "::") {
+			add("action class", "action '%s' encodes a class with '::'", policy.Action)
+		}
+		if subject, subjectAnnotations, ok := strings.Cut(policy.Subject, "@"); ok {
+			for _, annotation := range strings.Split(subjectAnnotations, "@") {
+				key, _, _ := strings.Cut(annotation, "=")
+				add("@"+key, "subject '%s' uses the @%s annotation", subject, key)
+			}
+		}
+		if objectAnnotations != "" {
+			for _, annotation := range strings.Split(objectAnnotations, "@") {
+				key, _, _ := strings.Cut(annotation, "=")
+				add("@"+key, "object '%s' uses the @%s annotation", object, key)
+			}
+		}
+		if policy.Comment != "" {
+			add("inline comment", "inline comment would be read as part of the effect field")
+		}
+	}
+
+	for _, role := range pml.Roles {
+		if _, declared := pml.Model.RoleDefinition[role.Type]; !declared {
+			issues = append(issues, CompatIssue{
+				Extension: "rule type",
+				Message: fmt.Sprintf("rule type '%s' (%s, %s) is not declared in [role_definition]",
+					role.Type, role.Member, role.Role),
+			})
+		}
+	}
+	for _, genfs := range pml.Genfs {
+		issues = append(issues, CompatIssue{
+			Extension: "rule type",
+			Message:   fmt.Sprintf("genfs rule (%s, %s) is not a Casbin rule type", genfs.FSType, genfs.Path),
+		})
+	}
+	for _, node := range pml.Nodes {
+		issues = append(issues, CompatIssue{
+			Extension: "rule type",
+			Message:   fmt.Sprintf("n rule (%s) is not a Casbin rule type", node.Network),
+		})
+	}
+	for _, netif := range pml.Netifs {
+		issues = append(issues, CompatIssue{
+			Extension: "rule type",
+			Message:   fmt.Sprintf("f rule (%s) is not a Casbin rule type", netif.Interface),
+		})
+	}
+	for _, ft := range pml.NamedTransitions {
+		issues = append(issues, CompatIssue{
+			Extension: "rule type",
+			Message:   fmt.Sprintf("ft rule (%s, %s, \"%s\") is not a Casbin rule type", ft.Source, ft.Target, ft.Filename),
+		})
+	}
+	for _, sid := range pml.SIDs {
+		issues = append(issues, CompatIssue{
+			Extension: "rule type",
+			Message:   fmt.Sprintf("sid rule (%s) is not a Casbin rule type", sid.Name),
+		})
+	}
+	for _, call := range pml.InterfaceCalls {
+		issues = append(issues, CompatIssue{
+			Extension: "rule type",
+			Message:   fmt.Sprintf("i rule (%s) is not a Casbin rule type", call.Name),
+		})
+	}
+
+	return issues
+}
@@ -0,0 +1,83 @@
+package compiler
+
+import (
+	"testing"
+
+	"github.com/cici0602/pml-to-selinux/models"
+)
+
+func TestCheckCasbinCompat(t *testing.T) {
+	model := &models.PMLModel{
+		PolicyDefinition: map[string][]string{
+			"p":  {"sub", "obj", "act", "eft"},
+			"p2": {"sub", "obj", "act", "eft"},
+		},
+		RoleDefinition: map[string][]string{"g": {"_", "_"}},
+	}
+
+	tests := []struct {
+		name   string
+		pml    models.ParsedPML
+		want   []string // extensions, in order
+		wantAt string   // String() of the first issue
+	}{
+		{
+			name: "plain casbin",
+			pml: models.ParsedPML{
+				Policies: []models.Policy{{Type: "p", Subject: "app_t", Object: "/opt/app", Action: "read", Effect: "allow"}},
+				Roles:    []models.RoleRelation{{Type: "g", Member: "alice", Role: "app_r"}},
+			},
+		},
+		{
+			name: "classes, conditions and annotations",
+			pml: models.ParsedPML{
+				Policies: []models.Policy{
+					{Type: "p", Subject: "app_t@level=s1", Object: "/srv/app?cond=app_ok::dir@audit=true", Action: "search::dir", Effect: "allow",
+						File: "policy.csv", Line: 3},
+				},
+			},
+			want:   []string{"?cond=", "object class", "action class", "@level", "@audit"},
+			wantAt: "policy.csv:3: object '/srv/app?cond=app_ok::dir@audit=true' uses a ?cond= boolean condition",
+		},
+		{
+			name: "rule types, transitions and comments",
+			pml: models.ParsedPML{
+				Policies: []models.Policy{
+					{Type: "p3", Subject: "app_t", Object: "/opt/app", Action: "read", Effect: "allow"},
+					{Type: "p2", Subject: "init_t", Object: "/opt/app/bin", Action: "transition", Effect: "app_t"},
+					{Type: "p", Subject: "app_t", Object: "/opt/app", Action: "read", Effect: "allow", Comment: "why"},
+				},
+				Roles: []models.RoleRelation{{Type: "ra", Member: "user_r", Role: "app_r"}},
+				Genfs: []models.GenfsDeclaration{{FSType: "proc", Path: "/app", Type: "app_proc_t"}},
+				SIDs:  []models.SIDDeclaration{{Name: "kernel", Type: "kernel_t"}},
+			},
+			want: []string{"rule type", "transition", "inline comment", "rule type", "rule type", "rule type"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.pml.Model = model
+			issues := CheckCasbinCompat(&tt.pml)
+			if len(issues) != len(tt.want) {
+				t.Fatalf("issues = %v, want extensions %v", issues, tt.want)
+			}
+			for i, want := range tt.want {
+				if issues[i].Extension != want {
+					t.Errorf("issue %d extension = %q, want %q", i, issues[i].Extension, want)
+				}
+			}
+			if tt.wantAt != "" && issues[0].String() != tt.wantAt {
+				t.Errorf("first issue = %q, want %q", issues[0].String(), tt.wantAt)
+			}
+		})
+	}
+
+	fieldCount := models.ParsedPML{
+		Model:    &models.PMLModel{PolicyDefinition: map[string][]string{"p": {"sub", "obj", "act"}}},
+		Policies: []models.Policy{{Type: "p", Subject: "app_t", Object: "/opt/app", Action: "read", Effect: "allow"}},
+	}
+	if issues := CheckCasbinCompat(&fieldCount); len(issues) != 1 || issues[0].Extension != "field count" {
+		t.Errorf("expected a field count issue, got %v", issues)
+	}
+}
@@ -2,6 +2,7 @@ package compiler
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/cici0602/pml-to-selinux/models"
@@ -254,6 +255,141 @@ func FormatDiff(result *DiffResult) string {
 	return builder.String()
 }
 
+// EquivalenceResult reports whether two policies grant exactly the same access
+type EquivalenceResult struct {
+	Equivalent bool
+	OnlyInA    []string // Access granted only by the first policy
+	OnlyInB    []string // Access granted only by the second policy
+}
+
+// CheckEquivalence compares the access two policies define: allow rules,
+// conditional and optional allow rules, auditallow, dontaudit and neverallow
+// rules, type transitions and interface calls. Rules are canonicalized first, so
+// splitting or merging rules, class sets and permission order do not count as
+// differences.
+func CheckEquivalence(a, b *models.SELinuxPolicy) *EquivalenceResult {
+	accessA := canonicalAccess(a)
+	accessB := canonicalAccess(b)
+
+	result := &EquivalenceResult{
+		OnlyInA: accessDifference(accessA, accessB),
+		OnlyInB: accessDifference(accessB, accessA),
+	}
+	result.Equivalent = len(result.OnlyInA) == 0 && len(result.OnlyInB) == 0
+	return result
+}
+
+// canonicalAccess maps each rule key, e.g. "allow source target:class" or
+// "if (cond) allow source target:class", to the set of permissions it names.
+// Statements without permissions, such as transitions, map to an empty set.
+func canonicalAccess(policy *models.SELinuxPolicy) map[string]map[string]bool {
+	access := make(map[string]map[string]bool)
+	add := func(prefix string, rules []models.AllowRule) {
+		for _, rule := range rules {
+			for _, class := range rule.AllClasses() {
+				key := fmt.Sprintf("%s %s %s:%s", prefix, rule.SourceType, rule.TargetType, class)
+				if rule.Condition != "" {
+					key = fmt.Sprintf("if (%s) %s", rule.Condition, key)
+				}
+				if rule.Optional != "" {
+					key = "optional " + key
+				}
+				if access[key] == nil {
+					access[key] = make(map[string]bool)
+				}
+				for _, perm := range rule.Permissions {
+					access[key][perm] = true
+				}
+			}
+		}
+	}
+	add("allow", policy.Rules)
+	add("allow", policy.CondRules)
+	add("allow", policy.OptionalRules)
+	add("auditallow", policy.AuditRules)
+	add("dontaudit", policy.DontauditRules)
+	add("neverallow", neverallowAsAllowRules(policy.NeverallowRules))
+
+	for _, trans := range policy.Transitions {
+		access[fmt.Sprintf("type_transition %s %s:%s %s", trans.SourceType, trans.TargetType, trans.Class, trans.NewType)] = map[string]bool{}
+	}
+	for _, trans := range policy.NamedTransitions {
+		access[fmt.Sprintf("type_transition %s %s:%s %s \"%s\"", trans.SourceType, trans.TargetType, trans.Class, trans.NewType, trans.Filename)] = map[string]bool{}
+	}
+	for _, call := range policy.InterfaceCalls {
+		access[fmt.Sprintf("%s(%s)", call.Name, strings.Join(call.Args, ", "))] = map[string]bool{}
+	}
+	return access
+}
+
+// neverallowAsAllowRules converts neverallow rules to allow rules so they are keyed like the others
+func neverallowAsAllowRules(rules []models.NeverallowRule) []models.AllowRule {
+	converted := make([]models.AllowRule, 0, len(rules))
+	for _, rule := range rules {
+		converted = append(converted, models.AllowRule{
+			SourceType:  rule.SourceType,
+			TargetType:  rule.TargetType,
+			Class:       rule.Class,
+			Permissions: rule.Permissions,
+		})
+	}
+	return converted
+}
+
+// accessDifference renders the permissions in from that are missing in other, one rule
+// per key, and the statements without permissions that other lacks altogether
+func accessDifference(from, other map[string]map[string]bool) []string {
+	diff := make([]string, 0)
+	for key, perms := range from {
+		if len(perms) == 0 {
+			if _, ok := other[key]; !ok {
+				diff = append(diff, key)
+			}
+			continue
+		}
+		missing := make([]string, 0)
+		for perm := range perms {
+			if !other[key][perm] {
+				missing = append(missing, perm)
+			}
+		}
+		if len(missing) > 0 {
+			sort.Strings(missing)
+			diff = append(diff, fmt.Sprintf("%s { %s }", key, strings.Join(missing, " ")))
+		}
+	}
+	sort.Strings(diff)
+	return diff
+}
+
+// FormatEquivalence formats the equivalence result as a human-readable string
+func FormatEquivalence(result *EquivalenceResult) string {
+	if result.Equivalent {
+		return "Policies are equivalent.\n"
+	}
+
+	var builder strings.Builder
+	builder.WriteString("Policies are NOT equivalent.\n\n")
+
+	if len(result.OnlyInA) > 0 {
+		builder.WriteString("Only granted by A:\n")
+		for _, r := range result.OnlyInA {
+			builder.WriteString(fmt.Sprintf("  - %s\n", r))
+		}
+		builder.WriteString("\n")
+	}
+
+	if len(result.OnlyInB) > 0 {
+		builder.WriteString("Only granted by B:\n")
+		for _, r := range result.OnlyInB {
+			builder.WriteString(fmt.Sprintf("  + %s\n", r))
+		}
+		builder.WriteString("\n")
+	}
+
+	return builder.String()
+}
+
 // ConflictAnalysis contains detected policy conflicts
 type ConflictAnalysis struct {
 	AllowDenyConflicts   []string // Rules where same access is both allowed and denied
@@ -261,18 +397,26 @@ type ConflictAnalysis struct {
 	TypeMismatches       []string // Type usage inconsistencies
 	MissingDependencies  []string // Types used but not declared
 	CircularDependencies []string // Circular dependencies in type transitions
+	TrappedDomains       []string // Transition targets lacking the self-process permissions to run
 }
 
+// minimalDomainPermissions are the process permissions a domain needs on itself to function
+var minimalDomainPermissions = []string{"fork", "sigchld"}
+
 // DetectConflicts analyzes a policy for potential conflicts
 func DetectConflicts(policy *models.SELinuxPolicy) *ConflictAnalysis {
 	analysis := &ConflictAnalysis{
-		AllowDenyConflicts:   make([]string, 0), // Not used in simplified version
+		AllowDenyConflicts:   make([]string, 0),
 		OverlappingRules:     make([]string, 0),
 		TypeMismatches:       make([]string, 0),
 		MissingDependencies:  make([]string, 0),
 		CircularDependencies: make([]string, 0),
+		TrappedDomains:       make([]string, 0),
 	}
 
+	// Check for allowed access a neverallow forbids
+	analysis.AllowDenyConflicts = detectAllowDenyConflicts(policy)
+
 	// Check for overlapping rules
 	analysis.OverlappingRules = detectOverlappingRules(policy)
 
@@ -282,13 +426,33 @@ func DetectConflicts(policy *models.SELinuxPolicy) *ConflictAnalysis {
 	// Check for circular dependencies in transitions
 	analysis.CircularDependencies = detectCircularDependencies(policy)
 
+	// Check that domains entered by a transition can run
+	analysis.TrappedDomains = detectTrappedDomains(policy)
+
 	return analysis
 }
 
-// detectAllowDenyConflicts - Removed in simplified version (no deny rules)
-// Deny rules are not supported in the MVP version
+// detectAllowDenyConflicts finds allow rules granting permissions a neverallow
+// forbids for the same source, target and class; such a policy does not build
 func detectAllowDenyConflicts(policy *models.SELinuxPolicy) []string {
-	return make([]string, 0)
+	conflicts := make([]string, 0)
+
+	for _, never := range policy.NeverallowRules {
+		for _, rule := range append(append([]models.AllowRule(nil), policy.Rules...), policy.CondRules...) {
+			if rule.SourceType != never.SourceType || rule.TargetType != never.TargetType ||
+				!containsAttribute(rule.AllClasses(), never.Class) {
+				continue
+			}
+			for _, perm := range never.Permissions {
+				if containsAttribute(rule.Permissions, perm) {
+					conflicts = append(conflicts, fmt.Sprintf("%s -> %s:%s %s is both allowed and neverallowed",
+						never.SourceType, never.TargetType, never.Class, perm))
+				}
+			}
+		}
+	}
+
+	return conflicts
 }
 
 // detectOverlappingRules finds rules that may have unintended overlaps
@@ -343,6 +507,11 @@ func detectMissingTypes(policy *models.SELinuxPolicy) []string {
 		usedTypes[trans.TargetType] = true
 		usedTypes[trans.NewType] = true
 	}
+	for _, trans := range policy.NamedTransitions {
+		usedTypes[trans.SourceType] = true
+		usedTypes[trans.TargetType] = true
+		usedTypes[trans.NewType] = true
+	}
 
 	// Find missing types
 	for typeName := range usedTypes {
@@ -404,6 +573,47 @@ func detectCircularDependencies(policy *models.SELinuxPolicy) []string {
 	return circular
 }
 
+// detectTrappedDomains finds domains reachable via process transitions that lack the
+// minimal self-process permissions; processes entering them compile fine but break at runtime
+func detectTrappedDomains(policy *models.SELinuxPolicy) []string {
+	trapped := make([]string, 0)
+
+	checked := make(map[string]bool)
+	for _, trans := range policy.Transitions {
+		if trans.Class != "process" || checked[trans.NewType] {
+			continue
+		}
+		checked[trans.NewType] = true
+
+		// Collect the process permissions the domain holds on itself
+		granted := make(map[string]bool)
+		for _, rule := range policy.Rules {
+			if rule.SourceType != trans.NewType || (rule.TargetType != trans.NewType && rule.TargetType != "self") {
+				continue
+			}
+			if !containsAttribute(rule.AllClasses(), "process") {
+				continue
+			}
+			for _, perm := range rule.Permissions {
+				granted[perm] = true
+			}
+		}
+
+		missing := make([]string, 0)
+		for _, perm := range minimalDomainPermissions {
+			if !granted[perm] {
+				missing = append(missing, perm)
+			}
+		}
+		if len(missing) > 0 {
+			trapped = append(trapped, fmt.Sprintf("Domain '%s' is entered from '%s' but lacks self:process { %s }",
+				trans.NewType, trans.SourceType, strings.Join(missing, " ")))
+		}
+	}
+
+	return trapped
+}
+
 // FormatConflictAnalysis formats conflict analysis as a human-readable string
 func FormatConflictAnalysis(analysis *ConflictAnalysis) string {
 	var builder strings.Builder
@@ -446,6 +656,15 @@ func FormatConflictAnalysis(analysis *ConflictAnalysis) string {
 		builder.WriteString("\n")
 	}
 
+	if len(analysis.TrappedDomains) > 0 {
+		hasConflicts = true
+		builder.WriteString("Trapped Domains:\n")
+		for _, trapped := range analysis.TrappedDomains {
+			builder.WriteString(fmt.Sprintf("  ! %s\n", trapped))
+		}
+		builder.WriteString("\n")
+	}
+
 	if !hasConflicts {
 		return "No conflicts detected.\n"
 	}
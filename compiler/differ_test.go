@@ -1,6 +1,7 @@
 package compiler
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/cici0602/pml-to-selinux/models"
@@ -121,3 +122,80 @@ func TestFormatDiff_WithChanges(t *testing.T) {
 		t.Error("Expected non-empty diff output")
 	}
 }
+
+func TestCheckEquivalence(t *testing.T) {
+	split := &models.SELinuxPolicy{
+		Rules: []models.AllowRule{
+			{SourceType: "httpd_t", TargetType: "httpd_log_t", Class: "file", Permissions: []string{"write", "open"}},
+			{SourceType: "httpd_t", TargetType: "httpd_log_t", Class: "file", Permissions: []string{"append"}},
+			{SourceType: "httpd_t", TargetType: "httpd_log_t", Class: "lnk_file", Permissions: []string{"read"}},
+		},
+	}
+	merged := &models.SELinuxPolicy{
+		Rules: []models.AllowRule{
+			{SourceType: "httpd_t", TargetType: "httpd_log_t", Class: "file", Permissions: []string{"append", "open", "write"}},
+			{SourceType: "httpd_t", TargetType: "httpd_log_t", Class: "lnk_file", Permissions: []string{"read"}},
+		},
+	}
+
+	if result := CheckEquivalence(split, merged); !result.Equivalent {
+		t.Errorf("split and merged rules should be equivalent, got %+v", result)
+	}
+
+	merged.Rules[0].Permissions = []string{"append", "open"}
+	result := CheckEquivalence(split, merged)
+	if result.Equivalent {
+		t.Fatal("policies with different permissions should not be equivalent")
+	}
+	if len(result.OnlyInA) != 1 || result.OnlyInA[0] != "allow httpd_t httpd_log_t:file { write }" {
+		t.Errorf("OnlyInA = %v", result.OnlyInA)
+	}
+	if len(result.OnlyInB) != 0 {
+		t.Errorf("OnlyInB = %v, want none", result.OnlyInB)
+	}
+}
+
+func TestDetectConflicts_TrappedDomains(t *testing.T) {
+	policy := &models.SELinuxPolicy{
+		Transitions: []models.TypeTransition{
+			{SourceType: "init_t", TargetType: "httpd_exec_t", Class: "process", NewType: "httpd_t"},
+			{SourceType: "init_t", TargetType: "worker_exec_t", Class: "process", NewType: "worker_t"},
+			{SourceType: "unconfined_t", TargetType: "worker_exec_t", Class: "process", NewType: "worker_t"},
+			{SourceType: "httpd_t", TargetType: "var_log_t", Class: "file", NewType: "httpd_log_t"},
+		},
+		Rules: []models.AllowRule{
+			{SourceType: "httpd_t", TargetType: "self", Class: "process", Permissions: []string{"fork", "sigchld", "signal"}},
+			{SourceType: "worker_t", TargetType: "worker_t", Class: "process", Permissions: []string{"fork"}},
+			{SourceType: "worker_t", TargetType: "init_t", Class: "process", Permissions: []string{"sigchld"}},
+		},
+	}
+
+	analysis := DetectConflicts(policy)
+	if len(analysis.TrappedDomains) != 1 {
+		t.Fatalf("Expected 1 trapped domain, got %v", analysis.TrappedDomains)
+	}
+	if !contains(analysis.TrappedDomains[0], "'worker_t'") || !contains(analysis.TrappedDomains[0], "{ sigchld }") {
+		t.Errorf("Unexpected trapped domain report: %s", analysis.TrappedDomains[0])
+	}
+	if !contains(FormatConflictAnalysis(analysis), "Trapped Domains:") {
+		t.Error("FormatConflictAnalysis() missing trapped domains section")
+	}
+}
+
+func TestDetectConflicts_AllowDeny(t *testing.T) {
+	policy := &models.SELinuxPolicy{
+		ModuleName: "app",
+		Rules: []models.AllowRule{
+			{SourceType: "app_t", TargetType: "app_data_t", Class: "file", Permissions: []string{"read", "write"}},
+		},
+		NeverallowRules: []models.NeverallowRule{
+			{SourceType: "app_t", TargetType: "app_data_t", Class: "file", Permissions: []string{"write", "append"}},
+			{SourceType: "app_t", TargetType: "shadow_t", Class: "file", Permissions: []string{"read"}},
+		},
+	}
+
+	analysis := DetectConflicts(policy)
+	if len(analysis.AllowDenyConflicts) != 1 || !strings.Contains(analysis.AllowDenyConflicts[0], "app_t -> app_data_t:file write") {
+		t.Errorf("AllowDenyConflicts = %v, want the allowed write", analysis.AllowDenyConflicts)
+	}
+}
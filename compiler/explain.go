@@ -0,0 +1,113 @@
+package compiler
+
+import (
+	"strings"
+
+	"github.com/cici0602/pml-to-selinux/mapping"
+)
+
+// ConflictExplanation shows why the objects of a conflict overlap
+type ConflictExplanation struct {
+	AllowObject  string
+	DenyObject   string
+	AllowPattern string // SELinux regex of the allow rule's object
+	DenyPattern  string // SELinux regex of the deny rule's object
+	SamplePath   string // A path both regexes match, empty if none was found
+}
+
+// ExplainConflict converts both objects of a conflict to SELinux regexes and
+// searches for a sample path matching both, which demonstrates the overlap
+func ExplainConflict(conflict ConflictInfo) ConflictExplanation {
+	pathMapper := mapping.NewPathMapper()
+	explanation := ConflictExplanation{
+		AllowObject:  conflict.AllowRule.Object,
+		DenyObject:   conflict.DenyRule.Object,
+		AllowPattern: pathMapper.ConvertToSELinuxPattern(conflict.AllowRule.Object),
+		DenyPattern:  pathMapper.ConvertToSELinuxPattern(conflict.DenyRule.Object),
+	}
+
+	candidates := append(samplePaths(explanation.AllowObject), samplePaths(explanation.DenyObject)...)
+	for _, candidate := range candidates {
+		allowMatch, err := pathMapper.MatchPattern(explanation.AllowPattern, candidate)
+		if err != nil || !allowMatch {
+			continue
+		}
+		denyMatch, err := pathMapper.MatchPattern(explanation.DenyPattern, candidate)
+		if err != nil || !denyMatch {
+			continue
+		}
+		explanation.SamplePath = candidate
+		break
+	}
+
+	return explanation
+}
+
+// samplePaths returns concrete paths an object pattern is likely to match:
+// the pattern with every wildcard filled in, a child of it, and its parent directory
+func samplePaths(object string) []string {
+	sample := instantiatePattern(object)
+	paths := []string{sample, strings.TrimSuffix(sample, "/") + "/sample"}
+	if i := strings.LastIndex(sample, "/"); i > 0 {
+		paths = append(paths, sample[:i])
+	}
+	return paths
+}
+
+// instantiatePattern fills the wildcards of a Casbin path pattern with literals:
+// '*', '**' and '?' become "sample"-like names, {a,b} its first alternative and
+// [a-z] its first character; optional regex groups such as (/.*)? are dropped
+// and escaped characters such as \[ are kept as literals
+func instantiatePattern(pattern string) string {
+	var result strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch c {
+		case '\\':
+			// An escaped character is a literal
+			if i+1 < len(pattern) {
+				i++
+			}
+			result.WriteByte(pattern[i])
+		case '*':
+			if strings.HasPrefix(pattern[i:], "**") {
+				i++
+			}
+			result.WriteString("sample")
+		case '?':
+			result.WriteByte('x')
+		case '{':
+			end := strings.IndexByte(pattern[i:], '}')
+			if end == -1 {
+				result.WriteByte(c)
+				continue
+			}
+			first, _, _ := strings.Cut(pattern[i+1:i+end], ",")
+			result.WriteString(instantiatePattern(strings.TrimSpace(first)))
+			i += end
+		case '(':
+			// A user-written regex group such as (/.*)? is left out when optional
+			end := strings.IndexByte(pattern[i:], ')')
+			if end == -1 || !strings.HasPrefix(pattern[i+end+1:], "?") {
+				result.WriteByte(c)
+				continue
+			}
+			i += end + 1
+		case '[':
+			end := strings.IndexByte(pattern[i:], ']')
+			if end <= 1 {
+				result.WriteByte(c)
+				continue
+			}
+			first := pattern[i+1]
+			if first == '^' || first == '!' {
+				first = '_'
+			}
+			result.WriteByte(first)
+			i += end
+		default:
+			result.WriteByte(c)
+		}
+	}
+	return result.String()
+}
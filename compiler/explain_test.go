@@ -0,0 +1,37 @@
+package compiler
+
+import (
+	"testing"
+
+	"github.com/cici0602/pml-to-selinux/models"
+)
+
+func TestExplainConflict(t *testing.T) {
+	tests := []struct {
+		name       string
+		allow      string
+		deny       string
+		wantSample string
+	}{
+		{name: "identical objects", allow: "/var/www/index.html", deny: "/var/www/index.html", wantSample: "/var/www/index.html"},
+		{name: "recursive allow", allow: "/var/www/*", deny: "/var/www/private/*.key", wantSample: "/var/www/private/sample.key"},
+		{name: "char class", allow: "/etc/app/*", deny: "/etc/app/[a-z]*.conf", wantSample: "/etc/app/asample.conf"},
+		{name: "shared directory only", allow: "/srv/a/b*", deny: "/srv/a/c*", wantSample: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conflict := ConflictInfo{
+				AllowRule: models.DecodedPolicy{Policy: models.Policy{Object: tt.allow}},
+				DenyRule:  models.DecodedPolicy{Policy: models.Policy{Object: tt.deny}},
+			}
+			explanation := ExplainConflict(conflict)
+			if explanation.AllowPattern == "" || explanation.DenyPattern == "" {
+				t.Errorf("expected both patterns, got %+v", explanation)
+			}
+			if explanation.SamplePath != tt.wantSample {
+				t.Errorf("SamplePath = %q, want %q", explanation.SamplePath, tt.wantSample)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package compiler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cici0602/pml-to-selinux/mapping"
+	"github.com/cici0602/pml-to-selinux/models"
+)
+
+// ExcludePolicies drops the policy lines whose subject is one of subjects or
+// whose object matches one of the object patterns, so a subset module can be
+// compiled from a larger policy. Object patterns use the path pattern syntax
+// and are matched through their SELinux regex; objects that are not paths are
+// matched literally. It returns the number of lines dropped.
+func ExcludePolicies(pml *models.ParsedPML, subjects, objects []string) (int, error) {
+	if len(subjects) == 0 && len(objects) == 0 {
+		return 0, nil
+	}
+
+	typeMapper := mapping.NewTypeMapper("")
+	pathMapper := mapping.NewPathMapper()
+
+	excludedSubjects := make(map[string]bool)
+	for _, subject := range subjects {
+		excludedSubjects[typeMapper.SubjectToType(subject)] = true
+	}
+	objectPatterns := make([]string, 0, len(objects))
+	for _, object := range objects {
+		pattern := pathMapper.ConvertToSELinuxPattern(object)
+		if _, err := pathMapper.MatchPattern(pattern, ""); err != nil {
+			return 0, fmt.Errorf("exclude object '%s': %w", object, err)
+		}
+		objectPatterns = append(objectPatterns, pattern)
+	}
+
+	kept := make([]models.Policy, 0, len(pml.Policies))
+	for _, policy := range pml.Policies {
+		subject, _, _ := strings.Cut(policy.Subject, "@")
+		if excludedSubjects[typeMapper.SubjectToType(subject)] {
+			continue
+		}
+		if excludedObject(pathMapper, policyPath(policy.Object), objects, objectPatterns) {
+			continue
+		}
+		kept = append(kept, policy)
+	}
+
+	removed := len(pml.Policies) - len(kept)
+	pml.Policies = kept
+	return removed, nil
+}
+
+// policyPath strips the annotations, class and condition from a raw policy object
+func policyPath(object string) string {
+	object, _, _ = strings.Cut(object, "@")
+	object, _, _ = strings.Cut(object, "::")
+	object, _, _ = strings.Cut(object, "?cond=")
+	return object
+}
+
+// excludedObject reports whether the object equals one of the excluded objects
+// or, for paths, matches one of their patterns
+func excludedObject(pathMapper *mapping.PathMapper, object string, objects, patterns []string) bool {
+	for i, excluded := range objects {
+		if object == excluded {
+			return true
+		}
+		if !strings.HasPrefix(object, "/") || !strings.HasPrefix(excluded, "/") {
+			continue
+		}
+		if matched, err := pathMapper.MatchPattern(patterns[i], object); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
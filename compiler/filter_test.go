@@ -0,0 +1,64 @@
+package compiler
+
+import (
+	"testing"
+
+	"github.com/cici0602/pml-to-selinux/models"
+)
+
+func TestExcludePolicies(t *testing.T) {
+	newPML := func() *models.ParsedPML {
+		return &models.ParsedPML{Policies: []models.Policy{
+			{Type: "p", Subject: "nginx_t", Object: "/var/www/*", Action: "read", Effect: "allow"},
+			{Type: "p", Subject: "app_t", Object: "/tmp/app/cache@nofc", Action: "write", Effect: "allow"},
+			{Type: "p", Subject: "app_t", Object: "/tmp/*", Action: "write", Effect: "allow"},
+			{Type: "p", Subject: "app_t", Object: "/etc/app.conf::file", Action: "read", Effect: "allow"},
+			{Type: "p", Subject: "app_t", Object: "tcp:8080", Action: "name_bind", Effect: "allow"},
+		}}
+	}
+
+	tests := []struct {
+		name     string
+		subjects []string
+		objects  []string
+		want     []string // objects of the remaining policies
+	}{
+		{
+			name:     "subject without _t suffix",
+			subjects: []string{"nginx"},
+			want:     []string{"/tmp/app/cache@nofc", "/tmp/*", "/etc/app.conf::file", "tcp:8080"},
+		},
+		{
+			name:    "object pattern matches paths below it",
+			objects: []string{"/tmp/*"},
+			want:    []string{"/var/www/*", "/etc/app.conf::file", "tcp:8080"},
+		},
+		{
+			name:    "exact object and non-path object",
+			objects: []string{"/etc/app.conf", "tcp:8080"},
+			want:    []string{"/var/www/*", "/tmp/app/cache@nofc", "/tmp/*"},
+		},
+		{
+			name: "no filters",
+			want: []string{"/var/www/*", "/tmp/app/cache@nofc", "/tmp/*", "/etc/app.conf::file", "tcp:8080"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pml := newPML()
+			removed, err := ExcludePolicies(pml, tt.subjects, tt.objects)
+			if err != nil {
+				t.Fatalf("ExcludePolicies() error = %v", err)
+			}
+			if removed != 5-len(tt.want) || len(pml.Policies) != len(tt.want) {
+				t.Fatalf("removed %d, kept %+v, want %v", removed, pml.Policies, tt.want)
+			}
+			for i, policy := range pml.Policies {
+				if policy.Object != tt.want[i] {
+					t.Errorf("policy %d object = %s, want %s", i, policy.Object, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package compiler
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/cici0602/pml-to-selinux/models"
+)
+
+// Severity of a lint finding
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Finding is a problem Lint found in a policy line
+type Finding struct {
+	File     string
+	Line     int
+	Severity Severity
+	Code     string // "syntax", "duplicate", "subsumed", the name of an analyzer lint or a LintPolicy check
+	Message  string
+	Risk     int // Impact of the problem from 0 to 100, see RiskScore
+}
+
+// String renders the finding as file:line: severity: message [code], without
+// the position for findings on the generated policy or the options
+func (f Finding) String() string {
+	if f.File == "" {
+		return fmt.Sprintf("%s: %s [%s]", f.Severity, f.Message, f.Code)
+	}
+	return fmt.Sprintf("%s:%d: %s: %s [%s]", f.File, f.Line, f.Severity, f.Message, f.Code)
+}
+
+// riskScores rank finding codes by impact: broken lines and access that lets a
+// domain run code it wrote rank highest, redundant lines lowest
+var riskScores = map[string]int{
+	"syntax":           100,
+	"options":          100,
+	"model":            100,
+	"generate":         100,
+	"wx":               90,
+	"unconfined-trans": 80,
+	"world-writable":   70,
+	"broad-perms":      60,
+	"root-wildcard":    60,
+	"exec-no-trans":    50,
+	"trapped-domain":   50,
+	"unknown-class":    40,
+	"conflict":         30,
+	"circular-trans":   20,
+	"subsumed":         10,
+	"duplicate":        5,
+	"overlap":          5,
+}
+
+// RiskScore returns the risk of findings with the given code, 0 for unknown codes
+func RiskScore(code string) int {
+	return riskScores[code]
+}
+
+// SortFindingsByRisk orders findings by descending risk, keeping the order of equal risks
+func SortFindingsByRisk(findings []Finding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Risk > findings[j].Risk
+	})
+}
+
+// FilterFindingsByRisk returns the findings whose risk is at least minRisk
+func FilterFindingsByRisk(findings []Finding, minRisk int) []Finding {
+	filtered := make([]Finding, 0, len(findings))
+	for _, f := range findings {
+		if f.Risk >= minRisk {
+			filtered = append(filtered, f)
+		}
+	}
+	return filtered
+}
+
+// TotalRisk sums the risk of the findings
+func TotalRisk(findings []Finding) int {
+	total := 0
+	for _, f := range findings {
+		total += f.Risk
+	}
+	return total
+}
+
+// LintOptions selects the lint passes Lint runs and configures them
+type LintOptions struct {
+	Enable              []string // Analyzer lints to enable in addition to the defaults
+	Disable             []string // Analyzer lints to disable
+	BroadPermsThreshold int      // Score above which broad-perms warns, 0 for the default
+	StrictPaths         bool     // Reject unclean object paths as syntax errors
+}
+
+// policyRulePrefix matches the "policy rule N: " or "policy rules N and M: "
+// prefix of analyzer messages, capturing the first rule number
+var policyRulePrefix = regexp.MustCompile(`^policy rules? (\d+)(?: and \d+)?: `)
+
+// Lint runs the lint passes on policy lines without a model or the rest of the
+// compile pipeline: syntax checks of each line, duplicate and subsumed rules,
+// and the enabled analyzer lints on the lines that passed the syntax checks.
+// Unknown lint names in opts are reported as findings without a line.
+// Findings carry the risk of their code and are sorted by file and line.
+func Lint(policies []models.Policy, opts LintOptions) []Finding {
+	findings := make([]Finding, 0)
+
+	// Syntax: lines that cannot be decoded or fail validation
+	parser := &Parser{}
+	analyzer := NewAnalyzer(&models.DecodedPML{})
+	analyzer.SetQuiet(true)
+	analyzer.SetStrictPaths(opts.StrictPaths)
+	valid := make([]models.DecodedPolicy, 0, len(policies))
+	origin := make([]models.Policy, 0, len(policies)) // source line of each valid policy
+	for i := range policies {
+		policy := policies[i]
+		decoded, err := parser.decodePolicy(&policy)
+		if err == nil {
+			err = analyzer.validatePolicy(len(valid), *decoded)
+		}
+		if err != nil {
+			findings = append(findings, Finding{
+				File:     policy.File,
+				Line:     policy.Line,
+				Severity: SeverityError,
+				Code:     "syntax",
+				Message:  findingMessage(err),
+			})
+			continue
+		}
+		valid = append(valid, *decoded)
+		origin = append(origin, policy)
+	}
+
+	// Duplicate and subsumed rules
+	for _, issue := range LintSource(policies) {
+		findings = append(findings, Finding{
+			File:     issue.File,
+			Line:     issue.Line,
+			Severity: SeverityWarning,
+			Code:     issue.Code,
+			Message:  issue.Message,
+		})
+	}
+
+	// Analyzer lints, each warning attributed to the line of the rule it names
+	analyzer = NewAnalyzer(&models.DecodedPML{Policies: valid})
+	analyzer.SetQuiet(true)
+	analyzer.SetBroadPermsThreshold(opts.BroadPermsThreshold)
+	for _, name := range opts.Enable {
+		if err := analyzer.EnableLint(name); err != nil {
+			findings = append(findings, Finding{Severity: SeverityError, Code: "options", Message: err.Error()})
+		}
+	}
+	for _, name := range opts.Disable {
+		if err := analyzer.DisableLint(name); err != nil {
+			findings = append(findings, Finding{Severity: SeverityError, Code: "options", Message: err.Error()})
+		}
+	}
+	for _, l := range lints {
+		if !analyzer.lintEnabled(l) {
+			continue
+		}
+		reported := len(analyzer.warnings)
+		l.run(analyzer)
+		for _, warning := range analyzer.warnings[reported:] {
+			finding := Finding{Severity: SeverityWarning, Code: l.name, Message: warning}
+			if match := policyRulePrefix.FindStringSubmatch(warning); match != nil {
+				if rule, err := strconv.Atoi(match[1]); err == nil && rule >= 1 && rule <= len(origin) {
+					finding.File = origin[rule-1].File
+					finding.Line = origin[rule-1].Line
+					finding.Message = warning[len(match[0]):]
+				}
+			}
+			findings = append(findings, finding)
+		}
+	}
+
+	for i := range findings {
+		findings[i].Risk = RiskScore(findings[i].Code)
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].File != findings[j].File {
+			return findings[i].File < findings[j].File
+		}
+		return findings[i].Line < findings[j].Line
+	})
+	return findings
+}
+
+// findingMessage strips the position a finding already carries from an error
+func findingMessage(err error) string {
+	var parseErr *ParseError
+	if errors.As(err, &parseErr) {
+		return parseErr.Message
+	}
+	msg := err.Error()
+	if match := policyRulePrefix.FindString(msg); match != "" {
+		return msg[len(match):]
+	}
+	return msg
+}
@@ -0,0 +1,89 @@
+package compiler
+
+import (
+	"testing"
+
+	"github.com/cici0602/pml-to-selinux/models"
+)
+
+func TestLint(t *testing.T) {
+	policies := []models.Policy{
+		{Type: "p", Subject: "app_t", Object: "/var/lib/app/*", Action: "write", Effect: "allow", File: "policy.csv", Line: 1},
+		{Type: "p", Subject: "app_t", Object: "/var/lib/app/*", Action: "execute", Effect: "allow", File: "policy.csv", Line: 2},
+		{Type: "p", Subject: "app_t", Object: "/var/lib/app/*", Action: "write", Effect: "allow", File: "policy.csv", Line: 3},
+		{Type: "p", Subject: "app_t", Object: "/etc/app@color=red", Action: "read", Effect: "allow", File: "policy.csv", Line: 4},
+		{Type: "p", Subject: "app_t", Object: "/etc/app", Action: "read", Effect: "maybe", File: "policy.csv", Line: 5},
+	}
+
+	findings := Lint(policies, LintOptions{})
+	want := []struct {
+		line     int
+		severity Severity
+		code     string
+	}{
+		{1, SeverityWarning, "wx"},
+		{3, SeverityWarning, "duplicate"},
+		{4, SeverityError, "syntax"},
+		{5, SeverityError, "syntax"},
+	}
+	if len(findings) != len(want) {
+		t.Fatalf("Lint() = %v, want %d findings", findings, len(want))
+	}
+	for i, w := range want {
+		f := findings[i]
+		if f.File != "policy.csv" || f.Line != w.line || f.Severity != w.severity || f.Code != w.code {
+			t.Errorf("finding %d = %v, want line %d %s [%s]", i, f, w.line, w.severity, w.code)
+		}
+	}
+	if findings[3].Message != "invalid effect 'maybe', must be 'allow', 'deny' or 'dontaudit'" {
+		t.Errorf("syntax message should not repeat the rule number, got %q", findings[3].Message)
+	}
+
+	findings = Lint(policies[:2], LintOptions{Disable: []string{"wx"}})
+	if len(findings) != 0 {
+		t.Errorf("Lint() with wx disabled = %v, want none", findings)
+	}
+
+	findings = Lint(nil, LintOptions{Enable: []string{"nope"}})
+	if len(findings) != 1 || findings[0].Code != "options" || findings[0].Severity != SeverityError {
+		t.Errorf("Lint() with an unknown lint = %v, want one options error", findings)
+	}
+}
+
+func TestFindingsByRisk(t *testing.T) {
+	policies := []models.Policy{
+		{Type: "p", Subject: "app_t", Object: "/var/lib/app/*", Action: "write", Effect: "allow", File: "policy.csv", Line: 1},
+		{Type: "p", Subject: "app_t", Object: "/var/lib/app/*", Action: "execute", Effect: "allow", File: "policy.csv", Line: 2},
+		{Type: "p", Subject: "app_t", Object: "/var/lib/app/*", Action: "write", Effect: "allow", File: "policy.csv", Line: 3},
+		{Type: "p", Subject: "app_t", Object: "/etc/app", Action: "read", Effect: "maybe", File: "policy.csv", Line: 4},
+	}
+
+	findings := Lint(policies, LintOptions{})
+	for _, f := range findings {
+		if f.Risk != RiskScore(f.Code) {
+			t.Errorf("finding %v has risk %d, want %d", f, f.Risk, RiskScore(f.Code))
+		}
+	}
+	if total := TotalRisk(findings); total != 100+90+5 {
+		t.Errorf("TotalRisk() = %d, want %d", total, 100+90+5)
+	}
+
+	SortFindingsByRisk(findings)
+	wantCodes := []string{"syntax", "wx", "duplicate"}
+	if len(findings) != len(wantCodes) {
+		t.Fatalf("findings = %v, want %d", findings, len(wantCodes))
+	}
+	for i, code := range wantCodes {
+		if findings[i].Code != code {
+			t.Errorf("finding %d = [%s], want [%s]", i, findings[i].Code, code)
+		}
+	}
+
+	filtered := FilterFindingsByRisk(findings, 50)
+	if len(filtered) != 2 || filtered[1].Code != "wx" {
+		t.Errorf("FilterFindingsByRisk(50) = %v, want syntax and wx", filtered)
+	}
+	if RiskScore("nope") != 0 {
+		t.Errorf("RiskScore() of an unknown code = %d, want 0", RiskScore("nope"))
+	}
+}
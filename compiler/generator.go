@@ -2,6 +2,12 @@ package compiler
 
 import (
 	"fmt"
+	"io"
+	"net"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/cici0602/pml-to-selinux/mapping"
@@ -15,8 +21,68 @@ type Generator struct {
 	typeMapper   *mapping.TypeMapper
 	pathMapper   *mapping.PathMapper
 	actionMapper *mapping.ActionMapper
+	levelMapper  *mapping.LevelMapper
+
+	// emitConstraints enables constrain statements derived from role relations
+	emitConstraints bool
+
+	// allowCritical downgrades file contexts matching system-critical paths to a warning
+	allowCritical bool
+
+	// baseTypes are declared by a shared base module and only required here
+	baseTypes map[string]bool
+
+	// emitExpansions enables expandattribute statements for g2 attributes
+	emitExpansions   bool
+	expandAttributes bool
+
+	// noFCPrefixes are path prefixes whose objects get allow rules but no file contexts
+	noFCPrefixes []string
+
+	// policyVersion is the target policydb version, 0 when unknown
+	policyVersion int
+	// enableMap grants the map permission, which needs MinMapPolicyVersion
+	enableMap bool
+
+	// basePolicy allows base-only statements such as initial SID contexts and constraints
+	basePolicy bool
+
+	// strictActions rejects actions that are neither mapped nor raw SELinux permissions
+	strictActions bool
+
+	// groupFileTypes puts every file_type type under a <module>_file_types attribute
+	groupFileTypes bool
+
+	// userdom labels objects under /home/*/ through userdom interfaces instead of file contexts
+	userdom bool
+
+	// strict turns the empty-module warning into an error
+	strict bool
+
+	// denyMode selects how deny policies are rendered, DenyModeNeverallow by default
+	denyMode string
+
+	// deterministicAttributes sorts each type's attributes so typeattribute lines are diff-stable
+	deterministicAttributes bool
+
+	// out receives warnings, os.Stdout by default
+	out io.Writer
 }
 
+// Deny modes: deny policies become neverallow assertions, dontaudit rules
+// silencing the denial, or are skipped with a warning
+const (
+	DenyModeNeverallow = "neverallow"
+	DenyModeDontaudit  = "dontaudit"
+	DenyModeSkip       = "skip"
+)
+
+// MinXpermPolicyVersion is the first policydb version supporting ioctl extended permissions
+const MinXpermPolicyVersion = 30
+
+// MinMapPolicyVersion is the policydb version of the first kernel (4.13) checking the map permission
+const MinMapPolicyVersion = 31
+
 // NewGenerator creates a new Generator instance from decoded PML
 func NewGenerator(decoded *models.DecodedPML, moduleName string) *Generator {
 	return &Generator{
@@ -25,6 +91,118 @@ func NewGenerator(decoded *models.DecodedPML, moduleName string) *Generator {
 		typeMapper:   mapping.NewTypeMapper(moduleName),
 		pathMapper:   mapping.NewPathMapper(),
 		actionMapper: mapping.NewActionMapper(),
+		levelMapper:  mapping.NewLevelMapper(),
+		baseTypes:    make(map[string]bool),
+		denyMode:     DenyModeNeverallow,
+		out:          os.Stdout,
+
+		deterministicAttributes: true,
+	}
+}
+
+// SetOutput sets where warnings are written, os.Stdout by default
+func (g *Generator) SetOutput(w io.Writer) {
+	g.out = w
+}
+
+// SetEnableMap controls whether the "map" permission is granted alongside read and execute
+func (g *Generator) SetEnableMap(enabled bool) {
+	g.enableMap = enabled
+	g.actionMapper.SetMapEnabled(enabled)
+}
+
+// SetLevelMapper sets the mapper resolving named security levels and categories
+func (g *Generator) SetLevelMapper(levelMapper *mapping.LevelMapper) {
+	g.levelMapper = levelMapper
+}
+
+// SetNoFileContextPrefixes suppresses file-context generation for objects under
+// the given path prefixes, e.g. system-owned directories such as /tmp
+func (g *Generator) SetNoFileContextPrefixes(prefixes []string) {
+	g.noFCPrefixes = prefixes
+}
+
+// SetDeterministicAttributes controls whether type attributes are sorted (the default)
+// or kept in the order they were assigned
+func (g *Generator) SetDeterministicAttributes(enabled bool) {
+	g.deterministicAttributes = enabled
+}
+
+// SetPolicyVersion sets the target policydb version, which gates features such as
+// extended permissions that older kernels cannot load
+func (g *Generator) SetPolicyVersion(version int) {
+	g.policyVersion = version
+}
+
+// SetBasePolicy marks the output as a base policy rather than a loadable module,
+// which allows initial SID contexts (sid rules), constraints and object defaults
+func (g *Generator) SetBasePolicy(enabled bool) {
+	g.basePolicy = enabled
+}
+
+// SetStrictActions makes unknown actions an error instead of passing them
+// through as permissions of the same name, catching typos such as "raed"
+func (g *Generator) SetStrictActions(strict bool) {
+	g.strictActions = strict
+}
+
+// SetGroupFileTypes enables the <module>_file_types attribute grouping the module's file types
+func (g *Generator) SetGroupFileTypes(enabled bool) {
+	g.groupFileTypes = enabled
+}
+
+// SetUserdom makes objects under /home/*/ user home content: the .te calls
+// userdom_user_home_content for their types instead of the .fc labeling /home,
+// which would conflict with the labels the user domain policy manages
+func (g *Generator) SetUserdom(enabled bool) {
+	g.userdom = enabled
+}
+
+// SetStrict makes a module that grants nothing an error instead of a warning
+func (g *Generator) SetStrict(strict bool) {
+	g.strict = strict
+}
+
+// SetDenyMode selects how deny policies are rendered: DenyModeNeverallow (the
+// default), DenyModeDontaudit or DenyModeSkip
+func (g *Generator) SetDenyMode(mode string) {
+	g.denyMode = mode
+}
+
+// SetConstraints controls whether user-role and role-type constraints are generated
+func (g *Generator) SetConstraints(enabled bool) {
+	g.emitConstraints = enabled
+}
+
+// SetAllowCritical controls whether file contexts that match system-critical
+// paths (/etc/passwd, /bin/bash, ...) only warn instead of failing generation
+func (g *Generator) SetAllowCritical(allow bool) {
+	g.allowCritical = allow
+}
+
+// SetAttributeExpansion enables expandattribute statements for the attributes
+// assigned with g2 relations, expanding them (true) or keeping them (false)
+func (g *Generator) SetAttributeExpansion(expand bool) {
+	g.emitExpansions = true
+	g.expandAttributes = expand
+}
+
+// SetBaseModule registers a shared base module whose types are declared elsewhere.
+// Paths from the base keep the base module's type names, and those types are
+// added to the require block instead of being declared again.
+func (g *Generator) SetBaseModule(base *models.DecodedPML, baseName string) {
+	if baseName == "" {
+		baseName = NewGenerator(base, "").inferModuleName()
+	}
+	baseGenerator := NewGenerator(base, baseName)
+	for typeName := range baseGenerator.extractTypes() {
+		g.baseTypes[typeName] = true
+	}
+
+	for _, pmlPolicy := range base.Policies {
+		if strings.HasPrefix(pmlPolicy.Object, "/") {
+			g.typeMapper.AddCustomMapping(pmlPolicy.Object, baseGenerator.typeMapper.PathToType(pmlPolicy.Object))
+		}
 	}
 }
 
@@ -33,6 +211,10 @@ func (g *Generator) Generate() (*models.SELinuxPolicy, error) {
 	if g.decoded == nil {
 		return nil, fmt.Errorf("decoded PML cannot be nil")
 	}
+	if g.enableMap && g.policyVersion != 0 && g.policyVersion < MinMapPolicyVersion {
+		return nil, fmt.Errorf("the map permission requires policy version %d or later, policy version %d cannot load it",
+			MinMapPolicyVersion, g.policyVersion)
+	}
 
 	// Infer module name if not provided
 	moduleName := g.moduleName
@@ -45,20 +227,28 @@ func (g *Generator) Generate() (*models.SELinuxPolicy, error) {
 		Version:    "1.0.0",
 		Types:      make([]models.TypeDeclaration, 0),
 		Rules:      make([]models.AllowRule, 0),
-		// DenyRules removed - not supported in simplified version
+		// Deny policies become NeverallowRules or DontauditRules, see convertDenyRule
 		Transitions:  make([]models.TypeTransition, 0),
 		FileContexts: make([]models.FileContext, 0),
 		Capabilities: make([]models.CapabilityRule, 0),
 		PortBindings: make([]models.PortBinding, 0),
+		Constraints:  make([]models.Constraint, 0),
+		Roles:        make([]string, 0),
+		RoleAllows:   make([]models.RoleAllow, 0),
 	}
 
 	// Extract types from subjects and objects
 	types := g.extractTypes()
 	for typeName := range types {
+		if g.baseTypes[typeName] {
+			policy.BaseTypes = append(policy.BaseTypes, typeName)
+			continue
+		}
 		policy.Types = append(policy.Types, models.TypeDeclaration{
 			TypeName: typeName,
 		})
 	}
+	sort.Strings(policy.BaseTypes)
 
 	// Convert policies to SELinux rules
 	if err := g.convertPolicies(policy); err != nil {
@@ -69,27 +259,285 @@ func (g *Generator) Generate() (*models.SELinuxPolicy, error) {
 	if err := g.convertTransitions(policy); err != nil {
 		return nil, err
 	}
+	g.convertNamedTransitions(policy)
+	g.convertValidateTrans(policy)
+	g.convertObjectDefaults(policy)
+
+	// Convert role declarations and role allows
+	g.convertRoles(policy)
+
+	// Apply type attributes from g2 relations
+	g.convertTypeAttributes(policy)
 
 	// Generate file contexts from object paths
 	if err := g.generateFileContexts(policy); err != nil {
 		return nil, err
 	}
 
+	// Convert pseudo-filesystem labels
+	if err := g.convertGenfs(policy); err != nil {
+		return nil, err
+	}
+
+	// Convert network node and interface labels
+	if err := g.convertNetworkLabels(policy); err != nil {
+		return nil, err
+	}
+
+	// Keep interface calls, rendered as-is
+	policy.InterfaceCalls = append(policy.InterfaceCalls, g.decoded.InterfaceCalls...)
+
+	// Convert initial SID contexts (base policy only)
+	if err := g.convertInitialSIDs(policy); err != nil {
+		return nil, err
+	}
+
+	// Give attribute-less types the attributes their use implies
+	g.inferTypeAttributes(policy)
+	if g.groupFileTypes {
+		g.groupModuleFileTypes(policy)
+	}
+
+	// Refuse contexts that would relabel system-critical paths
+	if err := g.checkCriticalContexts(policy); err != nil {
+		return nil, err
+	}
+
+	// Generate constraints from role relations
+	if g.emitConstraints {
+		g.generateConstraints(policy)
+	}
+
+	// Levels above s0 need the MLS constraints enforcing them, which only a
+	// base policy declares; a module relies on its base policy's
+	if g.basePolicy && usesMLSLevels(policy) {
+		for _, c := range mapping.GenerateMLSConstraints() {
+			policy.AddConstraint(c)
+		}
+	}
+
+	// A module cannot declare portcon: its ports are labeled with semanage port instead
+	if !g.basePolicy && len(policy.PortBindings) > 0 {
+		for _, binding := range policy.PortBindings {
+			fmt.Fprintf(g.out, "Warning: %s port %s is not labeled by the module, a module cannot declare portcon; run: semanage port -a -t %s -p %s %s\n",
+				binding.Protocol, binding.Ports(), binding.PortType, binding.Protocol, binding.Ports())
+		}
+		policy.SemanagePorts = policy.PortBindings
+		policy.PortBindings = make([]models.PortBinding, 0)
+	}
+
+	// Constraints, object defaults and object contexts belong to the base policy
+	if statement := policy.BaseOnlyStatement(); statement != "" && !g.basePolicy {
+		return nil, fmt.Errorf("%s statements are only valid in a base policy (use --base-policy or --mode monolithic)", statement)
+	}
+
+	// Reject identifiers that would not compile
+	if err := checkReservedIdentifiers(policy); err != nil {
+		return nil, err
+	}
+
+	// Drop neverallow permissions the module allows itself
+	g.checkNeverallowRules(policy)
+
+	// Flag modules that install but grant nothing
+	if err := g.checkEmptyModule(policy); err != nil {
+		return nil, err
+	}
+
+	if g.deterministicAttributes {
+		for i := range policy.Types {
+			sort.Strings(policy.Types[i].Attributes)
+		}
+	}
+
 	return policy, nil
 }
 
-// inferModuleName infers module name from policy structure
+// checkReservedIdentifiers ensures the module, type and attribute names
+// do not collide with SELinux policy language keywords
+func checkReservedIdentifiers(policy *models.SELinuxPolicy) error {
+	if mapping.IsReservedWord(policy.ModuleName) {
+		return fmt.Errorf("module name '%s' is a reserved SELinux keyword, choose another name with --name", policy.ModuleName)
+	}
+
+	for _, typeDecl := range policy.Types {
+		if mapping.IsReservedWord(typeDecl.TypeName) {
+			return fmt.Errorf("type name '%s' is a reserved SELinux keyword", typeDecl.TypeName)
+		}
+		for _, attr := range typeDecl.Attributes {
+			if mapping.IsReservedWord(attr) {
+				return fmt.Errorf("attribute '%s' on type '%s' is a reserved SELinux keyword", attr, typeDecl.TypeName)
+			}
+		}
+	}
+
+	for _, role := range policy.Roles {
+		if mapping.IsReservedWord(role) {
+			return fmt.Errorf("role name '%s' is a reserved SELinux keyword", role)
+		}
+	}
+
+	return nil
+}
+
+// checkCriticalContexts tests every generated file context against the built-in
+// list of system-critical paths so an application policy cannot mislabel them
+func (g *Generator) checkCriticalContexts(policy *models.SELinuxPolicy) error {
+	for _, fc := range policy.FileContexts {
+		matched, err := g.pathMapper.MatchCriticalPaths(fc.PathPattern)
+		if err != nil {
+			return fmt.Errorf("file context '%s': %w", fc.PathPattern, err)
+		}
+		if len(matched) == 0 {
+			continue
+		}
+
+		msg := fmt.Sprintf("file context '%s' (%s) would relabel system-critical path %s",
+			fc.PathPattern, fc.SELinuxType, strings.Join(matched, ", "))
+		if !g.allowCritical {
+			return fmt.Errorf("%s, use --allow-critical to override", msg)
+		}
+		fmt.Fprintf(g.out, "Warning: %s\n", msg)
+	}
+	return nil
+}
+
+// checkEmptyModule warns, or fails in strict mode, when the policy has no allow
+// rules and no type transitions: the module would only declare types, which
+// almost always means the allows were misformatted or every rule was a deny
+func (g *Generator) checkEmptyModule(policy *models.SELinuxPolicy) error {
+	if len(policy.Rules)+len(policy.CondRules)+len(policy.OptionalRules) > 0 ||
+		len(policy.Transitions)+len(policy.NamedTransitions)+len(policy.InterfaceCalls) > 0 {
+		return nil
+	}
+
+	denies := 0
+	for _, p := range g.decoded.Policies {
+		if p.Effect == "deny" {
+			denies++
+		}
+	}
+	msg := fmt.Sprintf("module '%s' has no allow rules, type transitions or interface calls and would grant nothing (%d of %d policy rules are deny rules)",
+		policy.ModuleName, denies, len(g.decoded.Policies))
+	if g.strict {
+		return fmt.Errorf("%s", msg)
+	}
+	fmt.Fprintf(g.out, "Warning: %s\n", msg)
+	return nil
+}
+
+// generateConstraints builds constrain statements from the g role relations.
+// Members ending in _u are SELinux users and members ending in _t are domains;
+// each may only transition into the roles it is related to.
+func (g *Generator) generateConstraints(policy *models.SELinuxPolicy) {
+	userRoles := make(map[string][]string)
+	typeRoles := make(map[string][]string)
+	for _, rel := range g.decoded.Roles {
+		switch {
+		case strings.HasSuffix(rel.Member, "_u"):
+			userRoles[rel.Member] = append(userRoles[rel.Member], rel.Role)
+		case strings.HasSuffix(rel.Member, "_t"):
+			typeRoles[rel.Member] = append(typeRoles[rel.Member], rel.Role)
+		}
+	}
+
+	for _, user := range sortedKeys(userRoles) {
+		policy.AddConstraint(models.Constraint{
+			Classes:     []string{"process"},
+			Permissions: []string{"transition"},
+			Expression:  fmt.Sprintf("u2 != %s or r2 == %s", user, nameSet(userRoles[user])),
+			Comment:     fmt.Sprintf("User %s may only enter its authorized roles", user),
+		})
+	}
+
+	for _, domain := range sortedKeys(typeRoles) {
+		policy.AddConstraint(models.Constraint{
+			Classes:     []string{"process"},
+			Permissions: []string{"transition"},
+			Expression:  fmt.Sprintf("t2 != %s or r2 == %s", domain, nameSet(typeRoles[domain])),
+			Comment:     fmt.Sprintf("Domain %s may only run in its authorized roles", domain),
+		})
+	}
+}
+
+// usesMLSLevels reports whether any file context carries a level above s0
+func usesMLSLevels(policy *models.SELinuxPolicy) bool {
+	for _, fc := range policy.FileContexts {
+		if fc.Range == nil {
+			continue
+		}
+		for _, level := range []models.SecurityLevel{fc.Range.Low, fc.Range.High} {
+			if level.Sensitivity > 0 || len(level.Categories) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sortedKeys returns the keys of m in sorted order
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// nameSet renders names for a constraint expression: "a" or "{ a b }"
+func nameSet(names []string) string {
+	names = uniqueStringSlice(names)
+	sort.Strings(names)
+	if len(names) == 1 {
+		return names[0]
+	}
+	return "{ " + strings.Join(names, " ") + " }"
+}
+
+// inferModuleName infers the module name from the dominant domain: the subject
+// name that prefixes the most policy subjects (httpd covers httpd_t and httpd_cgi_t).
+// Ties go to the shorter, then alphabetically first name, so the result does not
+// depend on policy order.
 func (g *Generator) inferModuleName() string {
-	// Try to extract from first policy subject
-	if len(g.decoded.Policies) > 0 {
-		subject := g.decoded.Policies[0].Subject
-		// Clean and sanitize the subject name
-		name := strings.ToLower(subject)
-		name = strings.ReplaceAll(name, "_process", "")
-		name = strings.ReplaceAll(name, "_t", "")
-		return name
+	names := make([]string, 0, len(g.decoded.Policies))
+	candidates := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, policy := range g.decoded.Policies {
+		name := moduleNameFromSubject(policy.Subject)
+		names = append(names, name)
+		if !seen[name] {
+			seen[name] = true
+			candidates = append(candidates, name)
+		}
 	}
-	return "myapp"
+	sort.Strings(candidates)
+
+	best, bestScore := "", 0
+	for _, candidate := range candidates {
+		score := 0
+		for _, name := range names {
+			if name == candidate || strings.HasPrefix(name, candidate+"_") {
+				score++
+			}
+		}
+		if score > bestScore || score == bestScore && len(candidate) < len(best) {
+			best, bestScore = candidate, score
+		}
+	}
+
+	if best == "" {
+		return "myapp"
+	}
+	return best
+}
+
+// moduleNameFromSubject cleans a subject into a module name candidate
+func moduleNameFromSubject(subject string) string {
+	name := strings.ToLower(subject)
+	name = strings.ReplaceAll(name, "_process", "")
+	name = strings.ReplaceAll(name, "_t", "")
+	return name
 }
 
 // extractTypes extracts unique type names from subjects and objects
@@ -101,9 +549,10 @@ func (g *Generator) extractTypes() map[string]bool {
 		subjectType := g.typeMapper.SubjectToType(policy.Subject)
 		types[subjectType] = true
 
-		// Add object type from path (use decoded object without condition)
+		// Add object type from path (use decoded object without condition);
+		// objects of an optional module are declared by that module
 		objPath := policy.Object
-		if strings.HasPrefix(objPath, "/") {
+		if strings.HasPrefix(objPath, "/") && policy.Optional == "" {
 			objectType := g.typeMapper.PathToType(objPath)
 			types[objectType] = true
 		}
@@ -126,34 +575,239 @@ func (g *Generator) convertPolicies(policy *models.SELinuxPolicy) error {
 
 		// Determine target type based on object
 		var targetType string
-		if strings.HasPrefix(pmlPolicy.Object, "/") {
+		if strings.HasPrefix(pmlPolicy.Object, "/") && pmlPolicy.Optional != "" {
+			// A path of an optional module has the type that module gives it
+			targetType = mapping.NewTypeMapper(pmlPolicy.Optional).PathToType(pmlPolicy.Object)
+		} else if strings.HasPrefix(pmlPolicy.Object, "/") {
 			targetType = g.typeMapper.PathToType(pmlPolicy.Object)
 		} else {
 			targetType = g.typeMapper.SubjectToType(pmlPolicy.Object)
 		}
 
+		if g.strictActions && !pmlPolicy.CustomClass && !g.actionMapper.IsKnownAction(pmlPolicy.Action) {
+			return fmt.Errorf("object '%s': unknown action '%s' (strict actions)", pmlPolicy.Object, pmlPolicy.Action)
+		}
+
+		// Rule priorities exist only in CIL; refpolicy modules are ordered as a whole
+		if pmlPolicy.Priority != 0 {
+			fmt.Fprintf(g.out, "Warning: @priority=%d on '%s' ignored, refpolicy output has no rule priorities\n",
+				pmlPolicy.Priority, pmlPolicy.Object)
+		}
+
 		// Map action to SELinux class and permissions
 		class, perms := g.actionToPermissions(pmlPolicy.Action)
+		if !pmlPolicy.CustomClass && class == "file" && g.actionMapper.DropsMap(pmlPolicy.Action) {
+			fmt.Fprintf(g.out, "Warning: mmap on '%s' grants no map permission without --enable-map, only %s\n",
+				pmlPolicy.Object, strings.Join(perms, " "))
+		}
+		if pmlPolicy.CustomClass {
+			// Custom classes are unknown to the action mapper, the action is the permission
+			class, perms = pmlPolicy.Class, []string{pmlPolicy.Action}
+
+			// "dbus:system_bus" names the target type after the class prefix
+			if _, name, ok := strings.Cut(pmlPolicy.Object, ":"); ok && !strings.HasPrefix(pmlPolicy.Object, "/") {
+				targetType = g.typeMapper.SubjectToType(mapping.SanitizeTypeName(name))
+			}
+		}
+		if name, ok := strings.CutPrefix(pmlPolicy.Object, "packet:"); ok {
+			// "packet:httpd_packet_t" is a secmark packet type, labeled by iptables
+			targetType = g.typeMapper.SubjectToType(mapping.SanitizeTypeName(name))
+			packetPerms, err := packetPermissions(pmlPolicy.Action)
+			if err != nil {
+				return fmt.Errorf("object '%s': %w", pmlPolicy.Object, err)
+			}
+			class, perms = "packet", packetPerms
+			g.ensurePacketType(policy, targetType)
+		}
+		if strings.HasPrefix(pmlPolicy.Object, "tcp:") || strings.HasPrefix(pmlPolicy.Object, "udp:") {
+			// "tcp:8080" and "tcp:8000-8100" are ports, labeled by portcon
+			portType, err := g.portType(policy, pmlPolicy.Object)
+			if err != nil {
+				return fmt.Errorf("object '%s': %w", pmlPolicy.Object, err)
+			}
+			targetType = portType
+			class, perms = pmlPolicy.Class, portPermissions(g.actionMapper.MapActionWithClass(pmlPolicy.Action, pmlPolicy.Class))
+		}
 
 		if pmlPolicy.Effect == "allow" {
+			// An ioctl whitelist only applies when the ioctl permission itself is allowed
+			if len(pmlPolicy.IoctlRanges) > 0 && !containsAttribute(perms, "ioctl") {
+				perms = append(append([]string(nil), perms...), "ioctl")
+			}
+
 			rule := models.AllowRule{
 				SourceType:  sourceType,
 				TargetType:  targetType,
 				Class:       class,
 				Permissions: perms,
+				Comment:     policyComment(pmlPolicy),
+				Rationale:   []string{policyRationale(pmlPolicy)},
+			}
+			if pmlPolicy.Optional != "" {
+				// Rules on another module's types load only if that module is installed;
+				// they cannot also be conditional, audited or ioctl-restricted outside the block
+				if pmlPolicy.Condition != "" || pmlPolicy.Audit || len(pmlPolicy.IoctlRanges) > 0 {
+					return fmt.Errorf("object '%s': @optional cannot be combined with ?cond=, @audit or @xperm", pmlPolicy.Object)
+				}
+				rule.Optional = pmlPolicy.Optional
+				policy.OptionalRules = append(policy.OptionalRules, rule)
+				continue
+			}
+			if pmlPolicy.Condition != "" {
+				// Conditional rules are kept apart so the optimizer never merges them
+				// with unconditional access
+				if err := g.addBoolean(policy, pmlPolicy.Condition); err != nil {
+					return fmt.Errorf("object '%s': %w", pmlPolicy.Object, err)
+				}
+				rule.Condition = pmlPolicy.Condition
+				policy.CondRules = append(policy.CondRules, rule)
+			} else {
+				policy.Rules = append(policy.Rules, rule)
+			}
+
+			// Audited access is still allowed, but also logged
+			if pmlPolicy.Audit {
+				policy.AuditRules = append(policy.AuditRules, rule)
+			}
+
+			if len(pmlPolicy.IoctlRanges) > 0 {
+				if g.policyVersion < MinXpermPolicyVersion {
+					return fmt.Errorf("object '%s': ioctl extended permissions require policy version %d or later (use --policy-version)",
+						pmlPolicy.Object, MinXpermPolicyVersion)
+				}
+				policy.XpermRules = append(policy.XpermRules, models.XpermRule{
+					SourceType: sourceType,
+					TargetType: targetType,
+					Class:      class,
+					Operation:  "ioctl",
+					Ranges:     pmlPolicy.IoctlRanges,
+				})
 			}
-			policy.Rules = append(policy.Rules, rule)
 		} else if pmlPolicy.Effect == "deny" {
-			// Deny rules not supported in simplified version - log warning
-			// In production, you might want to use audit_deny or neverallow
-			fmt.Printf("Warning: Deny rule skipped (not supported): %s -> %s:%s\n",
-				sourceType, targetType, class)
+			g.convertDenyRule(policy, pmlPolicy, sourceType, targetType, class, perms)
+		} else if pmlPolicy.Effect == "dontaudit" {
+			// Neither conditional nor optional blocks hold dontaudit rules here
+			if pmlPolicy.Condition != "" || pmlPolicy.Optional != "" {
+				fmt.Fprintf(g.out, "Warning: Dontaudit rule skipped, ?cond= and @optional do not apply to dontaudit rules: %s -> %s:%s\n",
+					sourceType, targetType, class)
+				continue
+			}
+			policy.DontauditRules = append(policy.DontauditRules, models.AllowRule{
+				SourceType:     sourceType,
+				TargetType:     targetType,
+				Class:          class,
+				Permissions:    perms,
+				OriginalObject: pmlPolicy.Object,
+				Comment:        policyComment(pmlPolicy),
+			})
 		}
 	}
 
 	return nil
 }
 
+// convertDenyRule renders a deny policy according to the deny mode: a neverallow
+// on the action's own permissions, a dontaudit on all of them, or nothing
+func (g *Generator) convertDenyRule(policy *models.SELinuxPolicy, pmlPolicy models.DecodedPolicy, sourceType, targetType, class string, perms []string) {
+	if g.denyMode == DenyModeSkip {
+		fmt.Fprintf(g.out, "Warning: Deny rule skipped (deny mode skip): %s -> %s:%s\n",
+			sourceType, targetType, class)
+		return
+	}
+	// Neither statement may appear in a boolean or optional block here
+	if pmlPolicy.Condition != "" || pmlPolicy.Optional != "" {
+		fmt.Fprintf(g.out, "Warning: Deny rule skipped, ?cond= and @optional do not apply to deny rules: %s -> %s:%s\n",
+			sourceType, targetType, class)
+		return
+	}
+
+	if g.denyMode == DenyModeDontaudit {
+		policy.DontauditRules = append(policy.DontauditRules, models.AllowRule{
+			SourceType:     sourceType,
+			TargetType:     targetType,
+			Class:          class,
+			Permissions:    perms,
+			OriginalObject: pmlPolicy.Object,
+			Comment:        policyComment(pmlPolicy),
+		})
+		return
+	}
+
+	if !pmlPolicy.CustomClass && !strings.HasPrefix(pmlPolicy.Object, "packet:") {
+		class, perms = g.actionMapper.MapDenyAction(pmlPolicy.Action, "")
+	}
+	policy.NeverallowRules = append(policy.NeverallowRules, models.NeverallowRule{
+		SourceType:     sourceType,
+		TargetType:     targetType,
+		Class:          class,
+		Permissions:    perms,
+		OriginalObject: pmlPolicy.Object,
+		Comment:        policyComment(pmlPolicy),
+	})
+}
+
+// checkNeverallowRules removes from each neverallow the permissions the module
+// itself allows, which would fail the build; under the allow-override effect an
+// allow wins over a conflicting deny. A neverallow left without permissions is dropped.
+func (g *Generator) checkNeverallowRules(policy *models.SELinuxPolicy) {
+	if len(policy.NeverallowRules) == 0 {
+		return
+	}
+
+	allowed := make(map[string][]string)
+	for _, rules := range [][]models.AllowRule{policy.Rules, policy.CondRules, policy.OptionalRules} {
+		for _, rule := range rules {
+			for _, class := range rule.AllClasses() {
+				key := rule.SourceType + " " + rule.TargetType + ":" + class
+				allowed[key] = append(allowed[key], rule.Permissions...)
+			}
+		}
+	}
+
+	kept := make([]models.NeverallowRule, 0, len(policy.NeverallowRules))
+	for _, rule := range policy.NeverallowRules {
+		key := rule.SourceType + " " + rule.TargetType + ":" + rule.Class
+		perms := make([]string, 0, len(rule.Permissions))
+		var conflicting []string
+		for _, perm := range rule.Permissions {
+			if containsAttribute(allowed[key], perm) {
+				conflicting = append(conflicting, perm)
+			} else {
+				perms = append(perms, perm)
+			}
+		}
+		if len(conflicting) > 0 {
+			fmt.Fprintf(g.out, "Warning: deny rule on '%s' overridden, the module allows %s { %s }\n",
+				rule.OriginalObject, key, strings.Join(conflicting, " "))
+		}
+		if len(perms) == 0 {
+			continue
+		}
+		rule.Permissions = perms
+		kept = append(kept, rule)
+	}
+	policy.NeverallowRules = kept
+}
+
+// booleanNamePattern matches the booleans a ?cond= condition may name
+var booleanNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// addBoolean declares the boolean named by a condition ("name" or "!name"),
+// defaulting to false, unless it is already declared
+func (g *Generator) addBoolean(policy *models.SELinuxPolicy, condition string) error {
+	name := strings.TrimPrefix(condition, "!")
+	if !booleanNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid condition '%s', expected a boolean name optionally negated with '!'", condition)
+	}
+	for _, b := range policy.Booleans {
+		if b.Name == name {
+			return nil
+		}
+	}
+	policy.Booleans = append(policy.Booleans, models.Boolean{Name: name})
+	return nil
+}
+
 // convertTransitions converts decoded transitions to SELinux type_transition rules
 func (g *Generator) convertTransitions(policy *models.SELinuxPolicy) error {
 	for _, trans := range g.decoded.Transitions {
@@ -179,6 +833,204 @@ func (g *Generator) convertTransitions(policy *models.SELinuxPolicy) error {
 	return nil
 }
 
+// convertNamedTransitions converts ft declarations to filename type transitions
+func (g *Generator) convertNamedTransitions(policy *models.SELinuxPolicy) {
+	for _, ft := range g.decoded.NamedTransitions {
+		policy.NamedTransitions = append(policy.NamedTransitions, models.NamedTransition{
+			SourceType: ft.Source,
+			TargetType: ft.Target,
+			Class:      ft.Class,
+			NewType:    ft.NewType,
+			Filename:   ft.Filename,
+		})
+
+		g.ensureType(policy, ft.Source)
+		g.ensureType(policy, ft.Target)
+		g.ensureType(policy, ft.NewType)
+	}
+}
+
+// convertValidateTrans converts vt and mvt declarations to validatetrans statements
+func (g *Generator) convertValidateTrans(policy *models.SELinuxPolicy) {
+	for _, vt := range g.decoded.ValidateTrans {
+		policy.ValidateTrans = append(policy.ValidateTrans, models.ValidateTrans{
+			Classes:    vt.Classes,
+			Expression: vt.Expression,
+			MLS:        vt.MLS,
+		})
+	}
+}
+
+// convertObjectDefaults converts dt and dr declarations to default_type and
+// default_range statements
+func (g *Generator) convertObjectDefaults(policy *models.SELinuxPolicy) {
+	for _, dt := range g.decoded.DefaultTypes {
+		policy.DefaultTypes = append(policy.DefaultTypes, models.DefaultType{
+			Classes: dt.Classes,
+			Default: dt.Default,
+		})
+	}
+	for _, dr := range g.decoded.DefaultRanges {
+		policy.DefaultRanges = append(policy.DefaultRanges, models.DefaultRange{
+			Classes: dr.Classes,
+			Default: dr.Default,
+			Range:   dr.Range,
+		})
+	}
+}
+
+// convertRoles converts role declarations, role allows and role-type associations.
+// Roles used by a role allow or association are declared as well so the module is self-contained.
+func (g *Generator) convertRoles(policy *models.SELinuxPolicy) {
+	roles := make(map[string]bool)
+	for _, role := range g.decoded.RoleDeclarations {
+		roles[role] = true
+	}
+
+	for _, ra := range g.decoded.RoleAllows {
+		roles[ra.Member] = true
+		roles[ra.Role] = true
+		policy.RoleAllows = append(policy.RoleAllows, models.RoleAllow{
+			FromRole: ra.Member,
+			ToRole:   ra.Role,
+		})
+	}
+
+	// Associate roles with their domains: g relations on this module's types
+	// and g3 user domains, which are declared if needed
+	roleTypes := make(map[string][]string)
+	for _, rel := range g.decoded.Roles {
+		if strings.HasSuffix(rel.Member, "_t") && (policy.HasType(rel.Member) || g.baseTypes[rel.Member]) {
+			roleTypes[rel.Role] = append(roleTypes[rel.Role], rel.Member)
+		}
+	}
+	for _, rel := range g.decoded.UserDomains {
+		domain := g.typeMapper.SubjectToType(rel.Member)
+		roleTypes[rel.Role] = append(roleTypes[rel.Role], domain)
+		g.ensureType(policy, domain)
+	}
+	for _, role := range sortedKeys(roleTypes) {
+		types := uniqueStringSlice(roleTypes[role])
+		sort.Strings(types)
+		policy.RoleTypes = append(policy.RoleTypes, models.RoleType{Role: role, Types: types})
+		roles[role] = true
+	}
+
+	for role := range roles {
+		policy.Roles = append(policy.Roles, role)
+	}
+	sort.Strings(policy.Roles)
+}
+
+// convertTypeAttributes assigns g2 attributes to the declared types and, when
+// enabled, records whether each attribute is expanded
+func (g *Generator) convertTypeAttributes(policy *models.SELinuxPolicy) {
+	attributes := make(map[string]bool)
+	for _, rel := range g.decoded.TypeAttributes {
+		// Encoded values such as "bool:true" are not attributes
+		if strings.Contains(rel.Role, ":") {
+			continue
+		}
+
+		typeDecl := policy.GetTypeByName(g.typeMapper.SubjectToType(rel.Member))
+		if typeDecl == nil {
+			continue
+		}
+		if !containsAttribute(typeDecl.Attributes, rel.Role) {
+			typeDecl.Attributes = append(typeDecl.Attributes, rel.Role)
+		}
+		attributes[rel.Role] = true
+	}
+
+	if !g.emitExpansions {
+		return
+	}
+	for attr := range attributes {
+		policy.Expansions = append(policy.Expansions, models.AttributeExpansion{
+			Attribute: attr,
+			Expand:    g.expandAttributes,
+		})
+	}
+	sort.Slice(policy.Expansions, func(i, j int) bool {
+		return policy.Expansions[i].Attribute < policy.Expansions[j].Attribute
+	})
+}
+
+// inferTypeAttributes gives every declared type without attributes the ones its
+// use implies: domain for subjects and process transition domains, the path
+// categories of object types and file_type for file transition results. Types
+// that got attributes from g2 relations or transitions are left alone. A type
+// still without attributes escapes attribute-based rules such as files_type()
+// sweeps, so it is warned about.
+func (g *Generator) inferTypeAttributes(policy *models.SELinuxPolicy) {
+	inferred := make(map[string][]string)
+	add := func(typeName string, attrs ...string) {
+		inferred[typeName] = append(inferred[typeName], attrs...)
+	}
+
+	for _, pmlPolicy := range g.decoded.Policies {
+		add(g.typeMapper.SubjectToType(pmlPolicy.Subject), "domain")
+		if strings.HasPrefix(pmlPolicy.Object, "/") {
+			add(g.typeMapper.PathToType(pmlPolicy.Object), g.typeMapper.InferTypeCategory(pmlPolicy.Object)...)
+		}
+	}
+	for _, trans := range policy.Transitions {
+		if trans.Class == "process" {
+			add(trans.SourceType, "domain")
+			add(trans.NewType, "domain")
+		} else {
+			add(trans.SourceType, "domain")
+			add(trans.TargetType, "file_type")
+			add(trans.NewType, "file_type")
+		}
+	}
+	for _, trans := range policy.NamedTransitions {
+		add(trans.SourceType, "domain")
+		add(trans.TargetType, "file_type")
+		add(trans.NewType, "file_type")
+	}
+	for _, rel := range g.decoded.UserDomains {
+		add(g.typeMapper.SubjectToType(rel.Member), "domain")
+	}
+	for _, genfs := range policy.GenfsContexts {
+		add(genfs.SELinuxType, "file_type")
+	}
+
+	for i := range policy.Types {
+		typeDecl := &policy.Types[i]
+		if len(typeDecl.Attributes) > 0 {
+			continue
+		}
+		attrs := uniqueStringSlice(inferred[typeDecl.TypeName])
+		// A type both run as a domain and accessed as a file keeps the domain
+		if containsAttribute(attrs, "domain") {
+			attrs = []string{"domain"}
+		}
+		sort.Strings(attrs)
+		typeDecl.Attributes = attrs
+		if len(attrs) == 0 {
+			fmt.Fprintf(g.out, "Warning: type '%s' has no attributes and is not covered by attribute-based rules\n", typeDecl.TypeName)
+		}
+	}
+}
+
+// groupModuleFileTypes declares the <module>_file_types attribute and assigns
+// it to every file_type type, so other modules can be granted all of them at once
+func (g *Generator) groupModuleFileTypes(policy *models.SELinuxPolicy) {
+	attribute := policy.FileTypesAttribute()
+	grouped := false
+	for i := range policy.Types {
+		typeDecl := &policy.Types[i]
+		if containsAttribute(typeDecl.Attributes, "file_type") && !containsAttribute(typeDecl.Attributes, attribute) {
+			typeDecl.Attributes = append(typeDecl.Attributes, attribute)
+			grouped = true
+		}
+	}
+	if grouped && !containsAttribute(policy.Attributes, attribute) {
+		policy.Attributes = append(policy.Attributes, attribute)
+	}
+}
+
 // generateDomainTransitionRules generates helper rules for domain transitions
 // Adds the necessary rules for a process domain transition to work
 func (g *Generator) generateDomainTransitionRules(policy *models.SELinuxPolicy, sourceType, execType, targetType string) {
@@ -206,6 +1058,14 @@ func (g *Generator) generateDomainTransitionRules(policy *models.SELinuxPolicy,
 		Permissions: []string{"entrypoint"},
 	})
 
+	// Rule 4: Target domain can use the descriptors it inherits from the source domain
+	policy.Rules = append(policy.Rules, models.AllowRule{
+		SourceType:  targetType,
+		TargetType:  sourceType,
+		Class:       "fd",
+		Permissions: []string{"use"},
+	})
+
 	// Mark executable type with exec_type attribute if not already present
 	for i, typeDecl := range policy.Types {
 		if typeDecl.TypeName == execType {
@@ -217,8 +1077,101 @@ func (g *Generator) generateDomainTransitionRules(policy *models.SELinuxPolicy,
 	}
 }
 
+// packetPermissions maps an action on a labeled packet to packet permissions;
+// the action must name one, e.g. send or recv
+func packetPermissions(action string) ([]string, error) {
+	valid := mapping.ClassPermissions("packet")
+	perm := strings.ToLower(action)
+	if !containsAttribute(valid, perm) {
+		return nil, fmt.Errorf("action '%s' is not a packet permission (%s)", action, strings.Join(valid, ", "))
+	}
+	return []string{perm}, nil
+}
+
+// ensurePacketType declares a packet type with the packet_type attribute
+func (g *Generator) ensurePacketType(policy *models.SELinuxPolicy, typeName string) {
+	g.ensureTypeAttribute(policy, typeName, "packet_type")
+}
+
+// ensureTypeAttribute declares a type, if needed, and gives it the attribute
+func (g *Generator) ensureTypeAttribute(policy *models.SELinuxPolicy, typeName, attribute string) {
+	g.ensureType(policy, typeName)
+	for i, typeDecl := range policy.Types {
+		if typeDecl.TypeName == typeName && !containsAttribute(typeDecl.Attributes, attribute) {
+			policy.Types[i].Attributes = append(policy.Types[i].Attributes, attribute)
+		}
+	}
+}
+
+// portType returns the type of a "tcp:PORT" or "udp:LOW-HIGH" object. A single
+// port the base policy already labels keeps its type, e.g. http_port_t for
+// tcp:8080; any other port gets the module's port type and a portcon binding.
+func (g *Generator) portType(policy *models.SELinuxPolicy, object string) (string, error) {
+	protocol, ports, _ := strings.Cut(object, ":")
+	low, high, err := parsePortRange(ports)
+	if err != nil {
+		return "", err
+	}
+	if high == 0 {
+		if known, ok := mapping.NewFilesystemMapper().WellKnownPortType(protocol, low); ok {
+			return known, nil
+		}
+	}
+
+	typeName := g.typeMapper.SubjectToType(mapping.SanitizeTypeName(policy.ModuleName) + "_port")
+	g.ensureTypeAttribute(policy, typeName, "port_type")
+
+	binding := models.PortBinding{Port: low, PortEnd: high, Protocol: protocol, PortType: typeName}
+	for _, existing := range policy.PortBindings {
+		if existing == binding {
+			return typeName, nil
+		}
+	}
+	policy.AddPortBinding(binding)
+	return typeName, nil
+}
+
+// parsePortRange parses "8080" or "8000-8100"; high is 0 for a single port
+func parsePortRange(ports string) (int, int, error) {
+	lowText, highText, isRange := strings.Cut(ports, "-")
+	low, err := strconv.Atoi(lowText)
+	if err != nil || low < 1 || low > 65535 {
+		return 0, 0, fmt.Errorf("invalid port '%s', expected 1-65535", lowText)
+	}
+	if !isRange {
+		return low, 0, nil
+	}
+	high, err := strconv.Atoi(highText)
+	if err != nil || high < low || high > 65535 {
+		return 0, 0, fmt.Errorf("invalid port range '%s', expected LOW-HIGH within 1-65535", ports)
+	}
+	if high == low {
+		return low, 0, nil
+	}
+	return low, high, nil
+}
+
+// portPermissions turns socket permissions into the ones checked against a port
+// type: binding checks name_bind and connecting checks name_connect
+func portPermissions(perms []string) []string {
+	mapped := make([]string, 0, len(perms))
+	for _, perm := range perms {
+		switch perm {
+		case "bind":
+			perm = "name_bind"
+		case "connect":
+			perm = "name_connect"
+		}
+		mapped = append(mapped, perm)
+	}
+	return uniqueStringSlice(mapped)
+}
+
 // ensureType ensures a type is declared in the policy
 func (g *Generator) ensureType(policy *models.SELinuxPolicy, typeName string) {
+	if g.baseTypes[typeName] {
+		return
+	}
 	for _, t := range policy.Types {
 		if t.TypeName == typeName {
 			return
@@ -229,6 +1182,121 @@ func (g *Generator) ensureType(policy *models.SELinuxPolicy, typeName string) {
 	})
 }
 
+// excludedFromFileContexts reports whether the object lies under a --no-fc-for prefix
+func (g *Generator) excludedFromFileContexts(object string) bool {
+	for _, prefix := range g.noFCPrefixes {
+		prefix = strings.TrimSuffix(prefix, "/")
+		if object == prefix || strings.HasPrefix(object, prefix+"/") || strings.HasPrefix(object, prefix+"(") {
+			return true
+		}
+	}
+	return false
+}
+
+// isUserHomePath reports whether the object lies inside every user's home directory
+func isUserHomePath(object string) bool {
+	return strings.HasPrefix(object, "/home/*/")
+}
+
+// convertGenfs converts genfs declarations to genfscon contexts,
+// rejecting duplicate (fstype, path) pairs
+func (g *Generator) convertGenfs(policy *models.SELinuxPolicy) error {
+	if len(g.decoded.Genfs) == 0 {
+		return nil
+	}
+
+	fsMapper := mapping.NewFilesystemMapper()
+	rules := make([]mapping.GenfsconRule, 0, len(g.decoded.Genfs))
+	for _, genfs := range g.decoded.Genfs {
+		rules = append(rules, mapping.GenfsconRule{
+			FSType:  genfs.FSType,
+			Path:    genfs.Path,
+			Context: fsMapper.GenerateFilesystemContext(genfs.FSType, genfs.Path, genfs.Type, ""),
+		})
+	}
+	if errs := fsMapper.ValidateFilesystemPolicy(rules, nil); len(errs) > 0 {
+		return fmt.Errorf("invalid genfs rules: %v", errs[0])
+	}
+
+	for _, genfs := range g.decoded.Genfs {
+		policy.GenfsContexts = append(policy.GenfsContexts, models.GenfsContext{
+			FSType:      genfs.FSType,
+			Path:        genfs.Path,
+			SELinuxType: genfs.Type,
+		})
+		g.ensureType(policy, genfs.Type)
+	}
+
+	return nil
+}
+
+// convertNetworkLabels converts node declarations to nodecon address and netmask
+// pairs and interface declarations to netifcon contexts, rejecting a network or
+// interface labeled twice
+func (g *Generator) convertNetworkLabels(policy *models.SELinuxPolicy) error {
+	nodes := make(map[string]bool)
+	for _, node := range g.decoded.Nodes {
+		_, network, err := net.ParseCIDR(node.Network)
+		if err != nil {
+			return fmt.Errorf("node '%s': %w", node.Network, err)
+		}
+		if nodes[network.String()] {
+			return fmt.Errorf("node '%s' is labeled more than once", node.Network)
+		}
+		nodes[network.String()] = true
+
+		policy.Nodecons = append(policy.Nodecons, models.Nodecon{
+			Address:     network.IP.String(),
+			Netmask:     net.IP(network.Mask).String(),
+			SELinuxType: node.Type,
+		})
+		g.ensureTypeAttribute(policy, node.Type, "node_type")
+	}
+
+	netifs := make(map[string]bool)
+	for _, netif := range g.decoded.Netifs {
+		if netifs[netif.Interface] {
+			return fmt.Errorf("interface '%s' is labeled more than once", netif.Interface)
+		}
+		netifs[netif.Interface] = true
+
+		policy.Netifcons = append(policy.Netifcons, models.Netifcon{
+			Interface:   netif.Interface,
+			SELinuxType: netif.Type,
+		})
+		g.ensureTypeAttribute(policy, netif.Type, "netif_type")
+	}
+
+	return nil
+}
+
+// convertInitialSIDs converts sid declarations to initial SID contexts.
+// Modules cannot declare SIDs, so they are rejected unless generating a base policy.
+func (g *Generator) convertInitialSIDs(policy *models.SELinuxPolicy) error {
+	if len(g.decoded.SIDs) == 0 {
+		return nil
+	}
+	if !g.basePolicy {
+		return fmt.Errorf("sid rules are only valid in a base policy (use --base-policy): sid %s", g.decoded.SIDs[0].Name)
+	}
+
+	seen := make(map[string]bool)
+	for _, sid := range g.decoded.SIDs {
+		if seen[sid.Name] {
+			return fmt.Errorf("duplicate context for initial SID '%s'", sid.Name)
+		}
+		seen[sid.Name] = true
+
+		policy.InitialSIDs = append(policy.InitialSIDs, models.InitialSID{
+			Name:        sid.Name,
+			SELinuxType: sid.Type,
+		})
+		g.ensureType(policy, sid.Type)
+	}
+
+	return nil
+}
+
 // actionToPermissions maps PML action to SELinux class and permissions
 func (g *Generator) actionToPermissions(action string) (string, []string) {
 	// Use the action mapper for consistent mapping
@@ -240,27 +1308,73 @@ func (g *Generator) actionToPermissions(action string) (string, []string) {
 func (g *Generator) generateFileContexts(policy *models.SELinuxPolicy) error {
 	seenPaths := make(map[string]bool)
 
+	// An @nofc annotation on any rule excludes the object; an explicit level on
+	// any rule makes the module MLS-aware, so unannotated paths infer theirs
+	noFC := make(map[string]bool)
+	usesLevels := false
+	for _, pmlPolicy := range g.decoded.Policies {
+		if pmlPolicy.NoFileContext {
+			noFC[pmlPolicy.Object] = true
+		}
+		usesLevels = usesLevels || pmlPolicy.Level != "" || pmlPolicy.SubjectLevel != ""
+	}
+
 	for _, pmlPolicy := range g.decoded.Policies {
 		// Only generate contexts for file paths
 		if !strings.HasPrefix(pmlPolicy.Object, "/") {
 			continue
 		}
 
+		// Objects excluded by annotation or --no-fc-for keep their allow rules only,
+		// as do objects an optional module labels
+		if noFC[pmlPolicy.Object] || pmlPolicy.Optional != "" || g.excludedFromFileContexts(pmlPolicy.Object) {
+			continue
+		}
+
 		if seenPaths[pmlPolicy.Object] {
 			continue
 		}
 		seenPaths[pmlPolicy.Object] = true
 
 		// Generate recursive patterns for directories
-		patterns := g.pathMapper.GenerateRecursivePatterns(pmlPolicy.Object)
+		// Shared paths are labeled by the base module
 		objectType := g.typeMapper.PathToType(pmlPolicy.Object)
+		if g.baseTypes[objectType] {
+			continue
+		}
+		// Home directory content is labeled by the user domain policy
+		if g.userdom && isUserHomePath(pmlPolicy.Object) {
+			if !containsAttribute(policy.UserHomeContent, objectType) {
+				policy.UserHomeContent = append(policy.UserHomeContent, objectType)
+			}
+			continue
+		}
+
+		patterns := g.fileContextPatterns(pmlPolicy.Object)
+
+		// Resolve the optional @level= annotation into an MLS range
+		var levelRange *models.SecurityRange
+		if pmlPolicy.Level != "" {
+			r, err := g.levelMapper.MapRange(pmlPolicy.Level)
+			if err != nil {
+				return fmt.Errorf("object '%s': %w", pmlPolicy.Object, err)
+			}
+			if !r.IsValid() {
+				return fmt.Errorf("object '%s': invalid security range '%s', high level must dominate low level",
+					pmlPolicy.Object, pmlPolicy.Level)
+			}
+			levelRange = &r
+		} else if level, ok := g.levelMapper.InferLevelFromPath(pmlPolicy.Object); ok && usesLevels && level.Sensitivity > 0 {
+			levelRange = &models.SecurityRange{Low: level, High: level}
+		}
 
 		for _, pattern := range patterns {
 			fc := models.FileContext{
 				PathPattern: pattern.Pattern,
-				FileType:    pattern.FileType, // -- or -d
+				FileType:    pattern.FileType, // Inferred file type, e.g. block or all files
 				SELinuxType: objectType,
-				Comment:     fmt.Sprintf("Generated from PML policy: %s", pmlPolicy.Object),
+				Range:       levelRange,
+				Comment:     policyComment(pmlPolicy),
 			}
 
 			policy.FileContexts = append(policy.FileContexts, fc)
@@ -270,6 +1384,48 @@ func (g *Generator) generateFileContexts(policy *models.SELinuxPolicy) error {
 	return nil
 }
 
+// fileContextPatterns returns the file context patterns of a path object. A
+// braced object whose alternatives infer different file types, such as
+// /dev/{sda,null}, gets one pattern per alternative so each keeps its specifier.
+func (g *Generator) fileContextPatterns(object string) []mapping.PathPattern {
+	alternatives := mapping.ExpandBraces(object)
+	mixed := false
+	for _, alt := range alternatives[1:] {
+		if g.pathMapper.InferFileType(alt) != g.pathMapper.InferFileType(alternatives[0]) {
+			mixed = true
+			break
+		}
+	}
+	if !mixed {
+		return g.pathMapper.GenerateRecursivePatterns(object)
+	}
+
+	patterns := make([]mapping.PathPattern, 0, len(alternatives))
+	for _, alt := range alternatives {
+		patterns = append(patterns, g.pathMapper.GenerateRecursivePatterns(alt)...)
+	}
+	return patterns
+}
+
+// policyComment returns the author's comment on the policy line,
+// or a generated one naming the object
+func policyComment(pmlPolicy models.DecodedPolicy) string {
+	if pmlPolicy.Comment != "" {
+		return pmlPolicy.Comment
+	}
+	return fmt.Sprintf("Generated from PML policy: %s", pmlPolicy.Object)
+}
+
+// policyRationale describes what the policy line grants from its fields, e.g.
+// "httpd_t may read /var/www/*", followed by the author's comment if any
+func policyRationale(pmlPolicy models.DecodedPolicy) string {
+	rationale := fmt.Sprintf("%s may %s %s", pmlPolicy.Subject, pmlPolicy.Action, pmlPolicy.Object)
+	if pmlPolicy.Comment != "" {
+		rationale += ": " + pmlPolicy.Comment
+	}
+	return rationale
+}
+
 // Helper function to check if attributes contain a specific attribute
 func containsAttribute(attributes []string, attr string) bool {
 	for _, a := range attributes {
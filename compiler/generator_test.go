@@ -1,8 +1,10 @@
 package compiler
 
 import (
+	"strings"
 	"testing"
 
+	"github.com/cici0602/pml-to-selinux/mapping"
 	"github.com/cici0602/pml-to-selinux/models"
 )
 
@@ -106,6 +108,37 @@ func TestGenerator_InferModuleName(t *testing.T) {
 	}
 }
 
+func TestGenerator_InferModuleNameDominantDomain(t *testing.T) {
+	policies := []models.Policy{
+		{Type: "p", Subject: "logrotate_t", Object: "/var/log/*", Action: "write", Effect: "allow"},
+		{Type: "p", Subject: "httpd_t", Object: "/var/www/*", Action: "read", Effect: "allow"},
+		{Type: "p", Subject: "httpd_cgi_t", Object: "/var/www/cgi-bin/*", Action: "execute", Effect: "allow"},
+		{Type: "p", Subject: "httpd_t", Object: "/var/log/httpd/*", Action: "append", Effect: "allow"},
+	}
+	reversed := make([]models.Policy, len(policies))
+	for i, policy := range policies {
+		reversed[len(policies)-1-i] = policy
+	}
+
+	for _, order := range [][]models.Policy{policies, reversed} {
+		policy, err := NewGenerator(newTestDecodedPML(order...), "").Generate()
+		if err != nil {
+			t.Fatalf("Generate() error = %v", err)
+		}
+		if policy.ModuleName != "httpd" {
+			t.Errorf("ModuleName = %s, want httpd", policy.ModuleName)
+		}
+	}
+
+	policy, err := NewGenerator(newTestDecodedPML(policies...), "weblogs").Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if policy.ModuleName != "weblogs" {
+		t.Errorf("explicit ModuleName = %s, want weblogs", policy.ModuleName)
+	}
+}
+
 func TestGenerator_ActionToPermissions(t *testing.T) {
 	decoded := &models.DecodedPML{
 		Model:          &models.PMLModel{},
@@ -158,3 +191,1123 @@ func TestGenerator_ActionToPermissions(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerator_SecurityLevelAnnotation(t *testing.T) {
+	decoded := newTestDecodedPML(
+		models.Policy{Type: "p", Subject: "vault_t", Object: "/srv/vault/*@level=internal-secret", Action: "read", Effect: "allow"},
+	)
+
+	policy, err := NewGenerator(decoded, "vault").Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if len(policy.FileContexts) == 0 {
+		t.Fatal("expected file contexts")
+	}
+	if got := policy.FileContexts[0].Level(); got != "s1-s3" {
+		t.Errorf("file context level = %s, want s1-s3", got)
+	}
+
+	decoded = newTestDecodedPML(
+		models.Policy{Type: "p", Subject: "vault_t", Object: "/srv/vault/*@level=confidential:c0", Action: "read", Effect: "allow"},
+	)
+	policy, err = NewGenerator(decoded, "vault").Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if got := policy.FileContexts[0].Level(); got != "s2:c0" {
+		t.Errorf("file context level = %s, want s2:c0", got)
+	}
+
+	decoded = newTestDecodedPML(
+		models.Policy{Type: "p", Subject: "vault_t", Object: "/srv/vault/*@level=secret-internal", Action: "read", Effect: "allow"},
+	)
+	if _, err := NewGenerator(decoded, "vault").Generate(); err == nil {
+		t.Error("expected error for a range whose high level does not dominate the low level")
+	}
+}
+
+func TestGenerator_NamedCategoryLevel(t *testing.T) {
+	decoded := newTestDecodedPML(
+		models.Policy{Type: "p", Subject: "httpd_t", Object: "/data/secret/*::confidential:hr", Action: "read", Effect: "allow"},
+		models.Policy{Type: "p", Subject: "httpd_t", Object: "/data/top_secret/*", Action: "read", Effect: "allow"},
+	)
+	levelMapper := mapping.NewLevelMapper()
+	if err := levelMapper.Define("hr=c0"); err != nil {
+		t.Fatalf("Define() error = %v", err)
+	}
+
+	generator := NewGenerator(decoded, "httpd")
+	generator.SetLevelMapper(levelMapper)
+	policy, err := generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	levels := make(map[string]string)
+	for _, fc := range policy.FileContexts {
+		levels[fc.PathPattern] = fc.Level()
+	}
+	if got := levels["/data/secret(/.*)?"]; got != "s2:c0" {
+		t.Errorf("explicit level = %s, want s2:c0 (contexts: %v)", got, levels)
+	}
+	// Unannotated paths of an MLS-aware module fall back to the level their path names
+	if got := levels["/data/top_secret(/.*)?"]; got != "s4" {
+		t.Errorf("inferred level = %s, want s4 (contexts: %v)", got, levels)
+	}
+	// A module leaves the MLS constraints to its base policy
+	if len(policy.Constraints) != 0 {
+		t.Errorf("module should not declare constraints, got %v", policy.Constraints)
+	}
+
+	generator = NewGenerator(decoded, "httpd")
+	generator.SetLevelMapper(levelMapper)
+	generator.SetBasePolicy(true)
+	policy, err = generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if len(policy.Constraints) == 0 || !policy.Constraints[0].MLS {
+		t.Errorf("base policy with levels above s0 should declare MLS constraints, got %v", policy.Constraints)
+	}
+
+	// Without any explicit level, paths are not inferred
+	decoded = newTestDecodedPML(
+		models.Policy{Type: "p", Subject: "httpd_t", Object: "/data/top_secret/*", Action: "read", Effect: "allow"},
+	)
+	policy, err = NewGenerator(decoded, "httpd").Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if got := policy.FileContexts[0].Level(); got != "s0" {
+		t.Errorf("level of a module without levels = %s, want s0", got)
+	}
+}
+
+func TestGenerator_ReservedIdentifiers(t *testing.T) {
+	decoded := newTestDecodedPML(
+		models.Policy{Type: "p", Subject: "app_t", Object: "/opt/app/*", Action: "read", Effect: "allow"},
+	)
+
+	_, err := NewGenerator(decoded, "type").Generate()
+	if err == nil || !contains(err.Error(), "module name 'type'") {
+		t.Errorf("expected reserved module name error, got %v", err)
+	}
+
+	decoded.Transitions = []models.TransitionInfo{
+		{SourceType: "app_t", TargetType: "app_exec_t", Class: "process", NewType: "role"},
+	}
+	_, err = NewGenerator(decoded, "app").Generate()
+	if err == nil || !contains(err.Error(), "type name 'role'") {
+		t.Errorf("expected reserved type name error, got %v", err)
+	}
+}
+
+func TestGenerator_Constraints(t *testing.T) {
+	decoded := newTestDecodedPML(
+		models.Policy{Type: "p", Subject: "app_t", Object: "/opt/app/*", Action: "read", Effect: "allow"},
+	)
+	decoded.Roles = []models.RoleRelation{
+		{Type: "g", Member: "staff_u", Role: "staff_r"},
+		{Type: "g", Member: "staff_u", Role: "sysadm_r"},
+		{Type: "g", Member: "app_t", Role: "system_r"},
+	}
+
+	policy, err := NewGenerator(decoded, "app").Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if len(policy.Constraints) != 0 {
+		t.Errorf("constraints should only be generated when enabled, got %d", len(policy.Constraints))
+	}
+
+	generator := NewGenerator(decoded, "app")
+	generator.SetConstraints(true)
+	if _, err := generator.Generate(); err == nil || !strings.Contains(err.Error(), "only valid in a base policy") {
+		t.Errorf("constraints in a module should be rejected, got %v", err)
+	}
+
+	generator = NewGenerator(decoded, "app")
+	generator.SetConstraints(true)
+	generator.SetBasePolicy(true)
+	policy, err = generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if len(policy.Constraints) != 2 {
+		t.Fatalf("expected 2 constraints, got %d: %+v", len(policy.Constraints), policy.Constraints)
+	}
+	if got := policy.Constraints[0].Expression; got != "u2 != staff_u or r2 == { staff_r sysadm_r }" {
+		t.Errorf("user-role constraint = %q", got)
+	}
+	if got := policy.Constraints[1].Expression; got != "t2 != app_t or r2 == system_r" {
+		t.Errorf("role-type constraint = %q", got)
+	}
+}
+
+func TestGenerator_CriticalPathContexts(t *testing.T) {
+	decoded := newTestDecodedPML(
+		models.Policy{Type: "p", Subject: "app_t", Object: "/etc/*", Action: "read", Effect: "allow"},
+	)
+
+	_, err := NewGenerator(decoded, "app").Generate()
+	if err == nil || !contains(err.Error(), "/etc/shadow") {
+		t.Errorf("expected critical path error, got %v", err)
+	}
+
+	generator := NewGenerator(decoded, "app")
+	generator.SetAllowCritical(true)
+	if _, err := generator.Generate(); err != nil {
+		t.Errorf("--allow-critical should only warn, got %v", err)
+	}
+}
+
+func TestGenerator_StrictActions(t *testing.T) {
+	decoded := newTestDecodedPML(
+		models.Policy{Type: "p", Subject: "app_t", Object: "/opt/app/*", Action: "raed", Effect: "allow"},
+	)
+
+	if _, err := NewGenerator(decoded, "app").Generate(); err != nil {
+		t.Errorf("unknown actions should pass through by default, got %v", err)
+	}
+
+	generator := NewGenerator(decoded, "app")
+	generator.SetStrictActions(true)
+	if _, err := generator.Generate(); err == nil || !contains(err.Error(), "unknown action 'raed'") {
+		t.Errorf("expected unknown action error, got %v", err)
+	}
+}
+
+func TestGenerator_BaseModule(t *testing.T) {
+	base := newTestDecodedPML(
+		models.Policy{Type: "p", Subject: "acme_t", Object: "/srv/acme/shared/*", Action: "read", Effect: "allow"},
+	)
+	decoded := newTestDecodedPML(
+		models.Policy{Type: "p", Subject: "billing_t", Object: "/srv/acme/shared/*", Action: "read", Effect: "allow"},
+		models.Policy{Type: "p", Subject: "billing_t", Object: "/srv/billing/*", Action: "write", Effect: "allow"},
+	)
+
+	generator := NewGenerator(decoded, "billing")
+	generator.SetBaseModule(base, "acme")
+	policy, err := generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	if policy.HasType("acme_srv_acme_shared_t") {
+		t.Error("shared type should not be declared by the dependent module")
+	}
+	if len(policy.BaseTypes) != 1 || policy.BaseTypes[0] != "acme_srv_acme_shared_t" {
+		t.Errorf("BaseTypes = %v, want [acme_srv_acme_shared_t]", policy.BaseTypes)
+	}
+	for _, fc := range policy.FileContexts {
+		if fc.SELinuxType == "acme_srv_acme_shared_t" {
+			t.Errorf("shared path should be labeled by the base module, got %s", fc.PathPattern)
+		}
+	}
+
+	found := false
+	for _, rule := range policy.Rules {
+		if rule.TargetType == "acme_srv_acme_shared_t" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("expected a rule targeting the shared type")
+	}
+}
+
+func TestGenerator_Roles(t *testing.T) {
+	decoded := newTestDecodedPML(
+		models.Policy{Type: "p", Subject: "app_t", Object: "/opt/app/*", Action: "read", Effect: "allow"},
+	)
+	decoded.RoleDeclarations = []string{"webadmin_r"}
+	decoded.RoleAllows = []models.RoleRelation{{Type: "ra", Member: "user_r", Role: "webadmin_r"}}
+
+	policy, err := NewGenerator(decoded, "app").Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if len(policy.Roles) != 2 || policy.Roles[0] != "user_r" || policy.Roles[1] != "webadmin_r" {
+		t.Errorf("Roles = %v, want [user_r webadmin_r]", policy.Roles)
+	}
+	if len(policy.RoleAllows) != 1 || policy.RoleAllows[0].FromRole != "user_r" || policy.RoleAllows[0].ToRole != "webadmin_r" {
+		t.Errorf("RoleAllows = %v", policy.RoleAllows)
+	}
+}
+
+func TestGenerator_RoleTypes(t *testing.T) {
+	decoded := newTestDecodedPML(
+		models.Policy{Type: "p", Subject: "webadm_t", Object: "/etc/httpd/*", Action: "write", Effect: "allow"},
+	)
+	decoded.Roles = []models.RoleRelation{
+		{Type: "g", Member: "webadm_t", Role: "staff_r"},
+		{Type: "g", Member: "external_t", Role: "staff_r"},
+		{Type: "g", Member: "staff_u", Role: "staff_r"},
+	}
+	decoded.UserDomains = []models.RoleRelation{{Type: "g3", Member: "staff", Role: "staff_r"}}
+
+	policy, err := NewGenerator(decoded, "webadm").Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if len(policy.RoleTypes) != 1 || policy.RoleTypes[0].Role != "staff_r" ||
+		strings.Join(policy.RoleTypes[0].Types, " ") != "staff_t webadm_t" {
+		t.Errorf("RoleTypes = %+v, want [{staff_r [staff_t webadm_t]}]", policy.RoleTypes)
+	}
+	if !policy.HasType("staff_t") {
+		t.Errorf("user domain staff_t should be declared, got %+v", policy.Types)
+	}
+	if len(policy.Roles) != 1 || policy.Roles[0] != "staff_r" {
+		t.Errorf("Roles = %v, want [staff_r]", policy.Roles)
+	}
+}
+
+func TestGenerator_CustomClass(t *testing.T) {
+	decoded := newTestDecodedPML(
+		models.Policy{Type: "p", Subject: "app_t", Object: "dbus:system_bus", Action: "send_msg", Effect: "allow"},
+	)
+	decoded.Policies[0].Class = "dbus"
+	decoded.Policies[0].CustomClass = true
+
+	policy, err := NewGenerator(decoded, "app").Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if len(policy.Rules) != 1 {
+		t.Fatalf("expected 1 rule, got %d", len(policy.Rules))
+	}
+	rule := policy.Rules[0]
+	if rule.TargetType != "system_bus_t" || rule.Class != "dbus" || len(rule.Permissions) != 1 || rule.Permissions[0] != "send_msg" {
+		t.Errorf("unexpected rule: %+v", rule)
+	}
+}
+
+func TestGenerator_AttributeExpansion(t *testing.T) {
+	decoded := newTestDecodedPML(
+		models.Policy{Type: "p", Subject: "app_t", Object: "/opt/app/*", Action: "read", Effect: "allow"},
+	)
+	decoded.TypeAttributes = []models.RoleRelation{
+		{Type: "g2", Member: "app_t", Role: "web_domain"},
+		{Type: "g2", Member: "app_t", Role: "bool:true"},
+	}
+
+	policy, err := NewGenerator(decoded, "app").Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	typeDecl := policy.GetTypeByName("app_t")
+	if typeDecl == nil || len(typeDecl.Attributes) != 1 || typeDecl.Attributes[0] != "web_domain" {
+		t.Errorf("expected app_t to have attribute web_domain, got %+v", typeDecl)
+	}
+	if len(policy.Expansions) != 0 {
+		t.Errorf("expansions should only be emitted when enabled, got %v", policy.Expansions)
+	}
+
+	generator := NewGenerator(decoded, "app")
+	generator.SetAttributeExpansion(false)
+	policy, err = generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if len(policy.Expansions) != 1 || policy.Expansions[0].Attribute != "web_domain" || policy.Expansions[0].Expand {
+		t.Errorf("Expansions = %+v, want [{web_domain false}]", policy.Expansions)
+	}
+}
+
+func TestGenerator_NamedTransitions(t *testing.T) {
+	decoded := newTestDecodedPML(
+		models.Policy{Type: "p", Subject: "httpd_t", Object: "/var/lib/httpd/*", Action: "write", Effect: "allow"},
+	)
+	decoded.NamedTransitions = []models.NamedTransitionDeclaration{
+		{Source: "httpd_t", Target: "httpd_var_lib_t", Class: "file", NewType: "httpd_cache_t", Filename: "cache.db"},
+	}
+
+	policy, err := NewGenerator(decoded, "httpd").Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	want := models.NamedTransition{SourceType: "httpd_t", TargetType: "httpd_var_lib_t", Class: "file", NewType: "httpd_cache_t", Filename: "cache.db"}
+	if len(policy.NamedTransitions) != 1 || policy.NamedTransitions[0] != want {
+		t.Errorf("NamedTransitions = %+v, want [%+v]", policy.NamedTransitions, want)
+	}
+	if len(policy.Transitions) != 0 {
+		t.Errorf("named transitions should not be class-based transitions, got %v", policy.Transitions)
+	}
+}
+
+func TestGenerator_PacketObjects(t *testing.T) {
+	decoded := newTestDecodedPML(
+		models.Policy{Type: "p", Subject: "httpd_t", Object: "packet:httpd_packet_t", Action: "send", Effect: "allow"},
+		models.Policy{Type: "p", Subject: "httpd_t", Object: "packet:httpd_packet_t", Action: "recv", Effect: "allow"},
+	)
+
+	policy, err := NewGenerator(decoded, "httpd").Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	for _, rule := range policy.Rules {
+		if rule.TargetType != "httpd_packet_t" || rule.Class != "packet" {
+			t.Errorf("expected httpd_packet_t:packet rules, got %+v", rule)
+		}
+	}
+	declared := false
+	for _, typeDecl := range policy.Types {
+		if typeDecl.TypeName == "httpd_packet_t" {
+			declared = containsAttribute(typeDecl.Attributes, "packet_type")
+		}
+	}
+	if !declared {
+		t.Errorf("expected httpd_packet_t declared with packet_type, got %+v", policy.Types)
+	}
+
+	decoded = newTestDecodedPML(
+		models.Policy{Type: "p", Subject: "httpd_t", Object: "packet:httpd_packet_t", Action: "read", Effect: "allow"},
+	)
+	if _, err := NewGenerator(decoded, "httpd").Generate(); err == nil || !contains(err.Error(), "not a packet permission") {
+		t.Errorf("expected packet permission error, got %v", err)
+	}
+}
+
+func TestGenerator_Genfs(t *testing.T) {
+	decoded := newTestDecodedPML(
+		models.Policy{Type: "p", Subject: "app_t", Object: "/opt/app/*", Action: "read", Effect: "allow"},
+	)
+	decoded.Genfs = []models.GenfsDeclaration{
+		{FSType: "proc", Path: "/sys/kernel/modprobe", Type: "proc_security_t"},
+		{FSType: "sysfs", Path: "/class/net/eth0", Type: "sysfs_net_t"},
+	}
+
+	if _, err := NewGenerator(decoded, "app").Generate(); err == nil || !contains(err.Error(), "genfscon statements are only valid in a base policy") {
+		t.Errorf("expected genfscon to be rejected outside a base policy, got %v", err)
+	}
+
+	generator := NewGenerator(decoded, "app")
+	generator.SetBasePolicy(true)
+	policy, err := generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if len(policy.GenfsContexts) != 2 || policy.GenfsContexts[1].Path != "/class/net/eth0" {
+		t.Errorf("GenfsContexts = %+v", policy.GenfsContexts)
+	}
+	if policy.GetTypeByName("proc_security_t") == nil {
+		t.Error("expected genfs type proc_security_t to be declared")
+	}
+
+	decoded.Genfs = append(decoded.Genfs, models.GenfsDeclaration{FSType: "proc", Path: "/sys/kernel/modprobe", Type: "other_t"})
+	if _, err := generator.Generate(); err == nil || !contains(err.Error(), "duplicate") {
+		t.Errorf("expected duplicate genfs error, got %v", err)
+	}
+}
+
+func TestGenerator_NetworkLabels(t *testing.T) {
+	decoded := newTestDecodedPML(
+		models.Policy{Type: "p", Subject: "app_t", Object: "/opt/app/*", Action: "read", Effect: "allow"},
+	)
+	decoded.Nodes = []models.NodeDeclaration{
+		{Network: "192.168.1.0/24", Type: "node_internal_t"},
+		{Network: "2001:db8::/32", Type: "node_doc_t"},
+	}
+	decoded.Netifs = []models.NetifDeclaration{{Interface: "eth0", Type: "netif_internal_t"}}
+
+	if _, err := NewGenerator(decoded, "app").Generate(); err == nil || !contains(err.Error(), "netifcon statements are only valid in a base policy") {
+		t.Errorf("expected netifcon to be rejected outside a base policy, got %v", err)
+	}
+
+	generator := NewGenerator(decoded, "app")
+	generator.SetBasePolicy(true)
+	policy, err := generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	want := []models.Nodecon{
+		{Address: "192.168.1.0", Netmask: "255.255.255.0", SELinuxType: "node_internal_t"},
+		{Address: "2001:db8::", Netmask: "ffff:ffff::", SELinuxType: "node_doc_t"},
+	}
+	if len(policy.Nodecons) != 2 || policy.Nodecons[0] != want[0] || policy.Nodecons[1] != want[1] {
+		t.Errorf("Nodecons = %+v, want %+v", policy.Nodecons, want)
+	}
+	if len(policy.Netifcons) != 1 || policy.Netifcons[0] != (models.Netifcon{Interface: "eth0", SELinuxType: "netif_internal_t"}) {
+		t.Errorf("Netifcons = %+v", policy.Netifcons)
+	}
+	if decl := policy.GetTypeByName("node_internal_t"); decl == nil || !containsAttribute(decl.Attributes, "node_type") {
+		t.Errorf("expected node_internal_t declared with node_type, got %+v", decl)
+	}
+	if decl := policy.GetTypeByName("netif_internal_t"); decl == nil || !containsAttribute(decl.Attributes, "netif_type") {
+		t.Errorf("expected netif_internal_t declared with netif_type, got %+v", decl)
+	}
+
+	decoded.Nodes = append(decoded.Nodes, models.NodeDeclaration{Network: "192.168.1.0/24", Type: "other_t"})
+	if _, err := NewGenerator(decoded, "app").Generate(); err == nil || !contains(err.Error(), "more than once") {
+		t.Errorf("expected duplicate node error, got %v", err)
+	}
+}
+
+func TestGenerator_InitialSIDs(t *testing.T) {
+	decoded := newTestDecodedPML(
+		models.Policy{Type: "p", Subject: "app_t", Object: "/opt/app/*", Action: "read", Effect: "allow"},
+	)
+	decoded.SIDs = []models.SIDDeclaration{
+		{Name: "kernel", Type: "kernel_t"},
+		{Name: "unlabeled", Type: "unlabeled_t"},
+	}
+
+	if _, err := NewGenerator(decoded, "app").Generate(); err == nil || !contains(err.Error(), "base policy") {
+		t.Errorf("expected sid rules to be rejected outside a base policy, got %v", err)
+	}
+
+	generator := NewGenerator(decoded, "app")
+	generator.SetBasePolicy(true)
+	policy, err := generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if len(policy.InitialSIDs) != 2 || policy.InitialSIDs[0].Name != "kernel" {
+		t.Errorf("InitialSIDs = %+v", policy.InitialSIDs)
+	}
+	if policy.GetTypeByName("unlabeled_t") == nil {
+		t.Error("expected sid type unlabeled_t to be declared")
+	}
+
+	decoded.SIDs = append(decoded.SIDs, models.SIDDeclaration{Name: "kernel", Type: "other_t"})
+	if _, err := generator.Generate(); err == nil || !contains(err.Error(), "duplicate") {
+		t.Errorf("expected duplicate sid error, got %v", err)
+	}
+}
+
+func TestGenerator_DeterministicAttributes(t *testing.T) {
+	newDecoded := func() *models.DecodedPML {
+		decoded := newTestDecodedPML(
+			models.Policy{Type: "p", Subject: "app_t", Object: "/opt/app/*", Action: "read", Effect: "allow"},
+		)
+		decoded.Transitions = []models.TransitionInfo{
+			{SourceType: "init_t", TargetType: "app_exec_t", Class: "process", NewType: "app_t"},
+		}
+		decoded.TypeAttributes = []models.RoleRelation{
+			{Type: "g2", Member: "app_exec_t", Role: "zz_files"},
+			{Type: "g2", Member: "app_exec_t", Role: "app_files"},
+		}
+		return decoded
+	}
+
+	want := []string{"app_files", "exec_type", "zz_files"}
+	for run := 0; run < 5; run++ {
+		policy, err := NewGenerator(newDecoded(), "app").Generate()
+		if err != nil {
+			t.Fatalf("Generate() error = %v", err)
+		}
+		typeDecl := policy.GetTypeByName("app_exec_t")
+		if typeDecl == nil || strings.Join(typeDecl.Attributes, ",") != strings.Join(want, ",") {
+			t.Fatalf("run %d: attributes = %+v, want %v", run, typeDecl, want)
+		}
+	}
+
+	generator := NewGenerator(newDecoded(), "app")
+	generator.SetDeterministicAttributes(false)
+	policy, err := generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if got := strings.Join(policy.GetTypeByName("app_exec_t").Attributes, ","); got != "exec_type,zz_files,app_files" {
+		t.Errorf("unsorted attributes = %s, want assignment order", got)
+	}
+}
+
+func TestGenerator_NoFileContext(t *testing.T) {
+	decoded := newTestDecodedPML(
+		models.Policy{Type: "p", Subject: "app_t", Object: "/tmp/*", Action: "write", Effect: "allow"},
+		models.Policy{Type: "p", Subject: "app_t", Object: "/var/tmp/app@nofc", Action: "write", Effect: "allow"},
+		models.Policy{Type: "p", Subject: "app_t", Object: "/tmpdata/*", Action: "read", Effect: "allow"},
+	)
+
+	generator := NewGenerator(decoded, "app")
+	generator.SetNoFileContextPrefixes([]string{"/tmp/"})
+	policy, err := generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	for _, fc := range policy.FileContexts {
+		if strings.HasPrefix(fc.PathPattern, "/tmp/") || strings.HasPrefix(fc.PathPattern, "/var/tmp") {
+			t.Errorf("unexpected file context %s", fc.PathPattern)
+		}
+	}
+	if len(policy.FileContexts) == 0 {
+		t.Error("expected file contexts for /tmpdata")
+	}
+	if len(policy.Rules) != 3 {
+		t.Errorf("allow rules should be kept, got %d", len(policy.Rules))
+	}
+}
+
+func TestGenerator_IoctlXperms(t *testing.T) {
+	decoded := newTestDecodedPML(
+		models.Policy{Type: "p", Subject: "app_t", Object: "/dev/app0@xperm=ioctl:0x1234-0x1240", Action: "read", Effect: "allow"},
+	)
+
+	if _, err := NewGenerator(decoded, "app").Generate(); err == nil || !contains(err.Error(), "policy version 30") {
+		t.Errorf("expected xperms to require a policy version, got %v", err)
+	}
+
+	generator := NewGenerator(decoded, "app")
+	generator.SetPolicyVersion(MinXpermPolicyVersion)
+	policy, err := generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if len(policy.XpermRules) != 1 {
+		t.Fatalf("XpermRules = %+v, want 1 rule", policy.XpermRules)
+	}
+	xperm := policy.XpermRules[0]
+	if xperm.SourceType != "app_t" || xperm.Operation != "ioctl" || strings.Join(xperm.Ranges, " ") != "0x1234-0x1240" {
+		t.Errorf("XpermRule = %+v", xperm)
+	}
+	if len(policy.Rules) != 1 || !containsAttribute(policy.Rules[0].Permissions, "ioctl") {
+		t.Errorf("allow rule should grant ioctl, got %+v", policy.Rules)
+	}
+}
+
+func TestGenerator_MapPermission(t *testing.T) {
+	decoded := newTestDecodedPML(
+		models.Policy{Type: "p", Subject: "app_t", Object: "/opt/app/lib/*", Action: "mmap", Effect: "allow"},
+	)
+
+	// Without --enable-map, mmap cannot grant map and says so
+	var out strings.Builder
+	generator := NewGenerator(decoded, "app")
+	generator.SetOutput(&out)
+	policy, err := generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if containsAttribute(policy.Rules[0].Permissions, "map") {
+		t.Errorf("map granted without --enable-map: %v", policy.Rules[0].Permissions)
+	}
+	if !strings.Contains(out.String(), "Warning: mmap on '/opt/app/lib/*' grants no map permission") {
+		t.Errorf("expected a warning about the missing map permission, got %q", out.String())
+	}
+
+	generator = NewGenerator(decoded, "app")
+	generator.SetEnableMap(true)
+	generator.SetPolicyVersion(20)
+	if _, err := generator.Generate(); err == nil || !contains(err.Error(), "policy version 31") {
+		t.Errorf("expected map to require a policy version, got %v", err)
+	}
+
+	out.Reset()
+	generator = NewGenerator(decoded, "app")
+	generator.SetOutput(&out)
+	generator.SetEnableMap(true)
+	generator.SetPolicyVersion(MinMapPolicyVersion)
+	policy, err = generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if !containsAttribute(policy.Rules[0].Permissions, "map") {
+		t.Errorf("map not granted with --enable-map: %v", policy.Rules[0].Permissions)
+	}
+	if out.Len() != 0 {
+		t.Errorf("unexpected warnings %q", out.String())
+	}
+}
+
+func TestGenerator_PolicyComments(t *testing.T) {
+	commented := models.Policy{Type: "p", Subject: "app_t", Object: "/srv/app/*", Action: "read", Effect: "allow",
+		Comment: "app serves its own assets"}
+	decoded := newTestDecodedPML(
+		commented,
+		models.Policy{Type: "p", Subject: "app_t", Object: "/opt/app/*", Action: "read", Effect: "allow"},
+	)
+
+	policy, err := NewGenerator(decoded, "app").Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	want := map[string]string{
+		"/srv/app": "app serves its own assets",
+		"/opt/app": "Generated from PML policy: /opt/app/*",
+	}
+	for _, fc := range policy.FileContexts {
+		for prefix, comment := range want {
+			if strings.HasPrefix(fc.PathPattern, prefix) && fc.Comment != comment {
+				t.Errorf("file context %s comment = %q, want %q", fc.PathPattern, fc.Comment, comment)
+			}
+		}
+	}
+	if len(policy.Rules) != 2 || policy.Rules[0].Comment != want["/srv/app"] || policy.Rules[1].Comment != want["/opt/app"] {
+		t.Errorf("allow rule comments = %+v", policy.Rules)
+	}
+}
+
+func TestGenerator_ConditionalRules(t *testing.T) {
+	decoded := newTestDecodedPML(
+		models.Policy{Type: "p", Subject: "app_t", Object: "/srv/nfs/*?cond=app_use_nfs", Action: "read", Effect: "allow"},
+		models.Policy{Type: "p", Subject: "app_t", Object: "/srv/tmp/*?cond=!app_use_nfs", Action: "write", Effect: "allow"},
+		models.Policy{Type: "p", Subject: "app_t", Object: "/opt/app/*", Action: "read", Effect: "allow"},
+	)
+
+	policy, err := NewGenerator(decoded, "app").Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if len(policy.Booleans) != 1 || policy.Booleans[0].Name != "app_use_nfs" || policy.Booleans[0].Default {
+		t.Errorf("Booleans = %+v, want [{app_use_nfs false}]", policy.Booleans)
+	}
+	if len(policy.CondRules) != 2 || policy.CondRules[0].Condition != "app_use_nfs" || policy.CondRules[1].Condition != "!app_use_nfs" {
+		t.Errorf("CondRules = %+v", policy.CondRules)
+	}
+	if len(policy.Rules) != 1 {
+		t.Errorf("only the unconditional rule should be in Rules, got %+v", policy.Rules)
+	}
+
+	decoded = newTestDecodedPML(
+		models.Policy{Type: "p", Subject: "app_t", Object: "/srv/nfs/*?cond=a && b", Action: "read", Effect: "allow"},
+	)
+	if _, err := NewGenerator(decoded, "app").Generate(); err == nil || !contains(err.Error(), "invalid condition") {
+		t.Errorf("expected invalid condition error, got %v", err)
+	}
+}
+
+func TestGenerator_InferTypeAttributes(t *testing.T) {
+	decoded := newTestDecodedPML(
+		models.Policy{Type: "p", Subject: "app_t", Object: "/var/log/app/*", Action: "write", Effect: "allow"},
+	)
+	decoded.Transitions = []models.TransitionInfo{
+		{SourceType: "init_t", TargetType: "app_exec_t", Class: "process", NewType: "app_t"},
+		{SourceType: "app_t", TargetType: "tmp_t", Class: "file", NewType: "app_tmp_t"},
+	}
+	decoded.TypeAttributes = []models.RoleRelation{
+		{Type: "g2", Member: "tmp_t", Role: "app_files"},
+	}
+
+	policy, err := NewGenerator(decoded, "app").Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	want := map[string]string{
+		"app_t":      "domain",
+		"init_t":     "domain",
+		"app_exec_t": "exec_type",
+		"app_tmp_t":  "file_type",
+		"tmp_t":      "app_files",
+	}
+	want[mapping.NewTypeMapper("app").PathToType("/var/log/app/*")] = "file_type,logfile"
+	for typeName, attrs := range want {
+		typeDecl := policy.GetTypeByName(typeName)
+		if typeDecl == nil {
+			t.Errorf("type %s not declared", typeName)
+			continue
+		}
+		if got := strings.Join(typeDecl.Attributes, ","); got != attrs {
+			t.Errorf("%s attributes = %s, want %s", typeName, got, attrs)
+		}
+	}
+}
+
+func TestGenerator_GroupFileTypes(t *testing.T) {
+	decoded := newTestDecodedPML(
+		models.Policy{Type: "p", Subject: "app_t", Object: "/var/log/app/*", Action: "write", Effect: "allow"},
+		models.Policy{Type: "p", Subject: "app_t", Object: "/usr/bin/app", Action: "execute", Effect: "allow"},
+	)
+
+	policy, err := NewGenerator(decoded, "app").Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if len(policy.Attributes) != 0 {
+		t.Errorf("attributes should only be declared when grouping, got %v", policy.Attributes)
+	}
+
+	generator := NewGenerator(decoded, "app")
+	generator.SetGroupFileTypes(true)
+	policy, err = generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if len(policy.Attributes) != 1 || policy.Attributes[0] != "app_file_types" {
+		t.Fatalf("Attributes = %v, want [app_file_types]", policy.Attributes)
+	}
+	for _, typeDecl := range policy.Types {
+		grouped := containsAttribute(typeDecl.Attributes, "app_file_types")
+		if grouped != containsAttribute(typeDecl.Attributes, "file_type") {
+			t.Errorf("%s attributes = %v, want app_file_types exactly on file types", typeDecl.TypeName, typeDecl.Attributes)
+		}
+	}
+}
+
+func TestGenerator_BracedFileTypes(t *testing.T) {
+	decoded := newTestDecodedPML(
+		models.Policy{Type: "p", Subject: "app_t", Object: "/dev/{sda,null}", Action: "read", Effect: "allow"},
+		models.Policy{Type: "p", Subject: "app_t", Object: "/etc/{a,b}.conf", Action: "read", Effect: "allow"},
+	)
+
+	policy, err := NewGenerator(decoded, "app").Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	got := make(map[string]string)
+	for _, fc := range policy.FileContexts {
+		got[fc.PathPattern] = fc.FileType
+	}
+	want := map[string]string{
+		"/dev/sda":          "block",
+		"/dev/null":         "char",
+		"/etc/(a|b)\\.conf": "regular file",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("file contexts = %v, want %v", got, want)
+	}
+	for pattern, fileType := range want {
+		if got[pattern] != fileType {
+			t.Errorf("%s file type = %q, want %q", pattern, got[pattern], fileType)
+		}
+	}
+}
+
+func TestGenerator_OptionalRules(t *testing.T) {
+	decoded := newTestDecodedPML(
+		models.Policy{Type: "p", Subject: "app_t", Object: "/etc/app.conf", Action: "read", Effect: "allow"},
+		models.Policy{Type: "p", Subject: "app_t", Object: "mysqld_t", Action: "connectto", Effect: "allow"},
+		models.Policy{Type: "p", Subject: "app_t", Object: "/var/www/*", Action: "read", Effect: "allow"},
+	)
+	decoded.Policies[1].Optional = "mysql"
+	decoded.Policies[2].Optional = "apache"
+
+	policy, err := NewGenerator(decoded, "app").Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if len(policy.Rules) != 1 || len(policy.OptionalRules) != 2 {
+		t.Fatalf("Rules = %+v, OptionalRules = %+v", policy.Rules, policy.OptionalRules)
+	}
+	if rule := policy.OptionalRules[1]; rule.Optional != "apache" || rule.TargetType != "apache_var_www_t" {
+		t.Errorf("optional path rule = %+v, want target apache_var_www_t", rule)
+	}
+	if policy.HasType("apache_var_www_t") || policy.HasType("app_var_www_t") || len(policy.FileContexts) != 1 {
+		t.Errorf("optional objects must not be declared or labeled, types %+v, contexts %+v", policy.Types, policy.FileContexts)
+	}
+
+	decoded.Policies[1].Condition = "app_use_mysql"
+	if _, err := NewGenerator(decoded, "app").Generate(); err == nil || !strings.Contains(err.Error(), "@optional cannot be combined") {
+		t.Errorf("expected combination error, got %v", err)
+	}
+}
+
+func TestGenerator_Userdom(t *testing.T) {
+	decoded := newTestDecodedPML(
+		models.Policy{Type: "p", Subject: "app_t", Object: "/home/*/.app/*", Action: "read", Effect: "allow"},
+		models.Policy{Type: "p", Subject: "app_t", Object: "/home/*/.app/*", Action: "write", Effect: "allow"},
+		models.Policy{Type: "p", Subject: "app_t", Object: "/etc/app.conf", Action: "read", Effect: "allow"},
+	)
+	homeType := mapping.NewTypeMapper("app").PathToType("/home/*/.app/*")
+
+	for _, enabled := range []bool{false, true} {
+		generator := NewGenerator(decoded, "app")
+		generator.SetUserdom(enabled)
+		policy, err := generator.Generate()
+		if err != nil {
+			t.Fatalf("Generate() error = %v", err)
+		}
+
+		homeContexts := 0
+		for _, fc := range policy.FileContexts {
+			if strings.HasPrefix(fc.PathPattern, "/home") {
+				homeContexts++
+			}
+		}
+		if enabled {
+			if homeContexts != 0 || len(policy.UserHomeContent) != 1 || policy.UserHomeContent[0] != homeType {
+				t.Errorf("userdom: home contexts = %d, UserHomeContent = %v, want none and [%s]", homeContexts, policy.UserHomeContent, homeType)
+			}
+			if !policy.HasType(homeType) || len(policy.FileContexts) != 1 {
+				t.Errorf("userdom: type %s must stay declared and /etc labeled, contexts %+v", homeType, policy.FileContexts)
+			}
+		} else if homeContexts == 0 || len(policy.UserHomeContent) != 0 {
+			t.Errorf("default: home contexts = %d, UserHomeContent = %v", homeContexts, policy.UserHomeContent)
+		}
+	}
+}
+
+func TestGenerator_DomainTransitionFdUse(t *testing.T) {
+	decoded := newTestDecodedPML(
+		models.Policy{Type: "p2", Subject: "app_t", Object: "app_helper_exec_t::process", Action: "transition", Effect: "app_helper_t"},
+		models.Policy{Type: "p2", Subject: "app_t", Object: "app_tmp_t::file", Action: "transition", Effect: "app_cache_t"},
+	)
+	decoded.Transitions = []models.TransitionInfo{*decoded.Policies[0].TransitionInfo, *decoded.Policies[1].TransitionInfo}
+
+	policy, err := NewGenerator(decoded, "app").Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	fdRules := 0
+	for _, rule := range policy.Rules {
+		if rule.Class != "fd" {
+			continue
+		}
+		fdRules++
+		if rule.SourceType != "app_helper_t" || rule.TargetType != "app_t" || strings.Join(rule.Permissions, " ") != "use" {
+			t.Errorf("fd rule = %+v, want allow app_helper_t app_t:fd use", rule)
+		}
+	}
+	if fdRules != 1 {
+		t.Errorf("expected one fd use rule for the process transition only, got %d in %+v", fdRules, policy.Rules)
+	}
+}
+
+func TestGenerator_EmptyModule(t *testing.T) {
+	denyOnly := newTestDecodedPML(
+		models.Policy{Type: "p", Subject: "app_t", Object: "/srv/app/secret", Action: "read", Effect: "deny"},
+	)
+	withAllow := newTestDecodedPML(
+		models.Policy{Type: "p", Subject: "app_t", Object: "/etc/app.conf", Action: "read", Effect: "allow"},
+	)
+
+	tests := []struct {
+		name    string
+		decoded *models.DecodedPML
+		strict  bool
+		wantErr bool
+	}{
+		{"deny only warns", denyOnly, false, false},
+		{"deny only fails under strict", denyOnly, true, true},
+		{"allow rules pass under strict", withAllow, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			generator := NewGenerator(tt.decoded, "app")
+			generator.SetStrict(tt.strict)
+			_, err := generator.Generate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Generate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "1 of 1 policy rules are deny rules") {
+				t.Errorf("error should count the dropped deny rules, got %v", err)
+			}
+		})
+	}
+}
+
+func TestGenerator_Rationale(t *testing.T) {
+	decoded := newTestDecodedPML(
+		models.Policy{Type: "p", Subject: "httpd_t", Object: "/var/www/*", Action: "read", Effect: "allow", Comment: "serve static content"},
+		models.Policy{Type: "p", Subject: "httpd_t", Object: "/var/www/*", Action: "write", Effect: "allow"},
+	)
+	policy, err := NewGenerator(decoded, "httpd").Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if err := NewOptimizer(policy).Optimize(); err != nil {
+		t.Fatalf("Optimize() error = %v", err)
+	}
+
+	var rationales []string
+	for _, rule := range policy.Rules {
+		if rule.SourceType == "httpd_t" && rule.Class == "file" {
+			rationales = append(rationales, rule.Rationale...)
+		}
+	}
+	want := []string{"httpd_t may read /var/www/*: serve static content", "httpd_t may write /var/www/*"}
+	if strings.Join(rationales, "|") != strings.Join(want, "|") {
+		t.Errorf("rationales of the merged rule = %v, want %v", rationales, want)
+	}
+}
+
+func TestGenerator_DenyModes(t *testing.T) {
+	decoded := newTestDecodedPML(
+		models.Policy{Type: "p", Subject: "app_t", Object: "/srv/app/data/*", Action: "read", Effect: "allow"},
+		models.Policy{Type: "p", Subject: "app_t", Object: "/srv/app/secret", Action: "read", Effect: "deny"},
+		models.Policy{Type: "p", Subject: "app_t", Object: "/srv/app/data/*", Action: "mmap", Effect: "deny"},
+	)
+	secretType := mapping.NewTypeMapper("app").PathToType("/srv/app/secret")
+
+	t.Run("neverallow", func(t *testing.T) {
+		policy, err := NewGenerator(decoded, "app").Generate()
+		if err != nil {
+			t.Fatalf("Generate() error = %v", err)
+		}
+		// mmap on the data files is dropped: the module allows their read, open and getattr
+		if len(policy.NeverallowRules) != 1 {
+			t.Fatalf("NeverallowRules = %+v, want only the secret", policy.NeverallowRules)
+		}
+		rule := policy.NeverallowRules[0]
+		if rule.SourceType != "app_t" || rule.TargetType != secretType || rule.Class != "file" || strings.Join(rule.Permissions, " ") != "read" {
+			t.Errorf("neverallow = %+v, want app_t %s:file read", rule, secretType)
+		}
+		if len(policy.DontauditRules) != 0 {
+			t.Errorf("DontauditRules = %+v, want none", policy.DontauditRules)
+		}
+	})
+
+	t.Run("dontaudit", func(t *testing.T) {
+		generator := NewGenerator(decoded, "app")
+		generator.SetDenyMode(DenyModeDontaudit)
+		policy, err := generator.Generate()
+		if err != nil {
+			t.Fatalf("Generate() error = %v", err)
+		}
+		if len(policy.NeverallowRules) != 0 || len(policy.DontauditRules) != 2 {
+			t.Fatalf("neverallow %+v, dontaudit %+v, want two dontaudit rules", policy.NeverallowRules, policy.DontauditRules)
+		}
+		if perms := strings.Join(policy.DontauditRules[0].Permissions, " "); perms != "read open getattr" {
+			t.Errorf("dontaudit should silence the whole access, got %s", perms)
+		}
+	})
+
+	t.Run("skip", func(t *testing.T) {
+		generator := NewGenerator(decoded, "app")
+		generator.SetDenyMode(DenyModeSkip)
+		policy, err := generator.Generate()
+		if err != nil {
+			t.Fatalf("Generate() error = %v", err)
+		}
+		if len(policy.NeverallowRules) != 0 || len(policy.DontauditRules) != 0 {
+			t.Errorf("neverallow %+v, dontaudit %+v, want none", policy.NeverallowRules, policy.DontauditRules)
+		}
+	})
+}
+
+func TestGenerator_DontauditEffect(t *testing.T) {
+	decoded := newTestDecodedPML(
+		models.Policy{Type: "p", Subject: "httpd_t", Object: "/var/www/*", Action: "read", Effect: "allow"},
+		models.Policy{Type: "p", Subject: "httpd_t", Object: "tmp_t", Action: "search::dir", Effect: "dontaudit"},
+		models.Policy{Type: "p", Subject: "httpd_t", Object: "/srv/cache/*?cond=httpd_use_cache", Action: "read", Effect: "dontaudit"},
+	)
+
+	policy, err := NewGenerator(decoded, "httpd").Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	// The conditional dontaudit is skipped with a warning
+	if len(policy.DontauditRules) != 1 {
+		t.Fatalf("DontauditRules = %+v, want one rule", policy.DontauditRules)
+	}
+	rule := policy.DontauditRules[0]
+	if rule.SourceType != "httpd_t" || rule.TargetType != "tmp_t" || rule.Class != "dir" || strings.Join(rule.Permissions, " ") != "search getattr" {
+		t.Errorf("dontaudit = %+v, want httpd_t tmp_t:dir { search getattr }", rule)
+	}
+	if len(policy.Rules) != 1 || len(policy.NeverallowRules) != 0 || len(policy.Booleans) != 0 {
+		t.Errorf("dontaudit rules should grant and assert nothing, got allow %+v, neverallow %+v, booleans %+v",
+			policy.Rules, policy.NeverallowRules, policy.Booleans)
+	}
+}
+
+func TestGenerator_NeverallowConflict(t *testing.T) {
+	decoded := newTestDecodedPML(
+		models.Policy{Type: "p", Subject: "app_t", Object: "/srv/app/data/*", Action: "write", Effect: "allow"},
+		models.Policy{Type: "p", Subject: "app_t", Object: "/srv/app/data/*", Action: "write", Effect: "deny"},
+	)
+
+	// The analyzer still reports the conflict; under allow-override the allow wins
+	analyzer := NewAnalyzer(decoded)
+	analyzer.SetQuiet(true)
+	if err := analyzer.Analyze(); err != nil {
+		t.Fatalf("Analyze() error = %v", err)
+	}
+	if len(analyzer.GetConflicts()) != 1 {
+		t.Errorf("conflicts = %v, want 1", analyzer.GetConflicts())
+	}
+
+	policy, err := NewGenerator(decoded, "app").Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if len(policy.NeverallowRules) != 0 {
+		t.Errorf("a neverallow on allowed permissions would not compile, got %+v", policy.NeverallowRules)
+	}
+}
+
+func TestGenerator_PortObjects(t *testing.T) {
+	decoded := newTestDecodedPML(
+		models.Policy{Type: "p", Subject: "app_t", Object: "tcp:8080", Action: "bind", Effect: "allow"},
+		models.Policy{Type: "p", Subject: "app_t", Object: "tcp:9000", Action: "bind", Effect: "allow"},
+		models.Policy{Type: "p", Subject: "app_t", Object: "tcp:8000-8100", Action: "connect", Effect: "allow"},
+		models.Policy{Type: "p", Subject: "app_t", Object: "udp:9000::udp_socket", Action: "name_bind", Effect: "allow"},
+	)
+
+	want := []models.PortBinding{
+		{Port: 9000, Protocol: "tcp", PortType: "app_port_t"},
+		{Port: 8000, PortEnd: 8100, Protocol: "tcp", PortType: "app_port_t"},
+		{Port: 9000, Protocol: "udp", PortType: "app_port_t"},
+	}
+
+	// A base policy labels the ports with portcon
+	generator := NewGenerator(decoded, "app")
+	generator.SetBasePolicy(true)
+	policy, err := generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if len(policy.PortBindings) != len(want) {
+		t.Fatalf("PortBindings = %+v, want %+v", policy.PortBindings, want)
+	}
+	for i := range want {
+		if policy.PortBindings[i] != want[i] {
+			t.Errorf("PortBindings[%d] = %+v, want %+v", i, policy.PortBindings[i], want[i])
+		}
+	}
+
+	// A module cannot declare portcon and leaves its ports to semanage port
+	var out strings.Builder
+	generator = NewGenerator(decoded, "app")
+	generator.SetOutput(&out)
+	policy, err = generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if len(policy.PortBindings) != 0 || len(policy.SemanagePorts) != len(want) {
+		t.Fatalf("module PortBindings = %+v, SemanagePorts = %+v, want only %+v labeled by semanage",
+			policy.PortBindings, policy.SemanagePorts, want)
+	}
+	if !strings.Contains(out.String(), "semanage port -a -t app_port_t -p tcp 8000-8100") {
+		t.Errorf("expected a warning with the semanage port command, got %q", out.String())
+	}
+
+	grants := make(map[string]bool)
+	for _, rule := range policy.Rules {
+		for _, perm := range rule.Permissions {
+			grants[rule.TargetType+":"+rule.Class+" "+perm] = true
+		}
+	}
+	for _, grant := range []string{
+		"http_port_t:tcp_socket name_bind",
+		"app_port_t:tcp_socket name_bind",
+		"app_port_t:tcp_socket name_connect",
+		"app_port_t:udp_socket name_bind",
+	} {
+		if !grants[grant] {
+			t.Errorf("missing %s in %+v", grant, policy.Rules)
+		}
+	}
+	if !policy.HasType("app_port_t") || policy.HasType("http_port_t") {
+		t.Errorf("only the module's port type should be declared, got %+v", policy.Types)
+	}
+
+	for _, object := range []string{"tcp:http", "tcp:70000", "tcp:9000-8000"} {
+		decoded := newTestDecodedPML(models.Policy{Type: "p", Subject: "app_t", Object: object, Action: "bind", Effect: "allow"})
+		if _, err := NewGenerator(decoded, "app").Generate(); err == nil {
+			t.Errorf("expected error for %s", object)
+		}
+	}
+}
+
+func TestGenerator_Output(t *testing.T) {
+	decoded := newTestDecodedPML(
+		models.Policy{Type: "p", Subject: "app_t", Object: "/srv/app/*", Action: "read", Effect: "allow"},
+		models.Policy{Type: "p", Subject: "app_t", Object: "/srv/app/secret", Action: "read", Effect: "deny"},
+	)
+
+	var out strings.Builder
+	generator := NewGenerator(decoded, "app")
+	generator.SetDenyMode(DenyModeSkip)
+	generator.SetOutput(&out)
+	if _, err := generator.Generate(); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if !strings.Contains(out.String(), "Warning: Deny rule skipped (deny mode skip)") {
+		t.Errorf("warnings should go to the configured output, got %q", out.String())
+	}
+}
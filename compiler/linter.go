@@ -0,0 +1,107 @@
+package compiler
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/cici0602/pml-to-selinux/models"
+)
+
+// LintPolicy runs every check of the compile pipeline on parsed PML and reports
+// them as findings: the passes of Lint with all analyzer lints enabled unless
+// opts disables them, the analyzer's allow/deny conflicts, and the conflict
+// analysis of the generated policy. The later checks need the whole policy and
+// only run when every line passed the syntax checks. Findings on generated
+// rules carry no position.
+func LintPolicy(pml *models.ParsedPML, opts LintOptions) []Finding {
+	opts.Enable = append(LintNames(), opts.Enable...)
+	if opts.BroadPermsThreshold <= 0 {
+		opts.BroadPermsThreshold = DefaultBroadPermsThreshold
+	}
+	findings := Lint(pml.Policies, opts)
+	for _, f := range findings {
+		if f.Severity == SeverityError {
+			return findings
+		}
+	}
+
+	add := func(file string, line int, severity Severity, code, message string) {
+		findings = append(findings, Finding{
+			File:     file,
+			Line:     line,
+			Severity: severity,
+			Code:     code,
+			Message:  message,
+			Risk:     RiskScore(code),
+		})
+	}
+
+	decoded, err := (&Parser{}).Decode(pml)
+	if err != nil {
+		add("", 0, SeverityError, "syntax", findingMessage(err))
+		return sortFindings(findings)
+	}
+
+	// Allow and deny rules matching the same access, reported once at the deny
+	analyzer := NewAnalyzer(decoded)
+	analyzer.SetQuiet(true)
+	if err := analyzer.Analyze(); err != nil {
+		add("", 0, SeverityError, "model", err.Error())
+		return sortFindings(findings)
+	}
+	reported := make(map[string]bool)
+	for _, conflict := range analyzer.GetConflicts() {
+		key := fmt.Sprintf("%s:%d: %s", conflict.DenyRule.File, conflict.DenyRule.Line, conflict.Reason)
+		if !reported[key] {
+			reported[key] = true
+			add(conflict.DenyRule.File, conflict.DenyRule.Line, SeverityWarning, "conflict", conflict.Reason)
+		}
+	}
+
+	// Conflicts in the generated policy. Undeclared types are not reported:
+	// modules require the types of the base policy instead of declaring them.
+	policy, err := NewGenerator(analyzer.Resolved(), "").Generate()
+	if err != nil {
+		add("", 0, SeverityError, "generate", err.Error())
+		return sortFindings(findings)
+	}
+	analysis := DetectConflicts(policy)
+	for _, message := range analysis.AllowDenyConflicts {
+		add("", 0, SeverityError, "conflict", message)
+	}
+	for _, message := range analysis.OverlappingRules {
+		add("", 0, SeverityWarning, "overlap", message)
+	}
+	for _, message := range analysis.CircularDependencies {
+		add("", 0, SeverityWarning, "circular-trans", message)
+	}
+	for _, message := range analysis.TrappedDomains {
+		add("", 0, SeverityWarning, "trapped-domain", message)
+	}
+
+	return sortFindings(findings)
+}
+
+// sortFindings orders findings by file and line, findings without a position last
+func sortFindings(findings []Finding) []Finding {
+	sort.SliceStable(findings, func(i, j int) bool {
+		if (findings[i].File == "") != (findings[j].File == "") {
+			return findings[j].File == ""
+		}
+		if findings[i].File != findings[j].File {
+			return findings[i].File < findings[j].File
+		}
+		return findings[i].Line < findings[j].Line
+	})
+	return findings
+}
+
+// FailsAt reports whether any finding is at least as severe as the threshold
+func FailsAt(findings []Finding, threshold Severity) bool {
+	for _, f := range findings {
+		if f.Severity == SeverityError || f.Severity == threshold {
+			return true
+		}
+	}
+	return false
+}
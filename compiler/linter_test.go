@@ -0,0 +1,72 @@
+package compiler
+
+import (
+	"testing"
+
+	"github.com/cici0602/pml-to-selinux/models"
+)
+
+func TestLintPolicy(t *testing.T) {
+	line := func(n int, subject, object, action, effect string) models.Policy {
+		return models.Policy{Type: "p", Subject: subject, Object: object, Action: action, Effect: effect, File: "policy.csv", Line: n}
+	}
+	pml := &models.ParsedPML{
+		Model: &models.PMLModel{
+			RequestDefinition: map[string][]string{"r": {"sub", "obj", "act"}},
+			PolicyDefinition:  map[string][]string{"p": {"sub", "obj", "act", "eft"}},
+			Matchers:          "r.sub == p.sub && r.obj == p.obj && r.act == p.act",
+			Effect:            "some(where (p.eft == allow))",
+		},
+		Policies: []models.Policy{
+			line(1, "httpd_t", "/var/www/*", "read", "allow"),
+			line(2, "httpd_t", "/var/www/*", "read", "allow"),
+			line(3, "httpd_t", "/var/www/secret", "read", "deny"),
+			line(4, "httpd_t", "tmp_t", "write", "allow"),
+			line(5, "httpd_t", "unconfined_t", "transition", "allow"),
+		},
+	}
+
+	findings := LintPolicy(pml, LintOptions{})
+	// Findings on the generated policy come last, without a position
+	want := []struct {
+		file string
+		line int
+		code string
+	}{
+		{"policy.csv", 2, "duplicate"},
+		{"policy.csv", 3, "conflict"},
+		{"policy.csv", 4, "world-writable"},
+		{"policy.csv", 5, "unconfined-trans"},
+		{"", 0, "overlap"},
+	}
+	if len(findings) != len(want) {
+		t.Fatalf("LintPolicy() = %v, want %d findings", findings, len(want))
+	}
+	for i, w := range want {
+		f := findings[i]
+		if f.File != w.file || f.Line != w.line || f.Code != w.code || f.Severity != SeverityWarning || f.Risk != RiskScore(w.code) {
+			t.Errorf("finding %d = %v, want %s:%d [%s]", i, f, w.file, w.line, w.code)
+		}
+	}
+	if FailsAt(findings, SeverityError) || !FailsAt(findings, SeverityWarning) {
+		t.Errorf("warnings should fail only at --fail-on warning")
+	}
+
+	findings = LintPolicy(pml, LintOptions{Disable: []string{"world-writable", "unconfined-trans"}})
+	if len(findings) != 3 {
+		t.Errorf("LintPolicy() with lints disabled = %v, want 3 findings", findings)
+	}
+
+	// Syntax errors stop the checks that need the whole policy
+	pml.Policies = append(pml.Policies, line(6, "httpd_t", "/etc/app", "read", "maybe"))
+	findings = LintPolicy(pml, LintOptions{})
+	last := findings[len(findings)-1]
+	if last.Line != 6 || last.Code != "syntax" || !FailsAt(findings, SeverityError) {
+		t.Errorf("LintPolicy() = %v, want a syntax error last", findings)
+	}
+	for _, f := range findings {
+		if f.Code == "conflict" {
+			t.Errorf("conflicts should not be reported when a line fails the syntax checks: %v", f)
+		}
+	}
+}
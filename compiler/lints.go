@@ -0,0 +1,285 @@
+package compiler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cici0602/pml-to-selinux/mapping"
+)
+
+// lint is an optional analyzer pass that only reports warnings.
+// Names are stable so projects can enable or disable lints by name.
+type lint struct {
+	name           string
+	description    string
+	defaultEnabled bool
+	run            func(a *Analyzer)
+}
+
+// lints are run in this order after validation and conflict resolution
+var lints = []lint{
+	{
+		name:        "broad-perms",
+		description: "broad permission sets granted on broad targets",
+		run:         (*Analyzer).lintBroadPermissions,
+	},
+	{
+		name:           "wx",
+		description:    "objects a subject can both write and execute",
+		defaultEnabled: true,
+		run:            (*Analyzer).lintWriteExecute,
+	},
+	{
+		name:           "unknown-class",
+		description:    "object classes neither the action mapper nor the kernel policy defines",
+		defaultEnabled: true,
+		run:            (*Analyzer).lintUnknownClass,
+	},
+	{
+		name:           "exec-no-trans",
+		description:    "binaries a domain may run in place although it transitions on them",
+		defaultEnabled: true,
+		run:            (*Analyzer).lintExecuteNoTransition,
+	},
+	{
+		name:        "root-wildcard",
+		description: "objects matching every path below the root, such as /*",
+		run:         (*Analyzer).lintRootWildcard,
+	},
+	{
+		name:        "world-writable",
+		description: "write access to types every domain shares, such as tmp_t or etc_t",
+		run:         (*Analyzer).lintWorldWritable,
+	},
+	{
+		name:        "unconfined-trans",
+		description: "transitions into unconfined domains",
+		run:         (*Analyzer).lintUnconfinedTransition,
+	},
+}
+
+// LintNames returns the names of the available lints, sorted
+func LintNames() []string {
+	names := make([]string, 0, len(lints))
+	for _, l := range lints {
+		names = append(names, l.name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// EnableLint turns on a lint by name
+func (a *Analyzer) EnableLint(name string) error {
+	return a.setLint(name, true)
+}
+
+// DisableLint turns off a lint by name
+func (a *Analyzer) DisableLint(name string) error {
+	return a.setLint(name, false)
+}
+
+// setLint overrides whether a lint runs
+func (a *Analyzer) setLint(name string, enabled bool) error {
+	for _, l := range lints {
+		if l.name == name {
+			if a.lintOverrides == nil {
+				a.lintOverrides = make(map[string]bool)
+			}
+			a.lintOverrides[name] = enabled
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown lint '%s' (available: %s)", name, strings.Join(LintNames(), ", "))
+}
+
+// lintEnabled reports whether a lint runs: an explicit override wins, then
+// a broad-permission threshold enables broad-perms, then the lint's default
+func (a *Analyzer) lintEnabled(l lint) bool {
+	if enabled, ok := a.lintOverrides[l.name]; ok {
+		return enabled
+	}
+	if l.name == "broad-perms" && a.broadPermsThreshold > 0 {
+		return true
+	}
+	return l.defaultEnabled
+}
+
+// runLints runs every enabled lint
+func (a *Analyzer) runLints() {
+	for _, l := range lints {
+		if a.lintEnabled(l) {
+			l.run(a)
+		}
+	}
+}
+
+// Lint validates the policies and runs only the enabled lints, skipping
+// conflict detection and statistics
+func (a *Analyzer) Lint() error {
+	if err := a.validateModel(); err != nil {
+		a.errors = append(a.errors, err)
+		return err
+	}
+	if err := a.validatePolicies(); err != nil {
+		return err
+	}
+
+	a.runLints()
+	return nil
+}
+
+// wxWritePermissions are the permissions that let a subject change an object's content
+var wxWritePermissions = map[string]bool{"write": true, "append": true, "create": true}
+
+// lintWriteExecute warns when a subject may both write and execute the same object,
+// which lets it run code it wrote (W^X)
+func (a *Analyzer) lintWriteExecute() {
+	actionMapper := mapping.NewActionMapper()
+
+	type access struct {
+		writeRule, execRule int // 1-based rule numbers, 0 when not granted
+	}
+	accesses := make(map[string]*access)
+	var order []string
+
+	for i, policy := range a.decoded.Policies {
+		if policy.Effect != "allow" || policy.IsTransition {
+			continue
+		}
+
+		key := policy.Subject + "|" + policy.Object
+		acc, ok := accesses[key]
+		if !ok {
+			acc = &access{}
+			accesses[key] = acc
+			order = append(order, key)
+		}
+
+		_, perms := actionMapper.MapAction(policy.Action, policy.Class)
+		for _, perm := range perms {
+			if wxWritePermissions[perm] && acc.writeRule == 0 {
+				acc.writeRule = i + 1
+			}
+			if perm == "execute" && acc.execRule == 0 {
+				acc.execRule = i + 1
+			}
+		}
+	}
+
+	for _, key := range order {
+		acc := accesses[key]
+		if acc.writeRule == 0 || acc.execRule == 0 {
+			continue
+		}
+		subject, object, _ := strings.Cut(key, "|")
+		if acc.writeRule == acc.execRule {
+			a.addWarning(fmt.Sprintf("policy rule %d: subject '%s' can both write and execute '%s' (W^X)",
+				acc.writeRule, subject, object))
+		} else {
+			a.addWarning(fmt.Sprintf("policy rules %d and %d: subject '%s' can both write and execute '%s' (W^X)",
+				acc.writeRule, acc.execRule, subject, object))
+		}
+	}
+}
+
+// lintUnknownClass warns when a rule names an object class that is neither mapped
+// by the action mapper nor a standard SELinux class; such rules likely won't compile.
+// Classes registered through a class map are trusted.
+func (a *Analyzer) lintUnknownClass() {
+	known := make(map[string]bool)
+	for _, class := range mapping.NewActionMapper().GetSupportedClasses() {
+		known[class] = true
+	}
+
+	for i, policy := range a.decoded.Policies {
+		if policy.CustomClass || known[policy.Class] || mapping.IsStandardClass(policy.Class) {
+			continue
+		}
+		a.addWarning(fmt.Sprintf("policy rule %d: unknown object class '%s' for '%s' (register it with --class-map if intended)",
+			i+1, policy.Class, policy.Object))
+	}
+}
+
+// lintExecuteNoTransition warns when a domain is granted execute_no_trans on a
+// binary it also has a domain transition on: running the binary in place skips
+// the transition, so the rule likely wants exec_transition instead of execute
+func (a *Analyzer) lintExecuteNoTransition() {
+	transitions := make(map[string]bool)
+	for _, policy := range a.decoded.Policies {
+		if policy.IsTransition && policy.TransitionInfo != nil && policy.TransitionInfo.Class == "process" {
+			transitions[policy.TransitionInfo.SourceType+"|"+policy.TransitionInfo.TargetType] = true
+		}
+	}
+	if len(transitions) == 0 {
+		return
+	}
+
+	actionMapper := mapping.NewActionMapper()
+	for i, policy := range a.decoded.Policies {
+		if policy.Effect != "allow" || policy.IsTransition || !transitions[policy.Subject+"|"+policy.Object] {
+			continue
+		}
+		_, perms := actionMapper.MapAction(policy.Action, policy.Class)
+		if containsAttribute(perms, "execute_no_trans") {
+			a.addWarning(fmt.Sprintf("policy rule %d: subject '%s' can run '%s' without transitioning although it transitions on it (use exec_transition)",
+				i+1, policy.Subject, policy.Object))
+		}
+	}
+}
+
+// lintRootWildcard warns on allow rules whose object matches every path below
+// the root: the generated file context relabels the whole filesystem
+func (a *Analyzer) lintRootWildcard() {
+	for i, policy := range a.decoded.Policies {
+		if policy.Effect != "allow" || policy.IsTransition || !strings.HasPrefix(policy.Object, "/*") {
+			continue
+		}
+		a.addWarning(fmt.Sprintf("policy rule %d: object '%s' matches every path below the root", i+1, policy.Object))
+	}
+}
+
+// sharedTypes are system types every domain reads or writes; write access to
+// them lets a domain tamper with files other domains trust
+var sharedTypes = map[string]bool{
+	"tmp_t": true, "var_tmp_t": true, "etc_t": true, "usr_t": true, "var_t": true,
+	"root_t": true, "default_t": true, "home_root_t": true, "bin_t": true, "lib_t": true,
+}
+
+// lintWorldWritable warns when a subject may write a type every domain shares
+func (a *Analyzer) lintWorldWritable() {
+	actionMapper := mapping.NewActionMapper()
+	for i, policy := range a.decoded.Policies {
+		if policy.Effect != "allow" || policy.IsTransition || !sharedTypes[policy.Object] {
+			continue
+		}
+		_, perms := actionMapper.MapAction(policy.Action, policy.Class)
+		for _, perm := range perms {
+			if wxWritePermissions[perm] {
+				a.addWarning(fmt.Sprintf("policy rule %d: subject '%s' can write the shared type '%s'",
+					i+1, policy.Subject, policy.Object))
+				break
+			}
+		}
+	}
+}
+
+// lintUnconfinedTransition warns on transitions into unconfined domains, which
+// escape confinement altogether
+func (a *Analyzer) lintUnconfinedTransition() {
+	actionMapper := mapping.NewActionMapper()
+	for i, policy := range a.decoded.Policies {
+		target := ""
+		if policy.IsTransition && policy.TransitionInfo != nil && policy.TransitionInfo.Class == "process" {
+			target = policy.TransitionInfo.NewType
+		} else if policy.Effect == "allow" && !policy.IsTransition {
+			if _, perms := actionMapper.MapAction(policy.Action, policy.Class); containsAttribute(perms, "transition") {
+				target = policy.Object
+			}
+		}
+		if strings.Contains(target, "unconfined") {
+			a.addWarning(fmt.Sprintf("policy rule %d: subject '%s' transitions into the unconfined domain '%s'",
+				i+1, policy.Subject, target))
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package compiler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cici0602/pml-to-selinux/models"
+)
+
+func TestLintSelection(t *testing.T) {
+	policies := []models.Policy{
+		{Type: "p", Subject: "app_t", Object: "/opt/app/bin/*", Action: "write", Effect: "allow"},
+		{Type: "p", Subject: "app_t", Object: "/opt/app/bin/*", Action: "execute", Effect: "allow"},
+		{Type: "p", Subject: "app_t", Object: "/**", Action: "manage", Effect: "allow"},
+		{Type: "p", Subject: "app_t", Object: "/**", Action: "read", Effect: "deny"},
+	}
+
+	tests := []struct {
+		name     string
+		enable   []string
+		disable  []string
+		lintOnly bool
+		want     []string
+	}{
+		{name: "defaults", want: []string{"(W^X)"}},
+		{name: "disable wx", disable: []string{"wx"}},
+		{name: "enable broad-perms", enable: []string{"broad-perms"}, want: []string{"broad rule", "(W^X)"}},
+		{name: "lint only skips conflicts", enable: []string{"broad-perms"}, lintOnly: true, want: []string{"broad rule", "(W^X)"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analyzer := NewAnalyzer(newTestDecodedPML(policies...))
+			analyzer.SetQuiet(true)
+			for _, name := range tt.enable {
+				if err := analyzer.EnableLint(name); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for _, name := range tt.disable {
+				if err := analyzer.DisableLint(name); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			run := analyzer.Analyze
+			if tt.lintOnly {
+				run = analyzer.Lint
+			}
+			if err := run(); err != nil {
+				t.Fatalf("analysis error = %v", err)
+			}
+
+			var got []string
+			for _, warning := range analyzer.GetWarnings() {
+				if strings.Contains(warning, "conflict") || strings.Contains(warning, "dropped") {
+					if tt.lintOnly {
+						t.Errorf("lint-only run reported %q", warning)
+					}
+					continue
+				}
+				got = append(got, warning)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("warnings = %v, want %d matching %v", got, len(tt.want), tt.want)
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(got[i], want) {
+					t.Errorf("warning %d = %q, want containing %q", i, got[i], want)
+				}
+			}
+		})
+	}
+
+	if err := NewAnalyzer(newTestDecodedPML()).DisableLint("nope"); err == nil || !strings.Contains(err.Error(), "available: broad-perms, exec-no-trans, root-wildcard, unconfined-trans, unknown-class, world-writable, wx") {
+		t.Errorf("expected unknown lint error, got %v", err)
+	}
+}
+
+func TestLintUnknownClass(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy models.Policy
+		custom bool
+		want   bool
+	}{
+		{name: "inferred class", policy: models.Policy{Subject: "app_t", Object: "/opt/app/*", Action: "read", Effect: "allow"}},
+		{name: "mapped class", policy: models.Policy{Subject: "app_t", Object: "/opt/app::dir", Action: "read", Effect: "allow"}},
+		{name: "standard class", policy: models.Policy{Subject: "app_t", Object: "/opt/app::netlink_route_socket", Action: "read", Effect: "allow"}},
+		{name: "unknown class", policy: models.Policy{Subject: "app_t", Object: "/opt/app::weirdclass", Action: "read", Effect: "allow"}, want: true},
+		{name: "class map", policy: models.Policy{Subject: "app_t", Object: "/opt/app::weirdclass", Action: "read", Effect: "allow"}, custom: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoded := newTestDecodedPML(tt.policy)
+			decoded.Policies[0].CustomClass = tt.custom
+			analyzer := NewAnalyzer(decoded)
+			analyzer.SetQuiet(true)
+			if err := analyzer.Lint(); err != nil {
+				t.Fatalf("Lint() error = %v", err)
+			}
+
+			got := false
+			for _, warning := range analyzer.GetWarnings() {
+				if strings.Contains(warning, "unknown object class 'weirdclass'") {
+					got = true
+				}
+			}
+			if got != tt.want {
+				t.Errorf("unknown class warning = %v, want %v (warnings: %v)", got, tt.want, analyzer.GetWarnings())
+			}
+		})
+	}
+}
+
+func TestLintExecuteNoTransition(t *testing.T) {
+	transition := models.Policy{Type: "p2", Subject: "app_t", Object: "/usr/bin/helper::process", Action: "transition", Effect: "helper_t"}
+
+	tests := []struct {
+		name   string
+		policy models.Policy
+		want   bool
+	}{
+		{name: "execute in place", policy: models.Policy{Type: "p", Subject: "app_t", Object: "/usr/bin/helper", Action: "execute", Effect: "allow"}, want: true},
+		{name: "exec_transition", policy: models.Policy{Type: "p", Subject: "app_t", Object: "/usr/bin/helper", Action: "exec_transition", Effect: "allow"}},
+		{name: "other domain", policy: models.Policy{Type: "p", Subject: "other_t", Object: "/usr/bin/helper", Action: "execute", Effect: "allow"}},
+		{name: "other binary", policy: models.Policy{Type: "p", Subject: "app_t", Object: "/usr/bin/tool", Action: "execute", Effect: "allow"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analyzer := NewAnalyzer(newTestDecodedPML(transition, tt.policy))
+			analyzer.SetQuiet(true)
+			if err := analyzer.Lint(); err != nil {
+				t.Fatalf("Lint() error = %v", err)
+			}
+
+			got := false
+			for _, warning := range analyzer.GetWarnings() {
+				if strings.Contains(warning, "policy rule 2: subject 'app_t' can run '/usr/bin/helper' without transitioning") {
+					got = true
+				}
+			}
+			if got != tt.want {
+				t.Errorf("execute_no_trans warning = %v, want %v (warnings: %v)", got, tt.want, analyzer.GetWarnings())
+			}
+		})
+	}
+}
+
+func TestStyleLints(t *testing.T) {
+	tests := []struct {
+		lint   string
+		policy models.Policy
+		want   string
+	}{
+		{lint: "root-wildcard", policy: models.Policy{Type: "p", Subject: "app_t", Object: "/*", Action: "read", Effect: "allow"}, want: "matches every path below the root"},
+		{lint: "root-wildcard", policy: models.Policy{Type: "p", Subject: "app_t", Object: "/srv/*", Action: "read", Effect: "allow"}},
+		{lint: "world-writable", policy: models.Policy{Type: "p", Subject: "app_t", Object: "tmp_t", Action: "write", Effect: "allow"}, want: "can write the shared type 'tmp_t'"},
+		{lint: "world-writable", policy: models.Policy{Type: "p", Subject: "app_t", Object: "etc_t", Action: "read", Effect: "allow"}},
+		{lint: "unconfined-trans", policy: models.Policy{Type: "p", Subject: "app_t", Object: "unconfined_t", Action: "transition", Effect: "allow"}, want: "transitions into the unconfined domain 'unconfined_t'"},
+		{lint: "unconfined-trans", policy: models.Policy{Type: "p", Subject: "app_t", Object: "app_helper_t", Action: "transition", Effect: "allow"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.lint+" "+tt.policy.Object+" "+tt.policy.Action, func(t *testing.T) {
+			analyzer := NewAnalyzer(newTestDecodedPML(tt.policy))
+			analyzer.SetQuiet(true)
+			for _, name := range LintNames() {
+				if name != tt.lint {
+					analyzer.DisableLint(name)
+				}
+			}
+			if err := analyzer.EnableLint(tt.lint); err != nil {
+				t.Fatal(err)
+			}
+			analyzer.runLints()
+
+			warnings := analyzer.GetWarnings()
+			if tt.want == "" {
+				if len(warnings) != 0 {
+					t.Errorf("warnings = %v, want none", warnings)
+				}
+				return
+			}
+			if len(warnings) != 1 || !strings.Contains(warnings[0], tt.want) {
+				t.Errorf("warnings = %v, want one containing %q", warnings, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package compiler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Manifest records the artifacts of a compilation and the inputs they were generated from
+type Manifest struct {
+	Tool    string         `json:"tool"`
+	Version string         `json:"version"`
+	Inputs  []ManifestFile `json:"inputs"`
+	Outputs []ManifestFile `json:"outputs"`
+}
+
+// ManifestFile is a file with its SHA-256 digest and size in bytes
+type ManifestFile struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+}
+
+// NewManifest creates an empty manifest for the given tool version
+func NewManifest(version string) *Manifest {
+	return &Manifest{
+		Tool:    "pml2selinux",
+		Version: version,
+		Inputs:  make([]ManifestFile, 0),
+		Outputs: make([]ManifestFile, 0),
+	}
+}
+
+// AddInput hashes an input file and records it
+func (m *Manifest) AddInput(path string) error {
+	file, err := hashManifestFile(path)
+	if err != nil {
+		return err
+	}
+	m.Inputs = append(m.Inputs, file)
+	return nil
+}
+
+// AddOutput hashes a generated file and records it
+func (m *Manifest) AddOutput(path string) error {
+	file, err := hashManifestFile(path)
+	if err != nil {
+		return err
+	}
+	m.Outputs = append(m.Outputs, file)
+	return nil
+}
+
+// Write writes the manifest as indented JSON
+func (m *Manifest) Write(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+// hashManifestFile reads a file and returns its manifest entry
+func hashManifestFile(path string) (ManifestFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ManifestFile{}, fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+	sum := sha256.Sum256(data)
+	return ManifestFile{
+		Path:   path,
+		SHA256: hex.EncodeToString(sum[:]),
+		Size:   int64(len(data)),
+	}, nil
+}
@@ -0,0 +1,56 @@
+package compiler
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestManifest(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "policy.csv")
+	output := filepath.Join(dir, "app.te")
+	if err := os.WriteFile(input, []byte("p, app_t, /opt/app, read, allow\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(output, []byte("abc"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	manifest := NewManifest("1.2.3")
+	if err := manifest.AddInput(input); err != nil {
+		t.Fatalf("AddInput() error = %v", err)
+	}
+	if err := manifest.AddOutput(output); err != nil {
+		t.Fatalf("AddOutput() error = %v", err)
+	}
+	if err := manifest.AddOutput(filepath.Join(dir, "missing.fc")); err == nil {
+		t.Error("expected an error hashing a missing file")
+	}
+
+	manifestPath := filepath.Join(dir, "manifest.json")
+	if err := manifest.Write(manifestPath); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Manifest
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("manifest is not valid JSON: %v", err)
+	}
+
+	if decoded.Version != "1.2.3" || len(decoded.Inputs) != 1 || len(decoded.Outputs) != 1 {
+		t.Fatalf("manifest = %+v", decoded)
+	}
+	want := ManifestFile{
+		Path:   output,
+		SHA256: "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+		Size:   3,
+	}
+	if decoded.Outputs[0] != want {
+		t.Errorf("output = %+v, want %+v", decoded.Outputs[0], want)
+	}
+}
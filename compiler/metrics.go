@@ -0,0 +1,51 @@
+package compiler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cici0602/pml-to-selinux/models"
+)
+
+// metric describes a single gauge in the Prometheus text exposition format
+type metric struct {
+	name  string
+	help  string
+	value float64
+}
+
+// RenderPrometheusMetrics renders analysis and complexity metrics in the Prometheus
+// text exposition format, suitable for the node-exporter textfile collector
+func RenderPrometheusMetrics(moduleName string, stats *AnalysisStats, complexity ComplexityAnalysis) string {
+	metrics := []metric{
+		{"pml_policies", "Number of PML policy rules", float64(stats.TotalPolicies)},
+		{"pml_allow_rules", "Number of PML allow rules", float64(stats.AllowRules)},
+		{"pml_conflicts", "Number of conflicting PML rules", float64(stats.Conflicts)},
+		{"selinux_rules", "Number of generated SELinux allow rules", float64(complexity.TotalRules)},
+		{"selinux_types", "Number of generated SELinux types", float64(complexity.TotalTypes)},
+		{"selinux_complexity_score", "Policy complexity score (rules + types*2)", float64(complexity.ComplexityScore)},
+	}
+
+	var builder strings.Builder
+	for _, m := range metrics {
+		builder.WriteString(fmt.Sprintf("# HELP %s %s\n", m.name, m.help))
+		builder.WriteString(fmt.Sprintf("# TYPE %s gauge\n", m.name))
+		builder.WriteString(fmt.Sprintf("%s{module=%q} %g\n", m.name, moduleName, m.value))
+	}
+
+	return builder.String()
+}
+
+// RenderModuleSummary renders the one-line size summary printed after a compile:
+// "Module httpd: 24 types, 58 rules, 12 file contexts, 3 transitions". Rules
+// count every access vector rule, whether conditional, optional, auditallow,
+// dontaudit, neverallow or allowxperm; transitions include named ones.
+func RenderModuleSummary(policy *models.SELinuxPolicy) string {
+	rules := len(policy.Rules) + len(policy.CondRules) + len(policy.OptionalRules) +
+		len(policy.AuditRules) + len(policy.DontauditRules) + len(policy.NeverallowRules) +
+		len(policy.XpermRules)
+	transitions := len(policy.Transitions) + len(policy.NamedTransitions)
+
+	return fmt.Sprintf("Module %s: %d types, %d rules, %d file contexts, %d transitions",
+		policy.ModuleName, len(policy.Types), rules, len(policy.FileContexts), transitions)
+}
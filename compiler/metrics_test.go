@@ -0,0 +1,52 @@
+package compiler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cici0602/pml-to-selinux/models"
+)
+
+func TestRenderPrometheusMetrics(t *testing.T) {
+	stats := &AnalysisStats{TotalPolicies: 11, AllowRules: 10, Conflicts: 1}
+	complexity := ComplexityAnalysis{TotalRules: 6, TotalTypes: 5, ComplexityScore: 16}
+
+	result := RenderPrometheusMetrics("myweb", stats, complexity)
+
+	expected := []string{
+		"# TYPE pml_policies gauge\n",
+		`pml_policies{module="myweb"} 11`,
+		`pml_allow_rules{module="myweb"} 10`,
+		`pml_conflicts{module="myweb"} 1`,
+		`selinux_rules{module="myweb"} 6`,
+		`selinux_types{module="myweb"} 5`,
+		`selinux_complexity_score{module="myweb"} 16`,
+	}
+	for _, want := range expected {
+		if !strings.Contains(result, want) {
+			t.Errorf("missing %q in metrics output:\n%s", want, result)
+		}
+	}
+}
+
+func TestRenderModuleSummary(t *testing.T) {
+	rule := models.AllowRule{SourceType: "httpd_t", TargetType: "httpd_data_t", Class: "file", Permissions: []string{"read"}}
+	policy := &models.SELinuxPolicy{
+		ModuleName:       "httpd",
+		Types:            []models.TypeDeclaration{{TypeName: "httpd_t"}, {TypeName: "httpd_data_t"}},
+		Rules:            []models.AllowRule{rule},
+		CondRules:        []models.AllowRule{rule},
+		OptionalRules:    []models.AllowRule{rule},
+		AuditRules:       []models.AllowRule{rule},
+		DontauditRules:   []models.AllowRule{rule},
+		NeverallowRules:  []models.NeverallowRule{{SourceType: "httpd_t", TargetType: "shadow_t", Class: "file", Permissions: []string{"read"}}},
+		FileContexts:     []models.FileContext{{PathPattern: "/srv/httpd(/.*)?", SELinuxType: "httpd_data_t"}},
+		Transitions:      []models.TypeTransition{{SourceType: "httpd_t", TargetType: "tmp_t", Class: "file", NewType: "httpd_tmp_t"}},
+		NamedTransitions: []models.NamedTransition{{SourceType: "httpd_t", TargetType: "etc_t", Class: "file", NewType: "httpd_conf_t", Filename: "httpd.conf"}},
+	}
+
+	want := "Module httpd: 2 types, 6 rules, 1 file contexts, 2 transitions"
+	if got := RenderModuleSummary(policy); got != want {
+		t.Errorf("RenderModuleSummary() = %q, want %q", got, want)
+	}
+}
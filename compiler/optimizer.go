@@ -1,23 +1,59 @@
 package compiler
 
 import (
+	"fmt"
+	"io"
+	"os"
 	"sort"
+	"strings"
 
+	"github.com/cici0602/pml-to-selinux/mapping"
 	"github.com/cici0602/pml-to-selinux/models"
 )
 
 // Optimizer handles optimization of SELinux policies
 type Optimizer struct {
 	policy *models.SELinuxPolicy
+
+	// collapseClasses merges rules that differ only in class into one class-set rule
+	collapseClasses bool
+
+	// skipContexts leaves file contexts in their original order, duplicates included
+	skipContexts bool
+
+	// duplicateContextsRemoved counts file contexts dropped because an earlier
+	// one has the same pattern, file type and context
+	duplicateContextsRemoved int
+
+	// out receives warnings, os.Stdout by default
+	out io.Writer
 }
 
 // NewOptimizer creates a new Optimizer instance
 func NewOptimizer(policy *models.SELinuxPolicy) *Optimizer {
 	return &Optimizer{
 		policy: policy,
+		out:    os.Stdout,
 	}
 }
 
+// SetOutput sets where warnings are written, os.Stdout by default
+func (o *Optimizer) SetOutput(w io.Writer) {
+	o.out = w
+}
+
+// SetOptimizeContexts controls whether file contexts are deduplicated and sorted.
+// Disabling it keeps the source ordering for a faithful round-trip while rules are still optimized.
+func (o *Optimizer) SetOptimizeContexts(enabled bool) {
+	o.skipContexts = !enabled
+}
+
+// SetCollapseClasses controls whether rules sharing source, target and permissions
+// are collapsed into a single rule with a class set, e.g. { file lnk_file }
+func (o *Optimizer) SetCollapseClasses(enabled bool) {
+	o.collapseClasses = enabled
+}
+
 // Optimize optimizes the policy by merging rules, removing duplicates, etc.
 func (o *Optimizer) Optimize() error {
 	// Merge allow rules with same source, target, and class
@@ -26,14 +62,25 @@ func (o *Optimizer) Optimize() error {
 	// Remove duplicate types
 	o.deduplicateTypes()
 
-	// Remove duplicate file contexts
-	o.deduplicateFileContexts()
+	if !o.skipContexts {
+		// Remove duplicate file contexts
+		o.deduplicateFileContexts()
+	}
+
+	// Merge neverallow rules with same source, target, and class
+	o.deduplicateDenyRules()
 
-	// Deny rules removed in simplified version
+	// Merge contiguous ports with the same protocol and type into ranges
+	o.mergePortBindings()
 
 	// Remove redundant rules (covered by more general rules)
 	o.removeRedundantRules()
 
+	// Collapse rules that differ only in class
+	if o.collapseClasses {
+		o.collapseClassSets()
+	}
+
 	// Remove unused types
 	o.removeUnusedTypes()
 
@@ -55,6 +102,7 @@ func (o *Optimizer) mergeAllowRules() {
 		if existing, ok := ruleMap[key]; ok {
 			// Merge permissions
 			existing.Permissions = append(existing.Permissions, rule.Permissions...)
+			existing.Rationale = uniqueStringSlice(append(existing.Rationale, rule.Rationale...))
 			// Keep the first original object reference
 		} else {
 			// Create a copy of the rule
@@ -87,6 +135,49 @@ func (o *Optimizer) mergeAllowRules() {
 	o.policy.Rules = merged
 }
 
+// collapseClassSets merges rules with the same source, target and permission set
+// into one rule covering all their classes
+func (o *Optimizer) collapseClassSets() {
+	if len(o.policy.Rules) == 0 {
+		return
+	}
+
+	groups := make(map[string]*models.AllowRule)
+	order := make([]string, 0)
+
+	for _, rule := range o.policy.Rules {
+		perms := uniqueStringSlice(rule.Permissions)
+		sort.Strings(perms)
+		key := rule.SourceType + "|" + rule.TargetType + "|" + strings.Join(perms, " ")
+
+		if existing, ok := groups[key]; ok {
+			existing.Classes = append(existing.Classes, rule.AllClasses()...)
+			existing.Rationale = uniqueStringSlice(append(existing.Rationale, rule.Rationale...))
+			continue
+		}
+
+		ruleCopy := rule
+		ruleCopy.Permissions = perms
+		ruleCopy.Classes = append([]string{}, rule.AllClasses()...)
+		groups[key] = &ruleCopy
+		order = append(order, key)
+	}
+
+	collapsed := make([]models.AllowRule, 0, len(groups))
+	for _, key := range order {
+		rule := groups[key]
+		rule.Classes = uniqueStringSlice(rule.Classes)
+		sort.Strings(rule.Classes)
+		rule.Class = rule.Classes[0]
+		if len(rule.Classes) == 1 {
+			rule.Classes = nil
+		}
+		collapsed = append(collapsed, *rule)
+	}
+
+	o.policy.Rules = collapsed
+}
+
 // deduplicateTypes removes duplicate type declarations
 func (o *Optimizer) deduplicateTypes() {
 	if len(o.policy.Types) == 0 {
@@ -121,41 +212,121 @@ func (o *Optimizer) deduplicateTypes() {
 	o.policy.Types = deduplicated
 }
 
-// deduplicateFileContexts removes duplicate file context definitions
+// deduplicateFileContexts removes file contexts identical to an earlier one in
+// pattern, file type and context. File types are compared as rendered, so "",
+// "regular file" and "--" are alike. Entries labeling the same pattern and file
+// type differently are both kept, with a warning, since neither is a duplicate.
 func (o *Optimizer) deduplicateFileContexts() {
 	if len(o.policy.FileContexts) == 0 {
 		return
 	}
 
-	contextMap := make(map[string]models.FileContext)
+	seen := make(map[string]bool)
+	labels := make(map[string]models.FileContext)
+	deduplicated := make([]models.FileContext, 0, len(o.policy.FileContexts))
 
 	for _, fc := range o.policy.FileContexts {
-		key := fc.PathPattern + "|" + fc.FileType
+		target := fc.PathPattern + "|" + mapping.FileContextSpecifier(fc.FileType)
+		key := target + "|" + fc.SELinuxType + "|" + fc.Level()
 
-		if _, ok := contextMap[key]; !ok {
-			contextMap[key] = fc
-		}
 		// If duplicate, keep the first one
-	}
+		if seen[key] {
+			o.duplicateContextsRemoved++
+			continue
+		}
+		seen[key] = true
 
-	// Convert map back to slice
-	deduplicated := make([]models.FileContext, 0, len(contextMap))
-	for _, fc := range contextMap {
+		if first, ok := labels[target]; ok {
+			fmt.Fprintf(o.out, "Warning: file context '%s' %s is labeled both %s:%s and %s:%s\n",
+				fc.PathPattern, mapping.FileContextSpecifier(fc.FileType),
+				first.SELinuxType, first.Level(), fc.SELinuxType, fc.Level())
+		} else {
+			labels[target] = fc
+		}
 		deduplicated = append(deduplicated, fc)
 	}
 
 	// Sort file contexts for consistent output
-	sort.Slice(deduplicated, func(i, j int) bool {
+	sort.SliceStable(deduplicated, func(i, j int) bool {
 		return deduplicated[i].PathPattern < deduplicated[j].PathPattern
 	})
 
 	o.policy.FileContexts = deduplicated
 }
 
-// deduplicateDenyRules - Removed in simplified version
-// Deny rules are not supported in MVP
+// DuplicateContextsRemoved returns the number of duplicate file contexts
+// removed during the last Optimize call
+func (o *Optimizer) DuplicateContextsRemoved() int {
+	return o.duplicateContextsRemoved
+}
+
+// deduplicateDenyRules merges neverallow rules with the same source, target, and class
 func (o *Optimizer) deduplicateDenyRules() {
-	// No-op: deny rules not supported
+	if len(o.policy.NeverallowRules) == 0 {
+		return
+	}
+
+	ruleMap := make(map[string]*models.NeverallowRule)
+	order := make([]string, 0)
+	for _, rule := range o.policy.NeverallowRules {
+		key := rule.SourceType + "|" + rule.TargetType + "|" + rule.Class
+		if existing, ok := ruleMap[key]; ok {
+			existing.Permissions = append(existing.Permissions, rule.Permissions...)
+			continue
+		}
+		ruleCopy := rule
+		ruleCopy.Permissions = append([]string(nil), rule.Permissions...)
+		ruleMap[key] = &ruleCopy
+		order = append(order, key)
+	}
+
+	merged := make([]models.NeverallowRule, 0, len(order))
+	for _, key := range order {
+		rule := ruleMap[key]
+		rule.Permissions = uniqueStringSlice(rule.Permissions)
+		sort.Strings(rule.Permissions)
+		merged = append(merged, *rule)
+	}
+	o.policy.NeverallowRules = merged
+}
+
+// mergePortBindings sorts port bindings by protocol then port and collapses
+// bindings of the same protocol and type whose ports are contiguous into a
+// single range. Ports with a gap between them, or labeled with different
+// types, are kept apart.
+func (o *Optimizer) mergePortBindings() {
+	if len(o.policy.PortBindings) < 2 {
+		return
+	}
+
+	bindings := append([]models.PortBinding(nil), o.policy.PortBindings...)
+	sort.SliceStable(bindings, func(i, j int) bool {
+		if bindings[i].Protocol != bindings[j].Protocol {
+			return bindings[i].Protocol < bindings[j].Protocol
+		}
+		return bindings[i].Port < bindings[j].Port
+	})
+
+	// lastPort returns the last port a binding covers
+	lastPort := func(b models.PortBinding) int {
+		if b.PortEnd != 0 {
+			return b.PortEnd
+		}
+		return b.Port
+	}
+
+	merged := make([]models.PortBinding, 0, len(bindings))
+	latest := make(map[string]int) // "protocol|type" -> index of its latest binding in merged
+	for _, binding := range bindings {
+		key := binding.Protocol + "|" + binding.PortType
+		if i, ok := latest[key]; ok && lastPort(merged[i])+1 == binding.Port {
+			merged[i].PortEnd = lastPort(binding)
+			continue
+		}
+		latest[key] = len(merged)
+		merged = append(merged, binding)
+	}
+	o.policy.PortBindings = merged
 }
 
 // uniqueStringSlice removes duplicates from a string slice
@@ -183,6 +354,9 @@ type OptimizationStats struct {
 	OptimizedContextCount  int
 	OriginalDenyRuleCount  int
 	OptimizedDenyRuleCount int
+	DuplicateContextsCount int
+	OriginalPortCount      int
+	OptimizedPortCount     int
 }
 
 // GetStatistics calculates optimization statistics
@@ -194,8 +368,11 @@ func (o *Optimizer) GetStatistics(originalPolicy *models.SELinuxPolicy) Optimiza
 		OptimizedTypeCount:     len(o.policy.Types),
 		OriginalContextCount:   len(originalPolicy.FileContexts),
 		OptimizedContextCount:  len(o.policy.FileContexts),
-		OriginalDenyRuleCount:  0, // Deny rules not supported in MVP
-		OptimizedDenyRuleCount: 0, // Deny rules not supported in MVP
+		OriginalDenyRuleCount:  len(originalPolicy.NeverallowRules),
+		OptimizedDenyRuleCount: len(o.policy.NeverallowRules),
+		DuplicateContextsCount: o.duplicateContextsRemoved,
+		OriginalPortCount:      len(originalPolicy.PortBindings),
+		OptimizedPortCount:     len(o.policy.PortBindings),
 	}
 }
 
@@ -250,21 +427,73 @@ func (o *Optimizer) removeUnusedTypes() {
 		return
 	}
 
-	// Collect all types used in rules, transitions, and file contexts
+	// Collect all types used in rules, transitions, and labeling statements
 	usedTypes := make(map[string]bool)
 
 	for _, rule := range o.policy.Rules {
 		usedTypes[rule.SourceType] = true
 		usedTypes[rule.TargetType] = true
 	}
+	for _, rule := range o.policy.CondRules {
+		usedTypes[rule.SourceType] = true
+		usedTypes[rule.TargetType] = true
+	}
+	for _, rule := range o.policy.OptionalRules {
+		usedTypes[rule.SourceType] = true
+	}
 
-	// Deny rules removed in simplified version
+	for _, rule := range o.policy.NeverallowRules {
+		usedTypes[rule.SourceType] = true
+		usedTypes[rule.TargetType] = true
+	}
+	for _, rule := range o.policy.DontauditRules {
+		usedTypes[rule.SourceType] = true
+		usedTypes[rule.TargetType] = true
+	}
 
 	for _, trans := range o.policy.Transitions {
 		usedTypes[trans.SourceType] = true
 		usedTypes[trans.TargetType] = true
 		usedTypes[trans.NewType] = true
 	}
+	for _, trans := range o.policy.NamedTransitions {
+		usedTypes[trans.SourceType] = true
+		usedTypes[trans.TargetType] = true
+		usedTypes[trans.NewType] = true
+	}
+
+	// Types the policy labels objects with are used even without rules
+	for _, genfs := range o.policy.GenfsContexts {
+		usedTypes[genfs.SELinuxType] = true
+	}
+	for _, binding := range o.policy.PortBindings {
+		usedTypes[binding.PortType] = true
+	}
+	for _, binding := range o.policy.SemanagePorts {
+		usedTypes[binding.PortType] = true
+	}
+	for _, node := range o.policy.Nodecons {
+		usedTypes[node.SELinuxType] = true
+	}
+	for _, netif := range o.policy.Netifcons {
+		usedTypes[netif.SELinuxType] = true
+	}
+
+	for _, sid := range o.policy.InitialSIDs {
+		usedTypes[sid.SELinuxType] = true
+	}
+
+	// Home directory content types are labeled through userdom_user_home_content
+	for _, typeName := range o.policy.UserHomeContent {
+		usedTypes[typeName] = true
+	}
+
+	// Types a role is authorized for, e.g. g3 user domains, are declared for the role statement
+	for _, roleType := range o.policy.RoleTypes {
+		for _, typeName := range roleType.Types {
+			usedTypes[typeName] = true
+		}
+	}
 
 	// Keep only types that are used
 	usedTypesList := make([]models.TypeDeclaration, 0)
@@ -295,12 +524,12 @@ func isSubset(subset, superset []string) bool {
 
 // AnalyzeComplexity analyzes the complexity of the policy
 type ComplexityAnalysis struct {
-	TotalRules          int
-	TotalTypes          int
-	TotalBooleans       int
-	AverageRulesPerType float64
-	MaxRulesPerType     int
-	ComplexityScore     int // Simple heuristic: total_rules + total_types*2
+	TotalRules          int     `json:"total_rules"`
+	TotalTypes          int     `json:"total_types"`
+	TotalBooleans       int     `json:"total_booleans"`
+	AverageRulesPerType float64 `json:"average_rules_per_type"`
+	MaxRulesPerType     int     `json:"max_rules_per_type"`
+	ComplexityScore     int     `json:"complexity_score"` // Simple heuristic: total_rules + total_types*2
 }
 
 // AnalyzeComplexity performs complexity analysis on the policy
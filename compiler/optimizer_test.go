@@ -0,0 +1,259 @@
+package compiler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cici0602/pml-to-selinux/models"
+)
+
+func TestOptimizer_DuplicateContexts(t *testing.T) {
+	policy := models.NewSELinuxPolicy("test", "1.0.0")
+	policy.FileContexts = []models.FileContext{
+		{PathPattern: "/var/(log|tmp)(/.*)?", FileType: "", SELinuxType: "test_var_t"},
+		{PathPattern: "/var/(log|tmp)(/.*)?", FileType: "--", SELinuxType: "test_var_t"},
+		{PathPattern: "/var/(log|tmp)(/.*)?", FileType: "regular file", SELinuxType: "test_var_t"},
+		{PathPattern: "/etc/test(/.*)?", FileType: "-d", SELinuxType: "test_etc_t"},
+		{PathPattern: "/etc/test(/.*)?", FileType: "directory", SELinuxType: "test_etc_t"},
+		{PathPattern: "/etc/test(/.*)?", FileType: "all files", SELinuxType: "test_etc_t"},
+	}
+
+	optimizer := NewOptimizer(policy)
+	if err := optimizer.Optimize(); err != nil {
+		t.Fatalf("Optimize() error = %v", err)
+	}
+
+	// "", "--" and "regular file" render alike, as do "-d" and "directory"
+	if len(policy.FileContexts) != 3 {
+		t.Errorf("expected 3 file contexts, got %+v", policy.FileContexts)
+	}
+	if got := optimizer.DuplicateContextsRemoved(); got != 3 {
+		t.Errorf("DuplicateContextsRemoved() = %d, want 3", got)
+	}
+	if stats := optimizer.GetStatistics(policy); stats.DuplicateContextsCount != 3 {
+		t.Errorf("DuplicateContextsCount = %d, want 3", stats.DuplicateContextsCount)
+	}
+}
+
+func TestOptimizer_KeepsDistinctContextTypes(t *testing.T) {
+	policy := models.NewSELinuxPolicy("test", "1.0.0")
+	policy.FileContexts = []models.FileContext{
+		{PathPattern: "/srv/app(/.*)?", FileType: "--", SELinuxType: "test_a_t"},
+		{PathPattern: "/srv/app(/.*)?", FileType: "--", SELinuxType: "test_b_t"},
+		{PathPattern: "/srv/app(/.*)?", FileType: "--", SELinuxType: "test_a_t",
+			Range: &models.SecurityRange{Low: models.SecurityLevel{Sensitivity: 2}, High: models.SecurityLevel{Sensitivity: 2}}},
+	}
+
+	var out strings.Builder
+	optimizer := NewOptimizer(policy)
+	optimizer.SetOutput(&out)
+	if err := optimizer.Optimize(); err != nil {
+		t.Fatalf("Optimize() error = %v", err)
+	}
+
+	if len(policy.FileContexts) != 3 {
+		t.Errorf("lines with different contexts must not be treated as duplicates, got %+v", policy.FileContexts)
+	}
+	if optimizer.DuplicateContextsRemoved() != 0 {
+		t.Errorf("expected no duplicates removed, got %d", optimizer.DuplicateContextsRemoved())
+	}
+	if !strings.Contains(out.String(), "Warning: file context '/srv/app(/.*)?' -- is labeled both test_a_t:s0 and test_b_t:s0") {
+		t.Errorf("expected a warning about the conflicting labels, got %q", out.String())
+	}
+}
+
+func TestOptimizer_SkipContexts(t *testing.T) {
+	policy := models.NewSELinuxPolicy("test", "1.0.0")
+	policy.FileContexts = []models.FileContext{
+		{PathPattern: "/var/www(/.*)?", FileType: "--", SELinuxType: "test_www_t"},
+		{PathPattern: "/etc/test(/.*)?", FileType: "--", SELinuxType: "test_etc_t"},
+		{PathPattern: "/var/www(/.*)?", FileType: "--", SELinuxType: "test_www_t"},
+	}
+	policy.Rules = []models.AllowRule{
+		{SourceType: "test_t", TargetType: "test_www_t", Class: "file", Permissions: []string{"read"}},
+		{SourceType: "test_t", TargetType: "test_www_t", Class: "file", Permissions: []string{"open"}},
+	}
+
+	optimizer := NewOptimizer(policy)
+	optimizer.SetOptimizeContexts(false)
+	if err := optimizer.Optimize(); err != nil {
+		t.Fatalf("Optimize() error = %v", err)
+	}
+
+	if len(policy.FileContexts) != 3 {
+		t.Fatalf("expected file contexts to be left intact, got %d", len(policy.FileContexts))
+	}
+	if policy.FileContexts[0].PathPattern != "/var/www(/.*)?" || policy.FileContexts[1].PathPattern != "/etc/test(/.*)?" {
+		t.Error("file context order should be preserved")
+	}
+	if len(policy.Rules) != 1 {
+		t.Errorf("rules should still be merged, got %d", len(policy.Rules))
+	}
+}
+
+func TestOptimizer_CollapseClasses(t *testing.T) {
+	policy := models.NewSELinuxPolicy("test", "1.0.0")
+	policy.Rules = []models.AllowRule{
+		{SourceType: "test_t", TargetType: "test_data_t", Class: "file", Permissions: []string{"read", "getattr"}},
+		{SourceType: "test_t", TargetType: "test_data_t", Class: "lnk_file", Permissions: []string{"getattr", "read"}},
+		{SourceType: "test_t", TargetType: "test_data_t", Class: "dir", Permissions: []string{"search"}},
+	}
+
+	optimizer := NewOptimizer(policy)
+	optimizer.SetCollapseClasses(true)
+	if err := optimizer.Optimize(); err != nil {
+		t.Fatalf("Optimize() error = %v", err)
+	}
+
+	if len(policy.Rules) != 2 {
+		t.Fatalf("expected 2 rules after collapsing, got %d: %+v", len(policy.Rules), policy.Rules)
+	}
+
+	found := false
+	for _, rule := range policy.Rules {
+		if rule.ClassSpec() == "{ file lnk_file }" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected a { file lnk_file } class-set rule, got %+v", policy.Rules)
+	}
+}
+
+func TestOptimizer_MergePortBindings(t *testing.T) {
+	policy := models.NewSELinuxPolicy("test", "1.0.0")
+	for port := 8010; port >= 8000; port-- {
+		policy.PortBindings = append(policy.PortBindings, models.PortBinding{Protocol: "tcp", Port: port, PortType: "test_port_t"})
+	}
+	policy.PortBindings = append(policy.PortBindings,
+		models.PortBinding{Protocol: "tcp", Port: 8012, PortType: "test_port_t"},       // gap after 8010
+		models.PortBinding{Protocol: "tcp", Port: 8011, PortType: "test_admin_port_t"}, // contiguous, other type
+		models.PortBinding{Protocol: "udp", Port: 8011, PortType: "test_port_t"},       // contiguous, other protocol
+		models.PortBinding{Protocol: "tcp", Port: 8013, PortEnd: 8020, PortType: "test_port_t"},
+		models.PortBinding{Protocol: "tcp", Port: 80, PortType: "http_port_t"},
+	)
+	original := &models.SELinuxPolicy{PortBindings: append([]models.PortBinding(nil), policy.PortBindings...)}
+
+	optimizer := NewOptimizer(policy)
+	if err := optimizer.Optimize(); err != nil {
+		t.Fatalf("Optimize() error = %v", err)
+	}
+
+	want := []models.PortBinding{
+		{Protocol: "tcp", Port: 80, PortType: "http_port_t"},
+		{Protocol: "tcp", Port: 8000, PortEnd: 8010, PortType: "test_port_t"},
+		{Protocol: "tcp", Port: 8011, PortType: "test_admin_port_t"},
+		{Protocol: "tcp", Port: 8012, PortEnd: 8020, PortType: "test_port_t"},
+		{Protocol: "udp", Port: 8011, PortType: "test_port_t"},
+	}
+	if len(policy.PortBindings) != len(want) {
+		t.Fatalf("got %d port bindings, want %d: %+v", len(policy.PortBindings), len(want), policy.PortBindings)
+	}
+	for i := range want {
+		if policy.PortBindings[i] != want[i] {
+			t.Errorf("binding %d = %+v, want %+v", i, policy.PortBindings[i], want[i])
+		}
+	}
+
+	stats := optimizer.GetStatistics(original)
+	if stats.OriginalPortCount != 16 || stats.OptimizedPortCount != 5 {
+		t.Errorf("port counts = %d -> %d, want 16 -> 5", stats.OriginalPortCount, stats.OptimizedPortCount)
+	}
+}
+
+func TestOptimizer_KeepsLabelingTypes(t *testing.T) {
+	policy := models.NewSELinuxPolicy("test", "1.0.0")
+	policy.Types = []models.TypeDeclaration{
+		{TypeName: "node_internal_t"}, {TypeName: "netif_internal_t"}, {TypeName: "test_port_t"}, {TypeName: "unused_t"},
+	}
+	policy.Nodecons = []models.Nodecon{{Address: "10.0.0.0", Netmask: "255.0.0.0", SELinuxType: "node_internal_t"}}
+	policy.Netifcons = []models.Netifcon{{Interface: "eth0", SELinuxType: "netif_internal_t"}}
+	policy.PortBindings = []models.PortBinding{{Protocol: "tcp", Port: 9000, PortType: "test_port_t"}}
+
+	if err := NewOptimizer(policy).Optimize(); err != nil {
+		t.Fatalf("Optimize() error = %v", err)
+	}
+	if len(policy.Types) != 3 || policy.GetTypeByName("unused_t") != nil {
+		t.Errorf("expected only the labeling types to remain, got %+v", policy.Types)
+	}
+}
+
+func TestOptimizer_KeepsConditionalTargetTypes(t *testing.T) {
+	decoded := newTestDecodedPML(
+		models.Policy{Type: "p", Subject: "myapp_t", Object: "/srv/myapp/cache/*?cond=myapp_cache", Action: "write", Effect: "allow"},
+	)
+
+	policy, err := NewGenerator(decoded, "myapp").Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if len(policy.CondRules) != 1 {
+		t.Fatalf("CondRules = %+v, want one rule", policy.CondRules)
+	}
+	target := policy.CondRules[0].TargetType
+	if !policy.HasType(target) {
+		t.Fatalf("generator did not declare %s", target)
+	}
+
+	if err := NewOptimizer(policy).Optimize(); err != nil {
+		t.Fatalf("Optimize() error = %v", err)
+	}
+	if !policy.HasType(target) {
+		t.Errorf("type %s used only by a conditional rule was removed, types = %+v", target, policy.Types)
+	}
+}
+
+func TestOptimizer_KeepsRoleTypes(t *testing.T) {
+	decoded := newTestDecodedPML(
+		models.Policy{Type: "p", Subject: "myweb_t", Object: "/var/lib/myweb/data", Action: "read", Effect: "allow"},
+	)
+	decoded.UserDomains = []models.RoleRelation{{Type: "g3", Member: "staff_user", Role: "staff_r"}}
+
+	policy, err := NewGenerator(decoded, "myweb").Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if err := NewOptimizer(policy).Optimize(); err != nil {
+		t.Fatalf("Optimize() error = %v", err)
+	}
+	if !policy.HasType("staff_user_t") {
+		t.Errorf("user domain staff_user_t of role staff_r was removed, types = %+v", policy.Types)
+	}
+}
+
+func TestOptimizer_KeepsInitialSIDTypes(t *testing.T) {
+	decoded := newTestDecodedPML(
+		models.Policy{Type: "p", Subject: "app_t", Object: "/opt/app/*", Action: "read", Effect: "allow"},
+	)
+	decoded.SIDs = []models.SIDDeclaration{{Name: "kernel", Type: "mykernel_t"}}
+
+	generator := NewGenerator(decoded, "app")
+	generator.SetBasePolicy(true)
+	policy, err := generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if err := NewOptimizer(policy).Optimize(); err != nil {
+		t.Fatalf("Optimize() error = %v", err)
+	}
+	if !policy.HasType("mykernel_t") {
+		t.Errorf("type mykernel_t of the kernel sid context was removed, types = %+v", policy.Types)
+	}
+}
+
+func TestOptimizer_KeepsUserHomeContentTypes(t *testing.T) {
+	policy := models.NewSELinuxPolicy("app", "1.0.0")
+	policy.AddType("app_t")
+	policy.AddType("app_home_t")
+	policy.UserHomeContent = []string{"app_home_t"}
+
+	if err := NewOptimizer(policy).Optimize(); err != nil {
+		t.Fatalf("Optimize() error = %v", err)
+	}
+	if !policy.HasType("app_home_t") {
+		t.Errorf("home content type app_home_t was removed, types = %+v", policy.Types)
+	}
+	if policy.HasType("app_t") {
+		t.Errorf("unused type app_t should be removed, types = %+v", policy.Types)
+	}
+}
@@ -2,10 +2,19 @@ package compiler
 
 import (
 	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
+	"github.com/cici0602/pml-to-selinux/mapping"
 	"github.com/cici0602/pml-to-selinux/models"
 )
 
@@ -13,6 +22,11 @@ import (
 type Parser struct {
 	modelPath  string
 	policyPath string
+	policyDir  string // When set, every *.csv, *.json and *.yaml file in it is parsed instead of policyPath
+
+	// classMap maps object prefixes to SELinux classes (e.g., "dbus:" → "dbus"),
+	// consulted before the built-in class inference
+	classMap map[string]string
 }
 
 // ParseError represents a parsing error with location information
@@ -26,6 +40,17 @@ func (e *ParseError) Error() string {
 	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Message)
 }
 
+// sourceError attributes an error on a policy rule to the line the rule was
+// read from, in place of its rule number. Errors that already carry a position
+// and rules not read from a file are returned unchanged.
+func sourceError(policy models.Policy, err error) error {
+	var parseErr *ParseError
+	if policy.File == "" || errors.As(err, &parseErr) {
+		return err
+	}
+	return &ParseError{File: policy.File, Line: policy.Line, Message: findingMessage(err)}
+}
+
 // NewParser creates a new parser instance
 func NewParser(modelPath, policyPath string) *Parser {
 	return &Parser{
@@ -34,6 +59,100 @@ func NewParser(modelPath, policyPath string) *Parser {
 	}
 }
 
+// SetClassMap sets custom object-prefix to class mappings
+func (p *Parser) SetClassMap(classMap map[string]string) {
+	p.classMap = classMap
+}
+
+// SetPolicyDir parses every *.csv, *.json and *.yaml file in dir, in sorted order,
+// as one policy instead of the single policy file
+func (p *Parser) SetPolicyDir(dir string) {
+	p.policyDir = dir
+}
+
+// LoadClassMap reads object-prefix to class mappings from a file.
+// Each non-comment line holds a prefix and a class separated by whitespace:
+//
+//	dbus:  dbus
+//	key:   key
+func LoadClassMap(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open class map: %w", err)
+	}
+	defer file.Close()
+
+	classMap := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, &ParseError{
+				File:    path,
+				Line:    lineNum,
+				Message: fmt.Sprintf("class mapping expects 2 fields (prefix, class), got %d: %s", len(fields), line),
+			}
+		}
+		classMap[fields[0]] = fields[1]
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading class map: %w", err)
+	}
+
+	return classMap, nil
+}
+
+// LoadClassPermissions reads object classes and their permissions from a file
+// and adds them to the permission table permissions are validated against,
+// extending known classes and defining custom ones. Each non-comment line
+// holds a class followed by its permissions:
+//
+//	dbus     send_msg acquire_svc
+//	service  start stop status
+func LoadClassPermissions(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open class permissions: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return &ParseError{
+				File:    path,
+				Line:    lineNum,
+				Message: fmt.Sprintf("class permissions expect a class and at least one permission: %s", line),
+			}
+		}
+		mapping.AddClassPermissions(fields[0], fields[1:]...)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading class permissions: %w", err)
+	}
+
+	return nil
+}
+
 // Parse parses both model and policy files and returns ParsedPML in standard Casbin format
 func (p *Parser) Parse() (*models.ParsedPML, error) {
 	// Parse model file
@@ -43,15 +162,25 @@ func (p *Parser) Parse() (*models.ParsedPML, error) {
 	}
 
 	// Parse policy file - now returns standard format
-	policies, roles, err := p.parsePolicy()
+	rules, err := p.parsePolicy()
 	if err != nil {
 		return nil, err
 	}
 
 	return &models.ParsedPML{
 		Model:    model,
-		Policies: policies,
-		Roles:    roles,
+		Policies: rules.policies,
+		Roles:    rules.roles,
+		Genfs:    rules.genfs,
+		SIDs:     rules.sids,
+		Nodes:    rules.nodes,
+		Netifs:   rules.netifs,
+
+		NamedTransitions: rules.namedTransitions,
+		ValidateTrans:    rules.validateTrans,
+		DefaultTypes:     rules.defaultTypes,
+		DefaultRanges:    rules.defaultRanges,
+		InterfaceCalls:   rules.interfaceCalls,
 	}, nil
 }
 
@@ -69,7 +198,7 @@ func (p *Parser) Decode(pml *models.ParsedPML) (*models.DecodedPML, error) {
 	for _, policy := range pml.Policies {
 		decodedPolicy, err := p.decodePolicy(&policy)
 		if err != nil {
-			return nil, err
+			return nil, sourceError(policy, err)
 		}
 
 		decoded.Policies = append(decoded.Policies, *decodedPolicy)
@@ -88,9 +217,28 @@ func (p *Parser) Decode(pml *models.ParsedPML) (*models.DecodedPML, error) {
 		} else if role.Type == "g2" {
 			// Type attribute
 			decoded.TypeAttributes = append(decoded.TypeAttributes, role)
+		} else if role.Type == "role" {
+			// Role declaration
+			decoded.RoleDeclarations = append(decoded.RoleDeclarations, role.Role)
+		} else if role.Type == "ra" {
+			// Permitted role change
+			decoded.RoleAllows = append(decoded.RoleAllows, role)
+		} else if role.Type == "g3" {
+			// User domain reachable from a role
+			decoded.UserDomains = append(decoded.UserDomains, role)
 		}
 	}
 
+	decoded.Genfs = append(decoded.Genfs, pml.Genfs...)
+	decoded.SIDs = append(decoded.SIDs, pml.SIDs...)
+	decoded.Nodes = append(decoded.Nodes, pml.Nodes...)
+	decoded.Netifs = append(decoded.Netifs, pml.Netifs...)
+	decoded.NamedTransitions = append(decoded.NamedTransitions, pml.NamedTransitions...)
+	decoded.ValidateTrans = append(decoded.ValidateTrans, pml.ValidateTrans...)
+	decoded.DefaultTypes = append(decoded.DefaultTypes, pml.DefaultTypes...)
+	decoded.DefaultRanges = append(decoded.DefaultRanges, pml.DefaultRanges...)
+	decoded.InterfaceCalls = append(decoded.InterfaceCalls, pml.InterfaceCalls...)
+
 	return decoded, nil
 }
 
@@ -101,22 +249,89 @@ func (p *Parser) decodePolicy(policy *models.Policy) (*models.DecodedPolicy, err
 		Policy: *policy,
 	}
 
-	// Extract class from object if explicitly specified (format: "path::class")
+	// Extract the subject's clearance (format: "domain_t@level=low-high")
+	if subject, annotations, ok := strings.Cut(policy.Subject, "@"); ok {
+		decoded.Subject = subject
+		for _, annotation := range strings.Split(annotations, "@") {
+			key, value, _ := strings.Cut(annotation, "=")
+			if key != "level" || value == "" {
+				return nil, fmt.Errorf("subject '%s': only '@level=' annotations are supported on subjects", policy.Subject)
+			}
+			decoded.SubjectLevel = value
+		}
+	}
+
+	// Extract object annotations (format: "path@level=low-high@audit=true@nofc@xperm=ioctl:0x1234-0x1240")
 	objPath := policy.Object
+	if strings.Contains(objPath, "@") {
+		parts := strings.Split(objPath, "@")
+		objPath = parts[0]
+		decoded.Object = parts[0]
+		for _, annotation := range parts[1:] {
+			if err := applyAnnotation(decoded, annotation); err != nil {
+				return nil, fmt.Errorf("object '%s': %w", policy.Object, err)
+			}
+		}
+	}
+
+	// Extract class from action if explicitly specified (format: "action::class")
+	actionClass := ""
+	if strings.Contains(policy.Action, "::") {
+		parts := strings.SplitN(policy.Action, "::", 2)
+		decoded.Action = parts[0]
+		actionClass = parts[1]
+	}
+
+	// Extract class and security level from the object's "::" suffixes
+	// (format: "path::class", "path::confidential:hr" or "path::class::s2")
+	objectClass := ""
 	if strings.Contains(objPath, "::") {
-		parts := strings.SplitN(objPath, "::", 2)
+		parts := strings.Split(objPath, "::")
+		objPath = parts[0]
 		decoded.Object = parts[0]
-		decoded.Class = parts[1]
+		for _, suffix := range parts[1:] {
+			if !isLevelSuffix(suffix) {
+				objectClass = suffix
+				continue
+			}
+			if decoded.Level != "" {
+				return nil, fmt.Errorf("object '%s': security level given twice", policy.Object)
+			}
+			decoded.Level = suffix
+		}
+	}
+
+	if objectClass != "" {
+		decoded.Class = objectClass
+
+		// Both sides name a class: they must agree
+		if actionClass != "" && actionClass != decoded.Class {
+			return nil, &ParseError{
+				File: policy.File,
+				Line: policy.Line,
+				Message: fmt.Sprintf("action class '%s' contradicts object class '%s' in rule: %s, %s, %s",
+					actionClass, decoded.Class, policy.Subject, policy.Object, policy.Action),
+			}
+		}
+	} else if actionClass != "" {
+		decoded.Class = actionClass
+	} else if class, ok := p.lookupClassMap(objPath); ok {
+		// Custom class from the class map
+		decoded.Class = class
+		decoded.CustomClass = true
 	} else {
 		// Auto-infer class from object and action
-		decoded.Class = inferClass(objPath, policy.Action)
+		decoded.Class = inferClass(objPath, decoded.Action)
 	}
 
-	// Check if object contains a condition (?cond=)
-	if strings.Contains(decoded.Object, "?cond=") {
-		parts := strings.SplitN(decoded.Object, "?cond=", 2)
-		decoded.Object = parts[0]
-		decoded.Condition = parts[1]
+	// Check if object contains a condition (?cond=, or its alias ?bool=)
+	for _, marker := range []string{"?cond=", "?bool="} {
+		if strings.Contains(decoded.Object, marker) {
+			parts := strings.SplitN(decoded.Object, marker, 2)
+			decoded.Object = parts[0]
+			decoded.Condition = parts[1]
+			break
+		}
 	}
 
 	// Check if this is a type transition (p2 with action="transition")
@@ -133,6 +348,124 @@ func (p *Parser) decodePolicy(policy *models.Policy) (*models.DecodedPolicy, err
 	return decoded, nil
 }
 
+// rawSensitivityPattern matches a raw sensitivity such as "s2"
+var rawSensitivityPattern = regexp.MustCompile(`^s[0-9]+$`)
+
+// isLevelSuffix reports whether an object's "::" suffix is a security level
+// rather than a class: class names never contain ':' or '-', and no class is
+// named like a raw sensitivity or a default level
+func isLevelSuffix(suffix string) bool {
+	return strings.ContainsAny(suffix, ":-") || rawSensitivityPattern.MatchString(suffix) ||
+		mapping.NewLevelMapper().HasLevel(suffix)
+}
+
+// lookupClassMap returns the class of the longest class-map prefix matching the object
+func (p *Parser) lookupClassMap(object string) (string, bool) {
+	class, longest := "", -1
+	for prefix, c := range p.classMap {
+		if strings.HasPrefix(object, prefix) && len(prefix) > longest {
+			class, longest = c, len(prefix)
+		}
+	}
+	return class, longest != -1
+}
+
+// Bounds of CIL module priorities; semodule installs at DefaultPriority unless told otherwise
+const (
+	MinPriority     = 1
+	MaxPriority     = 999
+	DefaultPriority = 400
+)
+
+// optionalModulePattern matches the module names @optional= may depend on
+var optionalModulePattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]*$`)
+
+// interfaceNamePattern matches the names of refpolicy interfaces, which are m4 macro names
+var interfaceNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// netifNamePattern matches Linux network interface names, at most 15 characters
+var netifNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]{0,14}$`)
+
+// applyAnnotation applies a single "key=value" object annotation to the decoded policy
+func applyAnnotation(decoded *models.DecodedPolicy, annotation string) error {
+	// Flag annotations take no value
+	if annotation == "nofc" {
+		decoded.NoFileContext = true
+		return nil
+	}
+
+	key, value, ok := strings.Cut(annotation, "=")
+	if !ok || value == "" {
+		return fmt.Errorf("invalid annotation '@%s', expected '@key=value'", annotation)
+	}
+
+	switch key {
+	case "level":
+		decoded.Level = value
+	case "audit":
+		switch value {
+		case "true":
+			decoded.Audit = true
+		case "false":
+			decoded.Audit = false
+		default:
+			return fmt.Errorf("invalid audit value '%s', must be 'true' or 'false'", value)
+		}
+	case "xperm":
+		// Repeatable: each annotation adds one command or command range
+		operation, commands, _ := strings.Cut(value, ":")
+		if operation != "ioctl" {
+			return fmt.Errorf("unsupported xperm operation '%s', only 'ioctl' is supported", operation)
+		}
+		ioctlRange, err := parseIoctlRange(commands)
+		if err != nil {
+			return err
+		}
+		decoded.IoctlRanges = append(decoded.IoctlRanges, ioctlRange)
+	case "optional":
+		if !optionalModulePattern.MatchString(value) {
+			return fmt.Errorf("invalid optional module '%s', must be a module name", value)
+		}
+		decoded.Optional = value
+	case "priority":
+		priority, err := strconv.Atoi(value)
+		if err != nil || priority < MinPriority || priority > MaxPriority {
+			return fmt.Errorf("invalid priority '%s', must be %d-%d", value, MinPriority, MaxPriority)
+		}
+		decoded.Priority = priority
+	default:
+		return fmt.Errorf("unknown annotation '@%s'", key)
+	}
+
+	return nil
+}
+
+// parseIoctlRange validates an ioctl command ("0x8910") or inclusive range
+// ("0x1234-0x1240") of 16-bit hex values and returns it in lowercase
+func parseIoctlRange(value string) (string, error) {
+	low, high, isRange := strings.Cut(value, "-")
+	if !isRange {
+		high = low
+	}
+
+	var bounds [2]uint64
+	for i, bound := range []string{low, high} {
+		if !strings.HasPrefix(strings.ToLower(bound), "0x") {
+			return "", fmt.Errorf("invalid ioctl command '%s', expected hex such as 0x8910", bound)
+		}
+		n, err := strconv.ParseUint(bound[2:], 16, 16)
+		if err != nil {
+			return "", fmt.Errorf("invalid ioctl command '%s', expected hex between 0x0000 and 0xffff", bound)
+		}
+		bounds[i] = n
+	}
+	if bounds[0] > bounds[1] {
+		return "", fmt.Errorf("invalid ioctl range '%s', low is greater than high", value)
+	}
+
+	return strings.ToLower(value), nil
+}
+
 // inferClass infers the SELinux object class from the object path and action
 // This implements intelligent defaults for common patterns
 func inferClass(object string, action string) string {
@@ -153,6 +486,11 @@ func inferClass(object string, action string) string {
 		return "udp_socket"
 	}
 
+	// Labeled packets (packet:type format, secmark)
+	if strings.HasPrefix(object, "packet:") {
+		return "packet"
+	}
+
 	// Unix socket files (.sock suffix)
 	if strings.HasSuffix(object, ".sock") || strings.Contains(object, ".sock") {
 		// Check action to determine socket type vs sock_file
@@ -218,8 +556,16 @@ func isDirectoryAction(action string) bool {
 	return false
 }
 
-// parseModel parses the PML model configuration file (.conf)
+// parseModel parses the PML model file, a .conf file or its JSON or YAML equivalent
 func (p *Parser) parseModel() (*models.PMLModel, error) {
+	if isJSONFile(p.modelPath) || isYAMLFile(p.modelPath) {
+		return p.parseStructuredModel()
+	}
+	return p.parseConfModel()
+}
+
+// parseConfModel parses the PML model configuration file (.conf)
+func (p *Parser) parseConfModel() (*models.PMLModel, error) {
 	file, err := os.Open(p.modelPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open model file: %w", err)
@@ -326,16 +672,108 @@ func parseDefinitionValue(value string) []string {
 	return result
 }
 
-// parsePolicy parses the CSV policy file in standard Casbin format
-func (p *Parser) parsePolicy() ([]models.Policy, []models.RoleRelation, error) {
-	file, err := os.Open(p.policyPath)
+// parsePolicy parses the policy file, or every policy file in the policy directory,
+// in standard Casbin format
+func (p *Parser) parsePolicy() (*policyRules, error) {
+	files, err := p.policyFiles()
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to open policy file: %w", err)
+		return nil, err
 	}
-	defer file.Close()
 
-	var policies []models.Policy
-	var roles []models.RoleRelation
+	rules := &policyRules{}
+	for _, file := range files {
+		var err error
+		switch {
+		case isJSONFile(file):
+			err = p.parseJSONPolicyFile(file, rules)
+		case isYAMLFile(file):
+			err = p.parseYAMLPolicyFile(file, rules)
+		default:
+			err = p.parseCSVPolicyFile(file, rules)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return rules, nil
+}
+
+// ParsePolicies parses only the policy rules (p, p2, p3) of the policy files,
+// without the model, for checks on the policy source itself
+func (p *Parser) ParsePolicies() ([]models.Policy, error) {
+	rules, err := p.parsePolicy()
+	if err != nil {
+		return nil, err
+	}
+	return rules.policies, nil
+}
+
+// policyFiles returns the policy file, or the files of the policy directory
+func (p *Parser) policyFiles() ([]string, error) {
+	if p.policyDir != "" {
+		return policyDirFiles(p.policyDir)
+	}
+	return []string{p.policyPath}, nil
+}
+
+// SourceFiles returns the model file followed by every policy file the parser reads
+func (p *Parser) SourceFiles() ([]string, error) {
+	files, err := p.policyFiles()
+	if err != nil {
+		return nil, err
+	}
+	return append([]string{p.modelPath}, files...), nil
+}
+
+// policyRules accumulates rules across the contributing policy files
+type policyRules struct {
+	policies []models.Policy
+	roles    []models.RoleRelation
+	genfs    []models.GenfsDeclaration
+	sids     []models.SIDDeclaration
+	nodes    []models.NodeDeclaration
+	netifs   []models.NetifDeclaration
+
+	namedTransitions []models.NamedTransitionDeclaration
+	validateTrans    []models.ValidateTransDeclaration
+	defaultTypes     []models.DefaultTypeDeclaration
+	defaultRanges    []models.DefaultRangeDeclaration
+	interfaceCalls   []models.InterfaceCall
+}
+
+// policyDirFiles lists the *.csv, *.json and *.yaml files in dir, sorted for determinism
+func policyDirFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy directory: %w", err)
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		if filepath.Ext(name) == ".csv" || isJSONFile(name) || isYAMLFile(name) {
+			files = append(files, filepath.Join(dir, name))
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no *.csv, *.json or *.yaml policy files in %s", dir)
+	}
+	sort.Strings(files)
+
+	return files, nil
+}
+
+// parseCSVPolicyFile parses a CSV policy file
+func (p *Parser) parseCSVPolicyFile(path string, rules *policyRules) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open policy file: %w", err)
+	}
+	defer file.Close()
 
 	scanner := bufio.NewScanner(file)
 	lineNum := 0
@@ -349,72 +787,523 @@ func (p *Parser) parsePolicy() ([]models.Policy, []models.RoleRelation, error) {
 			continue
 		}
 
-		// Parse CSV line
-		fields := parseCSVLine(line)
+		// Parse CSV line, without its inline comment
+		rule, comment := splitInlineComment(line)
+		fields := parseCSVLine(rule)
 		if len(fields) == 0 {
 			continue
 		}
 
-		// Determine the type of rule
-		ruleType := fields[0]
+		policyCount := len(rules.policies)
+		if err := parsePolicyRule(fields, line, path, lineNum, rules); err != nil {
+			return err
+		}
+
+		// Keep the author's comment with the policy rule it annotates
+		if comment != "" && len(rules.policies) > policyCount {
+			rules.policies[len(rules.policies)-1].Comment = comment
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading policy file: %w", err)
+	}
 
-		switch ruleType {
-		case "p", "p2", "p3":
-			// Standard Casbin triple policy rule: p, subject, object, action, effect
-			if len(fields) != 5 {
-				return nil, nil, &ParseError{
-					File:    p.policyPath,
-					Line:    lineNum,
-					Message: fmt.Sprintf("policy rule expects 5 fields (type, sub, obj, act, eft), got %d: %s", len(fields), line),
-				}
+	return nil
+}
+
+// parseJSONPolicyFile parses a JSON policy file holding an array of rules,
+// each an object (see entryFields) or an array of fields as in the CSV format:
+//
+//	[
+//	  {"subject": "httpd_t", "object": "/var/www/*", "action": "read", "effect": "allow"},
+//	  ["g", "httpd_t", "web_domain"]
+//	]
+func (p *Parser) parseJSONPolicyFile(path string, rules *policyRules) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to open policy file: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := decoder.Token(); err != nil || tok != json.Delim('[') {
+		return &ParseError{File: path, Line: 1, Message: "JSON policy file must contain an array of rules"}
+	}
+
+	for decoder.More() {
+		lineNum := jsonLineAt(data, decoder.InputOffset())
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			return &ParseError{File: path, Line: lineNum, Message: fmt.Sprintf("invalid JSON: %v", err)}
+		}
+
+		var fields []string
+		if bytes.HasPrefix(raw, []byte("{")) {
+			var entry map[string]string
+			if err := json.Unmarshal(raw, &entry); err != nil {
+				return &ParseError{File: path, Line: lineNum, Message: fmt.Sprintf("rule values must be strings: %v", err)}
 			}
-			// Validate effect field
-			effect := strings.TrimSpace(fields[4])
-			if effect != "allow" && effect != "deny" {
-				return nil, nil, &ParseError{
-					File:    p.policyPath,
-					Line:    lineNum,
-					Message: fmt.Sprintf("invalid effect '%s', must be 'allow' or 'deny'", effect),
-				}
+			if fields, err = entryFields(entry); err != nil {
+				return &ParseError{File: path, Line: lineNum, Message: err.Error()}
 			}
+		} else if err := json.Unmarshal(raw, &fields); err != nil {
+			return &ParseError{
+				File:    path,
+				Line:    lineNum,
+				Message: fmt.Sprintf("rule must be an object or an array of strings: %v", err),
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+
+		if err := parsePolicyRule(fields, strings.Join(fields, ", "), path, lineNum, rules); err != nil {
+			return err
+		}
+	}
+
+	if _, err := decoder.Token(); err != nil {
+		return &ParseError{File: path, Line: jsonLineAt(data, decoder.InputOffset()), Message: fmt.Sprintf("invalid JSON: %v", err)}
+	}
+
+	return nil
+}
+
+// jsonLineAt returns the line of the first value at or after offset,
+// skipping the separator and whitespace left by the decoder
+func jsonLineAt(data []byte, offset int64) int {
+	pos := int(offset)
+	for pos < len(data) && strings.ContainsRune(" \t\r\n,", rune(data[pos])) {
+		pos++
+	}
+	return bytes.Count(data[:pos], []byte("\n")) + 1
+}
+
+// transitionFilename unquotes the filename of a named transition; it must be a single path component
+func transitionFilename(field string) (string, bool) {
+	filename := strings.Trim(strings.TrimSpace(field), `"`)
+	return filename, filename != "" && !strings.ContainsAny(filename, `/"`)
+}
 
-			policies = append(policies, models.Policy{
-				Type:    ruleType,
+// parsePolicyRule parses a single rule's fields, reporting errors against file:lineNum
+func parsePolicyRule(fields []string, line, file string, lineNum int, rules *policyRules) error {
+	// Determine the type of rule
+	ruleType := fields[0]
+
+	switch ruleType {
+	case "p", "p2", "p3":
+		// Standard Casbin triple policy rule: p, subject, object, action, effect
+		if len(fields) != 5 {
+			return &ParseError{
+				File:    file,
+				Line:    lineNum,
+				Message: fmt.Sprintf("policy rule expects 5 fields (type, sub, obj, act, eft), got %d: %s", len(fields), line),
+			}
+		}
+		// Validate effect field; a p2 transition rule carries the new type there instead
+		effect := strings.TrimSpace(fields[4])
+		isTransition := ruleType == "p2" && strings.TrimSpace(fields[3]) == "transition"
+		if !isTransition && effect != "allow" && effect != "deny" && effect != "dontaudit" {
+			return &ParseError{
+				File:    file,
+				Line:    lineNum,
+				Message: fmt.Sprintf("invalid effect '%s', must be 'allow', 'deny' or 'dontaudit'", effect),
+			}
+		}
+
+		rules.policies = append(rules.policies, models.Policy{
+			Type:    ruleType,
+			Subject: strings.TrimSpace(fields[1]),
+			Object:  strings.TrimSpace(fields[2]),
+			Action:  strings.TrimSpace(fields[3]),
+			Effect:  effect,
+			File:    file,
+			Line:    lineNum,
+		})
+
+	case "g", "g2", "g3":
+		// Standard role relation: g, member, role
+		if len(fields) != 3 {
+			return &ParseError{
+				File:    file,
+				Line:    lineNum,
+				Message: fmt.Sprintf("role relation expects 3 fields, got %d: %s", len(fields), line),
+			}
+		}
+		rules.roles = append(rules.roles, models.RoleRelation{
+			Type:   ruleType,
+			Member: strings.TrimSpace(fields[1]),
+			Role:   strings.TrimSpace(fields[2]),
+		})
+
+	case "role":
+		// Role declaration: role, webadmin_r
+		if len(fields) != 2 {
+			return &ParseError{
+				File:    file,
+				Line:    lineNum,
+				Message: fmt.Sprintf("role declaration expects 2 fields, got %d: %s", len(fields), line),
+			}
+		}
+		rules.roles = append(rules.roles, models.RoleRelation{
+			Type: ruleType,
+			Role: strings.TrimSpace(fields[1]),
+		})
+
+	case "ra":
+		// Role allow: ra, from_role, to_role
+		if len(fields) != 3 {
+			return &ParseError{
+				File:    file,
+				Line:    lineNum,
+				Message: fmt.Sprintf("role allow expects 3 fields, got %d: %s", len(fields), line),
+			}
+		}
+		rules.roles = append(rules.roles, models.RoleRelation{
+			Type:   ruleType,
+			Member: strings.TrimSpace(fields[1]),
+			Role:   strings.TrimSpace(fields[2]),
+		})
+
+	case "genfs":
+		// Pseudo-filesystem label: genfs, fstype, path, type
+		if len(fields) != 4 {
+			return &ParseError{
+				File:    file,
+				Line:    lineNum,
+				Message: fmt.Sprintf("genfs rule expects 4 fields (type, fstype, path, selinux_type), got %d: %s", len(fields), line),
+			}
+		}
+		if !strings.HasPrefix(fields[2], "/") {
+			return &ParseError{
+				File:    file,
+				Line:    lineNum,
+				Message: fmt.Sprintf("genfs path must be absolute within the filesystem: %s", fields[2]),
+			}
+		}
+		rules.genfs = append(rules.genfs, models.GenfsDeclaration{
+			FSType: fields[1],
+			Path:   fields[2],
+			Type:   fields[3],
+		})
+
+	case "n":
+		// Network node label: n, network, type
+		if len(fields) != 3 {
+			return &ParseError{
+				File:    file,
+				Line:    lineNum,
+				Message: fmt.Sprintf("node rule expects 3 fields (type, network, selinux_type), got %d: %s", len(fields), line),
+			}
+		}
+		ip, network, err := net.ParseCIDR(fields[1])
+		if err != nil {
+			return &ParseError{
+				File:    file,
+				Line:    lineNum,
+				Message: fmt.Sprintf("node network must be in CIDR notation, e.g. 192.168.1.0/24: %s", fields[1]),
+			}
+		}
+		if !ip.Equal(network.IP) {
+			return &ParseError{
+				File:    file,
+				Line:    lineNum,
+				Message: fmt.Sprintf("node network %s has host bits set, did you mean %s?", fields[1], network),
+			}
+		}
+		rules.nodes = append(rules.nodes, models.NodeDeclaration{
+			Network: fields[1],
+			Type:    fields[2],
+		})
+
+	case "f":
+		// Network interface label: f, interface, type
+		if len(fields) != 3 {
+			return &ParseError{
+				File:    file,
+				Line:    lineNum,
+				Message: fmt.Sprintf("interface rule expects 3 fields (type, interface, selinux_type), got %d: %s", len(fields), line),
+			}
+		}
+		if !netifNamePattern.MatchString(fields[1]) {
+			return &ParseError{
+				File:    file,
+				Line:    lineNum,
+				Message: fmt.Sprintf("invalid network interface name: %s", fields[1]),
+			}
+		}
+		rules.netifs = append(rules.netifs, models.NetifDeclaration{
+			Interface: fields[1],
+			Type:      fields[2],
+		})
+
+	case "sid", "initial_sid":
+		// Initial SID context: sid, name, type
+		if len(fields) != 3 {
+			return &ParseError{
+				File:    file,
+				Line:    lineNum,
+				Message: fmt.Sprintf("sid rule expects 3 fields (type, sid_name, selinux_type), got %d: %s", len(fields), line),
+			}
+		}
+		rules.sids = append(rules.sids, models.SIDDeclaration{
+			Name: fields[1],
+			Type: fields[2],
+		})
+
+	case "i":
+		// Interface call: i, interface, arg1[, arg2...]
+		if len(fields) < 3 {
+			return &ParseError{
+				File:    file,
+				Line:    lineNum,
+				Message: fmt.Sprintf("interface call expects at least 3 fields (type, interface, arg...), got %d: %s", len(fields), line),
+			}
+		}
+		name := strings.TrimSpace(fields[1])
+		if !interfaceNamePattern.MatchString(name) {
+			return &ParseError{
+				File:    file,
+				Line:    lineNum,
+				Message: fmt.Sprintf("invalid interface name '%s'", name),
+			}
+		}
+		args := make([]string, 0, len(fields)-2)
+		for _, arg := range fields[2:] {
+			args = append(args, strings.TrimSpace(arg))
+		}
+		if sig, ok := mapping.LookupInterface(name); ok && !sig.AcceptsArgs(len(args)) {
+			want := strconv.Itoa(len(sig.Params))
+			if sig.Optional > 0 {
+				want = fmt.Sprintf("%d to %d", len(sig.Params)-sig.Optional, len(sig.Params))
+			}
+			return &ParseError{
+				File:    file,
+				Line:    lineNum,
+				Message: fmt.Sprintf("interface %s takes %s arguments, got %d: %s", name, want, len(args), line),
+			}
+		}
+		rules.interfaceCalls = append(rules.interfaceCalls, models.InterfaceCall{
+			Name: name,
+			Args: args,
+		})
+
+	case "t":
+		// Type transition: t, source, parent_type::class, new_type[, filename]
+		if len(fields) != 4 && len(fields) != 5 {
+			return &ParseError{
+				File:    file,
+				Line:    lineNum,
+				Message: fmt.Sprintf("transition expects 4 or 5 fields (type, source, parent_type::class, new_type[, filename]), got %d: %s", len(fields), line),
+			}
+		}
+		target, class, ok := strings.Cut(strings.TrimSpace(fields[2]), "::")
+		if !ok || target == "" || class == "" {
+			return &ParseError{
+				File:    file,
+				Line:    lineNum,
+				Message: fmt.Sprintf("transition parent must be written type::class: %s", fields[2]),
+			}
+		}
+		if len(fields) == 4 {
+			// Unnamed transitions are p2 transition rules
+			rules.policies = append(rules.policies, models.Policy{
+				Type:    "p2",
 				Subject: strings.TrimSpace(fields[1]),
 				Object:  strings.TrimSpace(fields[2]),
-				Action:  strings.TrimSpace(fields[3]),
-				Effect:  effect,
+				Action:  "transition",
+				Effect:  strings.TrimSpace(fields[3]),
+				File:    file,
+				Line:    lineNum,
 			})
+			break
+		}
+		filename, ok := transitionFilename(fields[4])
+		if !ok {
+			return &ParseError{
+				File:    file,
+				Line:    lineNum,
+				Message: fmt.Sprintf("named transition filename must be a single path component: %s", fields[4]),
+			}
+		}
+		rules.namedTransitions = append(rules.namedTransitions, models.NamedTransitionDeclaration{
+			Source:   strings.TrimSpace(fields[1]),
+			Target:   target,
+			Class:    class,
+			NewType:  strings.TrimSpace(fields[3]),
+			Filename: filename,
+		})
 
-		case "g", "g2", "g3":
-			// Standard role relation: g, member, role
-			if len(fields) != 3 {
-				return nil, nil, &ParseError{
-					File:    p.policyPath,
-					Line:    lineNum,
-					Message: fmt.Sprintf("role relation expects 3 fields, got %d: %s", len(fields), line),
-				}
-			}
-			roles = append(roles, models.RoleRelation{
-				Type:   ruleType,
-				Member: strings.TrimSpace(fields[1]),
-				Role:   strings.TrimSpace(fields[2]),
-			})
+	case "ft":
+		// Named file transition: ft, source, parent_type, class, new_type, "filename"
+		if len(fields) != 6 {
+			return &ParseError{
+				File:    file,
+				Line:    lineNum,
+				Message: fmt.Sprintf("named transition expects 6 fields (type, source, parent_type, class, new_type, filename), got %d: %s", len(fields), line),
+			}
+		}
+		filename, ok := transitionFilename(fields[5])
+		if !ok {
+			return &ParseError{
+				File:    file,
+				Line:    lineNum,
+				Message: fmt.Sprintf("named transition filename must be a single path component: %s", fields[5]),
+			}
+		}
+		rules.namedTransitions = append(rules.namedTransitions, models.NamedTransitionDeclaration{
+			Source:   fields[1],
+			Target:   fields[2],
+			Class:    fields[3],
+			NewType:  fields[4],
+			Filename: filename,
+		})
 
-		default:
-			return nil, nil, &ParseError{
-				File:    p.policyPath,
+	case "vt", "mvt":
+		// Object relabel constraint: vt, classes, "expression"; mvt may compare MLS levels
+		if len(fields) != 3 {
+			return &ParseError{
+				File:    file,
+				Line:    lineNum,
+				Message: fmt.Sprintf("validatetrans rule expects 3 fields (type, classes, expression), got %d: %s", len(fields), line),
+			}
+		}
+		classes := strings.Fields(fields[1])
+		expression := strings.TrimSpace(strings.Trim(fields[2], `"`))
+		if len(classes) == 0 {
+			return &ParseError{File: file, Line: lineNum, Message: fmt.Sprintf("validatetrans rule needs an object class: %s", line)}
+		}
+		if err := validateTransExpression(expression, ruleType == "mvt"); err != nil {
+			return &ParseError{File: file, Line: lineNum, Message: err.Error()}
+		}
+		rules.validateTrans = append(rules.validateTrans, models.ValidateTransDeclaration{
+			Classes:    classes,
+			Expression: expression,
+			MLS:        ruleType == "mvt",
+		})
+
+	case "dt":
+		// Default type of new objects: dt, classes, source|target
+		if len(fields) != 3 {
+			return &ParseError{
+				File:    file,
+				Line:    lineNum,
+				Message: fmt.Sprintf("default_type rule expects 3 fields (type, classes, source|target), got %d: %s", len(fields), line),
+			}
+		}
+		classes := strings.Fields(fields[1])
+		if len(classes) == 0 {
+			return &ParseError{File: file, Line: lineNum, Message: fmt.Sprintf("default_type rule needs an object class: %s", line)}
+		}
+		if !objectDefaults[fields[2]] {
+			return &ParseError{File: file, Line: lineNum, Message: fmt.Sprintf("invalid default_type '%s', must be 'source' or 'target'", fields[2])}
+		}
+		rules.defaultTypes = append(rules.defaultTypes, models.DefaultTypeDeclaration{
+			Classes: classes,
+			Default: fields[2],
+		})
+
+	case "dr":
+		// Default range of new objects: dr, classes, [source|target,] low|high|low_high
+		if len(fields) != 3 && len(fields) != 4 {
+			return &ParseError{
+				File:    file,
 				Line:    lineNum,
-				Message: fmt.Sprintf("unknown rule type: %s (only p, p2, p3, g, g2, g3 are supported)", ruleType),
+				Message: fmt.Sprintf("default_range rule expects 3 or 4 fields (type, classes, [source|target,] low|high|low_high), got %d: %s", len(fields), line),
 			}
 		}
+		classes := strings.Fields(fields[1])
+		if len(classes) == 0 {
+			return &ParseError{File: file, Line: lineNum, Message: fmt.Sprintf("default_range rule needs an object class: %s", line)}
+		}
+		def, rangeName := "target", fields[len(fields)-1]
+		if len(fields) == 4 {
+			def = fields[2]
+		}
+		if !objectDefaults[def] {
+			return &ParseError{File: file, Line: lineNum, Message: fmt.Sprintf("invalid default_range '%s', must be 'source' or 'target'", def)}
+		}
+		if !defaultRanges[rangeName] {
+			return &ParseError{File: file, Line: lineNum, Message: fmt.Sprintf("invalid default_range '%s', must be 'low', 'high' or 'low_high'", rangeName)}
+		}
+		rules.defaultRanges = append(rules.defaultRanges, models.DefaultRangeDeclaration{
+			Classes: classes,
+			Default: def,
+			Range:   rangeName,
+		})
+
+	default:
+		return &ParseError{
+			File:    file,
+			Line:    lineNum,
+			Message: fmt.Sprintf("unknown rule type: %s (only p, p2, p3, g, g2, g3, role, ra, genfs, n, f, sid, ft, vt, mvt, dt, dr, i are supported)", ruleType),
+		}
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, nil, fmt.Errorf("error reading policy file: %w", err)
+	return nil
+}
+
+// objectDefaults are the contexts default_type and default_range may inherit from
+var objectDefaults = map[string]bool{"source": true, "target": true}
+
+// defaultRanges are the levels of the inherited range default_range may use
+var defaultRanges = map[string]bool{"low": true, "high": true, "low_high": true}
+
+// mlsLevelOperands are the level operands only mlsvalidatetrans expressions may use
+var mlsLevelOperands = map[string]bool{"l1": true, "l2": true, "h1": true, "h2": true}
+
+// validateTransExpression checks that a validatetrans expression is non-empty,
+// has balanced parentheses and, unless mls, does not compare levels
+func validateTransExpression(expression string, mls bool) error {
+	if expression == "" {
+		return fmt.Errorf("validatetrans expression must not be empty")
+	}
+	if strings.Contains(expression, ";") {
+		return fmt.Errorf("invalid validatetrans expression '%s': unexpected ';'", expression)
+	}
+	depth := 0
+	for _, c := range expression {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		}
+		if depth < 0 {
+			break
+		}
+	}
+	if depth != 0 {
+		return fmt.Errorf("invalid validatetrans expression '%s': unbalanced parentheses", expression)
+	}
+	if !mls {
+		for _, token := range strings.Fields(strings.NewReplacer("(", " ", ")", " ").Replace(expression)) {
+			if mlsLevelOperands[token] {
+				return fmt.Errorf("validatetrans expression '%s' compares MLS levels, use mvt", expression)
+			}
+		}
 	}
+	return nil
+}
 
-	return policies, roles, nil
+// splitInlineComment splits a policy line at a trailing "# comment". The '#' must
+// follow whitespace and be outside quotes, so it cannot be part of a field.
+func splitInlineComment(line string) (string, string) {
+	inQuotes := false
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '"':
+			inQuotes = !inQuotes
+		case '#':
+			if !inQuotes && i > 0 && (line[i-1] == ' ' || line[i-1] == '\t') {
+				return strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+			}
+		}
+	}
+	return line, ""
 }
 
 // parseCSVLine parses a CSV line, handling simple quoted fields
@@ -74,6 +74,22 @@ m = r.sub == p.sub`,
 			wantErr:     true,
 			errContains: "invalid effect",
 		},
+		{
+			name: "dontaudit effect",
+			modelData: `[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act, eft
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = r.sub == p.sub`,
+			policyData: "p, httpd_t, tmp_t, search::dir, dontaudit",
+			wantErr:    false,
+		},
 		{
 			name: "boolean with invalid value",
 			modelData: `[request_definition]
@@ -3,7 +3,12 @@ package compiler
 import (
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/cici0602/pml-to-selinux/mapping"
+	"github.com/cici0602/pml-to-selinux/models"
 )
 
 // TestParseModel tests parsing of PML model files
@@ -205,12 +210,332 @@ g2, httpd_t, web_domain
 			wantErr:      true,
 			wantPolicies: 0,
 		},
+		{
+			name: "type transition rule",
+			policyData: `p2, httpd_t, /usr/lib/cgi-bin::process, transition, httpd_cgi_t
+`,
+			wantPolicies: 1,
+			wantErr:      false,
+			checkPolicies: func(t *testing.T, p *Parser) {
+				pml, _ := p.Parse()
+				decoded, err := p.Decode(pml)
+				if err != nil {
+					t.Fatalf("Decode() error = %v", err)
+				}
+				if len(decoded.Transitions) != 1 || decoded.Transitions[0].NewType != "httpd_cgi_t" {
+					t.Errorf("Expected transition to httpd_cgi_t, got %v", decoded.Transitions)
+				}
+			},
+		},
 		{
 			name: "invalid role - wrong field count",
 			policyData: `g, user_u
 `,
 			wantErr: true,
 		},
+		{
+			name: "role declarations and role allows",
+			policyData: `p, httpd_t, /var/www/*, read, allow
+role, webadmin_r
+ra, user_r, webadmin_r
+`,
+			wantPolicies: 1,
+			wantRoles:    2,
+			wantErr:      false,
+			checkPolicies: func(t *testing.T, p *Parser) {
+				pml, _ := p.Parse()
+				decoded, err := p.Decode(pml)
+				if err != nil {
+					t.Fatalf("Decode() error = %v", err)
+				}
+				if len(decoded.RoleDeclarations) != 1 || decoded.RoleDeclarations[0] != "webadmin_r" {
+					t.Errorf("Expected role declaration webadmin_r, got %v", decoded.RoleDeclarations)
+				}
+				if len(decoded.RoleAllows) != 1 || decoded.RoleAllows[0].Member != "user_r" || decoded.RoleAllows[0].Role != "webadmin_r" {
+					t.Errorf("Expected role allow user_r -> webadmin_r, got %v", decoded.RoleAllows)
+				}
+			},
+		},
+		{
+			name: "invalid role allow - wrong field count",
+			policyData: `ra, user_r
+`,
+			wantErr: true,
+		},
+		{
+			name: "genfs labels",
+			policyData: `genfs, proc, /sys/kernel/modprobe, proc_security_t
+genfs, sysfs, /class/net/eth0, sysfs_net_t
+`,
+			wantPolicies: 0,
+			wantErr:      false,
+			checkPolicies: func(t *testing.T, p *Parser) {
+				pml, _ := p.Parse()
+				decoded, err := p.Decode(pml)
+				if err != nil {
+					t.Fatalf("Decode() error = %v", err)
+				}
+				want := models.GenfsDeclaration{FSType: "proc", Path: "/sys/kernel/modprobe", Type: "proc_security_t"}
+				if len(decoded.Genfs) != 2 || decoded.Genfs[0] != want {
+					t.Errorf("Expected genfs %+v first, got %v", want, decoded.Genfs)
+				}
+			},
+		},
+		{
+			name: "invalid genfs - relative path",
+			policyData: `genfs, proc, sys/kernel, proc_security_t
+`,
+			wantErr: true,
+		},
+		{
+			name: "network node and interface labels",
+			policyData: `n, 192.168.1.0/24, node_internal_t
+n, fd00::/8, node_ula_t
+f, eth0, netif_internal_t
+`,
+			wantPolicies: 0,
+			wantErr:      false,
+			checkPolicies: func(t *testing.T, p *Parser) {
+				pml, _ := p.Parse()
+				decoded, err := p.Decode(pml)
+				if err != nil {
+					t.Fatalf("Decode() error = %v", err)
+				}
+				want := []models.NodeDeclaration{{Network: "192.168.1.0/24", Type: "node_internal_t"}, {Network: "fd00::/8", Type: "node_ula_t"}}
+				if len(decoded.Nodes) != 2 || decoded.Nodes[0] != want[0] || decoded.Nodes[1] != want[1] {
+					t.Errorf("Expected nodes %+v, got %+v", want, decoded.Nodes)
+				}
+				if len(decoded.Netifs) != 1 || decoded.Netifs[0] != (models.NetifDeclaration{Interface: "eth0", Type: "netif_internal_t"}) {
+					t.Errorf("Expected interface eth0, got %+v", decoded.Netifs)
+				}
+			},
+		},
+		{
+			name: "invalid node - not CIDR",
+			policyData: `n, 192.168.1.0, node_internal_t
+`,
+			wantErr: true,
+		},
+		{
+			name: "invalid node - host bits set",
+			policyData: `n, 192.168.1.7/24, node_internal_t
+`,
+			wantErr: true,
+		},
+		{
+			name: "invalid interface name",
+			policyData: `f, eth0/1, netif_internal_t
+`,
+			wantErr: true,
+		},
+		{
+			name: "initial sids",
+			policyData: `sid, kernel, kernel_t
+initial_sid, unlabeled, unlabeled_t
+`,
+			wantPolicies: 0,
+			wantErr:      false,
+			checkPolicies: func(t *testing.T, p *Parser) {
+				pml, _ := p.Parse()
+				decoded, err := p.Decode(pml)
+				if err != nil {
+					t.Fatalf("Decode() error = %v", err)
+				}
+				want := []models.SIDDeclaration{{Name: "kernel", Type: "kernel_t"}, {Name: "unlabeled", Type: "unlabeled_t"}}
+				if len(decoded.SIDs) != 2 || decoded.SIDs[0] != want[0] || decoded.SIDs[1] != want[1] {
+					t.Errorf("Expected sids %+v, got %v", want, decoded.SIDs)
+				}
+			},
+		},
+		{
+			name: "invalid sid - wrong field count",
+			policyData: `sid, kernel
+`,
+			wantErr: true,
+		},
+		{
+			name: "validatetrans rules",
+			policyData: `vt, "file dir", "t1 == t2 or t3 == app_admin_t"
+mvt, file, "l1 eq l2"
+`,
+			wantPolicies: 0,
+			wantErr:      false,
+			checkPolicies: func(t *testing.T, p *Parser) {
+				pml, _ := p.Parse()
+				decoded, err := p.Decode(pml)
+				if err != nil {
+					t.Fatalf("Decode() error = %v", err)
+				}
+				if len(decoded.ValidateTrans) != 2 {
+					t.Fatalf("Expected 2 validatetrans rules, got %v", decoded.ValidateTrans)
+				}
+				vt := decoded.ValidateTrans[0]
+				if strings.Join(vt.Classes, " ") != "file dir" || vt.Expression != "t1 == t2 or t3 == app_admin_t" || vt.MLS {
+					t.Errorf("Unexpected validatetrans %+v", vt)
+				}
+				if !decoded.ValidateTrans[1].MLS {
+					t.Errorf("Expected mvt to be MLS, got %+v", decoded.ValidateTrans[1])
+				}
+			},
+		},
+		{
+			name: "invalid validatetrans - levels without mvt",
+			policyData: `vt, file, "l1 eq l2"
+`,
+			wantErr: true,
+		},
+		{
+			name: "invalid validatetrans - unbalanced parentheses",
+			policyData: `vt, file, "(t1 == t2 or t3 == app_admin_t"
+`,
+			wantErr: true,
+		},
+		{
+			name: "object defaults",
+			policyData: `dt, "file dir", target
+dr, file, low
+dr, process, source, low_high
+`,
+			wantPolicies: 0,
+			wantErr:      false,
+			checkPolicies: func(t *testing.T, p *Parser) {
+				pml, _ := p.Parse()
+				decoded, err := p.Decode(pml)
+				if err != nil {
+					t.Fatalf("Decode() error = %v", err)
+				}
+				if len(decoded.DefaultTypes) != 1 || strings.Join(decoded.DefaultTypes[0].Classes, " ") != "file dir" || decoded.DefaultTypes[0].Default != "target" {
+					t.Errorf("Unexpected default types %+v", decoded.DefaultTypes)
+				}
+				if len(decoded.DefaultRanges) != 2 {
+					t.Fatalf("Expected 2 default ranges, got %+v", decoded.DefaultRanges)
+				}
+				if dr := decoded.DefaultRanges[0]; dr.Default != "target" || dr.Range != "low" {
+					t.Errorf("Expected dr without source/target to default to target, got %+v", dr)
+				}
+				if dr := decoded.DefaultRanges[1]; dr.Default != "source" || dr.Range != "low_high" {
+					t.Errorf("Unexpected default range %+v", dr)
+				}
+			},
+		},
+		{
+			name: "invalid default_type keyword",
+			policyData: `dt, file, parent
+`,
+			wantErr: true,
+		},
+		{
+			name: "invalid default_range keyword",
+			policyData: `dr, file, target, middle
+`,
+			wantErr: true,
+		},
+		{
+			name: "named file transitions",
+			policyData: `ft, httpd_t, httpd_var_lib_t, file, httpd_cache_t, "cache.db"
+`,
+			wantPolicies: 0,
+			wantErr:      false,
+			checkPolicies: func(t *testing.T, p *Parser) {
+				pml, _ := p.Parse()
+				decoded, err := p.Decode(pml)
+				if err != nil {
+					t.Fatalf("Decode() error = %v", err)
+				}
+				want := models.NamedTransitionDeclaration{Source: "httpd_t", Target: "httpd_var_lib_t", Class: "file", NewType: "httpd_cache_t", Filename: "cache.db"}
+				if len(decoded.NamedTransitions) != 1 || decoded.NamedTransitions[0] != want {
+					t.Errorf("Expected named transition %+v, got %v", want, decoded.NamedTransitions)
+				}
+			},
+		},
+		{
+			name: "invalid named transition - filename with path",
+			policyData: `ft, httpd_t, httpd_var_lib_t, file, httpd_cache_t, "cache/db"
+`,
+			wantErr: true,
+		},
+		{
+			name: "transitions with and without object name",
+			policyData: `t, httpd_t, tmp_t::file, httpd_tmp_t
+t, httpd_t, tmp_t::file, httpd_cache_t, cache.db
+`,
+			wantPolicies: 1,
+			wantErr:      false,
+			checkPolicies: func(t *testing.T, p *Parser) {
+				pml, _ := p.Parse()
+				decoded, err := p.Decode(pml)
+				if err != nil {
+					t.Fatalf("Decode() error = %v", err)
+				}
+				want := models.TransitionInfo{SourceType: "httpd_t", TargetType: "tmp_t", Class: "file", NewType: "httpd_tmp_t"}
+				if len(decoded.Transitions) != 1 || decoded.Transitions[0] != want {
+					t.Errorf("Expected transition %+v, got %v", want, decoded.Transitions)
+				}
+				named := models.NamedTransitionDeclaration{Source: "httpd_t", Target: "tmp_t", Class: "file", NewType: "httpd_cache_t", Filename: "cache.db"}
+				if len(decoded.NamedTransitions) != 1 || decoded.NamedTransitions[0] != named {
+					t.Errorf("Expected named transition %+v, got %v", named, decoded.NamedTransitions)
+				}
+			},
+		},
+		{
+			name: "invalid transition - parent without class",
+			policyData: `t, httpd_t, tmp_t, httpd_tmp_t
+`,
+			wantErr: true,
+		},
+		{
+			name: "interface calls",
+			policyData: `i, apache_read_config, myapp_t
+i, files_pid_filetrans, myapp_t, myapp_var_run_t, file
+i, custom_interface, myapp_t, some_literal
+`,
+			wantPolicies: 0,
+			wantErr:      false,
+			checkPolicies: func(t *testing.T, p *Parser) {
+				pml, _ := p.Parse()
+				if len(pml.InterfaceCalls) != 3 {
+					t.Fatalf("Expected 3 interface calls, got %d", len(pml.InterfaceCalls))
+				}
+				call := pml.InterfaceCalls[1]
+				if call.Name != "files_pid_filetrans" || strings.Join(call.Args, ",") != "myapp_t,myapp_var_run_t,file" {
+					t.Errorf("Unexpected interface call %+v", call)
+				}
+			},
+		},
+		{
+			name: "invalid interface call - wrong argument count",
+			policyData: `i, apache_read_config, myapp_t, httpd_t
+`,
+			wantErr: true,
+		},
+		{
+			name: "invalid interface call - no arguments",
+			policyData: `i, apache_read_config
+`,
+			wantErr: true,
+		},
+		{
+			name: "inline comments",
+			policyData: `p, httpd_t, /var/www/*, read, allow # web content is public
+p, httpd_t, "/srv/a #1", read, allow
+g, httpd_t, web_domain # not a policy rule
+`,
+			wantPolicies: 2,
+			wantRoles:    1,
+			wantErr:      false,
+			checkPolicies: func(t *testing.T, p *Parser) {
+				pml, _ := p.Parse()
+				if pml.Policies[0].Effect != "allow" || pml.Policies[0].Comment != "web content is public" {
+					t.Errorf("Expected effect 'allow' with comment, got %+v", pml.Policies[0])
+				}
+				if pml.Policies[1].Object != "/srv/a #1" || pml.Policies[1].Comment != "" {
+					t.Errorf("Expected quoted '#' to stay in the object, got %+v", pml.Policies[1])
+				}
+				if pml.Roles[0].Role != "web_domain" {
+					t.Errorf("Expected role 'web_domain', got %q", pml.Roles[0].Role)
+				}
+			},
+		},
 		{
 			name: "with comments and empty lines",
 			policyData: `# This is a comment
@@ -337,6 +662,158 @@ func TestParseError(t *testing.T) {
 	}
 }
 
+// TestDecodeAnnotations tests extraction of @key=value object annotations
+func TestDecodeAnnotations(t *testing.T) {
+	tests := []struct {
+		name        string
+		object      string
+		wantObject  string
+		wantClass   string
+		wantLevel   string
+		wantAudit   bool
+		wantNoFC    bool
+		wantIoctl   []string
+		wantPrio    int
+		wantOpt     string
+		errContains string
+	}{
+		{
+			name:       "level annotation",
+			object:     "/srv/secret/*@level=internal-secret",
+			wantObject: "/srv/secret/*",
+			wantClass:  "file",
+			wantLevel:  "internal-secret",
+		},
+		{
+			name:       "audit annotation with explicit class",
+			object:     "/etc/shadow::file@audit=true",
+			wantObject: "/etc/shadow",
+			wantClass:  "file",
+			wantAudit:  true,
+		},
+		{
+			name:       "multiple annotations",
+			object:     "/srv/data/*@level=s1@audit=true",
+			wantObject: "/srv/data/*",
+			wantClass:  "file",
+			wantLevel:  "s1",
+			wantAudit:  true,
+		},
+		{
+			name:       "nofc flag annotation",
+			object:     "/tmp/*@nofc@audit=true",
+			wantObject: "/tmp/*",
+			wantClass:  "file",
+			wantAudit:  true,
+			wantNoFC:   true,
+		},
+		{
+			name:       "repeated xperm annotations",
+			object:     "/dev/app0@xperm=ioctl:0x1234-0x1240@xperm=ioctl:0x89AB",
+			wantObject: "/dev/app0",
+			wantClass:  "file",
+			wantIoctl:  []string{"0x1234-0x1240", "0x89ab"},
+		},
+		{
+			name:        "xperm operation other than ioctl",
+			object:      "/dev/app0@xperm=nlmsg:0x10",
+			errContains: "only 'ioctl' is supported",
+		},
+		{
+			name:        "xperm command not hex",
+			object:      "/dev/app0@xperm=ioctl:1234",
+			errContains: "expected hex",
+		},
+		{
+			name:        "xperm command out of range",
+			object:      "/dev/app0@xperm=ioctl:0x10000",
+			errContains: "between 0x0000 and 0xffff",
+		},
+		{
+			name:        "xperm range reversed",
+			object:      "/dev/app0@xperm=ioctl:0x1240-0x1234",
+			errContains: "low is greater than high",
+		},
+		{
+			name:       "priority annotation",
+			object:     "/srv/data/*@priority=500",
+			wantObject: "/srv/data/*",
+			wantClass:  "file",
+			wantPrio:   500,
+		},
+		{
+			name:       "optional annotation",
+			object:     "/var/www/*@optional=apache",
+			wantObject: "/var/www/*",
+			wantClass:  "file",
+			wantOpt:    "apache",
+		},
+		{
+			name:        "optional module with a path",
+			object:      "/srv/data/*@optional=mysql/x",
+			errContains: "invalid optional module 'mysql/x'",
+		},
+		{
+			name:        "priority out of range",
+			object:      "/srv/data/*@priority=1000",
+			errContains: "invalid priority '1000', must be 1-999",
+		},
+		{
+			name:        "unknown annotation",
+			object:      "/srv/data/*@color=red",
+			errContains: "unknown annotation",
+		},
+		{
+			name:        "invalid audit value",
+			object:      "/srv/data/*@audit=maybe",
+			errContains: "invalid audit value",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := &Parser{}
+			decoded, err := parser.decodePolicy(&models.Policy{
+				Type: "p", Subject: "app_t", Object: tt.object, Action: "read", Effect: "allow",
+			})
+
+			if tt.errContains != "" {
+				if err == nil || !contains(err.Error(), tt.errContains) {
+					t.Errorf("decodePolicy() error = %v, should contain %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decodePolicy() error = %v", err)
+			}
+			if decoded.Object != tt.wantObject {
+				t.Errorf("Object = %q, want %q", decoded.Object, tt.wantObject)
+			}
+			if decoded.Class != tt.wantClass {
+				t.Errorf("Class = %q, want %q", decoded.Class, tt.wantClass)
+			}
+			if decoded.Level != tt.wantLevel {
+				t.Errorf("Level = %q, want %q", decoded.Level, tt.wantLevel)
+			}
+			if decoded.Audit != tt.wantAudit {
+				t.Errorf("Audit = %v, want %v", decoded.Audit, tt.wantAudit)
+			}
+			if decoded.NoFileContext != tt.wantNoFC {
+				t.Errorf("NoFileContext = %v, want %v", decoded.NoFileContext, tt.wantNoFC)
+			}
+			if strings.Join(decoded.IoctlRanges, " ") != strings.Join(tt.wantIoctl, " ") {
+				t.Errorf("IoctlRanges = %v, want %v", decoded.IoctlRanges, tt.wantIoctl)
+			}
+			if decoded.Priority != tt.wantPrio {
+				t.Errorf("Priority = %d, want %d", decoded.Priority, tt.wantPrio)
+			}
+			if decoded.Optional != tt.wantOpt {
+				t.Errorf("Optional = %q, want %q", decoded.Optional, tt.wantOpt)
+			}
+		})
+	}
+}
+
 // Helper function to check if a string contains a substring
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && (s == substr || len(substr) == 0 ||
@@ -351,3 +828,500 @@ func findSubstring(s, substr string) bool {
 	}
 	return false
 }
+
+// TestClassMap tests loading class mappings and their use during decoding
+func TestClassMap(t *testing.T) {
+	mapPath := filepath.Join(t.TempDir(), "classes.map")
+	mapData := `# custom object classes
+dbus:         dbus
+dbus:session  dbus_session
+key:          key
+`
+	if err := os.WriteFile(mapPath, []byte(mapData), 0644); err != nil {
+		t.Fatalf("Failed to write class map: %v", err)
+	}
+
+	classMap, err := LoadClassMap(mapPath)
+	if err != nil {
+		t.Fatalf("LoadClassMap() error = %v", err)
+	}
+	if len(classMap) != 3 {
+		t.Fatalf("Expected 3 mappings, got %d", len(classMap))
+	}
+
+	parser := &Parser{}
+	parser.SetClassMap(classMap)
+
+	tests := []struct {
+		object     string
+		wantClass  string
+		wantCustom bool
+	}{
+		{object: "dbus:system_bus", wantClass: "dbus", wantCustom: true},
+		{object: "dbus:session_bus", wantClass: "dbus_session", wantCustom: true},
+		{object: "key:keyring", wantClass: "key", wantCustom: true},
+		{object: "key:keyring::file", wantClass: "file", wantCustom: false},
+		{object: "tcp:8080", wantClass: "tcp_socket", wantCustom: false},
+		{object: "packet:httpd_packet_t", wantClass: "packet", wantCustom: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.object, func(t *testing.T) {
+			decoded, err := parser.decodePolicy(&models.Policy{Type: "p", Subject: "app_t", Object: tt.object, Action: "send_msg", Effect: "allow"})
+			if err != nil {
+				t.Fatalf("decodePolicy() error = %v", err)
+			}
+			if decoded.Class != tt.wantClass || decoded.CustomClass != tt.wantCustom {
+				t.Errorf("class = %q (custom %v), want %q (custom %v)", decoded.Class, decoded.CustomClass, tt.wantClass, tt.wantCustom)
+			}
+		})
+	}
+
+	if err := os.WriteFile(mapPath, []byte("dbus:\n"), 0644); err != nil {
+		t.Fatalf("Failed to write class map: %v", err)
+	}
+	if _, err := LoadClassMap(mapPath); err == nil {
+		t.Error("Expected error for mapping without a class")
+	}
+}
+
+// TestLoadClassPermissions tests that a permissions file defines classes raw permissions are checked against
+func TestLoadClassPermissions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "classes.perms")
+	data := `# permissions of a userspace object manager
+parser_test_service  start stop
+parser_test_service  status
+`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatalf("Failed to write class permissions: %v", err)
+	}
+	if err := LoadClassPermissions(path); err != nil {
+		t.Fatalf("LoadClassPermissions() error = %v", err)
+	}
+
+	for _, tt := range []struct {
+		action  string
+		wantErr bool
+	}{
+		{"status", false},
+		{"restart", true},
+	} {
+		analyzer := NewAnalyzer(newTestDecodedPML(
+			models.Policy{Type: "p", Subject: "app_t", Object: "app_t::parser_test_service", Action: tt.action, Effect: "allow"},
+		))
+		analyzer.SetQuiet(true)
+		if err := analyzer.Analyze(); (err != nil) != tt.wantErr {
+			t.Errorf("action %s: Analyze() error = %v, wantErr %v", tt.action, err, tt.wantErr)
+		}
+	}
+
+	if err := os.WriteFile(path, []byte("parser_test_service\n"), 0644); err != nil {
+		t.Fatalf("Failed to write class permissions: %v", err)
+	}
+	if err := LoadClassPermissions(path); err == nil {
+		t.Error("Expected error for a class without permissions")
+	}
+}
+
+func TestParsePolicyDir(t *testing.T) {
+	modelData := `[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act, eft
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = r.sub == p.sub
+`
+	tmpDir := t.TempDir()
+	modelPath := filepath.Join(tmpDir, "model.conf")
+	policyDir := filepath.Join(tmpDir, "policies")
+	files := map[string]string{
+		"20-logs.json": `[
+  ["p", "httpd_t", "/var/log/httpd/*", "append", "allow"],
+  ["g", "httpd_t", "web_domain"]
+]`,
+		"10-base.csv": "p, httpd_t, /var/www/*, read, allow\n",
+		"README.md":   "not a policy file\n",
+	}
+
+	if err := os.WriteFile(modelPath, []byte(modelData), 0644); err != nil {
+		t.Fatalf("Failed to write model file: %v", err)
+	}
+	if err := os.Mkdir(policyDir, 0755); err != nil {
+		t.Fatalf("Failed to create policy dir: %v", err)
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(policyDir, name), []byte(data), 0644); err != nil {
+			t.Fatalf("Failed to write policy file: %v", err)
+		}
+	}
+
+	parser := NewParser(modelPath, "")
+	parser.SetPolicyDir(policyDir)
+	pml, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(pml.Policies) != 2 || len(pml.Roles) != 1 {
+		t.Fatalf("Expected 2 policies and 1 role, got %d and %d", len(pml.Policies), len(pml.Roles))
+	}
+	// Files are merged in sorted order
+	if pml.Policies[0].Object != "/var/www/*" || pml.Policies[1].Object != "/var/log/httpd/*" {
+		t.Errorf("Unexpected policy order: %+v", pml.Policies)
+	}
+
+	// Errors name the contributing file and line
+	badPath := filepath.Join(policyDir, "30-bad.json")
+	if err := os.WriteFile(badPath, []byte("[\n  [\"p\", \"httpd_t\", \"/srv/*\", \"read\", \"allow\"],\n  [\"p\", \"httpd_t\"]\n]"), 0644); err != nil {
+		t.Fatalf("Failed to write policy file: %v", err)
+	}
+	_, err = parser.Parse()
+	parseErr, ok := err.(*ParseError)
+	if !ok {
+		t.Fatalf("Expected ParseError, got %v", err)
+	}
+	if parseErr.File != badPath || parseErr.Line != 3 {
+		t.Errorf("Error location = %s:%d, want %s:3", parseErr.File, parseErr.Line, badPath)
+	}
+}
+
+func TestDecodeActionClass(t *testing.T) {
+	tests := []struct {
+		name       string
+		object     string
+		action     string
+		wantAction string
+		wantClass  string
+		wantErr    bool
+	}{
+		{name: "action class only", object: "/var/lib/app", action: "search::dir", wantAction: "search", wantClass: "dir"},
+		{name: "object class only", object: "/var/lib/app::dir", action: "search", wantAction: "search", wantClass: "dir"},
+		{name: "matching classes", object: "/var/lib/app::dir", action: "search::dir", wantAction: "search", wantClass: "dir"},
+		{name: "contradicting classes", object: "/var/lib/app::file", action: "search::dir", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := &Parser{}
+			decoded, err := parser.decodePolicy(&models.Policy{
+				Type: "p", Subject: "app_t", Object: tt.object, Action: tt.action, Effect: "allow",
+				File: "policy.csv", Line: 7,
+			})
+
+			if tt.wantErr {
+				parseErr, ok := err.(*ParseError)
+				if !ok || parseErr.File != "policy.csv" || parseErr.Line != 7 {
+					t.Errorf("decodePolicy() error = %v, want ParseError at policy.csv:7", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decodePolicy() error = %v", err)
+			}
+			if decoded.Action != tt.wantAction || decoded.Class != tt.wantClass {
+				t.Errorf("got action %q class %q, want %q %q", decoded.Action, decoded.Class, tt.wantAction, tt.wantClass)
+			}
+		})
+	}
+}
+
+func TestDecodeObjectLevel(t *testing.T) {
+	tests := []struct {
+		object    string
+		wantLevel string
+		wantClass string
+	}{
+		{object: "/data/secret/*::confidential:hr", wantLevel: "confidential:hr", wantClass: "file"},
+		{object: "/data/secret::dir::s2", wantLevel: "s2", wantClass: "dir"},
+		{object: "/data/secret::secret", wantLevel: "secret", wantClass: "file"},
+		{object: "/data/secret::dir", wantLevel: "", wantClass: "dir"},
+	}
+
+	for _, tt := range tests {
+		parser := &Parser{}
+		decoded, err := parser.decodePolicy(&models.Policy{
+			Type: "p", Subject: "app_t", Object: tt.object, Action: "read", Effect: "allow",
+		})
+		if err != nil {
+			t.Fatalf("decodePolicy(%s) error = %v", tt.object, err)
+		}
+		if decoded.Object != "/data/secret/*" && decoded.Object != "/data/secret" {
+			t.Errorf("decodePolicy(%s) object = %q, want the path alone", tt.object, decoded.Object)
+		}
+		if decoded.Level != tt.wantLevel || decoded.Class != tt.wantClass {
+			t.Errorf("decodePolicy(%s) level %q class %q, want %q %q",
+				tt.object, decoded.Level, decoded.Class, tt.wantLevel, tt.wantClass)
+		}
+	}
+
+	parser := &Parser{}
+	if _, err := parser.decodePolicy(&models.Policy{
+		Type: "p", Subject: "app_t", Object: "/data/secret::s2@level=s3", Action: "read", Effect: "allow",
+	}); err == nil {
+		t.Error("expected error for a level given both as ::level and @level=")
+	}
+}
+
+func TestParseStructuredFormats(t *testing.T) {
+	confModel := `[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act, eft
+
+[role_definition]
+g = _, _
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = r.sub == p.sub && r.obj == p.obj && r.act == p.act
+`
+	jsonModel := `{
+  "request_definition": {"r": "sub, obj, act"},
+  "policy_definition": {"p": "sub, obj, act, eft"},
+  "role_definition": {"g": "_, _"},
+  "policy_effect": "some(where (p.eft == allow))",
+  "matchers": "r.sub == p.sub && r.obj == p.obj && r.act == p.act"
+}`
+	yamlModel := `request_definition:
+  r: sub, obj, act
+policy_definition:
+  p: sub, obj, act, eft
+role_definition:
+  g: _, _
+policy_effect: some(where (p.eft == allow))
+matchers: r.sub == p.sub && r.obj == p.obj && r.act == p.act
+`
+	csvPolicy := `p, httpd_t, /var/www/*?cond=httpd_enable, read::file, allow
+p, httpd_t, /etc/shadow, read, deny
+g, httpd_t, web_domain
+`
+	jsonPolicy := `[
+  {"subject": "httpd_t", "object": "/var/www/*", "action": "read", "class": "file", "effect": "allow", "cond": "httpd_enable"},
+  ["p", "httpd_t", "/etc/shadow", "read", "deny"],
+  {"member": "httpd_t", "role": "web_domain"}
+]`
+	yamlPolicy := `- subject: httpd_t
+  object: /var/www/*
+  action: read
+  class: file
+  effect: allow
+  cond: httpd_enable
+- [p, httpd_t, /etc/shadow, read, deny]
+- {type: g, member: httpd_t, role: web_domain}
+`
+
+	tmpDir := t.TempDir()
+	write := func(name, data string) string {
+		path := filepath.Join(tmpDir, name)
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		return path
+	}
+
+	want, err := NewParser(write("model.conf", confModel), write("policy.csv", csvPolicy)).Parse()
+	if err != nil {
+		t.Fatalf("Parse() of CSV error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		model  string
+		policy string
+	}{
+		{name: "json", model: write("model.json", jsonModel), policy: write("policy.json", jsonPolicy)},
+		{name: "yaml", model: write("model.yaml", yamlModel), policy: write("policy.yml", yamlPolicy)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewParser(tt.model, tt.policy).Parse()
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if !reflect.DeepEqual(got.Model, want.Model) {
+				t.Errorf("Model = %+v, want %+v", got.Model, want.Model)
+			}
+			if !reflect.DeepEqual(got.Roles, want.Roles) {
+				t.Errorf("Roles = %+v, want %+v", got.Roles, want.Roles)
+			}
+			if len(got.Policies) != len(want.Policies) {
+				t.Fatalf("Expected %d policies, got %d", len(want.Policies), len(got.Policies))
+			}
+			for i := range got.Policies {
+				g, w := got.Policies[i], want.Policies[i]
+				if g.Type != w.Type || g.Subject != w.Subject || g.Object != w.Object || g.Action != w.Action || g.Effect != w.Effect {
+					t.Errorf("Policy %d = %+v, want %+v", i, g, w)
+				}
+			}
+		})
+	}
+}
+
+func TestParseStructuredFormats_Errors(t *testing.T) {
+	modelData := `[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act, eft
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = r.sub == p.sub
+`
+	tests := []struct {
+		name        string
+		file        string
+		data        string
+		wantLine    int
+		errContains string
+	}{
+		{
+			name:        "unknown json key",
+			file:        "policy.json",
+			data:        "[\n  {\"subject\": \"httpd_t\", \"object\": \"/srv/*\", \"action\": \"read\", \"effect\": \"allow\", \"perm\": \"x\"}\n]",
+			wantLine:    2,
+			errContains: "unknown keys in policy rule: perm",
+		},
+		{
+			name:        "invalid json rule",
+			file:        "policy.json",
+			data:        "[\n  [\"p\", \"httpd_t\", \"/srv/*\", \"read\", \"allow\"],\n  {\"subject\": \"httpd_t\", \"action\": \"read\", \"effect\": \"allow\"}\n]",
+			wantLine:    3,
+			errContains: `p rule is missing "object"`,
+		},
+		{
+			name:        "yaml rule type without object form",
+			file:        "policy.yaml",
+			data:        "- [p, httpd_t, /srv/*, read, allow]\n- {type: sid, subject: kernel}\n",
+			wantLine:    2,
+			errContains: "sid rules must be written as an array of fields",
+		},
+		{
+			name:        "yaml scalar rule",
+			file:        "policy.yaml",
+			data:        "- p, httpd_t, /srv/*, read, allow\n",
+			wantLine:    1,
+			errContains: "rule must be an object or a list of fields",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			modelPath := filepath.Join(tmpDir, "model.conf")
+			policyPath := filepath.Join(tmpDir, tt.file)
+			if err := os.WriteFile(modelPath, []byte(modelData), 0644); err != nil {
+				t.Fatalf("Failed to write model file: %v", err)
+			}
+			if err := os.WriteFile(policyPath, []byte(tt.data), 0644); err != nil {
+				t.Fatalf("Failed to write policy file: %v", err)
+			}
+
+			_, err := NewParser(modelPath, policyPath).Parse()
+			parseErr, ok := err.(*ParseError)
+			if !ok {
+				t.Fatalf("Expected ParseError, got %v", err)
+			}
+			if parseErr.Line != tt.wantLine || !strings.Contains(parseErr.Message, tt.errContains) {
+				t.Errorf("Error = %v, want line %d containing %q", parseErr, tt.wantLine, tt.errContains)
+			}
+		})
+	}
+}
+
+func TestParseStructuredModel_Errors(t *testing.T) {
+	tests := []struct {
+		name        string
+		file        string
+		data        string
+		errContains string
+	}{
+		{name: "empty", file: "model.yaml", data: "\n", errContains: "empty model file"},
+		{name: "unknown json section", file: "model.json", data: `{"matcher": "r.sub == p.sub"}`, errContains: "unknown field"},
+		{name: "unknown yaml section", file: "model.yaml", data: "matcher: r.sub == p.sub\n", errContains: "not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			modelPath := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(modelPath, []byte(tt.data), 0644); err != nil {
+				t.Fatalf("Failed to write model file: %v", err)
+			}
+			_, err := NewParser(modelPath, "").parseModel()
+			if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("parseModel() error = %v, want containing %q", err, tt.errContains)
+			}
+		})
+	}
+}
+
+// TestLoadActionMappings tests that an action map defines actions the analyzer and generator map alike
+func TestLoadActionMappings(t *testing.T) {
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "actions.yaml")
+	data := `parser_test_deploy:
+  class: file
+  permissions: [write, create, setattr]
+send:
+  class: tcp_socket
+  permissions: [send]
+`
+	if err := os.WriteFile(yamlPath, []byte(data), 0644); err != nil {
+		t.Fatalf("Failed to write action map: %v", err)
+	}
+	config, err := LoadActionMappings(yamlPath)
+	if err != nil {
+		t.Fatalf("LoadActionMappings() error = %v", err)
+	}
+	mapping.RegisterActionMappings(config)
+
+	decoded := newTestDecodedPML(
+		models.Policy{Type: "p", Subject: "app_t", Object: "/opt/app/releases/*", Action: "parser_test_deploy", Effect: "allow"},
+	)
+	analyzer := NewAnalyzer(decoded)
+	analyzer.SetQuiet(true)
+	if err := analyzer.Analyze(); err != nil {
+		t.Fatalf("Analyze() error = %v", err)
+	}
+	policy, err := NewGenerator(decoded, "app").Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if len(policy.Rules) != 1 || strings.Join(policy.Rules[0].Permissions, " ") != "write create setattr" {
+		t.Errorf("rules = %+v, want one granting write create setattr", policy.Rules)
+	}
+
+	tests := []struct {
+		name        string
+		file        string
+		data        string
+		errContains string
+	}{
+		{name: "unknown permission", file: "actions.json", data: `{"deploy": {"class": "file", "permissions": ["fly"]}}`, errContains: "action 'deploy': class file has no permission fly"},
+		{name: "missing permissions", file: "actions.yaml", data: "deploy:\n  class: file\n", errContains: "action 'deploy': permissions cannot be empty"},
+		{name: "unknown json field", file: "actions.json", data: `{"deploy": {"class": "file", "perms": ["write"]}}`, errContains: "unknown field"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.data), 0644); err != nil {
+				t.Fatalf("Failed to write action map: %v", err)
+			}
+			_, err := LoadActionMappings(path)
+			if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("LoadActionMappings() error = %v, want containing %q", err, tt.errContains)
+			}
+		})
+	}
+}
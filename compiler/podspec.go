@@ -0,0 +1,175 @@
+package compiler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/cici0602/pml-to-selinux/mapping"
+	"gopkg.in/yaml.v3"
+)
+
+// PodMount is a volume mount of one container of a pod spec
+type PodMount struct {
+	Container string // Container name
+	Domain    string // SELinux domain the container runs in
+	MountPath string
+	SubPath   bool // The mount is a single file or directory taken from the volume
+	ReadOnly  bool
+}
+
+// ParsePodSpecFile reads the volume mounts of a Kubernetes manifest.
+// Pods and the pod templates of workloads (Deployments, Jobs, ...) are read,
+// in YAML or JSON; several YAML documents may be separated by '---'.
+func ParsePodSpecFile(path string) ([]PodMount, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pod spec: %w", err)
+	}
+
+	return ParsePodSpec(path, data)
+}
+
+// ParsePodSpec parses the manifest data, see ParsePodSpecFile
+func ParsePodSpec(name string, data []byte) ([]PodMount, error) {
+	var docs []podManifest
+	if trimmed := strings.TrimSpace(string(data)); strings.HasPrefix(trimmed, "{") {
+		var doc podManifest
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("%s: invalid JSON: %w", name, err)
+		}
+		docs = append(docs, doc)
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		for {
+			var doc podManifest
+			err := decoder.Decode(&doc)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			docs = append(docs, doc)
+		}
+	}
+
+	mounts := make([]PodMount, 0)
+	for _, doc := range docs {
+		mounts = append(mounts, podMounts(doc.Spec.podSpec())...)
+	}
+	return mounts, nil
+}
+
+// RenderPodSpecPolicy renders the mounts as a starter PML policy: every mount
+// is readable by its container's domain and writable unless mounted read-only
+func RenderPodSpecPolicy(mounts []PodMount) string {
+	var builder strings.Builder
+
+	builder.WriteString("# Generated from a pod spec by pml2selinux import\n")
+	builder.WriteString("# Review every rule before use: each one grants access to a declared volume mount\n")
+
+	container := ""
+	for _, mount := range mounts {
+		if mount.Container != container {
+			container = mount.Container
+			builder.WriteString(fmt.Sprintf("\n# Container %s\n", container))
+		}
+
+		object := strings.TrimSuffix(mount.MountPath, "/") + "/*"
+		if mount.SubPath {
+			object = mount.MountPath
+		}
+		builder.WriteString(fmt.Sprintf("p, %s, %s, read, allow\n", mount.Domain, object))
+		if !mount.ReadOnly {
+			builder.WriteString(fmt.Sprintf("p, %s, %s, write, allow\n", mount.Domain, object))
+		}
+	}
+
+	return builder.String()
+}
+
+// podManifest holds the parts of a Kubernetes manifest the importer reads
+type podManifest struct {
+	Spec workloadSpec `json:"spec" yaml:"spec"`
+}
+
+// workloadSpec is the spec of a Pod, of a workload holding a pod template,
+// or of a CronJob holding a job template
+type workloadSpec struct {
+	JobTemplate *podTemplate `json:"jobTemplate" yaml:"jobTemplate"`
+	Template    *podTemplate `json:"template" yaml:"template"`
+
+	SecurityContext securityContext `json:"securityContext" yaml:"securityContext"`
+	InitContainers  []podContainer  `json:"initContainers" yaml:"initContainers"`
+	Containers      []podContainer  `json:"containers" yaml:"containers"`
+}
+
+type podTemplate struct {
+	Spec workloadSpec `json:"spec" yaml:"spec"`
+}
+
+type securityContext struct {
+	SELinuxOptions struct {
+		Type string `json:"type" yaml:"type"`
+	} `json:"seLinuxOptions" yaml:"seLinuxOptions"`
+}
+
+type podContainer struct {
+	Name            string          `json:"name" yaml:"name"`
+	SecurityContext securityContext `json:"securityContext" yaml:"securityContext"`
+	VolumeMounts    []volumeMount   `json:"volumeMounts" yaml:"volumeMounts"`
+}
+
+type volumeMount struct {
+	MountPath string `json:"mountPath" yaml:"mountPath"`
+	SubPath   string `json:"subPath" yaml:"subPath"`
+	ReadOnly  bool   `json:"readOnly" yaml:"readOnly"`
+}
+
+// podSpec returns the pod spec of a Pod or the pod template of a workload
+func (spec workloadSpec) podSpec() workloadSpec {
+	if spec.JobTemplate != nil {
+		spec = spec.JobTemplate.Spec
+	}
+	if spec.Template != nil {
+		return spec.Template.Spec
+	}
+	return spec
+}
+
+// podMounts lists the volume mounts of the containers and init containers of
+// a pod spec. A container runs in the seLinuxOptions type of its own security
+// context or the pod's, or else in a domain named after the container.
+func podMounts(spec workloadSpec) []PodMount {
+	mounts := make([]PodMount, 0)
+	podType := spec.SecurityContext.SELinuxOptions.Type
+
+	containers := append(append([]podContainer(nil), spec.InitContainers...), spec.Containers...)
+	for _, container := range containers {
+		domain := container.SecurityContext.SELinuxOptions.Type
+		if domain == "" {
+			domain = podType
+		}
+		if domain == "" {
+			domain = mapping.SanitizeTypeName(container.Name) + "_t"
+		}
+
+		for _, mount := range container.VolumeMounts {
+			if !strings.HasPrefix(mount.MountPath, "/") {
+				continue
+			}
+			mounts = append(mounts, PodMount{
+				Container: container.Name,
+				Domain:    domain,
+				MountPath: mount.MountPath,
+				SubPath:   mount.SubPath != "",
+				ReadOnly:  mount.ReadOnly,
+			})
+		}
+	}
+	return mounts
+}
@@ -0,0 +1,111 @@
+package compiler
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParsePodSpec(t *testing.T) {
+	manifest := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web # the app
+spec:
+  template:
+    spec:
+      containers:
+      - name: nginx
+        securityContext:
+          seLinuxOptions:
+            type: "web_container_t"
+        volumeMounts:
+        - name: html
+          mountPath: /usr/share/nginx/html
+          readOnly: true
+        - name: conf
+          mountPath: /etc/nginx/nginx.conf
+          subPath: nginx.conf
+      - name: log-shipper
+        volumeMounts:
+          - name: logs
+            mountPath: /var/log/app
+---
+kind: Pod
+spec:
+  containers:
+  - name: tool
+    volumeMounts: []
+`
+	mounts, err := ParsePodSpec("pod.yaml", []byte(manifest))
+	if err != nil {
+		t.Fatalf("ParsePodSpec() error = %v", err)
+	}
+	want := []PodMount{
+		{Container: "nginx", Domain: "web_container_t", MountPath: "/usr/share/nginx/html", ReadOnly: true},
+		{Container: "nginx", Domain: "web_container_t", MountPath: "/etc/nginx/nginx.conf", SubPath: true},
+		{Container: "log-shipper", Domain: "log_shipper_t", MountPath: "/var/log/app"},
+	}
+	if len(mounts) != len(want) {
+		t.Fatalf("ParsePodSpec() = %+v, want %+v", mounts, want)
+	}
+	for i := range want {
+		if mounts[i] != want[i] {
+			t.Errorf("mount %d = %+v, want %+v", i, mounts[i], want[i])
+		}
+	}
+
+	policy := RenderPodSpecPolicy(mounts)
+	for _, line := range []string{
+		"p, web_container_t, /usr/share/nginx/html/*, read, allow\n",
+		"p, web_container_t, /etc/nginx/nginx.conf, write, allow\n",
+		"p, log_shipper_t, /var/log/app/*, write, allow\n",
+	} {
+		if !strings.Contains(policy, line) {
+			t.Errorf("policy missing %q, got:\n%s", line, policy)
+		}
+	}
+	if strings.Contains(policy, "html/*, write") {
+		t.Errorf("read-only mount should not be writable, got:\n%s", policy)
+	}
+}
+
+func TestParsePodSpec_JSON(t *testing.T) {
+	manifest := `{"kind": "Pod", "spec": {"securityContext": {"seLinuxOptions": {"type": "app_t"}},
+		"containers": [{"name": "app", "volumeMounts": [{"mountPath": "/data", "readOnly": true}]}]}}`
+	mounts, err := ParsePodSpec("pod.json", []byte(manifest))
+	if err != nil {
+		t.Fatalf("ParsePodSpec() error = %v", err)
+	}
+	if len(mounts) != 1 || mounts[0].Domain != "app_t" || !mounts[0].ReadOnly {
+		t.Errorf("ParsePodSpec() = %+v", mounts)
+	}
+}
+
+func TestParsePodSpec_InvalidYAML(t *testing.T) {
+	if _, err := ParsePodSpec("pod.yaml", []byte("spec:\n  containers\n")); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected a line 2 error, got %v", err)
+	}
+}
+
+func TestParsePodSpec_FlowAndBlockScalars(t *testing.T) {
+	manifest := `kind: Pod
+spec:
+  containers:
+  - name: web
+    image: nginx
+    args:
+    - |
+      exec nginx
+      -g 'daemon off;'
+    volumeMounts:
+    - {name: cfg, mountPath: /etc/web, readOnly: true}
+`
+	mounts, err := ParsePodSpec("pod.yaml", []byte(manifest))
+	if err != nil {
+		t.Fatalf("ParsePodSpec() error = %v", err)
+	}
+	want := PodMount{Container: "web", Domain: "web_t", MountPath: "/etc/web", ReadOnly: true}
+	if len(mounts) != 1 || mounts[0] != want {
+		t.Errorf("ParsePodSpec() = %+v, want [%+v]", mounts, want)
+	}
+}
@@ -0,0 +1,80 @@
+package compiler
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// ProjectTemplateData holds the variables available to project templates
+type ProjectTemplateData struct {
+	ProjectName string // e.g. myapp
+	Domain      string // Main domain type, e.g. myapp_t
+	Year        int    // Current year, for copyright headers
+}
+
+// RenderProjectTemplates executes every *.tmpl file under templateDir, nested
+// directories included, with text/template and writes the result, without the
+// .tmpl suffix, to the same relative path in projectDir. All templates are
+// rendered before any file is written, so a failing template leaves no partial
+// project behind.
+func RenderProjectTemplates(templateDir, projectDir string) ([]string, error) {
+	data := ProjectTemplateData{
+		ProjectName: filepath.Base(projectDir),
+		Domain:      filepath.Base(projectDir) + "_t",
+		Year:        time.Now().Year(),
+	}
+
+	rendered := make(map[string]string)
+	var generated []string
+	err := filepath.WalkDir(templateDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || !strings.HasSuffix(path, ".tmpl") {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		tmpl, err := template.New(entry.Name()).Option("missingkey=error").Parse(string(content))
+		if err != nil {
+			return err
+		}
+		var output strings.Builder
+		if err := tmpl.Execute(&output, data); err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(templateDir, strings.TrimSuffix(path, ".tmpl"))
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(projectDir, rel)
+		rendered[target] = output.String()
+		generated = append(generated, target)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(generated) == 0 {
+		return nil, fmt.Errorf("no *.tmpl files in %s", templateDir)
+	}
+
+	for _, target := range generated {
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(target, []byte(rendered[target]), 0644); err != nil {
+			return nil, err
+		}
+	}
+	return generated, nil
+}
@@ -0,0 +1,93 @@
+package compiler
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTemplates creates the given template files under a temporary directory
+func writeTemplates(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestRenderProjectTemplates(t *testing.T) {
+	templateDir := writeTemplates(t, map[string]string{
+		"model.conf.tmpl":          "# model for {{.ProjectName}}\n",
+		"policy.csv.tmpl":          "p, {{.Domain}}, /opt/{{.ProjectName}}/*, read, allow\n",
+		"docs/nested/README.tmpl":  "# {{.ProjectName}}\n",
+		"notes.txt":                "not a template",
+		"docs/nested/ignored.conf": "{{.Missing}}",
+	})
+	projectDir := filepath.Join(t.TempDir(), "myapp")
+
+	generated, err := RenderProjectTemplates(templateDir, projectDir)
+	if err != nil {
+		t.Fatalf("RenderProjectTemplates() error = %v", err)
+	}
+	if len(generated) != 3 {
+		t.Errorf("generated = %v, want the three .tmpl files", generated)
+	}
+
+	want := map[string]string{
+		"model.conf":         "# model for myapp\n",
+		"policy.csv":         "p, myapp_t, /opt/myapp/*, read, allow\n",
+		"docs/nested/README": "# myapp\n",
+	}
+	for name, content := range want {
+		got, err := os.ReadFile(filepath.Join(projectDir, name))
+		if err != nil {
+			t.Errorf("%s not written: %v", name, err)
+			continue
+		}
+		if string(got) != content {
+			t.Errorf("%s = %q, want %q", name, got, content)
+		}
+	}
+	for _, name := range []string{"notes.txt", "notes", "docs/nested/ignored.conf"} {
+		if _, err := os.Stat(filepath.Join(projectDir, name)); err == nil {
+			t.Errorf("%s should not be copied into the project", name)
+		}
+	}
+}
+
+func TestRenderProjectTemplates_MissingKey(t *testing.T) {
+	templateDir := writeTemplates(t, map[string]string{
+		"a.conf.tmpl": "{{.ProjectName}}\n",
+		"b.conf.tmpl": "{{.Owner}}\n",
+	})
+	projectDir := filepath.Join(t.TempDir(), "myapp")
+
+	_, err := RenderProjectTemplates(templateDir, projectDir)
+	if err == nil || !strings.Contains(err.Error(), "Owner") {
+		t.Fatalf("expected an error naming the unknown variable, got %v", err)
+	}
+	if _, err := os.Stat(projectDir); err == nil {
+		t.Error("a failing template should leave no files behind")
+	}
+}
+
+func TestRenderProjectTemplates_Empty(t *testing.T) {
+	templateDir := writeTemplates(t, map[string]string{"README.md": "plain file"})
+
+	_, err := RenderProjectTemplates(templateDir, filepath.Join(t.TempDir(), "myapp"))
+	if err == nil || !strings.Contains(err.Error(), "no *.tmpl files") {
+		t.Errorf("expected an error for a directory without templates, got %v", err)
+	}
+
+	if _, err := RenderProjectTemplates(filepath.Join(t.TempDir(), "missing"), t.TempDir()); err == nil {
+		t.Error("expected an error for a missing template directory")
+	}
+}
@@ -0,0 +1,118 @@
+package compiler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cici0602/pml-to-selinux/mapping"
+	"github.com/cici0602/pml-to-selinux/models"
+)
+
+// QueryResult answers whether a subject may perform an action on an object
+type QueryResult struct {
+	Allowed     bool
+	SourceType  string
+	TargetType  string
+	Class       string
+	Permissions []string
+	Rule        *models.AllowRule // Rule granting the access, if allowed
+	Reason      string            // Why the access is allowed or denied
+}
+
+// Query simulates an access check against the generated policy.
+// The object path is labeled through the file contexts (most specific match wins),
+// then the allow rules are searched for one granting every required permission.
+func Query(policy *models.SELinuxPolicy, decoded *models.DecodedPML, subject, object, action string) *QueryResult {
+	pathMapper := mapping.NewPathMapper()
+	typeMapper := mapping.NewTypeMapper(policy.ModuleName)
+	class, perms := mapping.NewActionMapper().MapAction(action, "")
+
+	result := &QueryResult{
+		SourceType:  typeMapper.SubjectToType(subject),
+		Class:       class,
+		Permissions: perms,
+	}
+
+	// Label the object using the most specific matching file context
+	var label *models.FileContext
+	for i, fc := range policy.FileContexts {
+		matched, err := pathMapper.MatchPattern(fc.PathPattern, object)
+		if err != nil || !matched {
+			continue
+		}
+		if label == nil || len(fc.PathPattern) > len(label.PathPattern) {
+			label = &policy.FileContexts[i]
+		}
+	}
+	if label == nil {
+		result.Reason = fmt.Sprintf("denied: no file context in module '%s' labels %s", policy.ModuleName, object)
+		return result
+	}
+	result.TargetType = label.SELinuxType
+
+	// Collect the permissions granted for source → target:class
+	granted := make(map[string]bool)
+	for i, rule := range policy.Rules {
+		if rule.SourceType != result.SourceType || rule.TargetType != result.TargetType ||
+			!containsAttribute(rule.AllClasses(), class) {
+			continue
+		}
+		for _, perm := range rule.Permissions {
+			granted[perm] = true
+		}
+		if result.Rule == nil {
+			result.Rule = &policy.Rules[i]
+		}
+	}
+
+	missing := make([]string, 0)
+	for _, perm := range perms {
+		if !granted[perm] {
+			missing = append(missing, perm)
+		}
+	}
+
+	switch {
+	case len(missing) == 0:
+		result.Allowed = true
+		result.Reason = fmt.Sprintf("allowed: %s %s:%s grants { %s }",
+			result.SourceType, result.TargetType, class, strings.Join(perms, " "))
+	case len(granted) == 0:
+		result.Rule = nil
+		result.Reason = fmt.Sprintf("denied: no allow rule for %s %s:%s", result.SourceType, result.TargetType, class)
+	default:
+		result.Rule = nil
+		result.Reason = fmt.Sprintf("denied: missing permissions { %s } for %s %s:%s",
+			strings.Join(missing, " "), result.SourceType, result.TargetType, class)
+	}
+
+	if deny := matchingDeny(decoded, pathMapper, subject, object, action); deny != nil {
+		if result.Allowed {
+			// SELinux has no deny rules, a deny that lost to an allow is simply dropped
+			result.Reason += fmt.Sprintf(" (deny rule '%s, %s, %s' was dropped by the policy effect)",
+				deny.Subject, deny.Object, deny.Action)
+		} else {
+			result.Reason += fmt.Sprintf(" (deny rule '%s, %s, %s' matches)",
+				deny.Subject, deny.Object, deny.Action)
+		}
+	}
+
+	return result
+}
+
+// matchingDeny returns the first deny rule covering the queried access
+func matchingDeny(decoded *models.DecodedPML, pathMapper *mapping.PathMapper, subject, object, action string) *models.DecodedPolicy {
+	if decoded == nil {
+		return nil
+	}
+	for i, policy := range decoded.Policies {
+		if policy.Effect != "deny" || policy.Subject != subject || policy.Action != action {
+			continue
+		}
+		pattern := pathMapper.ConvertToSELinuxPattern(policy.Object)
+		if matched, err := pathMapper.MatchPattern(pattern, object); err == nil && matched {
+			return &decoded.Policies[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package compiler
+
+import (
+	"testing"
+
+	"github.com/cici0602/pml-to-selinux/models"
+)
+
+func TestQuery(t *testing.T) {
+	policy := &models.SELinuxPolicy{
+		ModuleName: "httpd",
+		Rules: []models.AllowRule{
+			{SourceType: "httpd_t", TargetType: "httpd_var_www_t", Class: "file", Permissions: []string{"read", "open", "getattr"}},
+			{SourceType: "httpd_t", TargetType: "httpd_var_www_uploads_t", Class: "file", Permissions: []string{"read"}},
+		},
+		FileContexts: []models.FileContext{
+			{PathPattern: "/var/www(/.*)?", SELinuxType: "httpd_var_www_t"},
+			{PathPattern: "/var/www/uploads(/.*)?", SELinuxType: "httpd_var_www_uploads_t"},
+		},
+	}
+	decoded := &models.DecodedPML{
+		Policies: []models.DecodedPolicy{
+			{Policy: models.Policy{Subject: "httpd", Object: "/var/www/uploads/*", Action: "write", Effect: "deny"}},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		object     string
+		action     string
+		allowed    bool
+		targetType string
+		reason     string
+	}{
+		{name: "allowed", object: "/var/www/index.html", action: "read", allowed: true, targetType: "httpd_var_www_t", reason: "allowed:"},
+		{name: "most specific context wins", object: "/var/www/uploads/a.png", action: "read", allowed: false, targetType: "httpd_var_www_uploads_t", reason: "missing permissions { open getattr }"},
+		{name: "no allow rule", object: "/var/www/index.html", action: "execute", allowed: false, targetType: "httpd_var_www_t", reason: "missing permissions"},
+		{name: "unlabeled object", object: "/etc/shadow", action: "read", allowed: false, reason: "no file context"},
+		{name: "deny rule explains denial", object: "/var/www/uploads/a.png", action: "write", allowed: false, targetType: "httpd_var_www_uploads_t", reason: "deny rule 'httpd, /var/www/uploads/*, write' matches"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Query(policy, decoded, "httpd", tt.object, tt.action)
+			if result.SourceType != "httpd_t" {
+				t.Errorf("SourceType = %q, want httpd_t", result.SourceType)
+			}
+			if result.Allowed != tt.allowed {
+				t.Errorf("Allowed = %v, want %v (%s)", result.Allowed, tt.allowed, result.Reason)
+			}
+			if result.TargetType != tt.targetType {
+				t.Errorf("TargetType = %q, want %q", result.TargetType, tt.targetType)
+			}
+			if !contains(result.Reason, tt.reason) {
+				t.Errorf("Reason = %q, want it to contain %q", result.Reason, tt.reason)
+			}
+		})
+	}
+}
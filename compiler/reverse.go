@@ -0,0 +1,225 @@
+package compiler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cici0602/pml-to-selinux/mapping"
+	"github.com/cici0602/pml-to-selinux/models"
+)
+
+// ReverseResult is a parsed .te module lowered into PML policy rows
+type ReverseResult struct {
+	ModuleName string
+	Policies   []models.Policy
+	Warnings   []string // Constructs PML cannot represent, or represents only approximately
+}
+
+// reverseAction is a default action and the permissions it grants on its class
+type reverseAction struct {
+	name        string
+	permissions []string
+}
+
+// Reverse lowers an SELinux policy back into PML rows. Each allow rule's
+// permissions are collapsed into the default actions whose permission sets they
+// contain, largest first, so { read open getattr } on a file becomes read. The
+// permissions left over are kept verbatim as perm::class rows, the way the AVC
+// importer writes them. Type transitions become p2 transition rows.
+func Reverse(policy *models.SELinuxPolicy) *ReverseResult {
+	result := &ReverseResult{ModuleName: policy.ModuleName}
+	mapper := mapping.NewActionMapper()
+	actions := reverseActions(mapper)
+
+	for _, rule := range policy.Rules {
+		for _, class := range rule.AllClasses() {
+			for _, action := range collapsePermissions(rule.Permissions, actions[class]) {
+				result.Policies = append(result.Policies, models.Policy{
+					Type:    "p",
+					Subject: rule.SourceType,
+					Object:  rule.TargetType,
+					Action:  action + "::" + class,
+					Effect:  "allow",
+				})
+			}
+			for _, perm := range leftoverPermissions(rule.Permissions, actions[class]) {
+				// A raw permission spelled like an action is read as that action
+				if granted := mapper.MapActionWithClass(perm, class); len(granted) != 1 || granted[0] != perm {
+					result.Warnings = append(result.Warnings, fmt.Sprintf(
+						"allow %s %s:%s %s: PML reads '%s' as an action granting { %s }, more than the original rule",
+						rule.SourceType, rule.TargetType, class, perm, perm, strings.Join(granted, " ")))
+				}
+				result.Policies = append(result.Policies, models.Policy{
+					Type:    "p",
+					Subject: rule.SourceType,
+					Object:  rule.TargetType,
+					Action:  perm + "::" + class,
+					Effect:  "allow",
+				})
+			}
+		}
+	}
+
+	for _, trans := range policy.Transitions {
+		result.Policies = append(result.Policies, models.Policy{
+			Type:    "p2",
+			Subject: trans.SourceType,
+			Object:  trans.TargetType + "::" + trans.Class,
+			Action:  "transition",
+			Effect:  trans.NewType,
+		})
+	}
+
+	for _, rule := range policy.AuditRules {
+		result.Warnings = append(result.Warnings, fmt.Sprintf(
+			"auditallow %s %s:%s { %s }: PML audits through @audit=true on an allow rule, add it by hand",
+			rule.SourceType, rule.TargetType, rule.ClassSpec(), strings.Join(rule.Permissions, " ")))
+	}
+	for _, rule := range policy.CondRules {
+		result.Warnings = append(result.Warnings, fmt.Sprintf(
+			"if (%s) allow %s %s:%s { %s }: conditional rules take a ?cond= object suffix, add it by hand",
+			rule.Condition, rule.SourceType, rule.TargetType, rule.ClassSpec(), strings.Join(rule.Permissions, " ")))
+	}
+	for _, rule := range policy.OptionalRules {
+		result.Warnings = append(result.Warnings, fmt.Sprintf(
+			"optional_policy %s: allow %s %s:%s { %s }: optional rules take an @optional= object suffix, add it by hand",
+			rule.Optional, rule.SourceType, rule.TargetType, rule.ClassSpec(), strings.Join(rule.Permissions, " ")))
+	}
+	for _, rule := range policy.DontauditRules {
+		result.Warnings = append(result.Warnings, fmt.Sprintf(
+			"dontaudit %s %s:%s { %s }: add it by hand as a dontaudit rule",
+			rule.SourceType, rule.TargetType, rule.ClassSpec(), strings.Join(rule.Permissions, " ")))
+	}
+	for _, rule := range policy.NeverallowRules {
+		result.Warnings = append(result.Warnings, fmt.Sprintf(
+			"neverallow %s %s:%s { %s }: add it by hand as a deny rule",
+			rule.SourceType, rule.TargetType, rule.Class, strings.Join(rule.Permissions, " ")))
+	}
+	for _, call := range policy.InterfaceCalls {
+		result.Warnings = append(result.Warnings, fmt.Sprintf(
+			"%s(%s): interface calls are i rows, add it by hand",
+			call.Name, strings.Join(call.Args, ", ")))
+	}
+	for _, trans := range policy.NamedTransitions {
+		result.Warnings = append(result.Warnings, fmt.Sprintf(
+			"type_transition %s %s:%s %s \"%s\": named transitions are ft declarations, not policy rows: ft, %s, %s, %s, %s, \"%s\"",
+			trans.SourceType, trans.TargetType, trans.Class, trans.NewType, trans.Filename,
+			trans.SourceType, trans.TargetType, trans.Class, trans.NewType, trans.Filename))
+	}
+	for _, decl := range policy.Types {
+		if len(decl.Attributes) > 0 {
+			result.Warnings = append(result.Warnings, fmt.Sprintf(
+				"type %s, %s: attributes are inferred from the rules, check the compiled module declares the same ones",
+				decl.TypeName, strings.Join(decl.Attributes, ", ")))
+		}
+	}
+
+	return result
+}
+
+// reverseActions returns the default actions of each class, largest permission
+// set first. Of actions with equal sets the one named after a permission it
+// grants wins, so read is preferred over mmap while map is disabled.
+func reverseActions(mapper *mapping.ActionMapper) map[string][]reverseAction {
+	byClass := make(map[string][]reverseAction)
+	for name, perm := range mapper.ExportMappings() {
+		byClass[perm.Class] = append(byClass[perm.Class], reverseAction{
+			name:        name,
+			permissions: mapper.MapActionWithClass(name, perm.Class),
+		})
+	}
+
+	for _, actions := range byClass {
+		sort.Slice(actions, func(i, j int) bool {
+			a, b := actions[i], actions[j]
+			if len(a.permissions) != len(b.permissions) {
+				return len(a.permissions) > len(b.permissions)
+			}
+			aNamed, bNamed := containsAttribute(a.permissions, a.name), containsAttribute(b.permissions, b.name)
+			if aNamed != bNamed {
+				return aNamed
+			}
+			return a.name < b.name
+		})
+	}
+	return byClass
+}
+
+// collapsePermissions returns the actions whose permissions all appear in perms,
+// taking each only when it covers a permission no earlier action did
+func collapsePermissions(perms []string, actions []reverseAction) []string {
+	granted := make(map[string]bool, len(perms))
+	for _, perm := range perms {
+		granted[perm] = true
+	}
+
+	covered := make(map[string]bool)
+	var chosen []string
+	for _, action := range actions {
+		contained, adds := true, false
+		for _, perm := range action.permissions {
+			if !granted[perm] {
+				contained = false
+				break
+			}
+			if !covered[perm] {
+				adds = true
+			}
+		}
+		if !contained || !adds {
+			continue
+		}
+		chosen = append(chosen, action.name)
+		for _, perm := range action.permissions {
+			covered[perm] = true
+		}
+	}
+	return chosen
+}
+
+// leftoverPermissions returns the permissions of perms no collapsed action covers
+func leftoverPermissions(perms []string, actions []reverseAction) []string {
+	covered := make(map[string]bool)
+	for _, name := range collapsePermissions(perms, actions) {
+		for _, action := range actions {
+			if action.name != name {
+				continue
+			}
+			for _, perm := range action.permissions {
+				covered[perm] = true
+			}
+		}
+	}
+
+	var leftover []string
+	for _, perm := range uniqueStringSlice(perms) {
+		if !covered[perm] {
+			leftover = append(leftover, perm)
+		}
+	}
+	return leftover
+}
+
+// RenderReversePolicy renders decompiled rows as a PML policy file, followed by
+// the warnings as comments so nothing that was dropped goes unnoticed
+func RenderReversePolicy(result *ReverseResult) string {
+	var builder strings.Builder
+
+	builder.WriteString(fmt.Sprintf("# Decompiled from module %s by pml2selinux decompile\n", result.ModuleName))
+	builder.WriteString("# Objects are the original target types; replace them with paths where the types label files\n\n")
+
+	for _, policy := range result.Policies {
+		builder.WriteString(fmt.Sprintf("%s, %s, %s, %s, %s\n",
+			policy.Type, policy.Subject, policy.Object, policy.Action, policy.Effect))
+	}
+
+	if len(result.Warnings) > 0 {
+		builder.WriteString("\n# Not decompiled:\n")
+		for _, warning := range result.Warnings {
+			builder.WriteString(fmt.Sprintf("# %s\n", warning))
+		}
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,121 @@
+package compiler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cici0602/pml-to-selinux/models"
+)
+
+func TestReverse(t *testing.T) {
+	tests := []struct {
+		name         string
+		rule         models.AllowRule
+		wantActions  []string
+		wantWarnings int
+	}{
+		{
+			name:        "read collapses",
+			rule:        models.AllowRule{SourceType: "app_t", TargetType: "etc_t", Class: "file", Permissions: []string{"getattr", "open", "read"}},
+			wantActions: []string{"read::file"},
+		},
+		{
+			name:        "read and write",
+			rule:        models.AllowRule{SourceType: "app_t", TargetType: "log_t", Class: "file", Permissions: []string{"read", "open", "getattr", "write", "append"}},
+			wantActions: []string{"read::file", "write::file"},
+		},
+		{
+			name:        "execute prefers the largest action",
+			rule:        models.AllowRule{SourceType: "app_t", TargetType: "bin_t", Class: "file", Permissions: []string{"execute", "read", "open", "getattr", "execute_no_trans"}},
+			wantActions: []string{"execute::file"},
+		},
+		{
+			name:        "unmapped permissions kept verbatim",
+			rule:        models.AllowRule{SourceType: "app_t", TargetType: "etc_t", Class: "file", Permissions: []string{"read", "open", "getattr", "ioctl", "lock"}},
+			wantActions: []string{"read::file", "ioctl::file", "lock::file"},
+		},
+		{
+			name:        "class set",
+			rule:        models.AllowRule{SourceType: "app_t", TargetType: "etc_t", Class: "file", Classes: []string{"file", "lnk_file"}, Permissions: []string{"read"}},
+			wantActions: []string{"read::file", "read::lnk_file"},
+			// read alone is read as the read action, which also grants open and getattr, on both classes
+			wantWarnings: 2,
+		},
+		{
+			name:        "capability",
+			rule:        models.AllowRule{SourceType: "app_t", TargetType: "self", Class: "capability", Permissions: []string{"net_bind_service"}},
+			wantActions: []string{"net_bind_service::capability"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := models.NewSELinuxPolicy("app", "1.0")
+			policy.AddAllowRule(tt.rule)
+
+			result := Reverse(policy)
+			var actions []string
+			for _, p := range result.Policies {
+				if p.Subject != tt.rule.SourceType || p.Object != tt.rule.TargetType || p.Effect != "allow" {
+					t.Errorf("unexpected row %+v", p)
+				}
+				actions = append(actions, p.Action)
+			}
+			if strings.Join(actions, " ") != strings.Join(tt.wantActions, " ") {
+				t.Errorf("actions = %v, want %v", actions, tt.wantActions)
+			}
+			if len(result.Warnings) != tt.wantWarnings {
+				t.Errorf("warnings = %v, want %d", result.Warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestReverse_TransitionsAndWarnings(t *testing.T) {
+	policy := models.NewSELinuxPolicy("app", "1.0")
+	policy.AddType("app_exec_t", "exec_type", "file_type")
+	policy.AddTransition(models.TypeTransition{SourceType: "init_t", TargetType: "app_exec_t", Class: "process", NewType: "app_t"})
+	policy.NamedTransitions = append(policy.NamedTransitions, models.NamedTransition{
+		SourceType: "app_t", TargetType: "tmp_t", Class: "file", NewType: "app_cache_t", Filename: "cache.db",
+	})
+	policy.AddAuditRule(models.AllowRule{SourceType: "app_t", TargetType: "etc_t", Class: "file", Permissions: []string{"read"}})
+
+	result := Reverse(policy)
+	if len(result.Policies) != 1 {
+		t.Fatalf("expected 1 row, got %+v", result.Policies)
+	}
+	want := models.Policy{Type: "p2", Subject: "init_t", Object: "app_exec_t::process", Action: "transition", Effect: "app_t"}
+	if result.Policies[0] != want {
+		t.Errorf("transition row = %+v, want %+v", result.Policies[0], want)
+	}
+	if len(result.Warnings) != 3 {
+		t.Errorf("expected warnings for the auditallow, named transition and attributes, got %v", result.Warnings)
+	}
+
+	rendered := RenderReversePolicy(result)
+	for _, line := range []string{
+		"p2, init_t, app_exec_t::process, transition, app_t\n",
+		`# type_transition app_t tmp_t:file app_cache_t "cache.db"`,
+	} {
+		if !strings.Contains(rendered, line) {
+			t.Errorf("rendered policy missing %q:\n%s", line, rendered)
+		}
+	}
+}
+
+func TestReverse_GuardedRuleWarnings(t *testing.T) {
+	policy := models.NewSELinuxPolicy("app", "1.0")
+	policy.CondRules = []models.AllowRule{{SourceType: "app_t", TargetType: "etc_t", Class: "file", Permissions: []string{"write"}, Condition: "app_write"}}
+	policy.OptionalRules = []models.AllowRule{{SourceType: "app_t", TargetType: "bin_t", Class: "file", Permissions: []string{"execute"}, Optional: "bar"}}
+	policy.DontauditRules = []models.AllowRule{{SourceType: "app_t", TargetType: "tmp_t", Class: "dir", Permissions: []string{"search"}}}
+	policy.AddNeverallowRule(models.NeverallowRule{SourceType: "app_t", TargetType: "shadow_t", Class: "file", Permissions: []string{"read"}})
+	policy.InterfaceCalls = []models.InterfaceCall{{Name: "domain_type", Args: []string{"app_t"}}}
+
+	result := Reverse(policy)
+	if len(result.Policies) != 0 {
+		t.Errorf("guarded rules must not become plain rows, got %+v", result.Policies)
+	}
+	if len(result.Warnings) != 5 {
+		t.Errorf("expected a warning per guarded rule and call, got %v", result.Warnings)
+	}
+}
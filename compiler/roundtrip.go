@@ -0,0 +1,55 @@
+package compiler
+
+import (
+	"strings"
+
+	"github.com/cici0602/pml-to-selinux/mapping"
+	"github.com/cici0602/pml-to-selinux/models"
+)
+
+// RoundtripFailure is a path object whose file-context patterns do not match
+// a concrete path of the object, which points at a bug in the path conversion
+type RoundtripFailure struct {
+	Object   string   // Casbin path pattern of the policy
+	Sample   string   // Concrete path instantiated from the object
+	Patterns []string // SELinux regexes generated for the object
+	Err      error    // Set when a generated regex does not compile
+}
+
+// CheckRegexRoundtrip converts every path object to its file-context patterns
+// and checks that the object, with its wildcards filled in, matches one of them
+func CheckRegexRoundtrip(decoded *models.DecodedPML) []RoundtripFailure {
+	pathMapper := mapping.NewPathMapper()
+	failures := make([]RoundtripFailure, 0)
+	seen := make(map[string]bool)
+
+	for _, policy := range decoded.Policies {
+		if !strings.HasPrefix(policy.Object, "/") || seen[policy.Object] {
+			continue
+		}
+		seen[policy.Object] = true
+
+		failure := RoundtripFailure{
+			Object: policy.Object,
+			Sample: instantiatePattern(policy.Object),
+		}
+		matched := false
+		for _, pattern := range pathMapper.GenerateRecursivePatterns(policy.Object) {
+			failure.Patterns = append(failure.Patterns, pattern.Pattern)
+			ok, err := pathMapper.MatchPattern(pattern.Pattern, failure.Sample)
+			if err != nil {
+				failure.Err = err
+				break
+			}
+			if ok {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			failures = append(failures, failure)
+		}
+	}
+
+	return failures
+}
@@ -0,0 +1,30 @@
+package compiler
+
+import (
+	"testing"
+
+	"github.com/cici0602/pml-to-selinux/models"
+)
+
+func TestCheckRegexRoundtrip(t *testing.T) {
+	decoded := newTestDecodedPML(
+		models.Policy{Type: "p", Subject: "app_t", Object: "/var/www/*", Action: "read", Effect: "allow"},
+		models.Policy{Type: "p", Subject: "app_t", Object: "/srv/{a,b}/*.html", Action: "read", Effect: "allow"},
+		models.Policy{Type: "p", Subject: "app_t", Object: "/opt/app(/.*)?", Action: "read", Effect: "allow"},
+		models.Policy{Type: "p", Subject: "app_t", Object: "/etc/app.conf", Action: "read", Effect: "allow"},
+		models.Policy{Type: "p", Subject: "app_t", Object: `/var/data/file\[1\].txt`, Action: "read", Effect: "allow"},
+		models.Policy{Type: "p", Subject: "app_t", Object: `/srv/\{raw\}/*`, Action: "read", Effect: "allow"},
+		models.Policy{Type: "p", Subject: "app_t", Object: "tcp:8080", Action: "name_bind", Effect: "allow"},
+	)
+	if failures := CheckRegexRoundtrip(decoded); len(failures) != 0 {
+		t.Errorf("CheckRegexRoundtrip() = %+v, want no failures", failures)
+	}
+
+	decoded = newTestDecodedPML(
+		models.Policy{Type: "p", Subject: "app_t", Object: "/srv/[z-a]", Action: "read", Effect: "allow"},
+	)
+	failures := CheckRegexRoundtrip(decoded)
+	if len(failures) != 1 || failures[0].Object != "/srv/[z-a]" {
+		t.Fatalf("CheckRegexRoundtrip() = %+v, want one failure", failures)
+	}
+}
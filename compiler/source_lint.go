@@ -0,0 +1,113 @@
+package compiler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cici0602/pml-to-selinux/mapping"
+	"github.com/cici0602/pml-to-selinux/models"
+)
+
+// SourceIssue is a problem found in the policy source before decoding
+type SourceIssue struct {
+	File    string
+	Line    int
+	Code    string // "duplicate" or "subsumed"
+	Message string
+}
+
+// String renders the issue with its source position
+func (i SourceIssue) String() string {
+	return fmt.Sprintf("%s:%d: %s", i.File, i.Line, i.Message)
+}
+
+// LintSource reports exact-duplicate policy rules and rules whose permissions
+// are subsumed by another rule on the same subject, object, class and effect,
+// which the optimizer would merge away. Both usually indicate copy-paste errors.
+func LintSource(policies []models.Policy) []SourceIssue {
+	var issues []SourceIssue
+
+	// Exact duplicates: same rule type, subject, object (including class), action and effect
+	firstSeen := make(map[string]int)
+	duplicate := make([]bool, len(policies))
+	for i, policy := range policies {
+		key := strings.Join([]string{policy.Type, policy.Subject, policy.Object, strings.ToLower(policy.Action), policy.Effect}, "|")
+		if first, ok := firstSeen[key]; ok {
+			duplicate[i] = true
+			issues = append(issues, SourceIssue{
+				File: policy.File,
+				Line: policy.Line,
+				Code: "duplicate",
+				Message: fmt.Sprintf("duplicate of line %d: %s, %s, %s, %s",
+					policies[first].Line, policy.Subject, policy.Object, policy.Action, policy.Effect),
+			})
+			continue
+		}
+		firstSeen[key] = i
+	}
+
+	// Subsumed rules: another rule grants a superset of the permissions
+	parser := &Parser{}
+	actionMapper := mapping.NewActionMapper()
+	type grant struct {
+		key   string
+		perms []string
+	}
+	grants := make([]*grant, len(policies))
+	for i := range policies {
+		if duplicate[i] || policies[i].Type == "p2" && policies[i].Action == "transition" {
+			continue
+		}
+		decoded, err := parser.decodePolicy(&policies[i])
+		if err != nil {
+			continue
+		}
+		class, perms := actionMapper.MapAction(decoded.Action, "")
+		grants[i] = &grant{
+			key:   strings.Join([]string{decoded.Subject, decoded.Object, decoded.Class, class, decoded.Condition, decoded.Effect}, "|"),
+			perms: perms,
+		}
+	}
+
+	for i, g := range grants {
+		if g == nil {
+			continue
+		}
+		for j, other := range grants {
+			if i == j || other == nil || g.key != other.key || !subsetOf(g.perms, other.perms) {
+				continue
+			}
+			// Of two rules granting the same permissions, only the later one is redundant
+			if subsetOf(other.perms, g.perms) && j > i {
+				continue
+			}
+			issues = append(issues, SourceIssue{
+				File: policies[i].File,
+				Line: policies[i].Line,
+				Code: "subsumed",
+				Message: fmt.Sprintf("'%s' on %s is subsumed by '%s' on line %d",
+					policies[i].Action, policies[i].Object, policies[j].Action, policies[j].Line),
+			})
+			break
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].File != issues[j].File {
+			return issues[i].File < issues[j].File
+		}
+		return issues[i].Line < issues[j].Line
+	})
+	return issues
+}
+
+// subsetOf reports whether every element of a is in b
+func subsetOf(a, b []string) bool {
+	for _, item := range a {
+		if !containsAttribute(b, item) {
+			return false
+		}
+	}
+	return true
+}
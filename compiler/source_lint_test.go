@@ -0,0 +1,53 @@
+package compiler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cici0602/pml-to-selinux/models"
+)
+
+func TestLintSource(t *testing.T) {
+	rule := func(line int, object, action string) models.Policy {
+		return models.Policy{Type: "p", Subject: "app_t", Object: object, Action: action, Effect: "allow", File: "policy.csv", Line: line}
+	}
+
+	tests := []struct {
+		name     string
+		policies []models.Policy
+		want     []string
+	}{
+		{
+			name:     "clean",
+			policies: []models.Policy{rule(1, "/var/www/*", "read"), rule(2, "/var/log/*", "write")},
+		},
+		{
+			name:     "exact duplicate",
+			policies: []models.Policy{rule(1, "/var/www/*", "read"), rule(2, "/var/log/*", "write"), rule(3, "/var/www/*", "read")},
+			want:     []string{"policy.csv:3: duplicate of line 1"},
+		},
+		{
+			name:     "subsumed permissions",
+			policies: []models.Policy{rule(1, "/var/log/*", "append"), rule(2, "/var/log/*", "write")},
+			want:     []string{"policy.csv:1: 'append' on /var/log/* is subsumed by 'write' on line 2"},
+		},
+		{
+			name:     "different class",
+			policies: []models.Policy{rule(1, "/var/log::dir", "append"), rule(2, "/var/log::file", "write")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := LintSource(tt.policies)
+			if len(issues) != len(tt.want) {
+				t.Fatalf("LintSource() = %v, want %v", issues, tt.want)
+			}
+			for i, want := range tt.want {
+				if !strings.HasPrefix(issues[i].String(), want) {
+					t.Errorf("issue %d = %q, want prefix %q", i, issues[i], want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,244 @@
+package compiler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/cici0602/pml-to-selinux/mapping"
+	"github.com/cici0602/pml-to-selinux/models"
+)
+
+// isJSONFile and isYAMLFile tell structured model and policy files apart by extension
+func isJSONFile(path string) bool {
+	return filepath.Ext(path) == ".json"
+}
+
+func isYAMLFile(path string) bool {
+	ext := filepath.Ext(path)
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// modelDocument is the JSON or YAML form of a model file, one key per section:
+//
+//	request_definition: {r: "sub, obj, act"}
+//	policy_definition: {p: "sub, obj, act, eft"}
+//	policy_effect: "some(where (p.eft == allow))"
+//	matchers: "r.sub == p.sub && r.obj == p.obj && r.act == p.act"
+type modelDocument struct {
+	RequestDefinition map[string]string `json:"request_definition" yaml:"request_definition"`
+	PolicyDefinition  map[string]string `json:"policy_definition" yaml:"policy_definition"`
+	RoleDefinition    map[string]string `json:"role_definition" yaml:"role_definition"`
+	PolicyEffect      string            `json:"policy_effect" yaml:"policy_effect"`
+	Matchers          string            `json:"matchers" yaml:"matchers"`
+}
+
+// parseStructuredModel parses a JSON or YAML model file into the model the .conf format gives
+func (p *Parser) parseStructuredModel() (*models.PMLModel, error) {
+	data, err := os.ReadFile(p.modelPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open model file: %w", err)
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, &ParseError{File: p.modelPath, Line: 0, Message: "empty model file"}
+	}
+
+	var doc modelDocument
+	if isYAMLFile(p.modelPath) {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(&doc)
+	} else {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&doc)
+	}
+	if err != nil {
+		return nil, &ParseError{File: p.modelPath, Line: 0, Message: fmt.Sprintf("invalid model: %v", err)}
+	}
+
+	model := &models.PMLModel{
+		RequestDefinition: make(map[string][]string),
+		PolicyDefinition:  make(map[string][]string),
+		RoleDefinition:    make(map[string][]string),
+		Effect:            strings.TrimSpace(doc.PolicyEffect),
+		Matchers:          strings.TrimSpace(doc.Matchers),
+	}
+	for key, value := range doc.RequestDefinition {
+		model.RequestDefinition[key] = parseDefinitionValue(value)
+	}
+	for key, value := range doc.PolicyDefinition {
+		model.PolicyDefinition[key] = parseDefinitionValue(value)
+	}
+	for key, value := range doc.RoleDefinition {
+		model.RoleDefinition[key] = parseDefinitionValue(value)
+	}
+	return model, nil
+}
+
+// policyEntryKeys are the keys a policy rule written as an object may use
+var policyEntryKeys = map[string]bool{
+	"type": true, "subject": true, "object": true, "action": true, "class": true,
+	"effect": true, "cond": true, "member": true, "role": true,
+}
+
+// entryFields turns a policy rule written as an object into the fields of its
+// CSV form, so it goes through the same validation as every other rule:
+//
+//	{"subject": "httpd_t", "object": "/var/www/*", "action": "read", "class": "file", "effect": "allow", "cond": "httpd_enable"}
+//
+// is "p, httpd_t, /var/www/*?cond=httpd_enable, read::file, allow". Role
+// relations use member and role. Other rule types are written as arrays.
+func entryFields(entry map[string]string) ([]string, error) {
+	var unknown []string
+	for key := range entry {
+		if !policyEntryKeys[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("unknown keys in policy rule: %s", strings.Join(unknown, ", "))
+	}
+
+	ruleType := entry["type"]
+	if ruleType == "" {
+		ruleType = "p"
+		if entry["member"] != "" {
+			ruleType = "g"
+		}
+	}
+
+	isPolicy := ruleType == "p" || ruleType == "p2" || ruleType == "p3"
+	var required []string
+	switch {
+	case isPolicy:
+		required = []string{"subject", "object", "action", "effect"}
+	case ruleType == "g" || ruleType == "g2" || ruleType == "g3":
+		required = []string{"member", "role"}
+	default:
+		return nil, fmt.Errorf("%s rules must be written as an array of fields", ruleType)
+	}
+	for _, key := range required {
+		if strings.TrimSpace(entry[key]) == "" {
+			return nil, fmt.Errorf("%s rule is missing %q", ruleType, key)
+		}
+	}
+
+	if isPolicy {
+		object, action := entry["object"], entry["action"]
+		if cond := entry["cond"]; cond != "" {
+			object += "?cond=" + cond
+		}
+		if class := entry["class"]; class != "" {
+			action += "::" + class
+		}
+		return []string{ruleType, entry["subject"], object, action, entry["effect"]}, nil
+	}
+	return []string{ruleType, entry["member"], entry["role"]}, nil
+}
+
+// parseYAMLPolicyFile parses a YAML policy file holding a list of rules, each
+// an object as in JSON policy files or a list of fields as in the CSV format.
+// Reported line numbers are those of the rule's first line.
+func (p *Parser) parseYAMLPolicyFile(path string, rules *policyRules) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to open policy file: %w", err)
+	}
+
+	var nodes []yaml.Node
+	if err := yaml.Unmarshal(data, &nodes); err != nil {
+		return &ParseError{File: path, Line: 1, Message: fmt.Sprintf("YAML policy file must contain a list of rules: %v", err)}
+	}
+
+	for _, node := range nodes {
+		var fields []string
+		switch node.Kind {
+		case yaml.MappingNode:
+			var entry map[string]string
+			if err := node.Decode(&entry); err != nil {
+				return &ParseError{File: path, Line: node.Line, Message: fmt.Sprintf("rule values must be strings: %v", err)}
+			}
+			if fields, err = entryFields(entry); err != nil {
+				return &ParseError{File: path, Line: node.Line, Message: err.Error()}
+			}
+		case yaml.SequenceNode:
+			if err := node.Decode(&fields); err != nil {
+				return &ParseError{File: path, Line: node.Line, Message: fmt.Sprintf("rule must be a list of strings: %v", err)}
+			}
+		default:
+			return &ParseError{File: path, Line: node.Line, Message: "rule must be an object or a list of fields"}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+
+		if err := parsePolicyRule(fields, strings.Join(fields, ", "), path, node.Line, rules); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// LoadActionMappings reads a JSON or YAML action map, the class and
+// permissions each of a project's own actions grants:
+//
+//	deploy:
+//	  class: file
+//	  permissions: [write, create, setattr]
+//
+// Each mapping is checked with ValidateMapping, so its permissions must exist
+// in its class. Mappings identical to a built-in one are not checked, so the
+// table export-mappings prints loads unchanged.
+func LoadActionMappings(path string) (map[string]mapping.ActionPermission, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open action map: %w", err)
+	}
+
+	config := make(map[string]mapping.ActionPermission)
+	if isJSONFile(path) {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&config)
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(&config)
+	}
+	if err != nil && err != io.EOF {
+		return nil, &ParseError{File: path, Line: 0, Message: fmt.Sprintf("invalid action map: %v", err)}
+	}
+
+	actions := make([]string, 0, len(config))
+	for action := range config {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	actionMapper := mapping.NewActionMapper()
+	builtin := actionMapper.ExportMappings()
+	for _, action := range actions {
+		perm := config[action]
+		if current, ok := builtin[action]; ok && current.Class == perm.Class &&
+			strings.Join(current.Permissions, " ") == strings.Join(perm.Permissions, " ") {
+			continue
+		}
+		if err := actionMapper.ValidateMapping(action, perm.Class, perm.Permissions); err != nil {
+			return nil, &ParseError{File: path, Line: 0, Message: fmt.Sprintf("action '%s': %v", action, err)}
+		}
+	}
+
+	return config, nil
+}
@@ -0,0 +1,448 @@
+package compiler
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/cici0602/pml-to-selinux/models"
+)
+
+// ParseTEFile reads and parses a .te file
+func ParseTEFile(path string) (*models.SELinuxPolicy, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read TE file: %w", err)
+	}
+
+	policy, err := ParseTE(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return policy, nil
+}
+
+// ParseTE parses the subset of the TE language produced by the TE generator:
+// policy_module, booleans, type declarations, allow, auditallow, dontaudit,
+// neverallow and type_transition rules.
+// Types named in require blocks become BaseTypes; role statements and
+// constraints are skipped.
+func ParseTE(content string) (*models.SELinuxPolicy, error) {
+	policy, _, err := parseTE(content, false)
+	return policy, err
+}
+
+// ParseTEModule parses a hand-written .te module the way ParseTE does, but
+// also understands the blocks and calls the TE generator writes: allow rules in
+// if/else and tunable_policy blocks become CondRules, those in optional_policy
+// blocks OptionalRules, and interface calls InterfaceCalls. What it cannot
+// parse is skipped instead of failing, e.g. other blocks, statements inside a
+// block that are not allow rules, and role statements. The skipped statements
+// are returned so callers can report what was left out.
+func ParseTEModule(content string) (*models.SELinuxPolicy, []string, error) {
+	return parseTE(content, true)
+}
+
+// ParseTEModuleFile reads and parses a .te module with ParseTEModule
+func ParseTEModuleFile(path string) (*models.SELinuxPolicy, []string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read TE file: %w", err)
+	}
+
+	policy, skipped, err := ParseTEModule(string(content))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return policy, skipped, nil
+}
+
+var (
+	teIfPattern       = regexp.MustCompile(`^if\s*\((.+)\)\s*\{$`)
+	teTunablePattern  = regexp.MustCompile("^tunable_policy\\(`(.+)',`$")
+	teCallPattern     = regexp.MustCompile("^([A-Za-z_][A-Za-z0-9_]*)\\(([^`']*)\\)$")
+	teNamePattern     = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	teOptionalOpening = "optional_policy(`"
+)
+
+// teBlock is an open block of a .te module
+type teBlock struct {
+	condition string // Boolean expression of an if or tunable_policy block
+	optional  string // Module an optional_policy block depends on
+	closer    string // Line closing the block: "}" or "')"
+	elseLine  string // Line switching to the negated condition, empty when there is none
+	depth     int    // Nesting depth of a block the parser skips; 0 for known blocks
+}
+
+// parseTE parses TE content; lenient collects unparsable statements rather than failing
+func parseTE(content string, lenient bool) (*models.SELinuxPolicy, []string, error) {
+	policy := models.NewSELinuxPolicy("", "")
+	var skipped []string
+	inRequire := false
+	var blocks []teBlock
+	comment := ""
+
+	for i, rawLine := range strings.Split(content, "\n") {
+		// The generator names the module of an optional_policy block in the comment above it
+		if trimmed := strings.TrimSpace(rawLine); strings.HasPrefix(trimmed, "#") {
+			comment = strings.TrimSpace(strings.TrimPrefix(trimmed, "#"))
+			continue
+		}
+		precedingComment := comment
+		comment = ""
+
+		line := rawLine
+		if idx := strings.Index(line, "#"); idx != -1 {
+			line = line[:idx]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		if inRequire {
+			if line == "}" || line == "')" {
+				inRequire = false
+				continue
+			}
+			parseTERequire(policy, line)
+			continue
+		}
+		if strings.HasPrefix(line, "require") || strings.HasPrefix(line, "gen_require(") {
+			if body, ok := inlineRequireBody(line); ok {
+				for _, stmt := range strings.Split(body, ";") {
+					parseTERequire(policy, stmt+";")
+				}
+				continue
+			}
+			inRequire = true
+			continue
+		}
+
+		if lenient {
+			if n := len(blocks); n > 0 && blocks[n-1].depth > 0 {
+				// Inside a skipped block only its nesting is tracked
+				blocks[n-1].depth += blockOpens(line) - blockCloses(line)
+				if blocks[n-1].depth <= 0 {
+					blocks = blocks[:n-1]
+				}
+				continue
+			}
+			if n := len(blocks); n > 0 {
+				switch line {
+				case blocks[n-1].closer:
+					blocks = blocks[:n-1]
+					continue
+				case blocks[n-1].elseLine:
+					blocks[n-1].condition = negateCondition(blocks[n-1].condition)
+					continue
+				}
+			}
+			if block, ok := openTEBlock(line, precedingComment); ok {
+				blocks = append(blocks, block)
+				if block.depth > 0 {
+					skipped = append(skipped, fmt.Sprintf("line %d: %s ... (block)", i+1, line))
+				}
+				continue
+			}
+			if len(blocks) > 0 {
+				if !parseTEBlockStatement(policy, line, blocks) {
+					skipped = append(skipped, fmt.Sprintf("line %d: %s", i+1, line))
+				}
+				continue
+			}
+			if call, ok := parseTECall(line); ok {
+				policy.InterfaceCalls = append(policy.InterfaceCalls, call)
+				continue
+			}
+		}
+
+		parsed, err := parseTEStatement(policy, line)
+		if err != nil {
+			if !lenient {
+				return nil, nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			parsed = false
+		}
+		if !parsed {
+			skipped = append(skipped, fmt.Sprintf("line %d: %s", i+1, line))
+		}
+	}
+
+	return policy, skipped, nil
+}
+
+// openTEBlock recognizes a line opening a block. if and tunable_policy blocks
+// guard their rules with a condition, optional_policy blocks with a module
+// dependency; any other block is skipped whole.
+func openTEBlock(line, comment string) (teBlock, bool) {
+	if m := teIfPattern.FindStringSubmatch(line); m != nil {
+		return teBlock{condition: strings.TrimSpace(m[1]), closer: "}", elseLine: "} else {"}, true
+	}
+	if m := teTunablePattern.FindStringSubmatch(line); m != nil {
+		return teBlock{condition: strings.TrimSpace(m[1]), closer: "')", elseLine: "',`"}, true
+	}
+	if line == teOptionalOpening {
+		module := "optional"
+		if teNamePattern.MatchString(comment) {
+			module = comment
+		}
+		return teBlock{optional: module, closer: "')"}, true
+	}
+	if opens := blockOpens(line) - blockCloses(line); opens > 0 {
+		return teBlock{depth: opens}, true
+	}
+	return teBlock{}, false
+}
+
+// blockOpens counts the block openings on a line
+func blockOpens(line string) int {
+	return strings.Count(line, "{") + strings.Count(line, "(`")
+}
+
+// blockCloses counts the block closings on a line
+func blockCloses(line string) int {
+	return strings.Count(line, "}") + strings.Count(line, "')")
+}
+
+// parseTEBlockStatement records an allow rule inside if, tunable_policy and
+// optional_policy blocks as a conditional or optional rule. It reports whether
+// the statement was recorded; anything else a block holds is not.
+func parseTEBlockStatement(policy *models.SELinuxPolicy, line string, blocks []teBlock) bool {
+	if !strings.HasPrefix(line, "allow ") || !strings.HasSuffix(line, ";") {
+		return false
+	}
+	rest := strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(line, "allow "), ";"))
+	if !strings.Contains(rest, ":") {
+		return false
+	}
+	rule, err := parseTERule(rest)
+	if err != nil {
+		return false
+	}
+
+	var conditions []string
+	for _, block := range blocks {
+		if block.condition != "" {
+			conditions = append(conditions, block.condition)
+		}
+		if block.optional != "" {
+			rule.Optional = block.optional
+		}
+	}
+	if len(conditions) > 0 {
+		rule.Condition = strings.Join(conditions, " && ")
+		policy.CondRules = append(policy.CondRules, rule)
+	} else {
+		policy.OptionalRules = append(policy.OptionalRules, rule)
+	}
+	return true
+}
+
+// negateCondition returns the condition of an else branch
+func negateCondition(condition string) string {
+	if name := strings.TrimPrefix(condition, "!"); name != condition && teNamePattern.MatchString(name) {
+		return name
+	}
+	if teNamePattern.MatchString(condition) {
+		return "!" + condition
+	}
+	return "!(" + condition + ")"
+}
+
+// parseTECall parses an interface or macro call such as apache_read_config(myapp_t)
+func parseTECall(line string) (models.InterfaceCall, bool) {
+	m := teCallPattern.FindStringSubmatch(line)
+	if m == nil || m[1] == "policy_module" || m[1] == "gen_bool" || m[1] == "gen_tunable" {
+		return models.InterfaceCall{}, false
+	}
+	call := models.InterfaceCall{Name: m[1]}
+	for _, arg := range strings.Split(m[2], ",") {
+		if arg = strings.TrimSpace(arg); arg != "" {
+			call.Args = append(call.Args, arg)
+		}
+	}
+	return call, true
+}
+
+// inlineRequireBody returns the statements of a require block opened and closed on one line
+func inlineRequireBody(line string) (string, bool) {
+	if strings.HasSuffix(line, "}") {
+		start := strings.Index(line, "{")
+		if start == -1 {
+			return "", true
+		}
+		return line[start+1 : len(line)-1], true
+	}
+	if strings.HasSuffix(line, "')") {
+		start := strings.Index(line, "`")
+		if start == -1 {
+			return "", true
+		}
+		return line[start+1 : len(line)-2], true
+	}
+	return "", false
+}
+
+// parseTERequire records the types named by a require statement as base types;
+// classes and attributes it requires are defined by the base policy anyway
+func parseTERequire(policy *models.SELinuxPolicy, stmt string) {
+	stmt = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(stmt), ";"))
+	keyword, rest, _ := strings.Cut(stmt, " ")
+	if keyword != "type" {
+		return
+	}
+	for _, name := range strings.Split(rest, ",") {
+		if name = strings.TrimSpace(name); name != "" && !containsAttribute(policy.BaseTypes, name) {
+			policy.BaseTypes = append(policy.BaseTypes, name)
+		}
+	}
+}
+
+// parseTEStatement parses a single TE statement into the policy. It reports
+// whether the statement was recorded; statements that do not grant type access
+// (role, constrain, ...) are accepted but not recorded.
+func parseTEStatement(policy *models.SELinuxPolicy, line string) (bool, error) {
+	if strings.HasPrefix(line, "policy_module(") {
+		args := strings.TrimSuffix(strings.TrimPrefix(line, "policy_module("), ")")
+		name, version, _ := strings.Cut(args, ",")
+		policy.ModuleName = strings.TrimSpace(name)
+		policy.Version = strings.TrimSpace(version)
+		return true, nil
+	}
+
+	for _, macro := range []string{"gen_bool(", "gen_tunable("} {
+		if strings.HasPrefix(line, macro) && strings.HasSuffix(line, ")") {
+			args := strings.TrimSuffix(strings.TrimPrefix(line, macro), ")")
+			name, value, _ := strings.Cut(args, ",")
+			policy.Booleans = append(policy.Booleans, models.Boolean{
+				Name:    strings.TrimSpace(name),
+				Default: strings.TrimSpace(value) == "true",
+			})
+			return true, nil
+		}
+	}
+
+	if !strings.HasSuffix(line, ";") {
+		return false, fmt.Errorf("statement must end with ';': %s", line)
+	}
+	stmt := strings.TrimSpace(strings.TrimSuffix(line, ";"))
+	keyword, rest, _ := strings.Cut(stmt, " ")
+
+	switch keyword {
+	case "type":
+		names := strings.Split(rest, ",")
+		for i := range names {
+			names[i] = strings.TrimSpace(names[i])
+		}
+		policy.AddType(names[0], names[1:]...)
+		return true, nil
+
+	case "allow", "auditallow", "dontaudit", "neverallow":
+		// Role allow: allow from_r to_r;
+		if !strings.Contains(rest, ":") {
+			return false, nil
+		}
+		rule, err := parseTERule(rest)
+		if err != nil {
+			return false, fmt.Errorf("%w: %s", err, line)
+		}
+		switch keyword {
+		case "allow":
+			policy.AddAllowRule(rule)
+		case "auditallow":
+			policy.AddAuditRule(rule)
+		case "dontaudit":
+			policy.DontauditRules = append(policy.DontauditRules, rule)
+		default:
+			for _, class := range rule.AllClasses() {
+				policy.AddNeverallowRule(models.NeverallowRule{
+					SourceType:  rule.SourceType,
+					TargetType:  rule.TargetType,
+					Class:       class,
+					Permissions: rule.Permissions,
+				})
+			}
+		}
+		return true, nil
+
+	case "type_transition":
+		// Named transitions end in a quoted filename
+		filename := ""
+		if start := strings.Index(rest, `"`); start != -1 {
+			filename = strings.Trim(rest[start:], `"`)
+			rest = rest[:start]
+		}
+		fields := strings.Fields(strings.Replace(rest, ":", " ", 1))
+		if len(fields) != 4 {
+			return false, fmt.Errorf("malformed type_transition: %s", line)
+		}
+		if filename != "" {
+			policy.NamedTransitions = append(policy.NamedTransitions, models.NamedTransition{
+				SourceType: fields[0],
+				TargetType: fields[1],
+				Class:      fields[2],
+				NewType:    fields[3],
+				Filename:   filename,
+			})
+			return true, nil
+		}
+		policy.AddTransition(models.TypeTransition{
+			SourceType: fields[0],
+			TargetType: fields[1],
+			Class:      fields[2],
+			NewType:    fields[3],
+		})
+		return true, nil
+	}
+
+	// Other statements (role, constrain, ...) do not grant type access
+	return false, nil
+}
+
+// parseTERule parses "source target:class perms" where class and perms may be sets
+func parseTERule(rest string) (models.AllowRule, error) {
+	types, classPerms, _ := strings.Cut(rest, ":")
+	typeFields := strings.Fields(types)
+	if len(typeFields) != 2 {
+		return models.AllowRule{}, fmt.Errorf("expected source and target type")
+	}
+
+	classes, remainder := parseTESet(strings.TrimSpace(classPerms))
+	perms, _ := parseTESet(strings.TrimSpace(remainder))
+	if len(classes) == 0 || len(perms) == 0 {
+		return models.AllowRule{}, fmt.Errorf("expected class and permissions")
+	}
+
+	rule := models.AllowRule{
+		SourceType:  typeFields[0],
+		TargetType:  typeFields[1],
+		Class:       classes[0],
+		Permissions: perms,
+	}
+	if len(classes) > 1 {
+		rule.Classes = classes
+	}
+	return rule, nil
+}
+
+// parseTESet parses a leading "{ a b }" set or a single name and returns the rest
+func parseTESet(s string) ([]string, string) {
+	if strings.HasPrefix(s, "{") {
+		end := strings.Index(s, "}")
+		if end == -1 {
+			return nil, ""
+		}
+		return strings.Fields(s[1:end]), s[end+1:]
+	}
+
+	fields := strings.SplitN(s, " ", 2)
+	if fields[0] == "" {
+		return nil, ""
+	}
+	if len(fields) == 1 {
+		return fields, ""
+	}
+	return fields[:1], fields[1]
+}
@@ -0,0 +1,184 @@
+package compiler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cici0602/pml-to-selinux/models"
+	"github.com/cici0602/pml-to-selinux/selinux"
+)
+
+func TestParseTE(t *testing.T) {
+	content := `policy_module(myapp, 1.0.0)
+
+type myapp_t;
+type myapp_exec_t, exec_type, file_type;
+
+require {
+	type bin_t;
+	class file { read };
+}
+
+# Rules for myapp_t
+allow myapp_t myapp_exec_t:{ file lnk_file } { read getattr };
+allow myapp_t bin_t:file execute;
+auditallow myapp_t bin_t:file execute;
+allow user_r myapp_r;
+type_transition myapp_t tmp_t:file myapp_tmp_t;
+`
+
+	policy, err := ParseTE(content)
+	if err != nil {
+		t.Fatalf("ParseTE() error = %v", err)
+	}
+
+	if policy.ModuleName != "myapp" || policy.Version != "1.0.0" {
+		t.Errorf("module = %s %s, want myapp 1.0.0", policy.ModuleName, policy.Version)
+	}
+	if len(policy.Types) != 2 || !policy.HasType("myapp_exec_t") {
+		t.Errorf("expected 2 declared types, got %+v", policy.Types)
+	}
+	if len(policy.Rules) != 2 {
+		t.Fatalf("expected 2 allow rules, got %d: %+v", len(policy.Rules), policy.Rules)
+	}
+	if policy.Rules[0].ClassSpec() != "{ file lnk_file }" || len(policy.Rules[0].Permissions) != 2 {
+		t.Errorf("unexpected class-set rule: %+v", policy.Rules[0])
+	}
+	if len(policy.AuditRules) != 1 {
+		t.Errorf("expected 1 auditallow rule, got %d", len(policy.AuditRules))
+	}
+	if len(policy.Transitions) != 1 || policy.Transitions[0].NewType != "myapp_tmp_t" {
+		t.Errorf("unexpected transitions: %+v", policy.Transitions)
+	}
+
+	if _, err := ParseTE("allow myapp_t bin_t:file read\n"); err == nil {
+		t.Error("expected error for statement without ';'")
+	}
+}
+
+func TestParseTE_RoundTrip(t *testing.T) {
+	policy := models.NewSELinuxPolicy("myapp", "1.0.0")
+	policy.AddType("myapp_t", "domain")
+	policy.AddAllowRule(models.AllowRule{SourceType: "myapp_t", TargetType: "myapp_log_t", Class: "file", Permissions: []string{"append", "open"}})
+	policy.AddAllowRule(models.AllowRule{SourceType: "myapp_t", TargetType: "myapp_log_t", Class: "dir", Permissions: []string{"search"}})
+
+	content, err := selinux.NewTEGenerator(policy).Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	parsed, err := ParseTE(content)
+	if err != nil {
+		t.Fatalf("ParseTE() error = %v", err)
+	}
+	if result := CheckEquivalence(policy, parsed); !result.Equivalent {
+		t.Errorf("generated TE should parse back to an equivalent policy:\n%s", FormatEquivalence(result))
+	}
+}
+
+func TestParseTEModule(t *testing.T) {
+	content := `policy_module(foo, 1.0)
+
+require {
+	type etc_t, tmp_t;
+}
+gen_require(` + "`" + ` type bin_t; ')
+
+gen_bool(foo_write, false)
+type foo_t;
+domain_type(foo_t)
+
+allow foo_t etc_t:file { read open getattr };
+type_transition foo_t tmp_t:file foo_cache_t "cache.db";
+neverallow foo_t etc_t:file write;
+dontaudit foo_t tmp_t:dir search;
+if (foo_write) {
+	allow foo_t etc_t:file write;
+	type_transition foo_t etc_t:file foo_etc_t;
+} else {
+	allow foo_t etc_t:file append;
+}
+# bar
+optional_policy(` + "`" + `
+	allow foo_t bin_t:file execute;
+')
+ifdef(` + "`" + `distro_redhat',` + "`" + `
+	allow foo_t bin_t:file read;
+')
+`
+
+	if _, err := ParseTE(content); err == nil {
+		t.Error("ParseTE should reject interface calls")
+	}
+
+	policy, skipped, err := ParseTEModule(content)
+	if err != nil {
+		t.Fatalf("ParseTEModule() error = %v", err)
+	}
+	if strings.Join(policy.BaseTypes, " ") != "etc_t tmp_t bin_t" {
+		t.Errorf("BaseTypes = %v", policy.BaseTypes)
+	}
+	// Rules inside the if and optional blocks must not become plain allows
+	if len(policy.Rules) != 1 || policy.Rules[0].Permissions[0] != "read" {
+		t.Errorf("expected only the unconditional allow rule, got %+v", policy.Rules)
+	}
+	if len(policy.CondRules) != 2 || policy.CondRules[0].Condition != "foo_write" || policy.CondRules[1].Condition != "!foo_write" {
+		t.Errorf("CondRules = %+v, want the if and else branches", policy.CondRules)
+	}
+	if len(policy.OptionalRules) != 1 || policy.OptionalRules[0].Optional != "bar" {
+		t.Errorf("OptionalRules = %+v, want one rule depending on bar", policy.OptionalRules)
+	}
+	if len(policy.Booleans) != 1 || policy.Booleans[0].Name != "foo_write" {
+		t.Errorf("Booleans = %+v", policy.Booleans)
+	}
+	if len(policy.NeverallowRules) != 1 || len(policy.DontauditRules) != 1 {
+		t.Errorf("NeverallowRules = %+v, DontauditRules = %+v", policy.NeverallowRules, policy.DontauditRules)
+	}
+	if len(policy.InterfaceCalls) != 1 || policy.InterfaceCalls[0].Name != "domain_type" {
+		t.Errorf("InterfaceCalls = %+v", policy.InterfaceCalls)
+	}
+	if len(policy.NamedTransitions) != 1 || policy.NamedTransitions[0].Filename != "cache.db" {
+		t.Errorf("unexpected named transitions: %+v", policy.NamedTransitions)
+	}
+	// The conditional transition and the ifdef block are left out
+	if len(skipped) != 2 {
+		t.Errorf("expected 2 skipped statements, got %d: %v", len(skipped), skipped)
+	}
+}
+
+func TestParseTEModule_GeneratedEquivalence(t *testing.T) {
+	policy := models.NewSELinuxPolicy("myapp", "1.0.0")
+	policy.AddType("myapp_t", "domain")
+	policy.AddAllowRule(models.AllowRule{SourceType: "myapp_t", TargetType: "myapp_data_t", Class: "file", Permissions: []string{"read"}})
+	policy.Booleans = []models.Boolean{{Name: "myapp_cache"}}
+	policy.CondRules = []models.AllowRule{
+		{SourceType: "myapp_t", TargetType: "myapp_cache_t", Class: "file", Permissions: []string{"write"}, Condition: "myapp_cache"},
+	}
+	policy.OptionalRules = []models.AllowRule{
+		{SourceType: "myapp_t", TargetType: "apache_var_www_t", Class: "file", Permissions: []string{"read"}, Optional: "apache"},
+	}
+	policy.InterfaceCalls = []models.InterfaceCall{{Name: "apache_read_config", Args: []string{"myapp_t"}}}
+	policy.AddNeverallowRule(models.NeverallowRule{SourceType: "myapp_t", TargetType: "shadow_t", Class: "file", Permissions: []string{"read"}})
+	policy.DontauditRules = []models.AllowRule{
+		{SourceType: "myapp_t", TargetType: "tmp_t", Class: "dir", Permissions: []string{"search"}},
+	}
+
+	content, err := selinux.NewTEGenerator(policy).Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	parsed, skipped, err := ParseTEModule(content)
+	if err != nil {
+		t.Fatalf("ParseTEModule() error = %v", err)
+	}
+	if len(skipped) != 0 {
+		t.Errorf("generated module should parse completely, skipped %v", skipped)
+	}
+	if result := CheckEquivalence(policy, parsed); !result.Equivalent {
+		t.Errorf("generated TE should parse back to an equivalent policy:\n%s", FormatEquivalence(result))
+	}
+
+	parsed.CondRules[0].Condition = "!myapp_cache"
+	if CheckEquivalence(policy, parsed).Equivalent {
+		t.Error("rules under different conditions should not be equivalent")
+	}
+}
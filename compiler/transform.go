@@ -0,0 +1,118 @@
+package compiler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cici0602/pml-to-selinux/models"
+)
+
+// PolicyTransformer mutates a generated policy before it is optimized and rendered,
+// e.g. to inject organization-specific rules.
+//
+// Transformers are registered by name, usually from an init function, and are run
+// in the order they are requested:
+//
+//	func init() {
+//		compiler.RegisterTransformer("acme-audit", acmeAuditTransformer{})
+//	}
+//
+// The compile command runs them with --transform <name> (repeatable).
+type PolicyTransformer interface {
+	Transform(policy *models.SELinuxPolicy) error
+}
+
+// PolicyTransformerFunc adapts a function to a PolicyTransformer
+type PolicyTransformerFunc func(policy *models.SELinuxPolicy) error
+
+// Transform calls f(policy)
+func (f PolicyTransformerFunc) Transform(policy *models.SELinuxPolicy) error {
+	return f(policy)
+}
+
+var (
+	transformersMu sync.RWMutex
+	transformers   = make(map[string]PolicyTransformer)
+)
+
+// RegisterTransformer makes a transformer available by name.
+// It panics if the name is registered twice or the transformer is nil.
+func RegisterTransformer(name string, transformer PolicyTransformer) {
+	transformersMu.Lock()
+	defer transformersMu.Unlock()
+
+	if transformer == nil {
+		panic("compiler: RegisterTransformer transformer is nil")
+	}
+	if _, dup := transformers[name]; dup {
+		panic("compiler: RegisterTransformer called twice for " + name)
+	}
+	transformers[name] = transformer
+}
+
+// Transformers returns the names of the registered transformers, sorted
+func Transformers() []string {
+	transformersMu.RLock()
+	defer transformersMu.RUnlock()
+
+	names := make([]string, 0, len(transformers))
+	for name := range transformers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RunTransformers applies the named transformers to the policy in order,
+// stopping at the first error
+func RunTransformers(policy *models.SELinuxPolicy, names []string) error {
+	for _, name := range names {
+		transformersMu.RLock()
+		transformer, ok := transformers[name]
+		transformersMu.RUnlock()
+		if !ok {
+			return fmt.Errorf("unknown transformer '%s' (available: %s)", name, strings.Join(Transformers(), ", "))
+		}
+		if err := transformer.Transform(policy); err != nil {
+			return fmt.Errorf("transformer '%s': %w", name, err)
+		}
+	}
+	return nil
+}
+
+// selfRulePermissions are the process permissions granted by the self-rules transformer
+var selfRulePermissions = []string{"fork", "sigchld", "signal", "signull"}
+
+// SelfRulesTransformer grants every domain (a type that is the source of an allow rule)
+// the common process permissions on itself
+type SelfRulesTransformer struct{}
+
+// Transform adds an allow <domain> self:process rule for each domain
+func (SelfRulesTransformer) Transform(policy *models.SELinuxPolicy) error {
+	seen := make(map[string]bool)
+	var domains []string
+	for _, rule := range policy.Rules {
+		if !seen[rule.SourceType] {
+			seen[rule.SourceType] = true
+			domains = append(domains, rule.SourceType)
+		}
+	}
+	sort.Strings(domains)
+
+	for _, domain := range domains {
+		policy.Rules = append(policy.Rules, models.AllowRule{
+			SourceType:  domain,
+			TargetType:  "self",
+			Class:       "process",
+			Permissions: append([]string(nil), selfRulePermissions...),
+			Comment:     "Added by the self-rules transformer",
+		})
+	}
+	return nil
+}
+
+func init() {
+	RegisterTransformer("self-rules", SelfRulesTransformer{})
+}
@@ -0,0 +1,83 @@
+package compiler
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/cici0602/pml-to-selinux/models"
+)
+
+// registerTestTransformer registers a transformer for the duration of the test
+func registerTestTransformer(t *testing.T, name string, transformer PolicyTransformer) {
+	t.Helper()
+	RegisterTransformer(name, transformer)
+	t.Cleanup(func() {
+		transformersMu.Lock()
+		defer transformersMu.Unlock()
+		delete(transformers, name)
+	})
+}
+
+func TestRunTransformers(t *testing.T) {
+	registerTestTransformer(t, "test-rename", PolicyTransformerFunc(func(policy *models.SELinuxPolicy) error {
+		policy.ModuleName += "_renamed"
+		return nil
+	}))
+	registerTestTransformer(t, "test-fail", PolicyTransformerFunc(func(policy *models.SELinuxPolicy) error {
+		return errors.New("boom")
+	}))
+
+	newPolicy := func() *models.SELinuxPolicy {
+		return &models.SELinuxPolicy{
+			ModuleName: "app",
+			Rules: []models.AllowRule{
+				{SourceType: "worker_t", TargetType: "var_t", Class: "file", Permissions: []string{"read"}},
+				{SourceType: "app_t", TargetType: "etc_t", Class: "file", Permissions: []string{"read"}},
+				{SourceType: "app_t", TargetType: "log_t", Class: "file", Permissions: []string{"append"}},
+			},
+		}
+	}
+
+	tests := []struct {
+		name        string
+		transforms  []string
+		wantErr     string
+		wantModule  string
+		wantSelfFor []string
+	}{
+		{name: "none", wantModule: "app"},
+		{name: "in order", transforms: []string{"test-rename", "self-rules"}, wantModule: "app_renamed", wantSelfFor: []string{"app_t", "worker_t"}},
+		{name: "unknown", transforms: []string{"nope"}, wantErr: "unknown transformer 'nope'"},
+		{name: "failing", transforms: []string{"test-fail"}, wantErr: "transformer 'test-fail': boom"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := newPolicy()
+			err := RunTransformers(policy, tt.transforms)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if policy.ModuleName != tt.wantModule {
+				t.Errorf("module name = %q, want %q", policy.ModuleName, tt.wantModule)
+			}
+
+			var selfFor []string
+			for _, rule := range policy.Rules {
+				if rule.TargetType == "self" && rule.Class == "process" {
+					selfFor = append(selfFor, rule.SourceType)
+				}
+			}
+			if strings.Join(selfFor, ",") != strings.Join(tt.wantSelfFor, ",") {
+				t.Errorf("self rules for %v, want %v", selfFor, tt.wantSelfFor)
+			}
+		})
+	}
+}
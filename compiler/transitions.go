@@ -0,0 +1,81 @@
+package compiler
+
+import (
+	"github.com/cici0602/pml-to-selinux/models"
+)
+
+// TransitionReport describes one type_transition and, for domain transitions,
+// whether the allow rules it needs to succeed exist in the policy
+type TransitionReport struct {
+	SourceType string // Source domain
+	EntryPoint string // Type of the executed file, or of the parent object for object transitions
+	NewType    string // Resulting domain or object type
+	Class      string
+
+	// Helper rules of a domain transition (class process); always true otherwise
+	Execute    bool // allow source entry:file execute
+	Transition bool // allow source new:process transition
+	Entrypoint bool // allow new entry:file entrypoint
+}
+
+// IsDomainTransition reports whether the transition changes the domain of a process
+func (r TransitionReport) IsDomainTransition() bool {
+	return r.Class == "process"
+}
+
+// Missing returns the helper rules the transition lacks, rendered as allow rules
+func (r TransitionReport) Missing() []string {
+	missing := make([]string, 0)
+	if !r.Execute {
+		missing = append(missing, "allow "+r.SourceType+" "+r.EntryPoint+":file execute")
+	}
+	if !r.Transition {
+		missing = append(missing, "allow "+r.SourceType+" "+r.NewType+":process transition")
+	}
+	if !r.Entrypoint {
+		missing = append(missing, "allow "+r.NewType+" "+r.EntryPoint+":file entrypoint")
+	}
+	return missing
+}
+
+// ReportTransitions lists every type_transition of the policy, checking the
+// execute, transition and entrypoint rules of each domain transition.
+// A domain transition without them silently keeps the process in its old domain.
+func ReportTransitions(policy *models.SELinuxPolicy) []TransitionReport {
+	reports := make([]TransitionReport, 0, len(policy.Transitions))
+	for _, trans := range policy.Transitions {
+		report := TransitionReport{
+			SourceType: trans.SourceType,
+			EntryPoint: trans.TargetType,
+			NewType:    trans.NewType,
+			Class:      trans.Class,
+			Execute:    true,
+			Transition: true,
+			Entrypoint: true,
+		}
+		if report.IsDomainTransition() {
+			report.Execute = grantsPermission(policy, trans.SourceType, trans.TargetType, "file", "execute")
+			report.Transition = grantsPermission(policy, trans.SourceType, trans.NewType, "process", "transition")
+			report.Entrypoint = grantsPermission(policy, trans.NewType, trans.TargetType, "file", "entrypoint")
+		}
+		reports = append(reports, report)
+	}
+	return reports
+}
+
+// grantsPermission reports whether an unconditional allow rule grants source
+// the permission on target:class, counting "self" rules when source is target
+func grantsPermission(policy *models.SELinuxPolicy, source, target, class, perm string) bool {
+	for _, rule := range policy.Rules {
+		if rule.SourceType != source || !containsAttribute(rule.AllClasses(), class) {
+			continue
+		}
+		if rule.TargetType != target && !(rule.TargetType == "self" && source == target) {
+			continue
+		}
+		if containsAttribute(rule.Permissions, perm) {
+			return true
+		}
+	}
+	return false
+}
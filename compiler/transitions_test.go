@@ -0,0 +1,48 @@
+package compiler
+
+import (
+	"testing"
+
+	"github.com/cici0602/pml-to-selinux/models"
+)
+
+func TestReportTransitions(t *testing.T) {
+	policy := &models.SELinuxPolicy{
+		ModuleName: "app",
+		Transitions: []models.TypeTransition{
+			{SourceType: "init_t", TargetType: "app_exec_t", Class: "process", NewType: "app_t"},
+			{SourceType: "cron_t", TargetType: "app_exec_t", Class: "process", NewType: "app_t"},
+			{SourceType: "app_t", TargetType: "var_run_t", Class: "file", NewType: "app_var_run_t"},
+		},
+		Rules: []models.AllowRule{
+			{SourceType: "init_t", TargetType: "app_exec_t", Class: "file", Permissions: []string{"execute", "read"}},
+			{SourceType: "init_t", TargetType: "app_t", Class: "process", Permissions: []string{"transition"}},
+			{SourceType: "app_t", TargetType: "app_exec_t", Classes: []string{"file", "lnk_file"}, Class: "file", Permissions: []string{"entrypoint"}},
+			{SourceType: "cron_t", TargetType: "app_exec_t", Class: "file", Permissions: []string{"read"}},
+		},
+	}
+
+	reports := ReportTransitions(policy)
+	if len(reports) != 3 {
+		t.Fatalf("ReportTransitions() returned %d reports, want 3", len(reports))
+	}
+
+	if missing := reports[0].Missing(); len(missing) != 0 {
+		t.Errorf("init_t -> app_t should be complete, missing %v", missing)
+	}
+
+	missing := reports[1].Missing()
+	want := []string{"allow cron_t app_exec_t:file execute", "allow cron_t app_t:process transition"}
+	if len(missing) != len(want) {
+		t.Fatalf("cron_t -> app_t missing = %v, want %v", missing, want)
+	}
+	for i := range want {
+		if missing[i] != want[i] {
+			t.Errorf("missing[%d] = %q, want %q", i, missing[i], want[i])
+		}
+	}
+
+	if reports[2].IsDomainTransition() || len(reports[2].Missing()) != 0 {
+		t.Errorf("object transitions need no helper rules, got %+v", reports[2])
+	}
+}
@@ -12,23 +12,70 @@ type ActionMapper struct {
 
 	// Default action mappings
 	defaultMappings map[string]ActionPermission
+
+	// Action aliases (alias -> canonical action), keyed by normalized alias
+	aliases map[string]string
+
+	// mapEnabled controls whether the "map" permission is emitted.
+	// Older policies do not define "map" for file classes, so it is off by default.
+	mapEnabled bool
 }
 
 // ActionPermission represents SELinux class and permission set
 type ActionPermission struct {
-	Class       string   // SELinux object class (e.g., "file", "dir", "tcp_socket")
-	Permissions []string // SELinux permissions (e.g., ["read", "open", "getattr"])
+	Class       string   `json:"class" yaml:"class"`             // SELinux object class (e.g., "file", "dir", "tcp_socket")
+	Permissions []string `json:"permissions" yaml:"permissions"` // SELinux permissions (e.g., ["read", "open", "getattr"])
+}
+
+// registeredMappings are the custom mappings every new ActionMapper starts with
+var registeredMappings = make(map[string]ActionPermission)
+
+// RegisterActionMappings adds custom mappings to every ActionMapper created
+// afterwards, so the analyzer's checks and the generator map a project's own
+// actions, e.g. from an action map file, the same way. Actions are normalized.
+func RegisterActionMappings(config map[string]ActionPermission) {
+	for action, perm := range config {
+		registeredMappings[normalizeActionName(action)] = perm
+	}
 }
 
-// NewActionMapper creates a new ActionMapper with default mappings
+// NewActionMapper creates a new ActionMapper with default mappings and the registered custom mappings
 func NewActionMapper() *ActionMapper {
 	am := &ActionMapper{
 		customMappings:  make(map[string]ActionPermission),
 		defaultMappings: getDefaultActionMappings(),
+		aliases:         getDefaultActionAliases(),
 	}
+	am.LoadCustomMappingsFromConfig(registeredMappings)
 	return am
 }
 
+// getDefaultActionAliases returns common spellings of the default actions
+func getDefaultActionAliases() map[string]string {
+	return map[string]string{
+		"read_file":  "read",
+		"write_file": "write",
+		"rd":         "read",
+		"wr":         "write",
+		"exec":       "execute",
+	}
+}
+
+// normalizeActionName lowercases an action and turns '-' and spaces into '_'
+func normalizeActionName(action string) string {
+	return strings.NewReplacer("-", "_", " ", "_").Replace(strings.ToLower(strings.TrimSpace(action)))
+}
+
+// normalizeAction normalizes an action and resolves aliases,
+// so "Read-File" and "rd" both become "read"
+func (am *ActionMapper) normalizeAction(action string) string {
+	normalized := normalizeActionName(action)
+	if canonical, ok := am.aliases[normalized]; ok {
+		return canonical
+	}
+	return normalized
+}
+
 // getDefaultActionMappings returns default action to permission mappings
 func getDefaultActionMappings() map[string]ActionPermission {
 	return map[string]ActionPermission{
@@ -45,6 +92,11 @@ func getDefaultActionMappings() map[string]ActionPermission {
 			Class:       "file",
 			Permissions: []string{"execute", "read", "open", "getattr", "execute_no_trans"},
 		},
+		// Running a binary only through a domain transition, never in the caller's domain
+		"exec_transition": {
+			Class:       "file",
+			Permissions: []string{"execute", "read", "open", "getattr"},
+		},
 		"create": {
 			Class:       "file",
 			Permissions: []string{"create", "write", "open"},
@@ -65,6 +117,10 @@ func getDefaultActionMappings() map[string]ActionPermission {
 			Class:       "file",
 			Permissions: []string{"setattr"},
 		},
+		"mmap": {
+			Class:       "file",
+			Permissions: []string{"map", "read", "open", "getattr"},
+		},
 		"rename": {
 			Class:       "file",
 			Permissions: []string{"rename"},
@@ -166,8 +222,61 @@ func (am *ActionMapper) AddCustomMapping(action string, class string, permission
 	}
 }
 
+// AddAlias registers an alternative name for an action, e.g. a project's own
+// verb for a custom mapping. The alias is normalized like actions are.
+func (am *ActionMapper) AddAlias(alias string, action string) {
+	am.aliases[normalizeActionName(alias)] = normalizeActionName(action)
+}
+
+// SetMapEnabled enables or disables the "map" permission.
+// When enabled, read, execute and exec_transition also grant map so mmap'ed files work on newer kernels.
+func (am *ActionMapper) SetMapEnabled(enabled bool) {
+	am.mapEnabled = enabled
+}
+
+// DropsMap reports whether action is mmap while map is disabled, in which case
+// it only grants the read permissions that come with it
+func (am *ActionMapper) DropsMap(action string) bool {
+	return !am.mapEnabled && am.normalizeAction(action) == "mmap"
+}
+
 // MapAction maps a PML action to SELinux class and permissions
 func (am *ActionMapper) MapAction(action string, objectClass string) (string, []string) {
+	action = am.normalizeAction(action)
+	class, permissions := am.mapAction(action, objectClass)
+	return class, am.applyMapPermission(action, class, permissions)
+}
+
+// applyMapPermission adds or strips the "map" permission depending on mapEnabled.
+// map is only defined for the file class, so other classes are left untouched.
+func (am *ActionMapper) applyMapPermission(action, class string, permissions []string) []string {
+	if class != "file" {
+		return permissions
+	}
+
+	if !am.mapEnabled {
+		if action != "mmap" {
+			return permissions
+		}
+		filtered := make([]string, 0, len(permissions))
+		for _, perm := range permissions {
+			if perm != "map" {
+				filtered = append(filtered, perm)
+			}
+		}
+		return filtered
+	}
+
+	if (action == "read" || action == "execute" || action == "exec_transition") && !containsString(permissions, "map") {
+		withMap := make([]string, 0, len(permissions)+1)
+		withMap = append(withMap, permissions...)
+		return append(withMap, "map")
+	}
+	return permissions
+}
+
+// mapAction resolves an action against the custom and default mappings
+func (am *ActionMapper) mapAction(action string, objectClass string) (string, []string) {
 	actionLower := strings.ToLower(action)
 
 	// Check custom mappings first
@@ -221,12 +330,96 @@ func (am *ActionMapper) adaptPermissionsToClass(permissions []string, class stri
 	return permissions
 }
 
+// denyPermissions are the permissions a deny of each action forbids. Actions are
+// granted helper permissions alongside their own, e.g. read also grants open and
+// getattr, and a neverallow on those would contradict every other access to the
+// object, so a deny only forbids what makes the action what it is:
+//
+//	read, list      -> read
+//	write           -> write append
+//	append          -> append
+//	execute         -> execute execute_no_trans
+//	exec_transition -> execute
+//	create          -> create
+//	mmap            -> map
+//	search          -> search
+//	add_name        -> add_name
+//	remove_name     -> remove_name
+//	rmdir           -> rmdir
+//
+// Other actions forbid all their permissions except open and getattr, or all of
+// them when nothing else is left (e.g. getattr).
+var denyPermissions = map[string][]string{
+	"read":            {"read"},
+	"list":            {"read"},
+	"write":           {"write", "append"},
+	"append":          {"append"},
+	"execute":         {"execute", "execute_no_trans"},
+	"exec_transition": {"execute"},
+	"create":          {"create"},
+	"mmap":            {"map"},
+	"search":          {"search"},
+	"add_name":        {"add_name"},
+	"remove_name":     {"remove_name"},
+	"rmdir":           {"rmdir"},
+}
+
+// MapDenyAction maps a denied PML action to the SELinux class and the permissions
+// its neverallow rule forbids, a subset of what MapAction grants for it
+func (am *ActionMapper) MapDenyAction(action string, objectClass string) (string, []string) {
+	class, permissions := am.MapAction(action, objectClass)
+
+	own := make([]string, 0, len(permissions))
+	for _, perm := range permissions {
+		if containsString(denyPermissions[am.normalizeAction(action)], perm) {
+			own = append(own, perm)
+		}
+	}
+	if len(own) > 0 {
+		return class, own
+	}
+
+	for _, perm := range permissions {
+		if perm != "open" && perm != "getattr" {
+			own = append(own, perm)
+		}
+	}
+	if len(own) > 0 {
+		return class, own
+	}
+	return class, permissions
+}
+
 // MapActionWithClass maps action to permissions for a specific class
 func (am *ActionMapper) MapActionWithClass(action string, class string) []string {
 	_, perms := am.MapAction(action, class)
 	return perms
 }
 
+// IsKnownAction reports whether an action is in the custom or default mappings,
+// or is a raw SELinux permission that MapAction passes through unchanged
+func (am *ActionMapper) IsKnownAction(action string) bool {
+	actionLower := am.normalizeAction(action)
+	if _, ok := am.customMappings[actionLower]; ok {
+		return true
+	}
+	if _, ok := am.defaultMappings[actionLower]; ok {
+		return true
+	}
+	return IsKnownPermission(actionLower)
+}
+
+// IsMappedAction reports whether an action is in the custom or default mappings,
+// as opposed to a raw permission that MapAction passes through unchanged
+func (am *ActionMapper) IsMappedAction(action string) bool {
+	actionLower := am.normalizeAction(action)
+	if _, ok := am.customMappings[actionLower]; ok {
+		return true
+	}
+	_, ok := am.defaultMappings[actionLower]
+	return ok
+}
+
 // GetSupportedActions returns a list of all supported actions
 func (am *ActionMapper) GetSupportedActions() []string {
 	actions := []string{}
@@ -343,6 +536,10 @@ func (am *ActionMapper) ValidateMapping(action string, class string, permissions
 		return fmt.Errorf("permissions cannot be empty")
 	}
 
+	if unknown := ValidatePermissions(class, permissions); len(unknown) > 0 {
+		return fmt.Errorf("class %s has no permission %s", class, strings.Join(unknown, ", "))
+	}
+
 	return nil
 }
 
@@ -1,6 +1,7 @@
 package mapping
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -204,6 +205,62 @@ func TestActionMapper_UnknownActions(t *testing.T) {
 	}
 }
 
+// TestActionMapper_IsKnownAction tests which actions strict mode accepts
+func TestActionMapper_IsKnownAction(t *testing.T) {
+	mapper := NewActionMapper()
+	mapper.AddCustomMapping("custom_read", "special_file", []string{"custom_read"})
+
+	tests := []struct {
+		action string
+		want   bool
+	}{
+		{"read", true},
+		{"READ", true},
+		{"custom_read", true},
+		{"name_connect", true},
+		{"raed", false},
+		{"unknown_action", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			if got := mapper.IsKnownAction(tt.action); got != tt.want {
+				t.Errorf("IsKnownAction(%q) = %v, want %v", tt.action, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestActionMapper_Aliases tests normalization and alias resolution
+func TestActionMapper_Aliases(t *testing.T) {
+	mapper := NewActionMapper()
+	mapper.AddCustomMapping("publish", "file", []string{"write", "append"})
+	mapper.AddAlias("Push-Out", "publish")
+
+	tests := []struct {
+		action    string
+		canonical string
+	}{
+		{"READ", "read"},
+		{"Read_File", "read"},
+		{"read-file", "read"},
+		{"rd", "read"},
+		{"wr", "write"},
+		{"exec", "execute"},
+		{"push out", "publish"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			class, perms := mapper.MapAction(tt.action, "")
+			wantClass, wantPerms := mapper.MapAction(tt.canonical, "")
+			if class != wantClass || strings.Join(perms, " ") != strings.Join(wantPerms, " ") {
+				t.Errorf("MapAction(%q) = %s %v, want %s %v", tt.action, class, perms, wantClass, wantPerms)
+			}
+		})
+	}
+}
+
 // TestActionMapper_CustomMappings tests custom mapping functionality
 func TestActionMapper_CustomMappings(t *testing.T) {
 	mapper := NewActionMapper()
@@ -1,6 +1,7 @@
 package mapping
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -35,6 +36,13 @@ func TestMapAction(t *testing.T) {
 			expectedClass: "file",
 			expectedPerms: []string{"execute", "read", "open", "getattr", "execute_no_trans"},
 		},
+		{
+			name:          "Execute only through a transition",
+			action:        "exec_transition",
+			objectClass:   "",
+			expectedClass: "file",
+			expectedPerms: []string{"execute", "read", "open", "getattr"},
+		},
 		{
 			name:          "Search directory",
 			action:        "search",
@@ -360,6 +368,20 @@ func TestValidateMapping(t *testing.T) {
 			permissions: []string{},
 			expectErr:   true,
 		},
+		{
+			name:        "Unknown permission",
+			action:      "read",
+			class:       "file",
+			permissions: []string{"reed", "open"},
+			expectErr:   true,
+		},
+		{
+			name:        "Class outside the permission table",
+			action:      "send",
+			class:       "dbus",
+			permissions: []string{"send_msg"},
+			expectErr:   false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -371,3 +393,103 @@ func TestValidateMapping(t *testing.T) {
 		})
 	}
 }
+
+func TestMapPermission(t *testing.T) {
+	mapper := NewActionMapper()
+
+	// map is off by default, even for the dedicated mmap action
+	_, perms := mapper.MapAction("mmap", "")
+	if containsString(perms, "map") {
+		t.Errorf("mmap should not emit map when disabled, got %v", perms)
+	}
+	_, perms = mapper.MapAction("read", "")
+	if containsString(perms, "map") {
+		t.Errorf("read should not emit map when disabled, got %v", perms)
+	}
+	if !mapper.DropsMap("mmap") || mapper.DropsMap("read") {
+		t.Error("DropsMap should only report mmap while map is disabled")
+	}
+
+	mapper.SetMapEnabled(true)
+	if mapper.DropsMap("mmap") {
+		t.Error("DropsMap should be false once map is enabled")
+	}
+
+	for _, action := range []string{"read", "execute", "mmap"} {
+		_, perms := mapper.MapAction(action, "")
+		if !containsString(perms, "map") {
+			t.Errorf("%s should include map when enabled, got %v", action, perms)
+		}
+	}
+
+	// map is not defined for directories
+	_, perms = mapper.MapAction("read", "dir")
+	if containsString(perms, "map") {
+		t.Errorf("read on dir should not include map, got %v", perms)
+	}
+
+	// Default mapping must not be mutated by appending map
+	mapper.SetMapEnabled(false)
+	_, perms = mapper.MapAction("read", "")
+	if containsString(perms, "map") {
+		t.Errorf("read mapping was mutated, got %v", perms)
+	}
+}
+
+func TestMapDenyAction(t *testing.T) {
+	am := NewActionMapper()
+
+	tests := []struct {
+		action    string
+		class     string
+		wantClass string
+		wantPerms []string
+	}{
+		{"read", "", "file", []string{"read"}},
+		{"write", "", "file", []string{"write", "append"}},
+		{"execute", "", "file", []string{"execute", "execute_no_trans"}},
+		{"list", "", "dir", []string{"read"}},
+		{"read", "dir", "dir", []string{"read"}},
+		{"delete", "", "file", []string{"unlink"}},
+		{"getattr", "", "file", []string{"getattr"}},
+		{"name_bind", "tcp_socket", "tcp_socket", []string{"name_bind"}},
+	}
+	for _, tt := range tests {
+		class, perms := am.MapDenyAction(tt.action, tt.class)
+		if class != tt.wantClass || strings.Join(perms, " ") != strings.Join(tt.wantPerms, " ") {
+			t.Errorf("MapDenyAction(%q, %q) = %s %v, want %s %v", tt.action, tt.class, class, perms, tt.wantClass, tt.wantPerms)
+		}
+	}
+}
+
+func TestValidatePermissions(t *testing.T) {
+	tests := []struct {
+		class string
+		perms []string
+		want  []string
+	}{
+		{"file", []string{"read", "open", "getattr"}, nil},
+		{"file", []string{"reed", "open", "serach"}, []string{"reed", "serach"}},
+		{"dir", []string{"search", "add_name"}, nil},
+		{"file", []string{"search"}, []string{"search"}},
+		{"tcp_socket", []string{"name_bind", "name_connect"}, nil},
+		{"capability", []string{"net_bind_service", "net_bind"}, []string{"net_bind"}},
+		{"no_such_class", []string{"anything"}, nil},
+	}
+
+	for _, tt := range tests {
+		got := ValidatePermissions(tt.class, tt.perms)
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("ValidatePermissions(%s, %v) = %v, want %v", tt.class, tt.perms, got, tt.want)
+		}
+	}
+
+	AddClassPermissions("test_object", "frob", "twiddle")
+	AddClassPermissions("test_object", "frob", "poke")
+	if got := ClassPermissions("test_object"); strings.Join(got, " ") != "frob poke twiddle" {
+		t.Errorf("ClassPermissions(test_object) = %v, want [frob poke twiddle]", got)
+	}
+	if got := ValidatePermissions("test_object", []string{"poke", "prod"}); strings.Join(got, " ") != "prod" {
+		t.Errorf("ValidatePermissions(test_object) = %v, want [prod]", got)
+	}
+}
@@ -0,0 +1,144 @@
+package mapping
+
+import "sort"
+
+// commonFilePermissions are shared by every file-like class (refpolicy common "file")
+var commonFilePermissions = []string{
+	"ioctl", "read", "write", "create", "getattr", "setattr", "lock", "relabelfrom",
+	"relabelto", "append", "map", "unlink", "link", "rename", "execute", "quotaon",
+	"mounton", "audit_access", "open", "execmod", "watch", "watch_mount", "watch_sb",
+	"watch_with_perm", "watch_reads",
+}
+
+// commonSocketPermissions are shared by every socket class (refpolicy common "socket")
+var commonSocketPermissions = []string{
+	"ioctl", "read", "write", "create", "getattr", "setattr", "lock", "relabelfrom",
+	"relabelto", "append", "map", "bind", "connect", "listen", "accept", "getopt",
+	"setopt", "shutdown", "recvfrom", "sendto", "name_bind",
+}
+
+// classPermissions is the full permission set of the object classes the compiler emits
+var classPermissions = map[string][]string{
+	"file":               withCommon(commonFilePermissions, "execute_no_trans", "entrypoint"),
+	"dir":                withCommon(commonFilePermissions, "add_name", "remove_name", "reparent", "search", "rmdir"),
+	"lnk_file":           commonFilePermissions,
+	"chr_file":           commonFilePermissions,
+	"blk_file":           commonFilePermissions,
+	"sock_file":          commonFilePermissions,
+	"fifo_file":          commonFilePermissions,
+	"tcp_socket":         withCommon(commonSocketPermissions, "node_bind", "name_connect"),
+	"udp_socket":         withCommon(commonSocketPermissions, "node_bind"),
+	"unix_stream_socket": withCommon(commonSocketPermissions, "connectto"),
+	"unix_dgram_socket":  commonSocketPermissions,
+	"process": {
+		"fork", "transition", "sigchld", "sigkill", "sigstop", "signull", "signal",
+		"ptrace", "getsched", "setsched", "getsession", "getpgid", "setpgid", "getcap",
+		"setcap", "share", "getattr", "setexec", "setfscreate", "noatsecure", "siginh",
+		"setrlimit", "rlimitinh", "dyntransition", "setcurrent", "execmem", "execstack",
+		"execheap", "setkeycreate", "setsockcreate", "getrlimit",
+	},
+	"packet": {"send", "recv", "relabelto", "forward_in", "forward_out"},
+	"capability": {
+		"chown", "dac_override", "dac_read_search", "fowner", "fsetid", "kill", "setgid",
+		"setuid", "setpcap", "linux_immutable", "net_bind_service", "net_broadcast",
+		"net_admin", "net_raw", "ipc_lock", "ipc_owner", "sys_module", "sys_rawio",
+		"sys_chroot", "sys_ptrace", "sys_pacct", "sys_admin", "sys_boot", "sys_nice",
+		"sys_resource", "sys_time", "sys_tty_config", "mknod", "lease", "audit_write",
+		"audit_control", "setfcap",
+	},
+}
+
+// withCommon appends class-specific permissions to a common permission set
+func withCommon(common []string, perms ...string) []string {
+	result := make([]string, 0, len(common)+len(perms))
+	result = append(result, common...)
+	return append(result, perms...)
+}
+
+// ClassPermissions returns the full permission set of a class, sorted,
+// or nil if the class is not known
+func ClassPermissions(class string) []string {
+	perms, ok := classPermissions[class]
+	if !ok {
+		return nil
+	}
+	sorted := append([]string(nil), perms...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+// Classes returns the classes of the permission table, sorted
+func Classes() []string {
+	classes := make([]string, 0, len(classPermissions))
+	for class := range classPermissions {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+	return classes
+}
+
+// ClassPermissionCount returns the number of permissions defined for a class, 0 if unknown
+func ClassPermissionCount(class string) int {
+	return len(classPermissions[class])
+}
+
+// standardClasses are the kernel object classes defined by refpolicy, beyond those in classPermissions
+var standardClasses = map[string]bool{
+	"security": true, "system": true, "filesystem": true, "fd": true, "anon_inode": true,
+	"capability2": true, "cap_userns": true, "cap2_userns": true, "socket": true,
+	"rawip_socket": true, "netlink_socket": true, "packet_socket": true, "key_socket": true,
+	"netlink_route_socket": true, "netlink_audit_socket": true, "netlink_selinux_socket": true,
+	"netlink_kobject_uevent_socket": true, "netlink_generic_socket": true, "icmp_socket": true,
+	"sctp_socket": true, "tun_socket": true, "vsock_socket": true, "node": true, "netif": true,
+	"peer": true, "association": true, "sem": true, "msg": true, "msgq": true,
+	"shm": true, "ipc": true, "key": true, "bpf": true, "perf_event": true, "lockdown": true,
+	"io_uring": true, "user_namespace": true, "dbus": true, "service": true, "passwd": true,
+	"kernel_service": true,
+}
+
+// IsStandardClass reports whether class is an object class the kernel policy defines
+func IsStandardClass(class string) bool {
+	_, known := classPermissions[class]
+	return known || standardClasses[class]
+}
+
+// IsKnownPermission reports whether perm is a permission of any class in classPermissions
+func IsKnownPermission(perm string) bool {
+	for _, perms := range classPermissions {
+		for _, p := range perms {
+			if p == perm {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AddClassPermissions adds permissions to a class of the permission table,
+// defining the class if it is not known yet. It is how policies using their
+// own object classes (e.g. from a userspace object manager) get them checked.
+func AddClassPermissions(class string, perms ...string) {
+	for _, perm := range perms {
+		if !containsString(classPermissions[class], perm) {
+			classPermissions[class] = append(classPermissions[class], perm)
+		}
+	}
+}
+
+// ValidatePermissions returns the permissions the class does not define, in
+// the order given. Classes missing from the table are not checked, so nil is
+// returned for them; IsStandardClass tells those apart.
+func ValidatePermissions(class string, perms []string) []string {
+	known, ok := classPermissions[class]
+	if !ok {
+		return nil
+	}
+
+	var unknown []string
+	for _, perm := range perms {
+		if !containsString(known, perm) {
+			unknown = append(unknown, perm)
+		}
+	}
+	return unknown
+}
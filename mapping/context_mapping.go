@@ -12,6 +12,15 @@ type PathMapper struct {
 	customMappings map[string]string
 }
 
+// criticalPaths are system paths that an application policy must never relabel
+var criticalPaths = []string{
+	"/",
+	"/etc/passwd",
+	"/etc/shadow",
+	"/bin/bash",
+	"/sbin/init",
+}
+
 // NewPathMapper creates a new PathMapper instance
 func NewPathMapper() *PathMapper {
 	return &PathMapper{
@@ -34,120 +43,212 @@ func (pm *PathMapper) AddCustomMapping(casbinPattern, selinuxPattern string) {
 //	/path/**/file        →  /path/.*/file (recursive subdirs)
 //	/etc/[a-z]*.conf     →  /etc/[a-z][^/]*\.conf (char class)
 //	/var/{log,tmp}/*     →  /var/(log|tmp)(/.*)? (brace expansion)
+//	/srv/file\[12\].txt  →  /srv/file\[12\]\.txt (escaped literals)
 func (pm *PathMapper) ConvertToSELinuxPattern(casbinPath string) string {
 	// Check for custom mapping first
 	if customPattern, ok := pm.customMappings[casbinPath]; ok {
 		return customPattern
 	}
 
-	pattern := casbinPath
-
-	// Handle brace expansion {a,b,c} → (a|b|c) BEFORE escaping
-	hasBraceExpansion := strings.Contains(pattern, "{")
-	pattern = pm.expandBraces(pattern)
+	return convertPattern(casbinPath, true)
+}
 
-	// Handle double-star /** pattern BEFORE handling /* (as /** can contain /*)
-	if strings.Contains(pattern, "/**") {
-		return pm.handleDoubleStarPattern(pattern)
-	}
+// convertPattern converts a Casbin path pattern to a SELinux regex in a single pass.
+// Constructs that cannot be paired up (unbalanced braces, brackets or parentheses,
+// invalid character classes) are escaped as literals so the result always compiles.
+// The trailing /* → (/.*)? rule only applies at the top level, not inside brace alternatives.
+func convertPattern(path string, topLevel bool) string {
+	var result strings.Builder
 
-	// Handle recursive patterns ending with /*
-	if strings.HasSuffix(pattern, "/*") {
-		// /var/www/* → /var/www(/.*)?
-		base := strings.TrimSuffix(pattern, "/*")
-		// Escape the base, but preserve regex patterns from brace expansion
-		base = pm.escapePreservingPatterns(base, hasBraceExpansion)
-		return base + "(/.*)?"
-	}
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		rest := path[i:]
+
+		switch {
+		// Trailing recursive patterns: /**, /**/*, and /* at the top level
+		case rest == "/**" || rest == "/**/*" || (topLevel && rest == "/*"):
+			result.WriteString("(/.*)?")
+			return result.String()
+
+		case c == '\\' && i+1 < len(path) && strings.IndexByte(escapableChars, path[i+1]) != -1:
+			// \[ \{ \* etc. are literal characters of a file name
+			result.WriteString(regexp.QuoteMeta(path[i+1 : i+2]))
+			i++
+
+		case c == '*' && strings.HasPrefix(rest, "**"):
+			// /usr/**/bin → /usr/.*/bin
+			result.WriteString(".*")
+			i++
+
+		case c == '*':
+			result.WriteString("[^/]+")
+
+		case c == '?':
+			result.WriteString(".")
+
+		case c == '{':
+			end := matchingClose(path, i, '{', '}')
+			if end == -1 || end == i+1 {
+				result.WriteString("\\{")
+				continue
+			}
+			// Brace expansion {a,b,c} → (a|b|c), alternatives may nest
+			alternatives := splitTopLevel(path[i+1:end], ',')
+			for j, alt := range alternatives {
+				alternatives[j] = convertPattern(strings.TrimSpace(alt), false)
+			}
+			result.WriteString("(" + strings.Join(alternatives, "|") + ")")
+			i = end
+
+		case c == '[':
+			end := strings.IndexByte(path[i+1:], ']')
+			if end <= 0 {
+				result.WriteString("\\[")
+				continue
+			}
+			class := path[i : i+end+2]
+			if _, err := regexp.Compile(class); err != nil {
+				result.WriteString("\\[")
+				continue
+			}
+			result.WriteString(class)
+			i += end + 1
+			// [a-z]* → [a-z][^/]* (the class already matches one character)
+			if i+1 < len(path) && path[i+1] == '*' && !strings.HasPrefix(path[i+1:], "**") {
+				result.WriteString("[^/]*")
+				i++
+			}
 
-	// Escape special regex characters except * and ? and character classes
-	if hasBraceExpansion {
-		pattern = pm.escapePreservingPatterns(pattern, true)
-	} else {
-		pattern = escapeRegexCharsPreservingWildcardsAndCharClasses(pattern)
-	}
+		case c == '(':
+			// Parenthesized groups are SELinux regex written by the user, e.g. (/.*)?
+			end := matchingClose(path, i, '(', ')')
+			if end == -1 {
+				result.WriteString("\\(")
+				continue
+			}
+			group := path[i : end+1]
+			if end+1 < len(path) && strings.IndexByte("?*+", path[end+1]) != -1 {
+				group += string(path[end+1])
+				end++
+			}
+			if _, err := regexp.Compile(group); err != nil {
+				result.WriteString("\\(")
+				continue
+			}
+			result.WriteString(group)
+			i = end
 
-	// Convert middle wildcards
-	// /etc/*.conf → /etc/[^/]+\.conf
-	// But preserve [^/]* if preceded by character class
-	pattern = pm.convertWildcards(pattern)
+		case strings.IndexByte("\\.+^$|-])}", c) != -1:
+			result.WriteByte('\\')
+			result.WriteByte(c)
 
-	// Convert ? to single character match
-	pattern = strings.ReplaceAll(pattern, "?", ".")
+		default:
+			result.WriteByte(c)
+		}
+	}
 
-	return pattern
+	return result.String()
 }
 
-// escapeRegexChars escapes special regex characters except * and ?
-func escapeRegexChars(s string) string {
-	// Escape backslash first to avoid double-escaping
-	result := strings.ReplaceAll(s, "\\", "\\\\")
+// escapableChars are the pattern characters a backslash turns into literals
+const escapableChars = `*?[]{}(),\`
 
-	// Then escape other special characters
-	specialChars := []string{".", "+", "(", ")", "[", "]", "{", "}", "^", "$", "|", "-"}
-	for _, char := range specialChars {
-		result = strings.ReplaceAll(result, char, "\\"+char)
+// indexUnescaped returns the index of the first character of chars in s that
+// is not escaped with a backslash, or -1
+func indexUnescaped(s string, chars string) int {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.IndexByte(chars, s[i]) != -1 {
+			return i
+		}
 	}
-
-	return result
+	return -1
 }
 
-// escapeRegexCharsPreservingWildcards escapes regex chars but preserves * and ?
-func escapeRegexCharsPreservingWildcards(s string) string {
-	// Escape backslash first to avoid double-escaping
-	result := strings.ReplaceAll(s, "\\", "\\\\")
-
-	// Then escape other special characters (but keep * and ? for later processing)
-	specialChars := []string{".", "+", "(", ")", "[", "]", "{", "}", "^", "$", "|", "-"}
-	for _, char := range specialChars {
-		result = strings.ReplaceAll(result, char, "\\"+char)
+// matchingClose returns the index of the delimiter closing the one at start, or -1
+func matchingClose(s string, start int, open, close byte) int {
+	depth := 0
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case open:
+			depth++
+		case close:
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
 	}
-
-	// * and ? are left as-is for further processing
-	return result
+	return -1
 }
 
-// escapeRegexCharsPreservingWildcardsAndCharClasses escapes regex chars but preserves *, ?, and [...]
-func escapeRegexCharsPreservingWildcardsAndCharClasses(s string) string {
-	// Protect both character classes [...] and parentheses from brace expansion (...)
-	// First, protect parentheses and pipes from brace expansion
-	s = strings.ReplaceAll(s, "(", "__LPAREN__")
-	s = strings.ReplaceAll(s, ")", "__RPAREN__")
-	s = strings.ReplaceAll(s, "|", "__PIPE__")
-
-	// Use regex to protect character classes temporarily
-	charClassRegex := regexp.MustCompile(`\[([^\]]+)\]`)
-
-	// Find all character classes
-	matches := charClassRegex.FindAllStringSubmatchIndex(s, -1)
-
-	// Build result by processing non-character-class parts
-	var result strings.Builder
-	lastIndex := 0
-
-	for _, match := range matches {
-		// Process text before character class
-		beforeClass := s[lastIndex:match[0]]
-		result.WriteString(escapeRegexCharsPreservingWildcards(beforeClass))
-
-		// Add character class as-is
-		result.WriteString(s[match[0]:match[1]])
+// splitTopLevel splits s on sep, ignoring separators nested inside braces
+func splitTopLevel(s string, sep byte) []string {
+	parts := []string{}
+	depth := 0
+	last := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case sep:
+			if depth == 0 {
+				parts = append(parts, s[last:i])
+				last = i + 1
+			}
+		}
+	}
+	return append(parts, s[last:])
+}
 
-		lastIndex = match[1]
+// ExpandBraces expands the brace alternatives of a path pattern into one path
+// per combination: /dev/{sda,null} gives /dev/sda and /dev/null. Braces may
+// nest; a path with empty or unbalanced braces is returned as is.
+func ExpandBraces(path string) []string {
+	start := indexUnescaped(path, "{")
+	if start == -1 {
+		return []string{path}
+	}
+	end := matchingClose(path, start, '{', '}')
+	if end == -1 || end == start+1 {
+		return []string{path}
 	}
 
-	// Process remaining text after last character class
-	if lastIndex < len(s) {
-		result.WriteString(escapeRegexCharsPreservingWildcards(s[lastIndex:]))
+	expanded := []string{}
+	for _, alt := range splitTopLevel(path[start+1:end], ',') {
+		expanded = append(expanded, ExpandBraces(path[:start]+strings.TrimSpace(alt)+path[end+1:])...)
 	}
+	return expanded
+}
 
-	// Restore parentheses and pipes
-	finalResult := result.String()
-	finalResult = strings.ReplaceAll(finalResult, "__LPAREN__", "(")
-	finalResult = strings.ReplaceAll(finalResult, "__RPAREN__", ")")
-	finalResult = strings.ReplaceAll(finalResult, "__PIPE__", "|")
+// escapeRegexChars escapes special regex characters except * and ?;
+// escaped pattern characters such as \[ become their regex literal
+func escapeRegexChars(s string) string {
+	var result strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		// An escaped pattern character such as \[ already is a literal
+		if c == '\\' && i+1 < len(s) && strings.IndexByte(escapableChars, s[i+1]) != -1 {
+			result.WriteString(regexp.QuoteMeta(s[i+1 : i+2]))
+			i++
+			continue
+		}
+		if strings.IndexByte(`\.+()[]{}^$|-`, c) != -1 {
+			result.WriteByte('\\')
+		}
+		result.WriteByte(c)
+	}
 
-	return finalResult
+	return result.String()
 }
 
 // IsDirectoryPattern checks if a path pattern represents a directory
@@ -316,6 +417,20 @@ func GetFileTypeSpecifier(fileType string) string {
 	}
 }
 
+// FileContextSpecifier returns the specifier a file context's file type is
+// rendered with: an unset file type means regular files ("--"), inferred file
+// type names map through GetFileTypeSpecifier ("all files" to none) and
+// specifiers are kept as they are
+func FileContextSpecifier(fileType string) string {
+	if fileType == "" {
+		return "--"
+	}
+	if strings.HasPrefix(fileType, "-") {
+		return fileType
+	}
+	return strings.TrimSpace(GetFileTypeSpecifier(fileType))
+}
+
 // ValidatePattern validates if a pattern is a valid SELinux file context pattern
 func (pm *PathMapper) ValidatePattern(pattern string) error {
 	// Check if pattern is a valid regex
@@ -372,8 +487,8 @@ func ExtractBasePath(path string) string {
 		return strings.TrimSuffix(path, "/*")
 	}
 
-	// Find the first wildcard
-	wildcardPos := strings.IndexAny(path, "*?")
+	// Find the first wildcard; escaped ones are part of a name
+	wildcardPos := indexUnescaped(path, "*?")
 	if wildcardPos == -1 {
 		return path
 	}
@@ -388,115 +503,6 @@ func ExtractBasePath(path string) string {
 	return "/"
 }
 
-// expandBraces expands brace patterns like {a,b,c} into (a|b|c)
-// Example: /var/{log,tmp}/* → /var/(log|tmp)/*
-func (pm *PathMapper) expandBraces(path string) string {
-	// Find brace patterns using regex
-	braceRegex := regexp.MustCompile(`\{([^}]+)\}`)
-	return braceRegex.ReplaceAllStringFunc(path, func(match string) string {
-		// Extract content between braces
-		content := match[1 : len(match)-1]
-		// Split by comma
-		alternatives := strings.Split(content, ",")
-		// Trim whitespace from each alternative
-		for i := range alternatives {
-			alternatives[i] = strings.TrimSpace(alternatives[i])
-		}
-		// Join with | for regex alternation
-		return "(" + strings.Join(alternatives, "|") + ")"
-	})
-}
-
-// handleDoubleStarPattern handles /** patterns for recursive subdirectory matching
-// Example: /usr/**/bin → /usr/.*/bin
-func (pm *PathMapper) handleDoubleStarPattern(path string) string {
-	// Check if path already contains regex patterns from brace expansion
-	hasRegexPatterns := strings.Contains(path, "(") && strings.Contains(path, "|")
-
-	// Check if it also ends with /*
-	endsWithSlashStar := strings.HasSuffix(path, "/*")
-	if endsWithSlashStar {
-		// Remove the /* suffix temporarily
-		path = strings.TrimSuffix(path, "/*")
-	}
-
-	// Special case: if path ends with /**, just add (/.*)?
-	if strings.HasSuffix(path, "/**") {
-		base := strings.TrimSuffix(path, "/**")
-		// Preserve existing regex patterns
-		if hasRegexPatterns {
-			base = escapeRegexCharsPreservingWildcardsAndCharClasses(base)
-		} else {
-			base = escapeRegexChars(base)
-		}
-		result := base + "(/.*)?"
-		// If originally ended with /*, that's already covered by (/.*)?
-		return result
-	}
-
-	// Escape special characters except ** and /
-	parts := strings.Split(path, "/**")
-	escapedParts := make([]string, len(parts))
-
-	for i, part := range parts {
-		// Skip empty parts
-		if part == "" {
-			continue
-		}
-
-		// Process wildcards in each part, preserving regex patterns
-		if hasRegexPatterns {
-			part = escapeRegexCharsPreservingWildcardsAndCharClasses(part)
-			// Need to convert remaining wildcards
-			part = pm.convertWildcards(part)
-		} else if strings.Contains(part, "*") {
-			part = escapeRegexCharsPreservingWildcards(part)
-			part = strings.ReplaceAll(part, "*", "[^/]+")
-		} else {
-			part = escapeRegexChars(part)
-		}
-		escapedParts[i] = part
-	}
-
-	// Join with .* for recursive matching
-	result := strings.Join(escapedParts, "/.*")
-
-	// Add /* pattern if it was there
-	if endsWithSlashStar {
-		result += "(/.*)?"
-	}
-
-	return result
-}
-
-// convertWildcards converts wildcards to regex patterns, handling character classes specially
-func (pm *PathMapper) convertWildcards(path string) string {
-	// First, handle character classes followed by wildcards
-	// [a-z]* should become [a-z][^/]* not [a-z][^/]+
-	// Use placeholder to avoid double replacement
-	charClassWildcard := regexp.MustCompile(`(\[[^\]]+\])\*`)
-	path = charClassWildcard.ReplaceAllString(path, "${1}__CHARWILD__")
-
-	// Convert remaining standalone * to [^/]+
-	path = strings.ReplaceAll(path, "*", "[^/]+")
-
-	// Restore character class wildcards
-	path = strings.ReplaceAll(path, "__CHARWILD__", "[^/]*")
-
-	return path
-}
-
-// escapePreservingPatterns escapes special characters while preserving regex patterns from expansions
-func (pm *PathMapper) escapePreservingPatterns(path string, preserveRegex bool) string {
-	if !preserveRegex {
-		return escapeRegexChars(path)
-	}
-
-	// Use the same logic as escapeRegexCharsPreservingWildcardsAndCharClasses
-	// to also preserve character classes
-	return escapeRegexCharsPreservingWildcardsAndCharClasses(path)
-}
-
 // MatchPattern checks if a path matches a SELinux pattern (for validation)
 func (pm *PathMapper) MatchPattern(selinuxPattern, testPath string) (bool, error) {
 	// Compile the pattern as a regex
@@ -508,6 +514,21 @@ func (pm *PathMapper) MatchPattern(selinuxPattern, testPath string) (bool, error
 	return regex.MatchString(testPath), nil
 }
 
+// MatchCriticalPaths returns the system-critical paths matched by a SELinux pattern
+func (pm *PathMapper) MatchCriticalPaths(selinuxPattern string) ([]string, error) {
+	matched := []string{}
+	for _, path := range criticalPaths {
+		ok, err := pm.MatchPattern(selinuxPattern, path)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matched = append(matched, path)
+		}
+	}
+	return matched, nil
+}
+
 // InferContextType determines the SELinux type based on path characteristics
 // This provides smart type suggestions for file contexts
 func (pm *PathMapper) InferContextType(path string) string {
@@ -559,6 +580,32 @@ func (pm *PathMapper) InferContextType(path string) string {
 	return "default_t"
 }
 
+// homeDirPattern is the file context pattern of any user's home directory,
+// as converted from /home/*
+const homeDirPattern = "/home/[^/]+"
+
+// HomeDirTemplate rewrites a file context pattern under /home into the
+// genhomedircon template refpolicy uses, so the contexts follow each user's
+// actual home directory:
+//
+//	/home/[^/]+/public_html(/.*)?  →  HOME_DIR/public_html(/.*)?
+//	/home/[^/]+                    →  HOME_DIR
+//	/home(/.*)?                    →  HOME_ROOT(/.*)?
+//	/home/lost\+found              →  HOME_ROOT/lost\+found
+//
+// Other patterns, including root's home /root, are not templated.
+func HomeDirTemplate(pattern string) (string, bool) {
+	if rest, ok := strings.CutPrefix(pattern, homeDirPattern); ok &&
+		(rest == "" || strings.HasPrefix(rest, "/") || strings.HasPrefix(rest, "(/")) {
+		return "HOME_DIR" + rest, true
+	}
+	if rest, ok := strings.CutPrefix(pattern, "/home"); ok &&
+		(rest == "" || strings.HasPrefix(rest, "/") || strings.HasPrefix(rest, "(/")) {
+		return "HOME_ROOT" + rest, true
+	}
+	return pattern, false
+}
+
 // SplitPathPattern splits a complex pattern into base and wildcard parts
 // Useful for generating more precise SELinux patterns
 func (pm *PathMapper) SplitPathPattern(path string) (base, wildcard string) {
@@ -1,6 +1,7 @@
 package mapping
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -366,3 +367,158 @@ func TestPathMapper_SystemdPaths(t *testing.T) {
 		})
 	}
 }
+
+// TestPathMapper_MatchCriticalPaths tests detection of patterns covering system-critical paths
+func TestPathMapper_MatchCriticalPaths(t *testing.T) {
+	mapper := NewPathMapper()
+
+	tests := []struct {
+		name     string
+		pattern  string
+		expected []string
+	}{
+		{name: "whole etc", pattern: "/etc(/.*)?", expected: []string{"/etc/passwd", "/etc/shadow"}},
+		{name: "everything", pattern: "(/.*)?", expected: []string{"/", "/etc/passwd", "/etc/shadow", "/bin/bash", "/sbin/init"}},
+		{name: "etc wildcard", pattern: "/etc/[^/]+", expected: []string{"/etc/passwd", "/etc/shadow"}},
+		{name: "application directory", pattern: "/etc/myapp(/.*)?", expected: []string{}},
+		{name: "application config", pattern: "/etc/[^/]+\\.conf", expected: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matched, err := mapper.MatchCriticalPaths(tt.pattern)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if strings.Join(matched, " ") != strings.Join(tt.expected, " ") {
+				t.Errorf("MatchCriticalPaths(%q) = %v, want %v", tt.pattern, matched, tt.expected)
+			}
+		})
+	}
+}
+
+func TestExpandBraces(t *testing.T) {
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"/dev/{sda,null}", []string{"/dev/sda", "/dev/null"}},
+		{"/srv/{a,b}/{x,y}.conf", []string{"/srv/a/x.conf", "/srv/a/y.conf", "/srv/b/x.conf", "/srv/b/y.conf"}},
+		{"/opt/{app,lib/{a,b}}", []string{"/opt/app", "/opt/lib/a", "/opt/lib/b"}},
+		{"/var/log/*", []string{"/var/log/*"}},
+		{"/etc/{}", []string{"/etc/{}"}},
+		{"/etc/{a,b", []string{"/etc/{a,b"}},
+		{`/srv/\{raw\}/{a,b}`, []string{`/srv/\{raw\}/a`, `/srv/\{raw\}/b`}},
+		{`/srv/{a\,b,c}`, []string{`/srv/a\,b`, "/srv/c"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got := ExpandBraces(tt.path)
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("ExpandBraces(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPathMapper_BraceGroups(t *testing.T) {
+	mapper := NewPathMapper()
+
+	tests := []struct {
+		name    string
+		path    string
+		pattern string
+		matches []string
+	}{
+		{name: "single group", path: "/opt/{foo,bar}/conf", pattern: "/opt/(foo|bar)/conf", matches: []string{"/opt/foo/conf", "/opt/bar/conf"}},
+		{name: "two groups", path: "/var/{log,cache}/{app,db}/*", pattern: "/var/(log|cache)/(app|db)(/.*)?", matches: []string{"/var/log/app/x", "/var/log/db/x", "/var/cache/app/x", "/var/cache/db/x"}},
+		{name: "nested group", path: "/srv/{a,{b,c}}/x", pattern: "/srv/(a|(b|c))/x", matches: []string{"/srv/a/x", "/srv/b/x", "/srv/c/x"}},
+		{name: "nested group with suffix", path: "/srv/{a,b{c,d}}", pattern: "/srv/(a|b(c|d))", matches: []string{"/srv/a", "/srv/bc", "/srv/bd"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pattern := mapper.ConvertToSELinuxPattern(tt.path)
+			if pattern != tt.pattern {
+				t.Fatalf("ConvertToSELinuxPattern(%q) = %q, want %q", tt.path, pattern, tt.pattern)
+			}
+			for _, path := range tt.matches {
+				if matched, err := mapper.MatchPattern(pattern, path); err != nil || !matched {
+					t.Errorf("pattern %q does not match %q (err %v)", pattern, path, err)
+				}
+			}
+			if matched, _ := mapper.MatchPattern(pattern, "/srv/other/x"); matched {
+				t.Errorf("pattern %q matches /srv/other/x", pattern)
+			}
+		})
+	}
+}
+
+func TestPathMapper_EscapedLiterals(t *testing.T) {
+	mapper := NewPathMapper()
+
+	tests := []struct {
+		name    string
+		path    string
+		pattern string
+		matches string
+	}{
+		{name: "literal brackets", path: `/var/data/file\[1\].txt`, pattern: `/var/data/file\[1\]\.txt`, matches: "/var/data/file[1].txt"},
+		{name: "literal braces", path: `/srv/\{raw\}`, pattern: `/srv/\{raw\}`, matches: "/srv/{raw}"},
+		{name: "literal star", path: `/srv/star\*`, pattern: `/srv/star\*`, matches: "/srv/star*"},
+		{name: "literal question mark", path: `/srv/what\?`, pattern: `/srv/what\?`, matches: "/srv/what?"},
+		{name: "escaped comma in braces", path: `/srv/{a\,b,c}`, pattern: `/srv/(a,b|c)`, matches: "/srv/a,b"},
+		{name: "literal backslash", path: `/srv/a\\b`, pattern: `/srv/a\\b`, matches: `/srv/a\b`},
+		{name: "escaped base of recursive pattern", path: `/srv/\[x\]/*`, pattern: `/srv/\[x\](/.*)?`, matches: "/srv/[x]/file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patterns := mapper.GenerateRecursivePatterns(tt.path)
+			if len(patterns) != 1 || patterns[0].Pattern != tt.pattern {
+				t.Fatalf("GenerateRecursivePatterns(%q) = %+v, want pattern %q", tt.path, patterns, tt.pattern)
+			}
+			matched, err := mapper.MatchPattern(patterns[0].Pattern, tt.matches)
+			if err != nil || !matched {
+				t.Errorf("pattern %q does not match %q (err %v)", patterns[0].Pattern, tt.matches, err)
+			}
+		})
+	}
+
+	typeName := NewTypeMapper("app").PathToType(`/var/data/file\[1\].txt`)
+	if typeName != "app_var_data_file1_txt_t" {
+		t.Errorf("PathToType() = %q, want app_var_data_file1_txt_t", typeName)
+	}
+}
+
+// TestHomeDirTemplate tests HOME_DIR and HOME_ROOT templating of converted home paths
+func TestHomeDirTemplate(t *testing.T) {
+	mapper := NewPathMapper()
+
+	tests := []struct {
+		path     string
+		want     string
+		template bool
+	}{
+		{"/home/*", "HOME_ROOT(/.*)?", true},
+		{"/home/*/.config", `HOME_DIR/\.config`, true},
+		{"/home/*/.config/*", `HOME_DIR/\.config(/.*)?`, true},
+		{"/home/*/*", "HOME_DIR(/.*)?", true},
+		{"/home/*/public_html/*", "HOME_DIR/public_html(/.*)?", true},
+		{"/home", "HOME_ROOT", true},
+		{"/root", "/root", false},
+		{"/root/*", "/root(/.*)?", false},
+		{"/homework/*", "/homework(/.*)?", false},
+		{"/var/home/*", "/var/home(/.*)?", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, ok := HomeDirTemplate(mapper.ConvertToSELinuxPattern(tt.path))
+			if got != tt.want || ok != tt.template {
+				t.Errorf("HomeDirTemplate(%s) = %q, %v, want %q, %v", tt.path, got, ok, tt.want, tt.template)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package mapping
+
+import (
+	"regexp"
+	"testing"
+	"unicode/utf8"
+)
+
+// malformedPatterns are inputs that used to produce invalid or surprising regexes
+var malformedPatterns = []struct {
+	name     string
+	input    string
+	expected string
+}{
+	{name: "placeholder text is literal", input: "/a/__LPAREN__b", expected: "/a/__LPAREN__b"},
+	{name: "char wildcard placeholder is literal", input: "/x/__CHARWILD__", expected: "/x/__CHARWILD__"},
+	{name: "unclosed brace", input: "/a/{b", expected: "/a/\\{b"},
+	{name: "unopened brace", input: "/a/b}", expected: "/a/b\\}"},
+	{name: "empty braces", input: "/a/{}", expected: "/a/\\{\\}"},
+	{name: "nested braces", input: "/a/{b,{c,d}}/e", expected: "/a/(b|(c|d))/e"},
+	{name: "unclosed bracket", input: "/a/[abc", expected: "/a/\\[abc"},
+	{name: "empty bracket", input: "/a/[]x]", expected: "/a/\\[\\]x\\]"},
+	{name: "unclosed paren", input: "/a/(b", expected: "/a/\\(b"},
+	{name: "unopened paren", input: "/a/b)", expected: "/a/b\\)"},
+	{name: "bare pipe", input: "/a|b", expected: "/a\\|b"},
+	{name: "existing recursive suffix", input: "/opt/myweb/config(/.*)?", expected: "/opt/myweb/config(/.*)?"},
+	{name: "bare double star", input: "**", expected: ".*"},
+	{name: "root double star", input: "/**", expected: "(/.*)?"},
+	{name: "double star inside segment", input: "/a/**b", expected: "/a/.*b"},
+	{name: "triple star", input: "/a/***", expected: "/a/.*[^/]+"},
+	{name: "trailing backslash", input: "/a\\", expected: "/a\\\\"},
+}
+
+func TestPathMapper_MalformedPatterns(t *testing.T) {
+	mapper := NewPathMapper()
+
+	for _, tt := range malformedPatterns {
+		t.Run(tt.name, func(t *testing.T) {
+			result := mapper.ConvertToSELinuxPattern(tt.input)
+			if result != tt.expected {
+				t.Errorf("ConvertToSELinuxPattern(%q) = %q, want %q", tt.input, result, tt.expected)
+			}
+			if _, err := regexp.Compile(result); err != nil {
+				t.Errorf("ConvertToSELinuxPattern(%q) produced invalid regex %q: %v", tt.input, result, err)
+			}
+		})
+	}
+}
+
+func FuzzConvertToSELinuxPattern(f *testing.F) {
+	seeds := []string{
+		"/var/www/*",
+		"/etc/*.conf",
+		"/usr/**/bin",
+		"/etc/[a-z]*.conf",
+		"/var/{log,tmp}/*",
+		"/a/b?c",
+		"/my-app/x+y",
+	}
+	for _, tt := range malformedPatterns {
+		seeds = append(seeds, tt.input)
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	mapper := NewPathMapper()
+	f.Fuzz(func(t *testing.T, input string) {
+		// Policy files are text, regexp rejects invalid UTF-8 regardless of escaping
+		if !utf8.ValidString(input) {
+			t.Skip()
+		}
+		result := mapper.ConvertToSELinuxPattern(input)
+		if _, err := regexp.Compile(result); err != nil {
+			t.Errorf("ConvertToSELinuxPattern(%q) produced invalid regex %q: %v", input, result, err)
+		}
+	})
+}
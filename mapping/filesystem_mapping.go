@@ -358,6 +358,18 @@ func isValidContext(context string) bool {
 	return len(parts) == 4 && parts[0] != "" && parts[1] != "" && parts[2] != "" && parts[3] != ""
 }
 
+// WellKnownPortType returns the type the base policy labels a common port with,
+// as listed by GeneratePortconRules, e.g. http_port_t for tcp 8080
+func (fm *FilesystemMapper) WellKnownPortType(protocol string, port int) (string, bool) {
+	for _, rule := range fm.GeneratePortconRules() {
+		if rule.Protocol == protocol && rule.Port == port && rule.PortEnd == 0 {
+			parts := strings.Split(rule.Context, ":")
+			return parts[2], true
+		}
+	}
+	return "", false
+}
+
 // GeneratePortconRules generates portcon rules for common network ports
 func (fm *FilesystemMapper) GeneratePortconRules() []PortconRule {
 	rules := []PortconRule{
@@ -0,0 +1,70 @@
+package mapping
+
+import "strings"
+
+// Parameter kinds of refpolicy interfaces
+const (
+	InterfaceParamType  = "type"  // A type or attribute, e.g. the calling domain
+	InterfaceParamClass = "class" // An object class or class set
+	InterfaceParamName  = "name"  // A literal, e.g. a filename or template prefix
+)
+
+// InterfaceSignature lists the parameter kinds of a refpolicy interface
+type InterfaceSignature struct {
+	Params   []string // Kind of each parameter, in order
+	Optional int      // Trailing parameters that may be left out
+}
+
+// knownInterfaces are common refpolicy interfaces whose calls are checked.
+// Calls to other interfaces are passed through unchecked.
+var knownInterfaces = map[string]InterfaceSignature{
+	"apache_read_config":            {Params: []string{InterfaceParamType}},
+	"auth_use_nsswitch":             {Params: []string{InterfaceParamType}},
+	"corenet_tcp_bind_http_port":    {Params: []string{InterfaceParamType}},
+	"corenet_tcp_connect_http_port": {Params: []string{InterfaceParamType}},
+	"dev_read_urand":                {Params: []string{InterfaceParamType}},
+	"domain_type":                   {Params: []string{InterfaceParamType}},
+	"domain_entry_file":             {Params: []string{InterfaceParamType, InterfaceParamType}},
+	"files_config_file":             {Params: []string{InterfaceParamType}},
+	"files_pid_file":                {Params: []string{InterfaceParamType}},
+	"files_read_etc_files":          {Params: []string{InterfaceParamType}},
+	"files_search_var_lib":          {Params: []string{InterfaceParamType}},
+	"files_tmp_file":                {Params: []string{InterfaceParamType}},
+	"files_type":                    {Params: []string{InterfaceParamType}},
+	"files_pid_filetrans":           {Params: []string{InterfaceParamType, InterfaceParamType, InterfaceParamClass, InterfaceParamName}, Optional: 1},
+	"files_tmp_filetrans":           {Params: []string{InterfaceParamType, InterfaceParamType, InterfaceParamClass, InterfaceParamName}, Optional: 1},
+	"init_daemon_domain":            {Params: []string{InterfaceParamType, InterfaceParamType}},
+	"kernel_read_system_state":      {Params: []string{InterfaceParamType}},
+	"logging_log_file":              {Params: []string{InterfaceParamType}},
+	"logging_log_filetrans":         {Params: []string{InterfaceParamType, InterfaceParamType, InterfaceParamClass, InterfaceParamName}, Optional: 1},
+	"logging_send_syslog_msg":       {Params: []string{InterfaceParamType}},
+	"miscfiles_read_localization":   {Params: []string{InterfaceParamType}},
+	"sysnet_dns_name_resolve":       {Params: []string{InterfaceParamType}},
+	"userdom_user_home_content":     {Params: []string{InterfaceParamType}},
+}
+
+// LookupInterface returns the signature of a known refpolicy interface
+func LookupInterface(name string) (InterfaceSignature, bool) {
+	sig, ok := knownInterfaces[name]
+	return sig, ok
+}
+
+// AcceptsArgs reports whether a call may pass count arguments
+func (s InterfaceSignature) AcceptsArgs(count int) bool {
+	return count <= len(s.Params) && count >= len(s.Params)-s.Optional
+}
+
+// InterfaceTypeArgs returns the arguments of a call that name types. For a
+// known interface these are its type parameters; for others, the arguments
+// following the _t naming convention.
+func InterfaceTypeArgs(name string, args []string) []string {
+	var types []string
+	sig, known := LookupInterface(name)
+	for i, arg := range args {
+		if known && i < len(sig.Params) && sig.Params[i] == InterfaceParamType ||
+			!known && strings.HasSuffix(arg, "_t") {
+			types = append(types, arg)
+		}
+	}
+	return types
+}
@@ -0,0 +1,32 @@
+package mapping
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInterfaceSignature(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		accepts  bool
+		typeArgs string
+	}{
+		{"apache_read_config", []string{"myapp_t"}, true, "myapp_t"},
+		{"apache_read_config", []string{"myapp_t", "httpd_t"}, false, "myapp_t"},
+		{"files_pid_filetrans", []string{"myapp_t", "myapp_run_t", "file"}, true, "myapp_t,myapp_run_t"},
+		{"files_pid_filetrans", []string{"myapp_t", "myapp_run_t", "file", `"app.pid"`}, true, "myapp_t,myapp_run_t"},
+		{"files_pid_filetrans", []string{"myapp_t", "myapp_run_t"}, false, "myapp_t,myapp_run_t"},
+		{"custom_interface", []string{"myapp_t", "literal"}, true, "myapp_t"},
+	}
+
+	for _, tt := range tests {
+		sig, known := LookupInterface(tt.name)
+		if accepts := !known || sig.AcceptsArgs(len(tt.args)); accepts != tt.accepts {
+			t.Errorf("%s%v accepted = %v, want %v", tt.name, tt.args, accepts, tt.accepts)
+		}
+		if got := strings.Join(InterfaceTypeArgs(tt.name, tt.args), ","); got != tt.typeArgs {
+			t.Errorf("InterfaceTypeArgs(%s, %v) = %s, want %s", tt.name, tt.args, got, tt.typeArgs)
+		}
+	}
+}
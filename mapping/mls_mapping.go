@@ -0,0 +1,209 @@
+package mapping
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cici0602/pml-to-selinux/models"
+)
+
+// LevelMapper handles conversion from named PML security levels to MLS levels
+type LevelMapper struct {
+	// Named level to sensitivity mappings
+	levels map[string]int
+	// Named category to category number mappings, e.g. "hr" → c0
+	categories map[string]int
+}
+
+// NewLevelMapper creates a new LevelMapper with default level names
+func NewLevelMapper() *LevelMapper {
+	return &LevelMapper{
+		levels: map[string]int{
+			"public":       0,
+			"internal":     1,
+			"confidential": 2,
+			"secret":       3,
+			"top_secret":   4,
+		},
+		categories: make(map[string]int),
+	}
+}
+
+// AddLevel adds a custom named level
+func (lm *LevelMapper) AddLevel(name string, sensitivity int) {
+	lm.levels[strings.ToLower(name)] = sensitivity
+}
+
+// AddCategory adds a custom named category
+func (lm *LevelMapper) AddCategory(name string, category int) {
+	lm.categories[strings.ToLower(name)] = category
+}
+
+// Define adds a named level or category from a "name=s5" or "name=c0" definition
+func (lm *LevelMapper) Define(definition string) error {
+	name, value, ok := strings.Cut(definition, "=")
+	name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+	if !ok || name == "" || len(value) < 2 {
+		return fmt.Errorf("invalid level definition '%s', expected 'name=sN' or 'name=cN'", definition)
+	}
+	num, err := strconv.Atoi(value[1:])
+	if err != nil || num < 0 {
+		return fmt.Errorf("invalid level definition '%s', expected 'name=sN' or 'name=cN'", definition)
+	}
+
+	switch value[0] {
+	case 's':
+		lm.AddLevel(name, num)
+	case 'c':
+		lm.AddCategory(name, num)
+	default:
+		return fmt.Errorf("invalid level definition '%s', expected 'name=sN' or 'name=cN'", definition)
+	}
+	return nil
+}
+
+// HasLevel reports whether name is a named level
+func (lm *LevelMapper) HasLevel(name string) bool {
+	_, ok := lm.levels[strings.ToLower(name)]
+	return ok
+}
+
+// InferLevelFromPath infers a level from the named levels appearing as path
+// components, the deepest one winning: /data/secret/* → s3. It reports false
+// when no component names a level.
+func (lm *LevelMapper) InferLevelFromPath(path string) (models.SecurityLevel, bool) {
+	var level models.SecurityLevel
+	found := false
+	components := strings.FieldsFunc(strings.ToLower(path), func(r rune) bool {
+		return r == '/' || r == '-' || r == '.'
+	})
+	for _, component := range components {
+		if sens, ok := lm.levels[component]; ok {
+			level, found = models.SecurityLevel{Sensitivity: sens}, true
+		}
+	}
+	return level, found
+}
+
+// MapLevel maps a named level or raw SELinux level to a SecurityLevel. The
+// sensitivity and each category may be given by name or number.
+// Examples:
+//
+//	internal          →  s1
+//	s2                →  s2
+//	s0:c1,c3          →  s0:c1,c3
+//	confidential:hr   →  s2:c0 (with hr added as category 0)
+func (lm *LevelMapper) MapLevel(level string) (models.SecurityLevel, error) {
+	level = strings.TrimSpace(level)
+	if level == "" {
+		return models.SecurityLevel{}, fmt.Errorf("security level cannot be empty")
+	}
+
+	sensPart, catPart, hasCats := strings.Cut(level, ":")
+	sens, named := lm.levels[strings.ToLower(sensPart)]
+	if !named {
+		sensitivity, err := parseRawLevel(sensPart)
+		if err != nil {
+			return models.SecurityLevel{}, fmt.Errorf("unknown security level '%s'", level)
+		}
+		sens = sensitivity.Sensitivity
+	}
+
+	result := models.SecurityLevel{Sensitivity: sens}
+	if !hasCats {
+		return result, nil
+	}
+	for _, cat := range strings.Split(catPart, ",") {
+		cat = strings.TrimSpace(cat)
+		if num, ok := lm.categories[strings.ToLower(cat)]; ok {
+			result.Categories = append(result.Categories, num)
+			continue
+		}
+		parsed, err := parseRawLevel("s0:" + cat)
+		if err != nil {
+			return models.SecurityLevel{}, fmt.Errorf("invalid category '%s' in level '%s'", cat, level)
+		}
+		result.Categories = append(result.Categories, parsed.Categories...)
+	}
+
+	return result, nil
+}
+
+// MapRange maps a level range such as "confidential-secret" or "s0-s3:c0,c1"
+// A single level maps to a range where low and high are equal
+func (lm *LevelMapper) MapRange(levelRange string) (models.SecurityRange, error) {
+	parts := strings.SplitN(levelRange, "-", 2)
+
+	low, err := lm.MapLevel(parts[0])
+	if err != nil {
+		return models.SecurityRange{}, err
+	}
+	if len(parts) == 1 {
+		return models.SecurityRange{Low: low, High: low}, nil
+	}
+
+	high, err := lm.MapLevel(parts[1])
+	if err != nil {
+		return models.SecurityRange{}, err
+	}
+	return models.SecurityRange{Low: low, High: high}, nil
+}
+
+// parseRawLevel parses an SELinux level like "s2" or "s2:c0,c5"
+func parseRawLevel(level string) (models.SecurityLevel, error) {
+	sensPart, catPart, _ := strings.Cut(level, ":")
+
+	if !strings.HasPrefix(sensPart, "s") {
+		return models.SecurityLevel{}, fmt.Errorf("unknown security level '%s'", level)
+	}
+	sens, err := strconv.Atoi(sensPart[1:])
+	if err != nil || sens < 0 {
+		return models.SecurityLevel{}, fmt.Errorf("invalid sensitivity in level '%s'", level)
+	}
+
+	result := models.SecurityLevel{Sensitivity: sens}
+	if catPart == "" {
+		return result, nil
+	}
+
+	for _, cat := range strings.Split(catPart, ",") {
+		cat = strings.TrimSpace(cat)
+		if !strings.HasPrefix(cat, "c") {
+			return models.SecurityLevel{}, fmt.Errorf("invalid category '%s' in level '%s'", cat, level)
+		}
+		num, err := strconv.Atoi(cat[1:])
+		if err != nil || num < 0 {
+			return models.SecurityLevel{}, fmt.Errorf("invalid category '%s' in level '%s'", cat, level)
+		}
+		result.Categories = append(result.Categories, num)
+	}
+
+	return result, nil
+}
+
+// mlsFileClasses are the file classes covered by the MLS constraints
+var mlsFileClasses = []string{"file", "dir", "lnk_file", "chr_file", "blk_file", "sock_file", "fifo_file"}
+
+// GenerateMLSConstraints returns the Bell-LaPadula constraints enforcing file
+// levels: reading requires the subject's level to dominate the object's (no
+// read-up), writing requires the object's level to dominate the subject's (no
+// write-down)
+func GenerateMLSConstraints() []models.Constraint {
+	return []models.Constraint{
+		{
+			Classes:     mlsFileClasses,
+			Permissions: []string{"read", "getattr"},
+			Expression:  "l1 dom l2",
+			Comment:     "No read-up: the subject level must dominate the object level",
+			MLS:         true,
+		},
+		{
+			Classes:     mlsFileClasses,
+			Permissions: []string{"write", "append", "setattr"},
+			Expression:  "l1 domby l2",
+			Comment:     "No write-down: the object level must dominate the subject level",
+			MLS:         true,
+		},
+	}
+}
@@ -0,0 +1,128 @@
+package mapping
+
+import (
+	"testing"
+)
+
+func TestLevelMapper_MapRange(t *testing.T) {
+	mapper := NewLevelMapper()
+
+	tests := []struct {
+		input     string
+		expected  string
+		wantValid bool
+		wantErr   bool
+	}{
+		{"internal", "s1", true, false},
+		{"confidential-secret", "s2-s3", true, false},
+		{"internal-secret", "s1-s3", true, false},
+		{"s0-s2:c0,c1", "s0-s2:c0,c1", true, false},
+		{"s0:c3", "s0:c3", true, false},
+		{"confidential:c0", "s2:c0", true, false},
+		{"internal:c1-secret:c1,c2", "s1:c1-s3:c1,c2", true, false},
+		{"secret-internal", "s3-s1", false, false},
+		{"s2:c1-s3", "s2:c1-s3", false, false},
+		{"unknown", "", false, true},
+		{"s1:x2", "", false, true},
+		{"confidential:hr", "", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			r, err := mapper.MapRange(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("MapRange(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if r.String() != tt.expected {
+				t.Errorf("MapRange(%q) = %s, want %s", tt.input, r.String(), tt.expected)
+			}
+			if r.IsValid() != tt.wantValid {
+				t.Errorf("MapRange(%q).IsValid() = %v, want %v", tt.input, r.IsValid(), tt.wantValid)
+			}
+		})
+	}
+}
+
+func TestLevelMapper_CustomLevel(t *testing.T) {
+	mapper := NewLevelMapper()
+	mapper.AddLevel("Restricted", 5)
+
+	level, err := mapper.MapLevel("restricted")
+	if err != nil {
+		t.Fatalf("MapLevel() error = %v", err)
+	}
+	if level.String() != "s5" {
+		t.Errorf("MapLevel(restricted) = %s, want s5", level.String())
+	}
+}
+
+func TestLevelMapper_CustomCategory(t *testing.T) {
+	mapper := NewLevelMapper()
+	mapper.AddCategory("HR", 0)
+	mapper.AddCategory("finance", 5)
+
+	level, err := mapper.MapLevel("confidential:hr,finance")
+	if err != nil {
+		t.Fatalf("MapLevel() error = %v", err)
+	}
+	if level.String() != "s2:c0,c5" {
+		t.Errorf("MapLevel(confidential:hr,finance) = %s, want s2:c0,c5", level.String())
+	}
+}
+
+func TestLevelMapper_Define(t *testing.T) {
+	mapper := NewLevelMapper()
+	for _, definition := range []string{"restricted=s5", "hr=c0", "finance = c7"} {
+		if err := mapper.Define(definition); err != nil {
+			t.Fatalf("Define(%s) error = %v", definition, err)
+		}
+	}
+
+	level, err := mapper.MapLevel("restricted:hr,finance")
+	if err != nil {
+		t.Fatalf("MapLevel() error = %v", err)
+	}
+	if level.String() != "s5:c0,c7" {
+		t.Errorf("MapLevel(restricted:hr,finance) = %s, want s5:c0,c7", level.String())
+	}
+
+	for _, definition := range []string{"hr", "hr=", "hr=x1", "hr=c-1", "=c0"} {
+		if err := mapper.Define(definition); err == nil {
+			t.Errorf("Define(%s) should fail", definition)
+		}
+	}
+}
+
+func TestLevelMapper_InferLevelFromPath(t *testing.T) {
+	mapper := NewLevelMapper()
+
+	tests := []struct {
+		path  string
+		level string
+		found bool
+	}{
+		{"/data/secret/*", "s3", true},
+		{"/data/internal/reports/secret", "s3", true},
+		{"/srv/Confidential-archive", "s2", true},
+		{"/var/www/html", "", false},
+		{"/data/secrets", "", false},
+	}
+
+	for _, tt := range tests {
+		level, found := mapper.InferLevelFromPath(tt.path)
+		if found != tt.found || (found && level.String() != tt.level) {
+			t.Errorf("InferLevelFromPath(%s) = %s, %v, want %s, %v", tt.path, level.String(), found, tt.level, tt.found)
+		}
+	}
+}
+
+func TestGenerateMLSConstraints(t *testing.T) {
+	for _, c := range GenerateMLSConstraints() {
+		if !c.MLS || len(c.Classes) == 0 || len(c.Permissions) == 0 || c.Expression == "" {
+			t.Errorf("incomplete MLS constraint %+v", c)
+		}
+	}
+}
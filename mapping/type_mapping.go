@@ -3,6 +3,7 @@ package mapping
 import (
 	"fmt"
 	"path/filepath"
+	"sort"
 	"strings"
 )
 
@@ -76,11 +77,15 @@ func (tm *TypeMapper) PathToType(path string) string {
 	typeName = strings.ReplaceAll(typeName, "+", "_")
 	typeName = strings.ReplaceAll(typeName, "*", "")
 	// Remove regex characters that might be in patterns
+	typeName = strings.ReplaceAll(typeName, "\\", "")
 	typeName = strings.ReplaceAll(typeName, "(", "")
 	typeName = strings.ReplaceAll(typeName, ")", "")
 	typeName = strings.ReplaceAll(typeName, "?", "")
 	typeName = strings.ReplaceAll(typeName, "[", "")
 	typeName = strings.ReplaceAll(typeName, "]", "")
+	typeName = strings.ReplaceAll(typeName, "{", "")
+	typeName = strings.ReplaceAll(typeName, "}", "")
+	typeName = strings.ReplaceAll(typeName, ",", "_")
 	typeName = strings.ReplaceAll(typeName, ":", "_")
 
 	// Clean up any double underscores
@@ -114,7 +119,7 @@ func (tm *TypeMapper) PathToType(path string) string {
 }
 
 // InferTypeCategory infers the SELinux type category/attribute based on the path
-// Returns suggested attributes for the type
+// Returns suggested attributes for the type, sorted
 func (tm *TypeMapper) InferTypeCategory(path string) []string {
 	attributes := make([]string, 0)
 
@@ -152,6 +157,7 @@ func (tm *TypeMapper) InferTypeCategory(path string) []string {
 		attributes = append(attributes, "file_type")
 	}
 
+	sort.Strings(attributes)
 	return attributes
 }
 
@@ -260,6 +266,31 @@ func SanitizeTypeName(name string) string {
 	return name
 }
 
+// reservedWords lists SELinux policy language keywords that cannot be used as identifiers
+var reservedWords = map[string]bool{
+	"allow": true, "auditallow": true, "dontaudit": true, "neverallow": true,
+	"allowxperm": true, "auditallowxperm": true, "dontauditxperm": true, "neverallowxperm": true,
+	"type": true, "types": true, "typeattribute": true, "typealias": true, "alias": true,
+	"attribute": true, "attribute_role": true, "expandattribute": true, "typebounds": true,
+	"type_transition": true, "type_change": true, "type_member": true,
+	"role": true, "roles": true, "roleattribute": true, "role_transition": true, "dominance": true,
+	"user": true, "class": true, "common": true, "inherits": true, "self": true,
+	"require": true, "optional": true, "else": true, "if": true, "bool": true, "tunable": true,
+	"module": true, "policycap": true, "permissive": true,
+	"sensitivity": true, "category": true, "level": true, "range": true, "range_transition": true,
+	"constrain": true, "mlsconstrain": true, "validatetrans": true, "mlsvalidatetrans": true,
+	"sid": true, "genfscon": true, "portcon": true, "netifcon": true, "nodecon": true,
+	"fs_use_xattr": true, "fs_use_task": true, "fs_use_trans": true,
+	"default_user": true, "default_role": true, "default_type": true, "default_range": true,
+	"not": true, "and": true, "or": true, "xor": true, "eq": true, "neq": true,
+	"dom": true, "domby": true, "incomp": true,
+}
+
+// IsReservedWord checks if a name collides with an SELinux policy language keyword
+func IsReservedWord(name string) bool {
+	return reservedWords[strings.ToLower(name)]
+}
+
 // contains checks if a string slice contains a string
 func contains(slice []string, item string) bool {
 	for _, s := range slice {
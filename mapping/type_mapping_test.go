@@ -285,3 +285,16 @@ func TestSanitizeTypeName(t *testing.T) {
 		})
 	}
 }
+
+func TestIsReservedWord(t *testing.T) {
+	for _, word := range []string{"type", "allow", "class", "self", "role", "attribute", "Type"} {
+		if !IsReservedWord(word) {
+			t.Errorf("IsReservedWord(%q) = false, want true", word)
+		}
+	}
+	for _, word := range []string{"httpd_t", "myapp", "typed"} {
+		if IsReservedWord(word) {
+			t.Errorf("IsReservedWord(%q) = true, want false", word)
+		}
+	}
+}
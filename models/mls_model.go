@@ -0,0 +1,75 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SecurityLevel represents an MLS/MCS security level: a sensitivity plus optional categories
+// Example: s2:c0,c5 → Sensitivity=2, Categories=[0 5]
+type SecurityLevel struct {
+	Sensitivity int   // Sensitivity number (s0, s1, ...)
+	Categories  []int // Category numbers (c0, c1, ...)
+}
+
+// SecurityRange represents an MLS range from a low to a high level
+// A single level is represented with Low == High
+type SecurityRange struct {
+	Low  SecurityLevel
+	High SecurityLevel
+}
+
+// String renders the level in SELinux syntax (e.g., "s0" or "s2:c0,c5")
+func (l SecurityLevel) String() string {
+	if len(l.Categories) == 0 {
+		return fmt.Sprintf("s%d", l.Sensitivity)
+	}
+
+	cats := make([]int, len(l.Categories))
+	copy(cats, l.Categories)
+	sort.Ints(cats)
+
+	parts := make([]string, len(cats))
+	for i, c := range cats {
+		parts[i] = fmt.Sprintf("c%d", c)
+	}
+	return fmt.Sprintf("s%d:%s", l.Sensitivity, strings.Join(parts, ","))
+}
+
+// Dominates reports whether l dominates other: a higher or equal sensitivity
+// and a superset of other's categories
+func (l SecurityLevel) Dominates(other SecurityLevel) bool {
+	if l.Sensitivity < other.Sensitivity {
+		return false
+	}
+
+	cats := make(map[int]bool, len(l.Categories))
+	for _, c := range l.Categories {
+		cats[c] = true
+	}
+	for _, c := range other.Categories {
+		if !cats[c] {
+			return false
+		}
+	}
+	return true
+}
+
+// Equal reports whether two levels are identical
+func (l SecurityLevel) Equal(other SecurityLevel) bool {
+	return l.Dominates(other) && other.Dominates(l)
+}
+
+// String renders the range in SELinux syntax ("s1" for a single level, "s1-s3" otherwise)
+func (r SecurityRange) String() string {
+	if r.Low.Equal(r.High) {
+		return r.Low.String()
+	}
+	return r.Low.String() + "-" + r.High.String()
+}
+
+// IsValid reports whether the high level dominates the low level
+func (r SecurityRange) IsValid() bool {
+	return r.High.Dominates(r.Low)
+}
@@ -14,19 +14,25 @@ type PMLModel struct {
 // This is the standard Casbin triple format (sub, obj, act) with optional effect
 // Class information is encoded in the Object field using format:
 //   - Explicit: "/var/log/myapp::file" or "tcp:8080::tcp_socket"
+//   - Security level: "/srv/secret/*@level=internal-secret"
+//   - Audit access: "/etc/shadow@audit=true"
 //   - Auto-inferred from path patterns (paths → file/dir, tcp:/udp: → socket)
 type Policy struct {
 	Type    string // "p", "p2", etc. - policy definition type
 	Subject string // e.g., "myapp_t" - SELinux domain/type
 	Object  string // e.g., "/var/www/*" or "/var/log/app.log::file" or "tcp:8080::tcp_socket"
-	Action  string // e.g., "read", "write", "execute", "bind", "transition"
+	Action  string // e.g., "read", "write", "execute", "bind", "transition" or "search::dir"
 	Effect  string // "allow" or "deny" (for p) or new_type (for p2 transitions)
+	File    string // Policy file the rule was read from, for error messages
+	Line    int    // Line of File the rule starts on, cited by errors on the rule
+	Comment string // Author's inline "# ..." comment on the policy line, if any
 }
 
 // RoleRelation represents a role/group relationship
-// This is used for both standard roles (g) and extended attributes (g2)
+// This is used for both standard roles (g) and extended attributes (g2),
+// as well as role declarations (role) and role allows (ra, Member → Role)
 type RoleRelation struct {
-	Type   string // "g", "g2", "g3", etc.
+	Type   string // "g", "g2", "g3", "role", "ra"
 	Member string // The member of the group or attribute name
 	Role   string // The role/group name or encoded value (e.g., "bool:true")
 }
@@ -37,7 +43,15 @@ type RoleRelation struct {
 type DecodedPolicy struct {
 	Policy                         // Embedded standard policy
 	Class          string          // Extracted or inferred SELinux object class (file, dir, tcp_socket, etc.)
+	CustomClass    bool            // Class came from a user-supplied class map and overrides the action's class
 	Condition      string          // Extracted condition (from ?cond= in object)
+	Level          string          // Extracted security level or range (from @level= in object)
+	SubjectLevel   string          // Subject clearance level or range (from @level= in subject)
+	Audit          bool            // Also emit an auditallow rule (from @audit=true in object)
+	NoFileContext  bool            // Do not generate file contexts for the object (from @nofc in object)
+	IoctlRanges    []string        // Allowed ioctl commands, e.g. "0x1234-0x1240" (from @xperm=ioctl:... in object)
+	Priority       int             // CIL rule priority (from @priority= in object), 0 when unset
+	Optional       string          // Module the rule depends on (from @optional= in object)
 	IsTransition   bool            // True if this is a type transition (p2 with action="transition")
 	TransitionInfo *TransitionInfo // Details for type transitions
 }
@@ -53,16 +67,101 @@ type TransitionInfo struct {
 // ParsedPML contains all parsed PML data in standard Casbin format
 type ParsedPML struct {
 	Model    *PMLModel
-	Policies []Policy       // All policies (p, p2, etc.)
-	Roles    []RoleRelation // All role relations (g, g2, etc.)
+	Policies []Policy           // All policies (p, p2, etc.)
+	Roles    []RoleRelation     // All role relations (g, g2, etc.)
+	Genfs    []GenfsDeclaration // Pseudo-filesystem labels (genfs)
+	SIDs     []SIDDeclaration   // Initial SID contexts (sid), base policy only
+	Nodes    []NodeDeclaration  // Network node labels (n)
+	Netifs   []NetifDeclaration // Network interface labels (f)
+
+	NamedTransitions []NamedTransitionDeclaration // Named file transitions (ft)
+	ValidateTrans    []ValidateTransDeclaration   // Object relabel constraints (vt, mvt)
+	DefaultTypes     []DefaultTypeDeclaration     // Type new objects inherit (dt)
+	DefaultRanges    []DefaultRangeDeclaration    // Range new objects inherit (dr)
+	InterfaceCalls   []InterfaceCall              // Refpolicy interface calls (i)
+}
+
+// GenfsDeclaration labels a path within a pseudo-filesystem
+// Example: genfs, proc, /sys/kernel/modprobe, proc_security_t
+type GenfsDeclaration struct {
+	FSType string // proc, sysfs, selinuxfs, etc.
+	Path   string // Path within the filesystem, any depth
+	Type   string // SELinux type for the path
+}
+
+// NodeDeclaration labels a network node, an address range in CIDR notation
+// Example: n, 192.168.1.0/24, node_internal_t
+type NodeDeclaration struct {
+	Network string // IPv4 or IPv6 network, e.g. "192.168.1.0/24" or "fd00::/8"
+	Type    string // SELinux type for the node
+}
+
+// NetifDeclaration labels a network interface
+// Example: f, eth0, netif_internal_t
+type NetifDeclaration struct {
+	Interface string // Interface name, e.g. "eth0"
+	Type      string // SELinux type for the interface and its packets
+}
+
+// SIDDeclaration assigns the context of a kernel initial SID
+// Example: sid, kernel, kernel_t
+type SIDDeclaration struct {
+	Name string // Initial SID name: kernel, security, unlabeled, file, etc.
+	Type string // SELinux type of the SID context
+}
+
+// NamedTransitionDeclaration labels a file of a specific name created in a directory
+// Example: ft, httpd_t, httpd_var_lib_t, file, httpd_cache_t, "cache.db"
+type NamedTransitionDeclaration struct {
+	Source   string // Domain that creates the file
+	Target   string // Type of the parent directory
+	Class    string // Object class of the created file
+	NewType  string // Type the file is labeled with
+	Filename string // Exact last path component, without quotes
+}
+
+// ValidateTransDeclaration constrains which contexts objects may be relabeled between
+// Example: vt, file, "t1 == t2 or t3 == app_admin_t"
+type ValidateTransDeclaration struct {
+	Classes    []string // Object classes the constraint applies to
+	Expression string   // Expression over the old (1), new (2) and process (3) contexts
+	MLS        bool     // mlsvalidatetrans (mvt), whose expression may compare levels
+}
+
+// DefaultTypeDeclaration selects which context new objects of the classes take their type from
+// Example: dt, file, target
+type DefaultTypeDeclaration struct {
+	Classes []string // Object classes the default applies to
+	Default string   // source or target
+}
+
+// DefaultRangeDeclaration selects which context and level new objects of the classes take their range from
+// Example: dr, file, target, low (dr, file, low defaults to target)
+type DefaultRangeDeclaration struct {
+	Classes []string // Object classes the default applies to
+	Default string   // source or target
+	Range   string   // low, high or low_high
 }
 
 // DecodedPML contains decoded PML data with SELinux-specific structures
 // This is created by decoding the standard ParsedPML
 type DecodedPML struct {
-	Model          *PMLModel
-	Policies       []DecodedPolicy  // Decoded policies
-	Roles          []RoleRelation   // Standard role relations (g)
-	TypeAttributes []RoleRelation   // Type attributes (g2)
-	Transitions    []TransitionInfo // Extracted type transitions (from p2)
+	Model            *PMLModel
+	Policies         []DecodedPolicy    // Decoded policies
+	Roles            []RoleRelation     // Standard role relations (g)
+	TypeAttributes   []RoleRelation     // Type attributes (g2)
+	RoleDeclarations []string           // Declared roles (role)
+	RoleAllows       []RoleRelation     // Permitted role changes (ra)
+	UserDomains      []RoleRelation     // User domains and the roles they run in (g3)
+	Transitions      []TransitionInfo   // Extracted type transitions (from p2)
+	Genfs            []GenfsDeclaration // Pseudo-filesystem labels (genfs)
+	SIDs             []SIDDeclaration   // Initial SID contexts (sid), base policy only
+	Nodes            []NodeDeclaration  // Network node labels (n)
+	Netifs           []NetifDeclaration // Network interface labels (f)
+
+	NamedTransitions []NamedTransitionDeclaration // Named file transitions (ft)
+	ValidateTrans    []ValidateTransDeclaration   // Object relabel constraints (vt, mvt)
+	DefaultTypes     []DefaultTypeDeclaration     // Type new objects inherit (dt)
+	DefaultRanges    []DefaultRangeDeclaration    // Range new objects inherit (dr)
+	InterfaceCalls   []InterfaceCall              // Refpolicy interface calls (i)
 }
@@ -1,17 +1,47 @@
 package models
 
+import (
+	"fmt"
+	"strings"
+)
+
 // SELinuxPolicy represents a complete SELinux policy module
 // Simplified for 80% use cases: basic domain, file/dir access, ports, sockets
 type SELinuxPolicy struct {
-	ModuleName   string
-	Version      string
-	Types        []TypeDeclaration
-	Rules        []AllowRule
-	Transitions  []TypeTransition
-	FileContexts []FileContext
-	Interfaces   []InterfaceDefinition
-	Capabilities []CapabilityRule
-	PortBindings []PortBinding
+	ModuleName       string
+	Version          string
+	Attributes       []string // Attributes declared by the module, e.g. its file type group
+	Types            []TypeDeclaration
+	Rules            []AllowRule
+	AuditRules       []AllowRule // auditallow rules: logged even though allowed
+	XpermRules       []XpermRule // allowxperm rules: extended permission whitelists
+	Booleans         []Boolean   // Booleans guarding conditional rules
+	CondRules        []AllowRule // Allow rules that only apply while their Condition holds
+	OptionalRules    []AllowRule // Allow rules that only load when their Optional module is installed
+	Transitions      []TypeTransition
+	NamedTransitions []NamedTransition // Filename transitions, kept apart from class-based ones
+	FileContexts     []FileContext
+	UserHomeContent  []string // Home directory content types, labeled through userdom_user_home_content
+	Interfaces       []InterfaceDefinition
+	InterfaceCalls   []InterfaceCall // Calls to refpolicy interfaces, written before the allow rules
+	Capabilities     []CapabilityRule
+	PortBindings     []PortBinding
+	SemanagePorts    []PortBinding // Ports of a module, labeled with semanage port since only a base policy declares portcon
+	Constraints      []Constraint
+	ValidateTrans    []ValidateTrans
+	NeverallowRules  []NeverallowRule
+	DontauditRules   []AllowRule
+	DefaultTypes     []DefaultType
+	DefaultRanges    []DefaultRange
+	BaseTypes        []string // Types declared by a shared base module, required rather than declared
+	Roles            []string
+	RoleAllows       []RoleAllow
+	RoleTypes        []RoleType // Types each role may run, sorted by role
+	Expansions       []AttributeExpansion
+	GenfsContexts    []GenfsContext
+	Nodecons         []Nodecon
+	Netifcons        []Netifcon
+	InitialSIDs      []InitialSID // Only rendered for base policies
 }
 
 // TypeDeclaration represents a SELinux type declaration
@@ -26,9 +56,57 @@ type AllowRule struct {
 	SourceType     string
 	TargetType     string
 	Class          string   // file, dir, tcp_socket, unix_stream_socket, etc.
+	Classes        []string // Class set when the rule covers several classes; Class holds the first
 	Permissions    []string // read, write, execute, name_bind, etc.
 	OriginalObject string   // Original object pattern from PML (for tracking)
 	Comment        string   // Human-readable comment
+	Condition      string   // Boolean guarding the rule, "!" negates; empty when unconditional
+	Optional       string   // Module whose types the rule needs; empty when it has no soft dependency
+	Rationale      []string // What each PML line behind the rule grants, for annotated output
+}
+
+// NeverallowRule asserts that no policy grants the permissions, generated from a deny policy
+// Example: neverallow app_t shadow_t:file read;
+type NeverallowRule struct {
+	SourceType     string
+	TargetType     string
+	Class          string
+	Permissions    []string // The denied action's own permissions, see ActionMapper.MapDenyAction
+	OriginalObject string   // Original object pattern from PML (for tracking)
+	Comment        string   // Human-readable comment
+}
+
+// Boolean is a policy boolean declared by the module
+// Example: gen_bool(app_use_nfs, false) or gen_tunable(app_use_nfs, false)
+type Boolean struct {
+	Name    string
+	Default bool
+}
+
+// XpermRule restricts an allowed operation to a set of extended permissions
+// Example: allowxperm app_t app_dev_t:chr_file ioctl { 0x1234-0x1240 };
+type XpermRule struct {
+	SourceType string
+	TargetType string
+	Class      string
+	Operation  string   // Only "ioctl" is supported
+	Ranges     []string // Commands or command ranges, e.g. "0x8910" or "0x1234-0x1240"
+}
+
+// AllClasses returns every class the rule applies to
+func (r AllowRule) AllClasses() []string {
+	if len(r.Classes) > 0 {
+		return r.Classes
+	}
+	return []string{r.Class}
+}
+
+// ClassSpec renders the class part of the rule: "file" or "{ file lnk_file }"
+func (r AllowRule) ClassSpec() string {
+	if len(r.Classes) > 1 {
+		return "{ " + strings.Join(r.Classes, " ") + " }"
+	}
+	return r.Class
 }
 
 // TypeTransition represents a type_transition rule
@@ -41,12 +119,124 @@ type TypeTransition struct {
 	Comment    string
 }
 
+// NamedTransition is a type_transition that only applies to files of one name
+// Example: type_transition httpd_t httpd_var_lib_t:file httpd_cache_t "cache.db";
+type NamedTransition struct {
+	SourceType string
+	TargetType string
+	Class      string
+	NewType    string
+	Filename   string // Exact last path component
+}
+
 // FileContext represents a file context mapping
 type FileContext struct {
-	PathPattern string // e.g., "/var/www/html(/.*)?"
-	FileType    string // -- for regular file, -d for directory, etc.
-	SELinuxType string // e.g., "httpd_var_www_t"
-	Comment     string // Human-readable comment
+	PathPattern string         // e.g., "/var/www/html(/.*)?"
+	FileType    string         // -- for regular file, -d for directory, etc.
+	SELinuxType string         // e.g., "httpd_var_www_t"
+	Range       *SecurityRange // MLS range; nil means the default s0
+	Comment     string         // Human-readable comment
+}
+
+// Level returns the MLS level or range rendered in the file context (default "s0")
+func (fc FileContext) Level() string {
+	if fc.Range == nil {
+		return "s0"
+	}
+	return fc.Range.String()
+}
+
+// GenfsContext labels a path within a pseudo-filesystem
+// Example: genfscon proc /sys/kernel/modprobe gen_context(system_u:object_r:proc_security_t:s0)
+type GenfsContext struct {
+	FSType      string // proc, sysfs, selinuxfs, etc.
+	Path        string // Path within the filesystem
+	SELinuxType string // e.g., "proc_security_t"
+}
+
+// Nodecon labels a network node by address and netmask
+// Example: nodecon 192.168.1.0 255.255.255.0 gen_context(system_u:object_r:node_internal_t:s0)
+type Nodecon struct {
+	Address     string // Network address, e.g. "192.168.1.0"
+	Netmask     string // e.g. "255.255.255.0" or "ffff:ffff::"
+	SELinuxType string // e.g., "node_internal_t"
+}
+
+// Netifcon labels a network interface; its packets get the same type
+// Example: netifcon eth0 gen_context(system_u:object_r:netif_internal_t:s0) gen_context(system_u:object_r:netif_internal_t:s0)
+type Netifcon struct {
+	Interface   string // e.g., "eth0"
+	SELinuxType string // e.g., "netif_internal_t"
+}
+
+// InitialSID declares a kernel initial SID and its context
+// Example: sid kernel gen_context(system_u:system_r:kernel_t:s0)
+type InitialSID struct {
+	Name        string // kernel, security, unlabeled, file, etc.
+	SELinuxType string // e.g., "kernel_t"
+}
+
+// Role returns the role of the SID context: system_r for the SIDs that label
+// processes, object_r otherwise
+func (sid InitialSID) Role() string {
+	if sid.Name == "kernel" || sid.Name == "init" {
+		return "system_r"
+	}
+	return "object_r"
+}
+
+// AttributeExpansion controls whether an attribute is expanded in the binary policy
+// Example: expandattribute web_domain true;
+type AttributeExpansion struct {
+	Attribute string
+	Expand    bool
+}
+
+// RoleAllow represents a role allow rule permitting a role change
+// Example: allow user_r webadmin_r;
+type RoleAllow struct {
+	FromRole string
+	ToRole   string
+}
+
+// RoleType associates a role with the domain types it may run
+// Example: role user_r types { user_t user_mail_t };
+type RoleType struct {
+	Role  string
+	Types []string
+}
+
+// Constraint represents a constrain statement
+// Example: constrain process transition ( u1 == u2 or r2 == user_r );
+type Constraint struct {
+	Classes     []string // process, file, etc.
+	Permissions []string // transition, create, relabelto, etc.
+	Expression  string   // Constraint expression without the surrounding parentheses
+	Comment     string   // Human-readable comment
+	MLS         bool     // Emit mlsconstrain instead of constrain
+}
+
+// ValidateTrans constrains object relabeling: the expression compares the old (1),
+// new (2) and relabeling process (3) contexts
+type ValidateTrans struct {
+	Classes    []string
+	Expression string // Expression without the surrounding parentheses
+	MLS        bool   // Emit mlsvalidatetrans instead of validatetrans
+}
+
+// DefaultType controls which context new objects inherit their type from
+// Example: default_type file target;
+type DefaultType struct {
+	Classes []string
+	Default string // source or target
+}
+
+// DefaultRange controls which context and level new objects inherit their range from
+// Example: default_range file target low;
+type DefaultRange struct {
+	Classes []string
+	Default string // source or target
+	Range   string // low, high or low_high
 }
 
 // InterfaceDefinition represents a SELinux interface
@@ -57,6 +247,13 @@ type InterfaceDefinition struct {
 	Body        string
 }
 
+// InterfaceCall represents a call to a refpolicy interface of another module
+// Example: apache_read_config(myapp_t)
+type InterfaceCall struct {
+	Name string
+	Args []string
+}
+
 // CapabilityRule represents a capability grant
 // For things like net_bind_service, setuid, etc.
 type CapabilityRule struct {
@@ -69,11 +266,20 @@ type CapabilityRule struct {
 // Used to generate semanage port commands or port_t declarations
 type PortBinding struct {
 	Port     int
+	PortEnd  int    // Last port of a range, 0 for a single port
 	Protocol string // tcp, udp
 	PortType string // e.g., "http_port_t", "myapp_port_t"
 	Comment  string
 }
 
+// Ports renders the port or port range, e.g. "8080" or "8000-8100"
+func (b PortBinding) Ports() string {
+	if b.PortEnd != 0 {
+		return fmt.Sprintf("%d-%d", b.Port, b.PortEnd)
+	}
+	return fmt.Sprintf("%d", b.Port)
+}
+
 // NewSELinuxPolicy creates a new SELinuxPolicy with default values
 func NewSELinuxPolicy(moduleName, version string) *SELinuxPolicy {
 	return &SELinuxPolicy{
@@ -81,11 +287,15 @@ func NewSELinuxPolicy(moduleName, version string) *SELinuxPolicy {
 		Version:      version,
 		Types:        make([]TypeDeclaration, 0),
 		Rules:        make([]AllowRule, 0),
+		AuditRules:   make([]AllowRule, 0),
 		Transitions:  make([]TypeTransition, 0),
 		FileContexts: make([]FileContext, 0),
 		Interfaces:   make([]InterfaceDefinition, 0),
 		Capabilities: make([]CapabilityRule, 0),
 		PortBindings: make([]PortBinding, 0),
+		Constraints:  make([]Constraint, 0),
+		Roles:        make([]string, 0),
+		RoleAllows:   make([]RoleAllow, 0),
 	}
 }
 
@@ -106,11 +316,31 @@ func (p *SELinuxPolicy) AddTypeWithComment(typeName, comment string, attributes
 	})
 }
 
+// FileTypesAttribute returns the name of the attribute grouping the module's file types
+func (p *SELinuxPolicy) FileTypesAttribute() string {
+	return p.ModuleName + "_file_types"
+}
+
 // AddAllowRule adds an allow rule to the policy
 func (p *SELinuxPolicy) AddAllowRule(rule AllowRule) {
 	p.Rules = append(p.Rules, rule)
 }
 
+// AddAuditRule adds an auditallow rule to the policy
+func (p *SELinuxPolicy) AddAuditRule(rule AllowRule) {
+	p.AuditRules = append(p.AuditRules, rule)
+}
+
+// AddNeverallowRule adds a neverallow rule to the policy
+func (p *SELinuxPolicy) AddNeverallowRule(rule NeverallowRule) {
+	p.NeverallowRules = append(p.NeverallowRules, rule)
+}
+
+// AddConstraint adds a constrain statement to the policy
+func (p *SELinuxPolicy) AddConstraint(c Constraint) {
+	p.Constraints = append(p.Constraints, c)
+}
+
 // AddFileContext adds a file context to the policy
 func (p *SELinuxPolicy) AddFileContext(fc FileContext) {
 	p.FileContexts = append(p.FileContexts, fc)
@@ -141,6 +371,36 @@ func (p *SELinuxPolicy) HasType(typeName string) bool {
 	return p.GetTypeByName(typeName) != nil
 }
 
+// BaseOnlyStatement returns the first statement kind the policy holds that only a
+// base policy may declare, or "" when it has none. Modules cannot declare
+// constraints, object defaults or object contexts (genfscon, portcon, netifcon,
+// nodecon): checkmodule rejects them.
+func (p *SELinuxPolicy) BaseOnlyStatement() string {
+	switch {
+	case len(p.Constraints) > 0 && p.Constraints[0].MLS:
+		return "mlsconstrain"
+	case len(p.Constraints) > 0:
+		return "constrain"
+	case len(p.ValidateTrans) > 0 && p.ValidateTrans[0].MLS:
+		return "mlsvalidatetrans"
+	case len(p.ValidateTrans) > 0:
+		return "validatetrans"
+	case len(p.DefaultTypes) > 0:
+		return "default_type"
+	case len(p.DefaultRanges) > 0:
+		return "default_range"
+	case len(p.GenfsContexts) > 0:
+		return "genfscon"
+	case len(p.PortBindings) > 0:
+		return "portcon"
+	case len(p.Netifcons) > 0:
+		return "netifcon"
+	case len(p.Nodecons) > 0:
+		return "nodecon"
+	}
+	return ""
+}
+
 // AddInterface adds an interface definition to the policy
 func (p *SELinuxPolicy) AddInterface(iface InterfaceDefinition) {
 	p.Interfaces = append(p.Interfaces, iface)
@@ -0,0 +1,482 @@
+package selinux
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cici0602/pml-to-selinux/mapping"
+	"github.com/cici0602/pml-to-selinux/models"
+)
+
+// CILGenerator handles generation of SELinux Common Intermediate Language (.cil)
+// modules, which secilc and semodule consume without the refpolicy M4 macros.
+// It walks the same policy as the TEGenerator, and file contexts become filecon
+// statements in the module itself.
+type CILGenerator struct {
+	policy *models.SELinuxPolicy
+}
+
+// NewCILGenerator creates a new CILGenerator instance
+func NewCILGenerator(policy *models.SELinuxPolicy) *CILGenerator {
+	return &CILGenerator{
+		policy: policy,
+	}
+}
+
+// cilFileTypes maps .fc file type specifiers to CIL filecon file types; no
+// specifier matches any file type
+var cilFileTypes = map[string]string{
+	"":   "any",
+	"--": "file",
+	"-d": "dir",
+	"-l": "symlink",
+	"-c": "char",
+	"-b": "block",
+	"-s": "socket",
+	"-p": "pipe",
+}
+
+// Generate generates the complete .cil file content. Statements follow the
+// order of the .te sections; each section is sorted like its .te counterpart.
+func (g *CILGenerator) Generate() (string, error) {
+	// Statements that only exist as M4 interfaces or infix expressions have no CIL rendering here
+	switch {
+	case len(g.policy.UserHomeContent) > 0:
+		return "", fmt.Errorf("CIL output does not support userdom home directory content")
+	case len(g.policy.Constraints) > 0 || len(g.policy.ValidateTrans) > 0:
+		return "", fmt.Errorf("CIL output does not support constrain and validatetrans statements")
+	case len(g.policy.InitialSIDs) > 0:
+		return "", fmt.Errorf("CIL output does not support initial SID contexts")
+	case len(g.policy.InterfaceCalls) > 0:
+		return "", fmt.Errorf("CIL output does not support refpolicy interface calls")
+	}
+
+	var builder strings.Builder
+
+	builder.WriteString(fmt.Sprintf("; SELinux CIL Module: %s\n", g.policy.ModuleName))
+	builder.WriteString(fmt.Sprintf("; Version: %s\n", g.policy.Version))
+	builder.WriteString("; Generated by PML-to-SELinux Compiler\n\n")
+
+	g.writeBooleans(&builder)
+	g.writeTypes(&builder)
+	g.writeRoles(&builder)
+	g.writeAttributeExpansions(&builder)
+	g.writeTypeTransitions(&builder)
+	g.writeRules(&builder, "", "allow", g.policy.Rules)
+	g.writeConditionalRules(&builder)
+	g.writeOptionalRules(&builder)
+	g.writeRules(&builder, "", "auditallow", g.policy.AuditRules)
+	if err := g.writeXpermRules(&builder); err != nil {
+		return "", err
+	}
+	g.writeRules(&builder, "", "neverallow", neverallowAsAllowRules(g.policy.NeverallowRules))
+	g.writeRules(&builder, "", "dontaudit", g.policy.DontauditRules)
+	g.writeObjectDefaults(&builder)
+	g.writeGenfsContexts(&builder)
+	g.writePortContexts(&builder)
+	g.writeNetworkContexts(&builder)
+	g.writeFileContexts(&builder)
+
+	return builder.String(), nil
+}
+
+// writeBooleans writes boolean declarations
+func (g *CILGenerator) writeBooleans(builder *strings.Builder) {
+	if len(g.policy.Booleans) == 0 {
+		return
+	}
+
+	booleans := make([]models.Boolean, len(g.policy.Booleans))
+	copy(booleans, g.policy.Booleans)
+	sort.Slice(booleans, func(i, j int) bool {
+		return booleans[i].Name < booleans[j].Name
+	})
+	for _, b := range booleans {
+		builder.WriteString(fmt.Sprintf("(boolean %s %t)\n", b.Name, b.Default))
+	}
+	builder.WriteString("\n")
+}
+
+// writeTypes writes attribute and type declarations and the attribute assignments
+func (g *CILGenerator) writeTypes(builder *strings.Builder) {
+	if len(g.policy.Types) == 0 && len(g.policy.Attributes) == 0 {
+		return
+	}
+
+	attributes := append([]string(nil), g.policy.Attributes...)
+	sort.Strings(attributes)
+	for _, attr := range attributes {
+		builder.WriteString(fmt.Sprintf("(typeattribute %s)\n", attr))
+	}
+
+	types := make([]models.TypeDeclaration, len(g.policy.Types))
+	copy(types, g.policy.Types)
+	sort.Slice(types, func(i, j int) bool {
+		return types[i].TypeName < types[j].TypeName
+	})
+	for _, typeDecl := range types {
+		builder.WriteString(fmt.Sprintf("(type %s)\n", typeDecl.TypeName))
+	}
+
+	// CIL assigns types to attributes per attribute rather than per type
+	members := make(map[string][]string)
+	for _, typeDecl := range types {
+		for _, attr := range typeDecl.Attributes {
+			members[attr] = append(members[attr], typeDecl.TypeName)
+		}
+	}
+	for _, attr := range sortedStringKeys(members) {
+		builder.WriteString(fmt.Sprintf("(typeattributeset %s (%s))\n", attr, strings.Join(members[attr], " ")))
+	}
+	builder.WriteString("\n")
+}
+
+// writeRoles writes role declarations, role allow rules and role-type associations
+func (g *CILGenerator) writeRoles(builder *strings.Builder) {
+	if len(g.policy.Roles) == 0 && len(g.policy.RoleAllows) == 0 && len(g.policy.RoleTypes) == 0 {
+		return
+	}
+
+	roles := append([]string(nil), g.policy.Roles...)
+	sort.Strings(roles)
+	for _, role := range roles {
+		builder.WriteString(fmt.Sprintf("(role %s)\n", role))
+	}
+	roleAllows := make([]models.RoleAllow, len(g.policy.RoleAllows))
+	copy(roleAllows, g.policy.RoleAllows)
+	sort.Slice(roleAllows, func(i, j int) bool {
+		if roleAllows[i].FromRole != roleAllows[j].FromRole {
+			return roleAllows[i].FromRole < roleAllows[j].FromRole
+		}
+		return roleAllows[i].ToRole < roleAllows[j].ToRole
+	})
+	for _, ra := range roleAllows {
+		builder.WriteString(fmt.Sprintf("(roleallow %s %s)\n", ra.FromRole, ra.ToRole))
+	}
+	for _, rt := range g.policy.RoleTypes {
+		for _, typeName := range rt.Types {
+			builder.WriteString(fmt.Sprintf("(roletype %s %s)\n", rt.Role, typeName))
+		}
+	}
+	builder.WriteString("\n")
+}
+
+// writeAttributeExpansions writes expandtypeattribute statements
+func (g *CILGenerator) writeAttributeExpansions(builder *strings.Builder) {
+	if len(g.policy.Expansions) == 0 {
+		return
+	}
+
+	expansions := make([]models.AttributeExpansion, len(g.policy.Expansions))
+	copy(expansions, g.policy.Expansions)
+	sort.Slice(expansions, func(i, j int) bool {
+		return expansions[i].Attribute < expansions[j].Attribute
+	})
+	for _, exp := range expansions {
+		builder.WriteString(fmt.Sprintf("(expandtypeattribute (%s) %t)\n", exp.Attribute, exp.Expand))
+	}
+	builder.WriteString("\n")
+}
+
+// writeTypeTransitions writes typetransition statements; domain transitions also
+// get the execute, transition and entrypoint rules the .te writes for them
+func (g *CILGenerator) writeTypeTransitions(builder *strings.Builder) {
+	if len(g.policy.Transitions) == 0 && len(g.policy.NamedTransitions) == 0 {
+		return
+	}
+
+	transitions := make([]models.TypeTransition, len(g.policy.Transitions))
+	copy(transitions, g.policy.Transitions)
+	sort.Slice(transitions, func(i, j int) bool {
+		if transitions[i].SourceType != transitions[j].SourceType {
+			return transitions[i].SourceType < transitions[j].SourceType
+		}
+		if transitions[i].TargetType != transitions[j].TargetType {
+			return transitions[i].TargetType < transitions[j].TargetType
+		}
+		return transitions[i].Class < transitions[j].Class
+	})
+	for _, trans := range transitions {
+		builder.WriteString(fmt.Sprintf("(typetransition %s %s %s %s)\n",
+			trans.SourceType, trans.TargetType, trans.Class, trans.NewType))
+		if trans.Class == "process" {
+			builder.WriteString(fmt.Sprintf("(allow %s %s (file (execute)))\n", trans.SourceType, trans.TargetType))
+			builder.WriteString(fmt.Sprintf("(allow %s %s (process (transition)))\n", trans.SourceType, trans.NewType))
+			builder.WriteString(fmt.Sprintf("(allow %s %s (file (entrypoint)))\n", trans.NewType, trans.TargetType))
+		}
+	}
+
+	named := make([]models.NamedTransition, len(g.policy.NamedTransitions))
+	copy(named, g.policy.NamedTransitions)
+	sort.Slice(named, func(i, j int) bool {
+		if named[i].SourceType != named[j].SourceType {
+			return named[i].SourceType < named[j].SourceType
+		}
+		if named[i].TargetType != named[j].TargetType {
+			return named[i].TargetType < named[j].TargetType
+		}
+		if named[i].Class != named[j].Class {
+			return named[i].Class < named[j].Class
+		}
+		return named[i].Filename < named[j].Filename
+	})
+	for _, trans := range named {
+		builder.WriteString(fmt.Sprintf("(typetransition %s %s %s \"%s\" %s)\n",
+			trans.SourceType, trans.TargetType, trans.Class, trans.Filename, trans.NewType))
+	}
+	builder.WriteString("\n")
+}
+
+// writeRules writes rules as statements of the given kind, one per source,
+// target and class with the permissions of every rule merged, sorted like the .te
+func (g *CILGenerator) writeRules(builder *strings.Builder, indent, statement string, rules []models.AllowRule) {
+	if len(rules) == 0 {
+		return
+	}
+
+	perms := make(map[string][]string)
+	for _, rule := range rules {
+		for _, class := range rule.AllClasses() {
+			key := rule.SourceType + " " + rule.TargetType + " " + class
+			perms[key] = append(perms[key], rule.Permissions...)
+		}
+	}
+	for _, key := range sortedStringKeys(perms) {
+		fields := strings.Fields(key)
+		keyPerms := uniqueStrings(perms[key])
+		sort.Strings(keyPerms)
+		builder.WriteString(fmt.Sprintf("%s(%s %s %s (%s (%s)))\n",
+			indent, statement, fields[0], fields[1], fields[2], strings.Join(keyPerms, " ")))
+	}
+	if indent == "" {
+		builder.WriteString("\n")
+	}
+}
+
+// writeConditionalRules writes the allow rules of each condition in a booleanif block
+func (g *CILGenerator) writeConditionalRules(builder *strings.Builder) {
+	byCondition := make(map[string][]models.AllowRule)
+	for _, rule := range g.policy.CondRules {
+		byCondition[rule.Condition] = append(byCondition[rule.Condition], rule)
+	}
+
+	for _, condition := range sortedStringKeys(byCondition) {
+		expression := condition
+		if name, negated := strings.CutPrefix(condition, "!"); negated {
+			expression = fmt.Sprintf("(not %s)", name)
+		}
+		builder.WriteString(fmt.Sprintf("(booleanif %s\n", expression))
+		builder.WriteString("\t(true\n")
+		g.writeRules(builder, "\t\t", "allow", byCondition[condition])
+		builder.WriteString("\t)\n")
+		builder.WriteString(")\n\n")
+	}
+}
+
+// writeOptionalRules writes the allow rules of each optional dependency in an
+// optional block, which is disabled when the types it uses are missing
+func (g *CILGenerator) writeOptionalRules(builder *strings.Builder) {
+	byModule := make(map[string][]models.AllowRule)
+	for _, rule := range g.policy.OptionalRules {
+		byModule[rule.Optional] = append(byModule[rule.Optional], rule)
+	}
+
+	for _, module := range sortedStringKeys(byModule) {
+		builder.WriteString(fmt.Sprintf("(optional %s_%s\n", g.policy.ModuleName, module))
+		g.writeRules(builder, "\t", "allow", byModule[module])
+		builder.WriteString(")\n\n")
+	}
+}
+
+// writeXpermRules writes allowx statements restricting ioctl commands
+func (g *CILGenerator) writeXpermRules(builder *strings.Builder) error {
+	if len(g.policy.XpermRules) == 0 {
+		return nil
+	}
+
+	rules := make([]models.XpermRule, len(g.policy.XpermRules))
+	copy(rules, g.policy.XpermRules)
+	sort.SliceStable(rules, func(i, j int) bool {
+		if rules[i].SourceType != rules[j].SourceType {
+			return rules[i].SourceType < rules[j].SourceType
+		}
+		if rules[i].TargetType != rules[j].TargetType {
+			return rules[i].TargetType < rules[j].TargetType
+		}
+		return rules[i].Class < rules[j].Class
+	})
+	for _, rule := range rules {
+		if len(rule.Ranges) == 0 {
+			return fmt.Errorf("allowx %s %s:%s has no commands", rule.SourceType, rule.TargetType, rule.Class)
+		}
+		builder.WriteString(fmt.Sprintf("(allowx %s %s (%s %s %s))\n",
+			rule.SourceType, rule.TargetType, rule.Operation, rule.Class, cilXpermExpression(rule.Ranges)))
+	}
+	builder.WriteString("\n")
+	return nil
+}
+
+// cilXpermExpression renders commands and command ranges as a CIL permission
+// expression: single commands form one list, e.g. (0x8910 0x8927), each range a
+// (range low high), joined by nested (or a b) since or takes two operands
+func cilXpermExpression(ranges []string) string {
+	var commands, operands []string
+	for _, r := range ranges {
+		if low, high, ok := strings.Cut(r, "-"); ok {
+			operands = append(operands, fmt.Sprintf("(range %s %s)", low, high))
+		} else {
+			commands = append(commands, r)
+		}
+	}
+	if len(commands) > 0 {
+		operands = append([]string{"(" + strings.Join(commands, " ") + ")"}, operands...)
+	}
+
+	expression := operands[len(operands)-1]
+	for i := len(operands) - 2; i >= 0; i-- {
+		expression = fmt.Sprintf("(or %s %s)", operands[i], expression)
+	}
+	return expression
+}
+
+// writeObjectDefaults writes defaulttype and defaultrange statements, in policy order
+func (g *CILGenerator) writeObjectDefaults(builder *strings.Builder) {
+	if len(g.policy.DefaultTypes) == 0 && len(g.policy.DefaultRanges) == 0 {
+		return
+	}
+
+	for _, dt := range g.policy.DefaultTypes {
+		for _, class := range dt.Classes {
+			builder.WriteString(fmt.Sprintf("(defaulttype %s %s)\n", class, dt.Default))
+		}
+	}
+	for _, dr := range g.policy.DefaultRanges {
+		for _, class := range dr.Classes {
+			builder.WriteString(fmt.Sprintf("(defaultrange %s %s %s)\n", class, dr.Default, dr.Range))
+		}
+	}
+	builder.WriteString("\n")
+}
+
+// writeGenfsContexts writes genfscon statements, in policy order
+func (g *CILGenerator) writeGenfsContexts(builder *strings.Builder) {
+	if len(g.policy.GenfsContexts) == 0 {
+		return
+	}
+
+	for _, genfs := range g.policy.GenfsContexts {
+		builder.WriteString(fmt.Sprintf("(genfscon %s \"%s\" %s)\n",
+			genfs.FSType, genfs.Path, cilContext(genfs.SELinuxType, nil)))
+	}
+	builder.WriteString("\n")
+}
+
+// writePortContexts writes portcon statements, in policy order
+func (g *CILGenerator) writePortContexts(builder *strings.Builder) {
+	if len(g.policy.PortBindings) == 0 {
+		return
+	}
+
+	for _, binding := range g.policy.PortBindings {
+		ports := strconv.Itoa(binding.Port)
+		if binding.PortEnd != 0 {
+			ports = fmt.Sprintf("(%d %d)", binding.Port, binding.PortEnd)
+		}
+		builder.WriteString(fmt.Sprintf("(portcon %s %s %s)\n",
+			binding.Protocol, ports, cilContext(binding.PortType, nil)))
+	}
+	builder.WriteString("\n")
+}
+
+// writeNetworkContexts writes netifcon and nodecon statements, in policy order
+func (g *CILGenerator) writeNetworkContexts(builder *strings.Builder) {
+	if len(g.policy.Netifcons)+len(g.policy.Nodecons) == 0 {
+		return
+	}
+
+	for _, netif := range g.policy.Netifcons {
+		context := cilContext(netif.SELinuxType, nil)
+		builder.WriteString(fmt.Sprintf("(netifcon %s %s %s)\n", netif.Interface, context, context))
+	}
+	for _, node := range g.policy.Nodecons {
+		builder.WriteString(fmt.Sprintf("(nodecon (%s) (%s) %s)\n",
+			node.Address, node.Netmask, cilContext(node.SELinuxType, nil)))
+	}
+	builder.WriteString("\n")
+}
+
+// writeFileContexts writes filecon statements sorted by path pattern
+func (g *CILGenerator) writeFileContexts(builder *strings.Builder) {
+	if len(g.policy.FileContexts) == 0 {
+		return
+	}
+
+	contexts := make([]models.FileContext, len(g.policy.FileContexts))
+	copy(contexts, g.policy.FileContexts)
+	sort.SliceStable(contexts, func(i, j int) bool {
+		return contexts[i].PathPattern < contexts[j].PathPattern
+	})
+	for _, fc := range contexts {
+		// Like the .fc, an unset file type means regular files and inferred file
+		// type names ("block", "all files") map to their specifier first
+		fileType := cilFileTypes[mapping.FileContextSpecifier(fc.FileType)]
+		builder.WriteString(fmt.Sprintf("(filecon \"%s\" %s %s)\n",
+			fc.PathPattern, fileType, cilContext(fc.SELinuxType, fc.Range)))
+	}
+	builder.WriteString("\n")
+}
+
+// cilContext renders an object context, s0 when r is nil
+func cilContext(typeName string, r *models.SecurityRange) string {
+	levels := models.SecurityRange{}
+	if r != nil {
+		levels = *r
+	}
+	return fmt.Sprintf("(system_u object_r %s (%s %s))", typeName, cilLevel(levels.Low), cilLevel(levels.High))
+}
+
+// cilLevel renders a level as (s2) or (s2 (c0 c5))
+func cilLevel(l models.SecurityLevel) string {
+	if len(l.Categories) == 0 {
+		return fmt.Sprintf("(s%d)", l.Sensitivity)
+	}
+	cats := append([]int(nil), l.Categories...)
+	sort.Ints(cats)
+	names := make([]string, len(cats))
+	for i, c := range cats {
+		names[i] = fmt.Sprintf("c%d", c)
+	}
+	return fmt.Sprintf("(s%d (%s))", l.Sensitivity, strings.Join(names, " "))
+}
+
+// neverallowAsAllowRules converts neverallow rules so they can be written like allow rules
+func neverallowAsAllowRules(rules []models.NeverallowRule) []models.AllowRule {
+	converted := make([]models.AllowRule, 0, len(rules))
+	for _, rule := range rules {
+		converted = append(converted, models.AllowRule{
+			SourceType:  rule.SourceType,
+			TargetType:  rule.TargetType,
+			Class:       rule.Class,
+			Permissions: rule.Permissions,
+		})
+	}
+	return converted
+}
+
+// sortedStringKeys returns the keys of m in sorted order
+func sortedStringKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// GenerateCIL is a convenience function to generate .cil file content
+func GenerateCIL(policy *models.SELinuxPolicy) (string, error) {
+	generator := NewCILGenerator(policy)
+	return generator.Generate()
+}
@@ -0,0 +1,57 @@
+package selinux
+
+import (
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/cici0602/pml-to-selinux/models"
+)
+
+// LoadInstalledTypes returns the types defined by the installed SELinux policy.
+// It shells out to seinfo (setools), which must be available on the system.
+func LoadInstalledTypes() (map[string]bool, error) {
+	if _, err := exec.LookPath("seinfo"); err != nil {
+		return nil, fmt.Errorf("seinfo not found, install setools to check contexts against the local policy")
+	}
+
+	output, err := exec.Command("seinfo", "-t").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list installed types: %w", err)
+	}
+
+	return parseSeinfoTypes(string(output)), nil
+}
+
+// parseSeinfoTypes parses "seinfo -t" output: a "Types: N" header followed by one type per line
+func parseSeinfoTypes(output string) map[string]bool {
+	types := make(map[string]bool)
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasSuffix(line, ":") || strings.Contains(line, ": ") {
+			continue
+		}
+		types[line] = true
+	}
+	return types
+}
+
+// CheckContextTypes returns the file context types that are neither declared by the
+// module nor defined in the installed policy, so they would not resolve on install
+func CheckContextTypes(policy *models.SELinuxPolicy, installed map[string]bool) []string {
+	unresolved := make(map[string]bool)
+	for _, fc := range policy.FileContexts {
+		if fc.SELinuxType == "<<none>>" || policy.HasType(fc.SELinuxType) || installed[fc.SELinuxType] {
+			continue
+		}
+		unresolved[fc.SELinuxType] = true
+	}
+
+	result := make([]string, 0, len(unresolved))
+	for typeName := range unresolved {
+		result = append(result, typeName)
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,38 @@
+package selinux
+
+import (
+	"testing"
+
+	"github.com/cici0602/pml-to-selinux/models"
+)
+
+func TestParseSeinfoTypes(t *testing.T) {
+	output := `
+Types: 3
+   bin_t
+   etc_t
+   httpd_sys_content_t
+`
+	types := parseSeinfoTypes(output)
+	if len(types) != 3 || !types["bin_t"] || !types["httpd_sys_content_t"] {
+		t.Errorf("unexpected types: %v", types)
+	}
+}
+
+func TestCheckContextTypes(t *testing.T) {
+	policy := &models.SELinuxPolicy{
+		Types: []models.TypeDeclaration{{TypeName: "myapp_data_t"}},
+		FileContexts: []models.FileContext{
+			{PathPattern: "/srv/myapp(/.*)?", SELinuxType: "myapp_data_t"},
+			{PathPattern: "/usr/bin/myapp", SELinuxType: "bin_t"},
+			{PathPattern: "/etc/myapp(/.*)?", SELinuxType: "myap_etc_t"},
+			{PathPattern: "/etc/myapp/old", SELinuxType: "myap_etc_t"},
+		},
+	}
+	installed := map[string]bool{"bin_t": true, "etc_t": true}
+
+	unresolved := CheckContextTypes(policy, installed)
+	if len(unresolved) != 1 || unresolved[0] != "myap_etc_t" {
+		t.Errorf("CheckContextTypes() = %v, want [myap_etc_t]", unresolved)
+	}
+}
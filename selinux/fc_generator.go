@@ -5,21 +5,50 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/cici0602/pml-to-selinux/mapping"
 	"github.com/cici0602/pml-to-selinux/models"
 )
 
 // FCGenerator handles generation of SELinux File Context (.fc) files
 type FCGenerator struct {
-	policy *models.SELinuxPolicy
+	policy        *models.SELinuxPolicy
+	preserveOrder bool   // write contexts in policy order instead of sorting and grouping
+	contextStyle  string // FCContextStyleGenContext or FCContextStyleLiteral
+	homeTemplate  bool   // write /home patterns as HOME_DIR and HOME_ROOT templates
 }
 
+// Context styles: the refpolicy gen_context(user:role:type,level) M4 macro, which
+// drops or fills in the level per build configuration, or a literal context
+const (
+	FCContextStyleGenContext = "gen_context"
+	FCContextStyleLiteral    = "literal"
+)
+
 // NewFCGenerator creates a new FCGenerator instance
 func NewFCGenerator(policy *models.SELinuxPolicy) *FCGenerator {
 	return &FCGenerator{
-		policy: policy,
+		policy:       policy,
+		contextStyle: FCContextStyleGenContext,
 	}
 }
 
+// SetContextStyle selects how contexts are written: FCContextStyleGenContext
+// (the default) or FCContextStyleLiteral
+func (g *FCGenerator) SetContextStyle(style string) {
+	g.contextStyle = style
+}
+
+// SetHomeDirTemplate controls whether patterns under /home are written with the
+// HOME_DIR and HOME_ROOT templates genhomedircon expands per user
+func (g *FCGenerator) SetHomeDirTemplate(enabled bool) {
+	g.homeTemplate = enabled
+}
+
+// SetPreserveOrder controls whether file contexts are written in their original order
+func (g *FCGenerator) SetPreserveOrder(preserve bool) {
+	g.preserveOrder = preserve
+}
+
 // Generate generates the complete .fc file content
 func (g *FCGenerator) Generate() (string, error) {
 	var builder strings.Builder
@@ -50,6 +79,15 @@ func (g *FCGenerator) writeFileContexts(builder *strings.Builder) error {
 		return nil
 	}
 
+	if g.preserveOrder {
+		for _, fc := range g.policy.FileContexts {
+			if err := g.writeFileContext(builder, fc); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
 	// Sort file contexts by path pattern for consistent output
 	contexts := make([]models.FileContext, len(g.policy.FileContexts))
 	copy(contexts, g.policy.FileContexts)
@@ -90,17 +128,27 @@ func (g *FCGenerator) writeFileContexts(builder *strings.Builder) error {
 // writeFileContext writes a single file context specification
 func (g *FCGenerator) writeFileContext(builder *strings.Builder, fc models.FileContext) error {
 	// Get file type specifier (e.g., "--" for file, "-d" for directory)
-	fileTypeSpec := fc.FileType
-	if fileTypeSpec == "" {
-		fileTypeSpec = "--" // default to regular file
+	fileTypeSpec := mapping.FileContextSpecifier(fc.FileType)
+
+	// Build the full SELinux context: gen_context(system_u:object_r:type_t,s0) or
+	// system_u:object_r:type_t:s0 (or the object's MLS range)
+	context := fmt.Sprintf("gen_context(system_u:object_r:%s,%s)", fc.SELinuxType, fc.Level())
+	if g.contextStyle == FCContextStyleLiteral {
+		context = fmt.Sprintf("system_u:object_r:%s:%s", fc.SELinuxType, fc.Level())
 	}
 
-	// Build the full SELinux context: system_u:object_r:type_t:s0
-	context := fmt.Sprintf("system_u:object_r:%s:s0", fc.SELinuxType)
+	pattern := fc.PathPattern
+	if g.homeTemplate {
+		pattern, _ = mapping.HomeDirTemplate(pattern)
+	}
 
-	// Format: /path/pattern file_type_spec gen_context(system_u:object_r:type_t:s0)
-	builder.WriteString(fmt.Sprintf("%s\t%s\tgen_context(%s)\n",
-		fc.PathPattern,
+	// Format: /path/pattern [file_type_spec] context
+	if fileTypeSpec == "" {
+		builder.WriteString(fmt.Sprintf("%s\t%s\n", pattern, context))
+		return nil
+	}
+	builder.WriteString(fmt.Sprintf("%s\t%s\t%s\n",
+		pattern,
 		fileTypeSpec,
 		context))
 
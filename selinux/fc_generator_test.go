@@ -36,7 +36,7 @@ func TestFCGenerator_Generate(t *testing.T) {
 		t.Error("Missing module name in header")
 	}
 
-	if !strings.Contains(result, "gen_context(system_u:object_r:httpd_sys_content_t:s0)") {
+	if !strings.Contains(result, "gen_context(system_u:object_r:httpd_sys_content_t,s0)") {
 		t.Error("Missing or malformed gen_context for httpd_sys_content_t")
 	}
 }
@@ -63,3 +63,141 @@ func TestFCGenerator_EmptyPolicy(t *testing.T) {
 		t.Error("Should not contain gen_context for empty policy")
 	}
 }
+
+func TestFCGenerator_SecurityRange(t *testing.T) {
+	policy := &models.SELinuxPolicy{
+		ModuleName: "vault",
+		Version:    "1.0.0",
+		FileContexts: []models.FileContext{
+			{
+				PathPattern: "/srv/vault(/.*)?",
+				FileType:    "--",
+				SELinuxType: "vault_srv_vault_t",
+				Range: &models.SecurityRange{
+					Low:  models.SecurityLevel{Sensitivity: 1},
+					High: models.SecurityLevel{Sensitivity: 3},
+				},
+			},
+		},
+	}
+
+	result, err := NewFCGenerator(policy).Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	if !strings.Contains(result, "gen_context(system_u:object_r:vault_srv_vault_t,s1-s3)") {
+		t.Errorf("Missing ranged context, got:\n%s", result)
+	}
+}
+
+func TestFCGenerator_PreserveOrder(t *testing.T) {
+	policy := &models.SELinuxPolicy{
+		ModuleName: "app",
+		Version:    "1.0.0",
+		FileContexts: []models.FileContext{
+			{PathPattern: "/var/app(/.*)?", FileType: "--", SELinuxType: "app_var_t"},
+			{PathPattern: "/etc/app(/.*)?", FileType: "--", SELinuxType: "app_etc_t"},
+		},
+	}
+
+	generator := NewFCGenerator(policy)
+	generator.SetPreserveOrder(true)
+	result, err := generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	if strings.Index(result, "/var/app") > strings.Index(result, "/etc/app") {
+		t.Errorf("expected source order to be preserved, got:\n%s", result)
+	}
+}
+
+func TestFCGenerator_FileTypeNames(t *testing.T) {
+	policy := &models.SELinuxPolicy{
+		ModuleName: "app",
+		Version:    "1.0.0",
+		FileContexts: []models.FileContext{
+			{PathPattern: "/dev/sda", FileType: "block", SELinuxType: "app_disk_t"},
+			{PathPattern: "/dev/null", FileType: "char", SELinuxType: "app_null_t"},
+			{PathPattern: "/var/app(/.*)?", FileType: "all files", SELinuxType: "app_var_t"},
+		},
+	}
+
+	result, err := NewFCGenerator(policy).Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	for _, want := range []string{
+		"/dev/sda\t-b\tgen_context(system_u:object_r:app_disk_t,s0)\n",
+		"/dev/null\t-c\tgen_context(system_u:object_r:app_null_t,s0)\n",
+		"/var/app(/.*)?\tgen_context(system_u:object_r:app_var_t,s0)\n",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Missing %q, got:\n%s", want, result)
+		}
+	}
+}
+
+func TestFCGenerator_ContextStyle(t *testing.T) {
+	policy := &models.SELinuxPolicy{
+		ModuleName: "app",
+		Version:    "1.0.0",
+		FileContexts: []models.FileContext{
+			{PathPattern: "/var/app(/.*)?", FileType: "--", SELinuxType: "app_var_t"},
+		},
+	}
+
+	tests := []struct {
+		style string
+		want  string
+	}{
+		{FCContextStyleGenContext, "/var/app(/.*)?\t--\tgen_context(system_u:object_r:app_var_t,s0)\n"},
+		{FCContextStyleLiteral, "/var/app(/.*)?\t--\tsystem_u:object_r:app_var_t:s0\n"},
+	}
+	for _, tt := range tests {
+		generator := NewFCGenerator(policy)
+		generator.SetContextStyle(tt.style)
+		result, err := generator.Generate()
+		if err != nil {
+			t.Fatalf("Generate() error = %v", err)
+		}
+		if !strings.Contains(result, tt.want) {
+			t.Errorf("style %s: missing %q, got:\n%s", tt.style, tt.want, result)
+		}
+	}
+}
+
+func TestFCGenerator_HomeDirTemplate(t *testing.T) {
+	policy := &models.SELinuxPolicy{
+		ModuleName: "app",
+		Version:    "1.0.0",
+		FileContexts: []models.FileContext{
+			{PathPattern: `/home/[^/]+/\.app(/.*)?`, FileType: "--", SELinuxType: "app_home_t"},
+			{PathPattern: "/var/app(/.*)?", FileType: "--", SELinuxType: "app_var_t"},
+		},
+	}
+
+	generator := NewFCGenerator(policy)
+	result, err := generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if !strings.Contains(result, "/home/[^/]+/\\.app(/.*)?\t--\t") {
+		t.Errorf("home patterns should stay literal by default, got:\n%s", result)
+	}
+
+	generator.SetHomeDirTemplate(true)
+	result, err = generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	for _, want := range []string{
+		"HOME_DIR/\\.app(/.*)?\t--\tgen_context(system_u:object_r:app_home_t,s0)\n",
+		"/var/app(/.*)?\t--\tgen_context(system_u:object_r:app_var_t,s0)\n",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("missing %q, got:\n%s", want, result)
+		}
+	}
+}
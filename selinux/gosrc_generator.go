@@ -0,0 +1,125 @@
+package selinux
+
+import (
+	"fmt"
+	"go/format"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/cici0602/pml-to-selinux/models"
+)
+
+// modelsImportPath is the import path of the package holding the policy IR
+const modelsImportPath = "github.com/cici0602/pml-to-selinux/models"
+
+// GoSourceGenerator renders a policy as a Go source file so it can be compiled into a binary
+type GoSourceGenerator struct {
+	policy      *models.SELinuxPolicy
+	packageName string
+}
+
+// NewGoSourceGenerator creates a new GoSourceGenerator instance
+func NewGoSourceGenerator(policy *models.SELinuxPolicy) *GoSourceGenerator {
+	return &GoSourceGenerator{
+		policy:      policy,
+		packageName: "policy",
+	}
+}
+
+// SetPackageName sets the package clause of the generated file (default "policy")
+func (g *GoSourceGenerator) SetPackageName(name string) {
+	g.packageName = name
+}
+
+// Generate generates a gofmt-ed Go file declaring the policy as
+// var Policy = &models.SELinuxPolicy{...}
+func (g *GoSourceGenerator) Generate() (string, error) {
+	var builder strings.Builder
+
+	builder.WriteString("// Code generated by pml2selinux. DO NOT EDIT.\n\n")
+	builder.WriteString(fmt.Sprintf("package %s\n\n", g.packageName))
+	builder.WriteString(fmt.Sprintf("import %q\n\n", modelsImportPath))
+	builder.WriteString(fmt.Sprintf("// Policy is the SELinux policy for module %s\n", g.policy.ModuleName))
+	builder.WriteString("var Policy = &")
+	if err := writeGoValue(&builder, reflect.ValueOf(*g.policy), false); err != nil {
+		return "", err
+	}
+	builder.WriteString("\n")
+
+	source, err := format.Source([]byte(builder.String()))
+	if err != nil {
+		return "", fmt.Errorf("generated Go source does not parse: %w", err)
+	}
+	return string(source), nil
+}
+
+// writeGoValue writes v as a Go expression. elideType drops the type of a
+// composite literal, as allowed for slice elements.
+func writeGoValue(builder *strings.Builder, v reflect.Value, elideType bool) error {
+	switch v.Kind() {
+	case reflect.String:
+		builder.WriteString(strconv.Quote(v.String()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		builder.WriteString(strconv.FormatInt(v.Int(), 10))
+	case reflect.Bool:
+		builder.WriteString(strconv.FormatBool(v.Bool()))
+	case reflect.Ptr:
+		if v.IsNil() {
+			builder.WriteString("nil")
+			return nil
+		}
+		builder.WriteString("&")
+		return writeGoValue(builder, v.Elem(), false)
+	case reflect.Slice:
+		if v.IsNil() {
+			builder.WriteString("nil")
+			return nil
+		}
+		if !elideType {
+			builder.WriteString(goTypeName(v.Type()))
+		}
+		builder.WriteString("{\n")
+		for i := 0; i < v.Len(); i++ {
+			if err := writeGoValue(builder, v.Index(i), true); err != nil {
+				return err
+			}
+			builder.WriteString(",\n")
+		}
+		builder.WriteString("}")
+	case reflect.Struct:
+		if !elideType {
+			builder.WriteString(goTypeName(v.Type()))
+		}
+		builder.WriteString("{\n")
+		for i := 0; i < v.NumField(); i++ {
+			// Zero fields are omitted; an empty but non-nil slice is not zero and is kept
+			if v.Field(i).IsZero() {
+				continue
+			}
+			builder.WriteString(v.Type().Field(i).Name + ": ")
+			if err := writeGoValue(builder, v.Field(i), false); err != nil {
+				return err
+			}
+			builder.WriteString(",\n")
+		}
+		builder.WriteString("}")
+	default:
+		return fmt.Errorf("cannot render %s as Go source", v.Type())
+	}
+	return nil
+}
+
+// goTypeName returns the type as written in the generated file
+func goTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Slice:
+		return "[]" + goTypeName(t.Elem())
+	case reflect.Ptr:
+		return "*" + goTypeName(t.Elem())
+	}
+	if t.PkgPath() == modelsImportPath {
+		return "models." + t.Name()
+	}
+	return t.Name()
+}
@@ -0,0 +1,123 @@
+package selinux
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cici0602/pml-to-selinux/models"
+)
+
+func newGoSourceTestPolicy() *models.SELinuxPolicy {
+	policy := models.NewSELinuxPolicy("myapp", "1.0.0")
+	policy.Types = append(policy.Types, models.TypeDeclaration{TypeName: "myapp_t", Attributes: []string{"domain"}})
+	policy.Rules = append(policy.Rules, models.AllowRule{
+		SourceType:  "myapp_t",
+		TargetType:  "myapp_data_t",
+		Class:       "file",
+		Classes:     []string{"file", "lnk_file"},
+		Permissions: []string{"read", "open"},
+		Comment:     "quoted \"comment\"\twith escapes",
+	})
+	policy.FileContexts = append(policy.FileContexts, models.FileContext{
+		PathPattern: "/var/lib/myapp(/.*)?",
+		SELinuxType: "myapp_data_t",
+		Range: &models.SecurityRange{
+			Low:  models.SecurityLevel{Sensitivity: 0},
+			High: models.SecurityLevel{Sensitivity: 2, Categories: []int{0, 5}},
+		},
+	})
+	policy.PortBindings = append(policy.PortBindings, models.PortBinding{Port: 8080, Protocol: "tcp", PortType: "myapp_port_t"})
+	policy.Expansions = []models.AttributeExpansion{{Attribute: "web_domain", Expand: true}}
+	return policy
+}
+
+func TestGoSourceGenerator_Generate(t *testing.T) {
+	generator := NewGoSourceGenerator(newGoSourceTestPolicy())
+	generator.SetPackageName("embedded")
+	result, err := generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	if _, err := parser.ParseFile(token.NewFileSet(), "policy.go", result, 0); err != nil {
+		t.Fatalf("generated source does not parse: %v\n%s", err, result)
+	}
+	for _, want := range []string{
+		"package embedded",
+		"var Policy = &models.SELinuxPolicy{",
+		"Range: &models.SecurityRange{",
+		"Categories: []int{",
+		`"quoted \"comment\"\twith escapes"`,
+		"Interfaces:   []models.InterfaceDefinition{},",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Missing %q, got:\n%s", want, result)
+		}
+	}
+}
+
+// TestGoSourceGenerator_RoundTrip compiles the generated file into a program that
+// re-renders the embedded policy and checks it matches the original rendering
+func TestGoSourceGenerator_RoundTrip(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping go run in short mode")
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go tool not available")
+	}
+
+	expected, err := NewGoSourceGenerator(newGoSourceTestPolicy()).Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	// The program must live inside this module to import the generated package
+	dir, err := os.MkdirTemp(".", "gosrc-roundtrip-")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		filepath.Join(dir, "policy", "policy.go"): expected,
+		filepath.Join(dir, "main.go"): `package main
+
+import (
+	"fmt"
+
+	"github.com/cici0602/pml-to-selinux/selinux"
+	"github.com/cici0602/pml-to-selinux/selinux/` + filepath.Base(dir) + `/policy"
+)
+
+func main() {
+	source, err := selinux.NewGoSourceGenerator(policy.Policy).Generate()
+	if err != nil {
+		panic(err)
+	}
+	fmt.Print(source)
+}
+`,
+	}
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+
+	output, err := exec.Command(goTool, "run", "./"+filepath.Base(dir)).CombinedOutput()
+	if err != nil {
+		t.Fatalf("go run failed: %v\n%s", err, output)
+	}
+	if string(output) != expected {
+		t.Errorf("round-tripped policy differs:\n%s\nwant:\n%s", output, expected)
+	}
+}
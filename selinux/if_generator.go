@@ -2,6 +2,7 @@ package selinux
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/cici0602/pml-to-selinux/models"
@@ -9,16 +10,39 @@ import (
 
 // IFGenerator handles generation of SELinux interface (.if) files
 type IFGenerator struct {
-	policy *models.SELinuxPolicy
+	policy          *models.SELinuxPolicy
+	booleanStyle    string
+	interfacePrefix string
 }
 
 // NewIFGenerator creates a new IFGenerator instance
 func NewIFGenerator(policy *models.SELinuxPolicy) *IFGenerator {
 	return &IFGenerator{
-		policy: policy,
+		policy:       policy,
+		booleanStyle: BooleanStyleBool,
 	}
 }
 
+// SetBooleanStyle selects whether booleans are runtime booleans or refpolicy tunables
+func (g *IFGenerator) SetBooleanStyle(style string) {
+	g.booleanStyle = style
+}
+
+// SetInterfacePrefix namespaces the generated interface names, e.g. prefix
+// "acme" turns httpd_read_files into acme_httpd_read_files. Type names and
+// require blocks keep using the module name.
+func (g *IFGenerator) SetInterfacePrefix(prefix string) {
+	g.interfacePrefix = strings.TrimSuffix(prefix, "_")
+}
+
+// interfaceName returns the name of the module interface with the given suffix
+func (g *IFGenerator) interfaceName(suffix string) string {
+	if g.interfacePrefix == "" {
+		return g.policy.ModuleName + "_" + suffix
+	}
+	return g.interfacePrefix + "_" + g.policy.ModuleName + "_" + suffix
+}
+
 // Generate generates the complete .if file content
 func (g *IFGenerator) Generate() (string, error) {
 	var builder strings.Builder
@@ -31,6 +55,10 @@ func (g *IFGenerator) Generate() (string, error) {
 	g.generateWriteInterface(&builder)
 	g.generateExecuteInterface(&builder)
 	g.generateDomainTransitionInterface(&builder)
+	g.generateDomainInterfaces(&builder)
+	g.generateManageAllFilesInterface(&builder)
+	g.generateContentInterfaces(&builder)
+	g.generateBooleanInterfaces(&builder)
 
 	return builder.String(), nil
 }
@@ -52,7 +80,7 @@ func (g *IFGenerator) generateReadInterface(builder *strings.Builder) {
 	builder.WriteString("##\t<summary>\n##\tDomain allowed access.\n##\t</summary>\n")
 	builder.WriteString("## </param>\n")
 	builder.WriteString("#\n")
-	builder.WriteString(fmt.Sprintf("interface(`%s_read_files',`\n", moduleName))
+	builder.WriteString(fmt.Sprintf("interface(`%s',`\n", g.interfaceName("read_files")))
 	builder.WriteString("\tgen_require(`\n")
 
 	// Collect read-related types from rules
@@ -87,7 +115,7 @@ func (g *IFGenerator) generateWriteInterface(builder *strings.Builder) {
 	builder.WriteString("##\t<summary>\n##\tDomain allowed access.\n##\t</summary>\n")
 	builder.WriteString("## </param>\n")
 	builder.WriteString("#\n")
-	builder.WriteString(fmt.Sprintf("interface(`%s_write_files',`\n", moduleName))
+	builder.WriteString(fmt.Sprintf("interface(`%s',`\n", g.interfaceName("write_files")))
 	builder.WriteString("\tgen_require(`\n")
 
 	// Collect write-related types
@@ -120,7 +148,7 @@ func (g *IFGenerator) generateExecuteInterface(builder *strings.Builder) {
 	builder.WriteString("##\t<summary>\n##\tDomain allowed access.\n##\t</summary>\n")
 	builder.WriteString("## </param>\n")
 	builder.WriteString("#\n")
-	builder.WriteString(fmt.Sprintf("interface(`%s_exec',`\n", moduleName))
+	builder.WriteString(fmt.Sprintf("interface(`%s',`\n", g.interfaceName("exec")))
 	builder.WriteString("\tgen_require(`\n")
 
 	// Collect execute-related types
@@ -157,7 +185,7 @@ func (g *IFGenerator) generateDomainTransitionInterface(builder *strings.Builder
 	builder.WriteString("##\t<summary>\n##\tDomain allowed to transition.\n##\t</summary>\n")
 	builder.WriteString("## </param>\n")
 	builder.WriteString("#\n")
-	builder.WriteString(fmt.Sprintf("interface(`%s_domtrans',`\n", moduleName))
+	builder.WriteString(fmt.Sprintf("interface(`%s',`\n", g.interfaceName("domtrans")))
 	builder.WriteString("\tgen_require(`\n")
 
 	// Collect types from transitions
@@ -181,6 +209,157 @@ func (g *IFGenerator) generateDomainTransitionInterface(builder *strings.Builder
 	builder.WriteString("')\n\n")
 }
 
+// generateManageAllFilesInterface generates an interface managing every file
+// type of the module through its file type attribute, if the module groups them
+func (g *IFGenerator) generateManageAllFilesInterface(builder *strings.Builder) {
+	attribute := g.policy.FileTypesAttribute()
+	declared := false
+	for _, attr := range g.policy.Attributes {
+		declared = declared || attr == attribute
+	}
+	if !declared {
+		return
+	}
+
+	builder.WriteString("########################################\n")
+	builder.WriteString(fmt.Sprintf("## <summary>\n##\tCreate, read, write, and delete all %s files.\n## </summary>\n", g.policy.ModuleName))
+	builder.WriteString("## <param name=\"domain\">\n")
+	builder.WriteString("##\t<summary>\n##\tDomain allowed access.\n##\t</summary>\n")
+	builder.WriteString("## </param>\n")
+	builder.WriteString("#\n")
+	builder.WriteString(fmt.Sprintf("interface(`%s',`\n", g.interfaceName("manage_all_files")))
+	builder.WriteString("\tgen_require(`\n")
+	builder.WriteString(fmt.Sprintf("\t\tattribute %s;\n", attribute))
+	builder.WriteString("\t')\n\n")
+	builder.WriteString(fmt.Sprintf("\tmanage_dirs_pattern($1, %s, %s)\n", attribute, attribute))
+	builder.WriteString(fmt.Sprintf("\tmanage_files_pattern($1, %s, %s)\n", attribute, attribute))
+	builder.WriteString("')\n\n")
+}
+
+// typeStem returns a type name without its _t suffix and the module prefix,
+// the part interface names of the type use: httpd_var_www_t in module httpd
+// gives var_www, and the module's own domain httpd_t gives ""
+func (g *IFGenerator) typeStem(typeName string) string {
+	stem := strings.TrimSuffix(typeName, "_t")
+	if stem == g.policy.ModuleName {
+		return ""
+	}
+	return strings.TrimPrefix(stem, g.policy.ModuleName+"_")
+}
+
+// generateDomainInterfaces generates a <module>_<domain>_domtrans interface per
+// domain of the module entered through a process transition, running its
+// entrypoint in the domain. The module's own domain is covered by <module>_domtrans.
+func (g *IFGenerator) generateDomainInterfaces(builder *strings.Builder) {
+	entrypoints := make(map[string][]string)
+	var domains []string
+	for _, trans := range g.policy.Transitions {
+		if trans.Class != "process" || !g.policy.HasType(trans.NewType) || g.typeStem(trans.NewType) == "" {
+			continue
+		}
+		if _, seen := entrypoints[trans.NewType]; !seen {
+			domains = append(domains, trans.NewType)
+		}
+		if !containsName(entrypoints[trans.NewType], trans.TargetType) {
+			entrypoints[trans.NewType] = append(entrypoints[trans.NewType], trans.TargetType)
+		}
+	}
+	sort.Strings(domains)
+
+	for _, domain := range domains {
+		sort.Strings(entrypoints[domain])
+
+		builder.WriteString("########################################\n")
+		builder.WriteString(fmt.Sprintf("## <summary>\n##\tExecute %s in the %s domain.\n## </summary>\n",
+			strings.Join(entrypoints[domain], ", "), domain))
+		builder.WriteString("## <param name=\"domain\">\n")
+		builder.WriteString("##\t<summary>\n##\tDomain allowed to transition.\n##\t</summary>\n")
+		builder.WriteString("## </param>\n")
+		builder.WriteString("#\n")
+		builder.WriteString(fmt.Sprintf("interface(`%s',`\n", g.interfaceName(g.typeStem(domain)+"_domtrans")))
+		builder.WriteString("\tgen_require(`\n")
+		builder.WriteString(fmt.Sprintf("\t\ttype %s;\n", domain))
+		for _, entrypoint := range entrypoints[domain] {
+			builder.WriteString(fmt.Sprintf("\t\ttype %s;\n", entrypoint))
+		}
+		builder.WriteString("\t')\n\n")
+		for _, entrypoint := range entrypoints[domain] {
+			builder.WriteString(fmt.Sprintf("\tdomtrans_pattern($1, %s, %s)\n", entrypoint, domain))
+		}
+		builder.WriteString("')\n\n")
+	}
+}
+
+// generateContentInterfaces generates <module>_read_<type>_content and
+// <module>_manage_<type>_content interfaces per file type the module declares,
+// granting another domain access to that content through refpolicy patterns
+func (g *IFGenerator) generateContentInterfaces(builder *strings.Builder) {
+	for _, decl := range g.policy.Types {
+		if !containsName(decl.Attributes, "file_type") {
+			continue
+		}
+		typeName := decl.TypeName
+		stem := g.typeStem(typeName)
+		if stem == "" {
+			stem = "files"
+		}
+
+		builder.WriteString("########################################\n")
+		builder.WriteString(fmt.Sprintf("## <summary>\n##\tRead %s files.\n## </summary>\n", typeName))
+		builder.WriteString("## <param name=\"domain\">\n")
+		builder.WriteString("##\t<summary>\n##\tDomain allowed access.\n##\t</summary>\n")
+		builder.WriteString("## </param>\n")
+		builder.WriteString("#\n")
+		builder.WriteString(fmt.Sprintf("interface(`%s',`\n", g.interfaceName("read_"+stem+"_content")))
+		builder.WriteString("\tgen_require(`\n")
+		builder.WriteString(fmt.Sprintf("\t\ttype %s;\n", typeName))
+		builder.WriteString("\t')\n\n")
+		builder.WriteString(fmt.Sprintf("\tlist_dirs_pattern($1, %s, %s)\n", typeName, typeName))
+		builder.WriteString(fmt.Sprintf("\tread_files_pattern($1, %s, %s)\n", typeName, typeName))
+		builder.WriteString("')\n\n")
+
+		builder.WriteString("########################################\n")
+		builder.WriteString(fmt.Sprintf("## <summary>\n##\tCreate, read, write, and delete %s files.\n## </summary>\n", typeName))
+		builder.WriteString("## <param name=\"domain\">\n")
+		builder.WriteString("##\t<summary>\n##\tDomain allowed access.\n##\t</summary>\n")
+		builder.WriteString("## </param>\n")
+		builder.WriteString("#\n")
+		builder.WriteString(fmt.Sprintf("interface(`%s',`\n", g.interfaceName("manage_"+stem+"_content")))
+		builder.WriteString("\tgen_require(`\n")
+		builder.WriteString(fmt.Sprintf("\t\ttype %s;\n", typeName))
+		builder.WriteString("\t')\n\n")
+		builder.WriteString(fmt.Sprintf("\tmanage_dirs_pattern($1, %s, %s)\n", typeName, typeName))
+		builder.WriteString(fmt.Sprintf("\tmanage_files_pattern($1, %s, %s)\n", typeName, typeName))
+		builder.WriteString("')\n\n")
+	}
+}
+
+// generateBooleanInterfaces generates a <module>_set_<boolean> interface per boolean,
+// letting another domain toggle it. Tunables cannot be required, only booleans are.
+func (g *IFGenerator) generateBooleanInterfaces(builder *strings.Builder) {
+	kind := "boolean"
+	if g.booleanStyle == BooleanStyleTunable {
+		kind = "tunable"
+	}
+
+	for _, b := range g.policy.Booleans {
+		builder.WriteString("########################################\n")
+		builder.WriteString(fmt.Sprintf("## <summary>\n##\tSet the %s %s.\n## </summary>\n", b.Name, kind))
+		builder.WriteString("## <param name=\"domain\">\n")
+		builder.WriteString("##\t<summary>\n##\tDomain allowed to set the value.\n##\t</summary>\n")
+		builder.WriteString("## </param>\n")
+		builder.WriteString("#\n")
+		builder.WriteString(fmt.Sprintf("interface(`%s',`\n", g.interfaceName("set_"+b.Name)))
+		if g.booleanStyle != BooleanStyleTunable {
+			builder.WriteString("\tgen_require(`\n")
+			builder.WriteString(fmt.Sprintf("\t\tbool %s;\n", b.Name))
+			builder.WriteString("\t')\n\n")
+		}
+		builder.WriteString("\tselinux_set_generic_booleans($1)\n")
+		builder.WriteString("')\n\n")
+	}
+}
+
 // Helper functions
 func hasReadPerm(perms []string) bool {
 	for _, p := range perms {
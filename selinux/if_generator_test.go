@@ -117,3 +117,135 @@ func TestIFGenerator_PermissionHelpers(t *testing.T) {
 		})
 	}
 }
+
+func TestIFGenerator_BooleanInterfaces(t *testing.T) {
+	policy := &models.SELinuxPolicy{
+		ModuleName: "app",
+		Booleans:   []models.Boolean{{Name: "app_use_nfs"}},
+	}
+
+	result, err := NewIFGenerator(policy).Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	for _, want := range []string{"interface(`app_set_app_use_nfs',`", "\t\tbool app_use_nfs;\n", "\tselinux_set_generic_booleans($1)\n"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Missing %q, got:\n%s", want, result)
+		}
+	}
+
+	generator := NewIFGenerator(policy)
+	generator.SetBooleanStyle(BooleanStyleTunable)
+	result, err = generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if !strings.Contains(result, "Set the app_use_nfs tunable.") || strings.Contains(result, "bool app_use_nfs;") {
+		t.Errorf("Tunable interface should not require the boolean, got:\n%s", result)
+	}
+}
+
+func TestIFGenerator_InterfacePrefix(t *testing.T) {
+	policy := &models.SELinuxPolicy{
+		ModuleName: "httpd",
+		Rules: []models.AllowRule{
+			{SourceType: "httpd_t", TargetType: "httpd_content_t", Class: "file", Permissions: []string{"read"}},
+		},
+		Booleans: []models.Boolean{{Name: "httpd_use_nfs"}},
+	}
+
+	generator := NewIFGenerator(policy)
+	generator.SetInterfacePrefix("acme_")
+	result, err := generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	for _, want := range []string{"interface(`acme_httpd_read_files',`", "interface(`acme_httpd_set_httpd_use_nfs',`", "\t\ttype httpd_content_t;\n", "##\thttpd policy module\n"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Missing %q, got:\n%s", want, result)
+		}
+	}
+	if strings.Contains(result, "interface(`httpd_") {
+		t.Errorf("Unprefixed interface name remains, got:\n%s", result)
+	}
+}
+
+func TestIFGenerator_ManageAllFiles(t *testing.T) {
+	policy := &models.SELinuxPolicy{ModuleName: "httpd"}
+	result, err := NewIFGenerator(policy).Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if strings.Contains(result, "manage_all_files") {
+		t.Errorf("Interface requires the file type attribute, got:\n%s", result)
+	}
+
+	policy.Attributes = []string{"httpd_file_types"}
+	result, err = NewIFGenerator(policy).Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	for _, want := range []string{"interface(`httpd_manage_all_files',`", "\t\tattribute httpd_file_types;\n", "\tmanage_files_pattern($1, httpd_file_types, httpd_file_types)\n"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Missing %q, got:\n%s", want, result)
+		}
+	}
+}
+
+func TestIFGenerator_TypeInterfaces(t *testing.T) {
+	policy := &models.SELinuxPolicy{
+		ModuleName: "httpd",
+		Types: []models.TypeDeclaration{
+			{TypeName: "httpd_t", Attributes: []string{"domain"}},
+			{TypeName: "httpd_helper_t", Attributes: []string{"domain"}},
+			{TypeName: "httpd_helper_exec_t", Attributes: []string{"exec_type", "file_type"}},
+			{TypeName: "httpd_var_www_t", Attributes: []string{"file_type"}},
+			{TypeName: "httpd_port_t", Attributes: []string{"port_type"}},
+		},
+		Transitions: []models.TypeTransition{
+			{SourceType: "httpd_t", TargetType: "httpd_helper_exec_t", Class: "process", NewType: "httpd_helper_t"},
+			{SourceType: "init_t", TargetType: "httpd_exec_t", Class: "process", NewType: "httpd_t"},
+			{SourceType: "httpd_t", TargetType: "tmp_t", Class: "file", NewType: "httpd_var_www_t"},
+		},
+	}
+
+	result, err := NewIFGenerator(policy).Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	for _, want := range []string{
+		"##\tExecute httpd_helper_exec_t in the httpd_helper_t domain.\n",
+		"interface(`httpd_helper_domtrans',`\n\tgen_require(`\n\t\ttype httpd_helper_t;\n\t\ttype httpd_helper_exec_t;\n\t')\n\n\tdomtrans_pattern($1, httpd_helper_exec_t, httpd_helper_t)\n')\n",
+		"interface(`httpd_read_var_www_content',`\n\tgen_require(`\n\t\ttype httpd_var_www_t;\n\t')\n\n\tlist_dirs_pattern($1, httpd_var_www_t, httpd_var_www_t)\n\tread_files_pattern($1, httpd_var_www_t, httpd_var_www_t)\n')\n",
+		"interface(`httpd_manage_var_www_content',`",
+		"\tmanage_files_pattern($1, httpd_var_www_t, httpd_var_www_t)\n",
+		"interface(`httpd_read_helper_exec_content',`",
+		"## <param name=\"domain\">\n",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Missing %q, got:\n%s", want, result)
+		}
+	}
+	// The module's own domain is covered by httpd_domtrans; file transitions
+	// and types without file_type get no interfaces of their own
+	for _, unwanted := range []string{"interface(`httpd__domtrans'", "interface(`httpd_var_www_domtrans'", "port_content"} {
+		if strings.Contains(result, unwanted) {
+			t.Errorf("Unexpected %q, got:\n%s", unwanted, result)
+		}
+	}
+	if strings.Count(result, "interface(`httpd_domtrans',`") != 1 {
+		t.Errorf("Expected one httpd_domtrans interface, got:\n%s", result)
+	}
+
+	generator := NewIFGenerator(policy)
+	generator.SetInterfacePrefix("acme")
+	result, err = generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	for _, want := range []string{"interface(`acme_httpd_helper_domtrans',`", "interface(`acme_httpd_read_var_www_content',`"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Missing %q, got:\n%s", want, result)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package selinux
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// InstallStep is one command of building and installing a policy module
+type InstallStep struct {
+	Command string
+	Args    []string
+}
+
+// String renders the step as a shell command line
+func (s InstallStep) String() string {
+	return strings.Join(append([]string{s.Command}, s.Args...), " ")
+}
+
+// InstallSteps returns the commands that compile the .te file, package it with
+// the .fc file into <module>.pp in outputDir and install it with semodule.
+// semodule runs through sudo unless asRoot is set.
+func InstallSteps(moduleName, tePath, fcPath, outputDir string, asRoot bool) []InstallStep {
+	modPath := filepath.Join(outputDir, moduleName+".mod")
+	ppPath := filepath.Join(outputDir, moduleName+".pp")
+
+	install := InstallStep{Command: "semodule", Args: []string{"-i", ppPath}}
+	if !asRoot {
+		install = InstallStep{Command: "sudo", Args: append([]string{install.Command}, install.Args...)}
+	}
+
+	return []InstallStep{
+		{Command: "checkmodule", Args: []string{"-M", "-m", "-o", modPath, tePath}},
+		{Command: "semodule_package", Args: []string{"-o", ppPath, "-m", modPath, "-fc", fcPath}},
+		install,
+	}
+}
+
+// MissingInstallTools returns the tools the steps need that are not on the PATH
+func MissingInstallTools(steps []InstallStep) []string {
+	missing := make([]string, 0)
+	for _, step := range steps {
+		tools := []string{step.Command}
+		if step.Command == "sudo" && len(step.Args) > 0 {
+			tools = append(tools, step.Args[0])
+		}
+		for _, tool := range tools {
+			if _, err := exec.LookPath(tool); err != nil {
+				missing = append(missing, tool)
+			}
+		}
+	}
+	return missing
+}
+
+// RunInstallStep runs a step with the terminal attached, so sudo can prompt
+func RunInstallStep(step InstallStep) error {
+	cmd := exec.Command(step.Command, step.Args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s failed: %w", step.Command, err)
+	}
+	return nil
+}
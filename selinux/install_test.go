@@ -0,0 +1,25 @@
+package selinux
+
+import "testing"
+
+func TestInstallSteps(t *testing.T) {
+	steps := InstallSteps("myapp", "out/myapp.te", "out/myapp.fc", "out", false)
+	want := []string{
+		"checkmodule -M -m -o out/myapp.mod out/myapp.te",
+		"semodule_package -o out/myapp.pp -m out/myapp.mod -fc out/myapp.fc",
+		"sudo semodule -i out/myapp.pp",
+	}
+	if len(steps) != len(want) {
+		t.Fatalf("InstallSteps() = %v, want %v", steps, want)
+	}
+	for i := range want {
+		if steps[i].String() != want[i] {
+			t.Errorf("step %d = %q, want %q", i, steps[i], want[i])
+		}
+	}
+
+	steps = InstallSteps("myapp", "out/myapp.te", "out/myapp.fc", "out", true)
+	if got := steps[2].String(); got != "semodule -i out/myapp.pp" {
+		t.Errorf("root install step = %q, want no sudo", got)
+	}
+}
@@ -0,0 +1,141 @@
+package selinux
+
+import (
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/cici0602/pml-to-selinux/models"
+)
+
+// InstalledDiff is the difference between a compiled policy and the loaded policy
+type InstalledDiff struct {
+	MissingTypes []string // Module types the loaded policy does not define
+	NotInstalled []string // Source rules the loaded policy does not grant, i.e. needs applying
+	Drift        []string // Loaded rules of module domains that the source does not grant
+}
+
+// Empty reports whether the loaded policy matches the compiled one
+func (d InstalledDiff) Empty() bool {
+	return len(d.MissingTypes) == 0 && len(d.NotInstalled) == 0 && len(d.Drift) == 0
+}
+
+// LoadInstalledRules returns the allow rules of the loaded policy whose source is
+// one of the given types. It shells out to sesearch (setools), which must be
+// available on the system.
+func LoadInstalledRules(sourceTypes []string) ([]models.AllowRule, error) {
+	if _, err := exec.LookPath("sesearch"); err != nil {
+		return nil, fmt.Errorf("sesearch not found, install setools to compare against the local policy")
+	}
+
+	rules := make([]models.AllowRule, 0)
+	for _, sourceType := range sourceTypes {
+		output, err := exec.Command("sesearch", "-A", "-s", sourceType).Output()
+		if err != nil {
+			return nil, fmt.Errorf("failed to query installed rules of %s: %w", sourceType, err)
+		}
+		rules = append(rules, parseSesearchRules(string(output))...)
+	}
+	return rules, nil
+}
+
+// parseSesearchRules parses the allow rules of "sesearch -A" output, one per line:
+// allow src tgt:class { perms }; with an optional [ boolean ]:True suffix
+func parseSesearchRules(output string) []models.AllowRule {
+	rules := make([]models.AllowRule, 0)
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "allow ") {
+			continue
+		}
+		line, _, _ = strings.Cut(line, ";")
+
+		fields := strings.Fields(strings.NewReplacer("{", " ", "}", " ").Replace(strings.TrimPrefix(line, "allow ")))
+		if len(fields) < 3 {
+			continue
+		}
+		target, class, ok := strings.Cut(fields[1], ":")
+		if !ok {
+			continue
+		}
+		rules = append(rules, models.AllowRule{
+			SourceType:  fields[0],
+			TargetType:  target,
+			Class:       class,
+			Permissions: fields[2:],
+		})
+	}
+	return rules
+}
+
+// DiffInstalled compares the compiled policy with the loaded policy's types and
+// rules, permission by permission. Drift only counts loaded rules whose source is
+// a module type itself; rules granted through attributes such as domain belong to
+// other modules.
+func DiffInstalled(policy *models.SELinuxPolicy, installedTypes map[string]bool, installedRules []models.AllowRule) InstalledDiff {
+	diff := InstalledDiff{}
+
+	moduleTypes := make(map[string]bool)
+	for _, typeDecl := range policy.Types {
+		moduleTypes[typeDecl.TypeName] = true
+		if !installedTypes[typeDecl.TypeName] {
+			diff.MissingTypes = append(diff.MissingTypes, typeDecl.TypeName)
+		}
+	}
+	sort.Strings(diff.MissingTypes)
+
+	source := permissionSet(append(append([]models.AllowRule(nil), policy.Rules...), policy.CondRules...))
+	installed := permissionSet(installedRules)
+
+	notInstalled := make(map[string][]string)
+	for key, perms := range source {
+		for perm := range perms {
+			if !installed[key][perm] {
+				notInstalled[key] = append(notInstalled[key], perm)
+			}
+		}
+	}
+	drift := make(map[string][]string)
+	for key, perms := range installed {
+		sourceType, _, _ := strings.Cut(key, " ")
+		if !moduleTypes[sourceType] {
+			continue
+		}
+		for perm := range perms {
+			if !source[key][perm] {
+				drift[key] = append(drift[key], perm)
+			}
+		}
+	}
+
+	diff.NotInstalled = formatPermissionSet(notInstalled)
+	diff.Drift = formatPermissionSet(drift)
+	return diff
+}
+
+// permissionSet indexes the permissions of rules by "source target:class"
+func permissionSet(rules []models.AllowRule) map[string]map[string]bool {
+	set := make(map[string]map[string]bool)
+	for _, rule := range rules {
+		key := fmt.Sprintf("%s %s:%s", rule.SourceType, rule.TargetType, rule.Class)
+		if set[key] == nil {
+			set[key] = make(map[string]bool)
+		}
+		for _, perm := range rule.Permissions {
+			set[key][perm] = true
+		}
+	}
+	return set
+}
+
+// formatPermissionSet renders each "source target:class" key as a sorted allow rule
+func formatPermissionSet(set map[string][]string) []string {
+	rules := make([]string, 0, len(set))
+	for key, perms := range set {
+		sort.Strings(perms)
+		rules = append(rules, fmt.Sprintf("allow %s { %s };", key, strings.Join(perms, " ")))
+	}
+	sort.Strings(rules)
+	return rules
+}
@@ -0,0 +1,68 @@
+package selinux
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cici0602/pml-to-selinux/models"
+)
+
+func TestParseSesearchRules(t *testing.T) {
+	output := `
+allow app_t app_etc_t:file { getattr open read };
+allow app_t self:process signal;
+allow domain app_t:process sigchld;
+allow app_t app_log_t:file { append open }; [ app_logging ]:True
+type_transition app_t tmp_t:file app_tmp_t;
+`
+	rules := parseSesearchRules(output)
+	if len(rules) != 4 {
+		t.Fatalf("parseSesearchRules() = %+v, want 4 rules", rules)
+	}
+	if got := rules[0]; got.SourceType != "app_t" || got.TargetType != "app_etc_t" || got.Class != "file" || strings.Join(got.Permissions, " ") != "getattr open read" {
+		t.Errorf("rule 0 = %+v", got)
+	}
+	if got := rules[1]; got.TargetType != "self" || strings.Join(got.Permissions, " ") != "signal" {
+		t.Errorf("rule 1 = %+v", got)
+	}
+	if got := rules[3]; got.TargetType != "app_log_t" || strings.Join(got.Permissions, " ") != "append open" {
+		t.Errorf("conditional rule = %+v", got)
+	}
+}
+
+func TestDiffInstalled(t *testing.T) {
+	policy := &models.SELinuxPolicy{
+		Types: []models.TypeDeclaration{{TypeName: "app_t"}, {TypeName: "app_etc_t"}, {TypeName: "app_cache_t"}},
+		Rules: []models.AllowRule{
+			{SourceType: "app_t", TargetType: "app_etc_t", Class: "file", Permissions: []string{"read", "open", "getattr"}},
+			{SourceType: "app_t", TargetType: "app_cache_t", Class: "dir", Permissions: []string{"search"}},
+		},
+	}
+	installedTypes := map[string]bool{"app_t": true, "app_etc_t": true}
+	installedRules := []models.AllowRule{
+		{SourceType: "app_t", TargetType: "app_etc_t", Class: "file", Permissions: []string{"getattr", "open"}},
+		{SourceType: "app_t", TargetType: "app_etc_t", Class: "file", Permissions: []string{"read", "write"}},
+		{SourceType: "domain", TargetType: "app_etc_t", Class: "file", Permissions: []string{"getattr"}},
+	}
+
+	diff := DiffInstalled(policy, installedTypes, installedRules)
+	if strings.Join(diff.MissingTypes, " ") != "app_cache_t" {
+		t.Errorf("MissingTypes = %v, want [app_cache_t]", diff.MissingTypes)
+	}
+	if strings.Join(diff.NotInstalled, "\n") != "allow app_t app_cache_t:dir { search };" {
+		t.Errorf("NotInstalled = %v", diff.NotInstalled)
+	}
+	if strings.Join(diff.Drift, "\n") != "allow app_t app_etc_t:file { write };" {
+		t.Errorf("Drift = %v, want only the module domain's write", diff.Drift)
+	}
+	if diff.Empty() {
+		t.Error("Empty() = true for a policy with differences")
+	}
+
+	installedTypes["app_cache_t"] = true
+	installedRules = append(installedRules[:1], models.AllowRule{SourceType: "app_t", TargetType: "app_cache_t", Class: "dir", Permissions: []string{"search"}},
+		models.AllowRule{SourceType: "app_t", TargetType: "app_etc_t", Class: "file", Permissions: []string{"read"}})
+	if diff := DiffInstalled(policy, installedTypes, installedRules); !diff.Empty() {
+		t.Errorf("DiffInstalled() = %+v, want no differences", diff)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/cici0602/pml-to-selinux/mapping"
 	"github.com/cici0602/pml-to-selinux/models"
 )
 
@@ -34,11 +35,13 @@ func (g *MacroGenerator) GenerateRequireBlock() string {
 		types[rule.SourceType] = true
 		types[rule.TargetType] = true
 
-		if classes[rule.Class] == nil {
-			classes[rule.Class] = make(map[string]bool)
-		}
-		for _, perm := range rule.Permissions {
-			classes[rule.Class][perm] = true
+		for _, class := range rule.AllClasses() {
+			if classes[class] == nil {
+				classes[class] = make(map[string]bool)
+			}
+			for _, perm := range rule.Permissions {
+				classes[class][perm] = true
+			}
 		}
 	}
 
@@ -57,6 +60,18 @@ func (g *MacroGenerator) GenerateRequireBlock() string {
 		types[trans.TargetType] = true
 		types[trans.NewType] = true
 	}
+	for _, trans := range g.policy.NamedTransitions {
+		types[trans.SourceType] = true
+		types[trans.TargetType] = true
+		types[trans.NewType] = true
+	}
+
+	// Extract from interface call arguments; the interface requires what it uses itself
+	for _, call := range g.policy.InterfaceCalls {
+		for _, typeName := range mapping.InterfaceTypeArgs(call.Name, call.Args) {
+			types[typeName] = true
+		}
+	}
 
 	// Remove declared types (they don't need to be in require)
 	declaredTypes := make(map[string]bool)
@@ -157,3 +172,16 @@ func (g *MacroGenerator) InferRequiredAttributes() []string {
 
 	return result
 }
+
+// HasExternalInterfaceArgs reports whether an interface call passes a type the
+// module does not declare, which must then be required
+func (g *MacroGenerator) HasExternalInterfaceArgs() bool {
+	for _, call := range g.policy.InterfaceCalls {
+		for _, typeName := range mapping.InterfaceTypeArgs(call.Name, call.Args) {
+			if typeName != "self" && !g.policy.HasType(typeName) {
+				return true
+			}
+		}
+	}
+	return false
+}
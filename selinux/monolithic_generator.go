@@ -0,0 +1,490 @@
+package selinux
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cici0602/pml-to-selinux/mapping"
+	"github.com/cici0602/pml-to-selinux/models"
+)
+
+// MonolithicGenerator handles generation of a monolithic policy.conf, the
+// checkpolicy input for policies built as a whole rather than from modules
+type MonolithicGenerator struct {
+	ruleFormatter
+	fsMapper *mapping.FilesystemMapper
+}
+
+// NewMonolithicGenerator creates a new MonolithicGenerator instance
+func NewMonolithicGenerator(policy *models.SELinuxPolicy) *MonolithicGenerator {
+	return &MonolithicGenerator{
+		ruleFormatter: ruleFormatter{policy: policy},
+		fsMapper:      mapping.NewFilesystemMapper(),
+	}
+}
+
+// SetAnnotate precedes each allow rule with a comment per PML line it was
+// generated from, saying what the line grants
+func (g *MonolithicGenerator) SetAnnotate(enabled bool) {
+	g.annotate = enabled
+}
+
+// Generate generates the complete policy.conf content. Sections follow the order
+// checkpolicy's grammar requires: classes and initial SIDs, access vectors, MLS
+// declarations, types and rules, users, constraints, then the labeling
+// statements. Nothing is required from other modules, so every type and
+// attribute the rules reference is declared here. File contexts have no
+// policy.conf syntax; they go to a separate file_contexts file.
+func (g *MonolithicGenerator) Generate() (string, error) {
+	var builder strings.Builder
+
+	if len(g.policy.OptionalRules) > 0 {
+		return "", fmt.Errorf("optional rules (%s) need a policy module; a monolithic policy has no optional blocks",
+			g.policy.OptionalRules[0].Optional)
+	}
+	if len(g.policy.InterfaceCalls) > 0 {
+		return "", fmt.Errorf("interface calls (%s) are refpolicy macros; a monolithic policy.conf cannot expand them",
+			g.policy.InterfaceCalls[0].Name)
+	}
+	classes, err := g.policyClasses()
+	if err != nil {
+		return "", err
+	}
+
+	g.writeHeader(&builder)
+	g.writeClasses(&builder, classes)
+
+	g.writeObjectDefaults(&builder)
+	g.writeMLS(&builder)
+
+	g.writeExternalDeclarations(&builder)
+	if err := g.writeTypeDeclarations(&builder); err != nil {
+		return "", err
+	}
+	g.writeAttributeExpansions(&builder)
+	g.writeBooleans(&builder)
+	if err := g.writeTypeTransitions(&builder); err != nil {
+		return "", err
+	}
+	if err := g.writeAllowRules(&builder); err != nil {
+		return "", err
+	}
+	g.writeConditionalRules(&builder)
+	g.writeAuditRules(&builder)
+	g.writeXpermRules(&builder)
+	if err := g.writeDenyRules(&builder); err != nil {
+		return "", err
+	}
+	g.writeSystemRole(&builder)
+	g.writeRoles(&builder)
+	g.writeUsers(&builder)
+
+	if err := g.writeConstraints(&builder); err != nil {
+		return "", err
+	}
+
+	g.writeSIDContexts(&builder)
+	g.writeFilesystemContexts(&builder)
+	g.writePortContexts(&builder)
+	g.writeNetworkContexts(&builder)
+
+	return builder.String(), nil
+}
+
+// writeHeader writes the file header with comments
+func (g *MonolithicGenerator) writeHeader(builder *strings.Builder) {
+	builder.WriteString("########################################\n")
+	builder.WriteString(fmt.Sprintf("# Monolithic SELinux Policy: %s\n", g.policy.ModuleName))
+	builder.WriteString(fmt.Sprintf("# Version: %s\n", g.policy.Version))
+	builder.WriteString("# Generated by PML-to-SELinux Compiler\n")
+	builder.WriteString("########################################\n\n")
+}
+
+// policyClasses returns the classes of the permission table, sorted, after
+// checking every class the policy uses has its permissions defined there
+func (g *MonolithicGenerator) policyClasses() ([]string, error) {
+	var used []string
+	for _, rules := range [][]models.AllowRule{g.policy.Rules, g.policy.CondRules, g.policy.AuditRules, g.policy.DontauditRules} {
+		for _, rule := range rules {
+			used = append(used, rule.AllClasses()...)
+		}
+	}
+	for _, rule := range g.policy.NeverallowRules {
+		used = append(used, rule.Class)
+	}
+	for _, rule := range g.policy.XpermRules {
+		used = append(used, rule.Class)
+	}
+	for _, trans := range g.policy.Transitions {
+		used = append(used, trans.Class)
+	}
+	for _, trans := range g.policy.NamedTransitions {
+		used = append(used, trans.Class)
+	}
+	for _, c := range g.policy.Constraints {
+		used = append(used, c.Classes...)
+	}
+
+	for _, class := range uniqueStrings(used) {
+		if mapping.ClassPermissions(class) == nil {
+			return nil, fmt.Errorf("class %s has no permission definition to declare; add it with a class permissions file", class)
+		}
+	}
+	return mapping.Classes(), nil
+}
+
+// writeClasses writes the class declarations, the initial SID declarations and
+// the permissions of each class, which together open a policy.conf
+func (g *MonolithicGenerator) writeClasses(builder *strings.Builder, classes []string) {
+	builder.WriteString("########################################\n")
+	builder.WriteString("# Object Classes\n")
+	builder.WriteString("########################################\n\n")
+
+	for _, class := range classes {
+		builder.WriteString(fmt.Sprintf("class %s\n", class))
+	}
+	builder.WriteString("\n")
+
+	// SIDs are declared in policy order since the kernel assigns them by position
+	if len(g.policy.InitialSIDs) > 0 {
+		for _, sid := range g.policy.InitialSIDs {
+			builder.WriteString(fmt.Sprintf("sid %s\n", sid.Name))
+		}
+		builder.WriteString("\n")
+	}
+
+	for _, class := range classes {
+		builder.WriteString(fmt.Sprintf("class %s { %s }\n", class, strings.Join(mapping.ClassPermissions(class), " ")))
+	}
+	builder.WriteString("\n")
+}
+
+// maxCategory returns the highest MLS category the file contexts use, or -1
+func (g *MonolithicGenerator) maxCategory() int {
+	highest := -1
+	for _, fc := range g.policy.FileContexts {
+		if fc.Range == nil {
+			continue
+		}
+		for _, level := range []models.SecurityLevel{fc.Range.Low, fc.Range.High} {
+			for _, category := range level.Categories {
+				if category > highest {
+					highest = category
+				}
+			}
+		}
+	}
+	return highest
+}
+
+// systemHigh returns the highest level the policy declares: s0 with every category
+func (g *MonolithicGenerator) systemHigh() string {
+	if highest := g.maxCategory(); highest >= 0 {
+		if highest == 0 {
+			return "s0:c0"
+		}
+		return fmt.Sprintf("s0:c0.c%d", highest)
+	}
+	return "s0"
+}
+
+// writeMLS declares the single sensitivity s0 and the categories the file
+// contexts use, so the s0 levels of the generated contexts are valid
+func (g *MonolithicGenerator) writeMLS(builder *strings.Builder) {
+	builder.WriteString("########################################\n")
+	builder.WriteString("# MLS Declarations\n")
+	builder.WriteString("########################################\n\n")
+
+	builder.WriteString("sensitivity s0;\n")
+	builder.WriteString("dominance { s0 }\n")
+	for category := 0; category <= g.maxCategory(); category++ {
+		builder.WriteString(fmt.Sprintf("category c%d;\n", category))
+	}
+	builder.WriteString(fmt.Sprintf("level %s;\n\n", g.systemHigh()))
+}
+
+// writeExternalDeclarations declares the attributes and types the policy uses
+// without declaring them, which a module would require from other modules
+func (g *MonolithicGenerator) writeExternalDeclarations(builder *strings.Builder) {
+	var attributes []string
+	for _, decl := range g.policy.Types {
+		for _, attr := range decl.Attributes {
+			if !containsName(g.policy.Attributes, attr) {
+				attributes = append(attributes, attr)
+			}
+		}
+	}
+	attributes = uniqueStrings(attributes)
+	sort.Strings(attributes)
+
+	referenced := append([]string(nil), g.policy.BaseTypes...)
+	for _, rules := range [][]models.AllowRule{g.policy.Rules, g.policy.CondRules, g.policy.AuditRules, g.policy.DontauditRules} {
+		for _, rule := range rules {
+			referenced = append(referenced, rule.SourceType, rule.TargetType)
+		}
+	}
+	for _, trans := range g.policy.Transitions {
+		referenced = append(referenced, trans.SourceType, trans.TargetType, trans.NewType)
+	}
+	for _, sid := range g.policy.InitialSIDs {
+		referenced = append(referenced, sid.SELinuxType)
+	}
+	for _, rule := range g.fsuseRules() {
+		referenced = append(referenced, contextType(rule.Context))
+	}
+	for _, rule := range g.genfsconRules() {
+		referenced = append(referenced, contextType(rule.Context))
+	}
+
+	var types []string
+	for _, name := range uniqueStrings(referenced) {
+		if name == "" || name == "self" || g.policy.HasType(name) || containsName(g.policy.Attributes, name) || containsName(attributes, name) {
+			continue
+		}
+		types = append(types, name)
+	}
+	sort.Strings(types)
+
+	if len(attributes) == 0 && len(types) == 0 {
+		return
+	}
+
+	builder.WriteString("########################################\n")
+	builder.WriteString("# External Declarations\n")
+	builder.WriteString("########################################\n\n")
+
+	for _, attr := range attributes {
+		builder.WriteString(fmt.Sprintf("attribute %s;\n", attr))
+	}
+	if len(attributes) > 0 && len(types) > 0 {
+		builder.WriteString("\n")
+	}
+	for _, typeName := range types {
+		builder.WriteString(fmt.Sprintf("type %s;\n", typeName))
+	}
+	builder.WriteString("\n")
+}
+
+// writeBooleans writes a bool declaration per boolean
+func (g *MonolithicGenerator) writeBooleans(builder *strings.Builder) {
+	if len(g.policy.Booleans) == 0 {
+		return
+	}
+
+	builder.WriteString("########################################\n")
+	builder.WriteString("# Booleans\n")
+	builder.WriteString("########################################\n\n")
+
+	booleans := make([]models.Boolean, len(g.policy.Booleans))
+	copy(booleans, g.policy.Booleans)
+	sort.Slice(booleans, func(i, j int) bool {
+		return booleans[i].Name < booleans[j].Name
+	})
+	for _, b := range booleans {
+		builder.WriteString(fmt.Sprintf("bool %s %t;\n", b.Name, b.Default))
+	}
+	builder.WriteString("\n")
+}
+
+// writeConditionalRules writes the allow rules of each condition, sorted by
+// condition, inside an if block
+func (g *MonolithicGenerator) writeConditionalRules(builder *strings.Builder) {
+	if len(g.policy.CondRules) == 0 {
+		return
+	}
+
+	builder.WriteString("########################################\n")
+	builder.WriteString("# Conditional Rules\n")
+	builder.WriteString("########################################\n\n")
+
+	var conditions []string
+	byCondition := make(map[string][]models.AllowRule)
+	for _, rule := range g.policy.CondRules {
+		if _, ok := byCondition[rule.Condition]; !ok {
+			conditions = append(conditions, rule.Condition)
+		}
+		byCondition[rule.Condition] = append(byCondition[rule.Condition], rule)
+	}
+	sort.Strings(conditions)
+
+	for _, condition := range conditions {
+		builder.WriteString(fmt.Sprintf("if (%s) {\n", condition))
+		g.writeBlockRules(builder, byCondition[condition])
+		builder.WriteString("}\n\n")
+	}
+}
+
+// writeSystemRole declares system_r, the role of processes, and authorizes it
+// for the domain types and the SIDs that label processes
+func (g *MonolithicGenerator) writeSystemRole(builder *strings.Builder) {
+	builder.WriteString("########################################\n")
+	builder.WriteString("# System Role\n")
+	builder.WriteString("########################################\n\n")
+
+	if !containsName(g.policy.Roles, "system_r") {
+		builder.WriteString("role system_r;\n")
+	}
+
+	var domains []string
+	for _, decl := range g.policy.Types {
+		if containsName(decl.Attributes, "domain") {
+			domains = append(domains, decl.TypeName)
+		}
+	}
+	for _, sid := range g.policy.InitialSIDs {
+		if sid.Role() == "system_r" {
+			domains = append(domains, sid.SELinuxType)
+		}
+	}
+	domains = uniqueStrings(domains)
+	sort.Strings(domains)
+	if len(domains) > 0 {
+		builder.WriteString(fmt.Sprintf("role system_r types %s;\n", nameList(domains)))
+	}
+	builder.WriteString("\n")
+}
+
+// writeUsers declares system_u, the user of every generated context, with the
+// policy's roles and the full MLS range
+func (g *MonolithicGenerator) writeUsers(builder *strings.Builder) {
+	builder.WriteString("########################################\n")
+	builder.WriteString("# Users\n")
+	builder.WriteString("########################################\n\n")
+
+	roles := []string{"system_r"}
+	for _, role := range g.policy.Roles {
+		if role != "system_r" && role != "object_r" {
+			roles = append(roles, role)
+		}
+	}
+	sort.Strings(roles)
+	builder.WriteString(fmt.Sprintf("user system_u roles %s level s0 range s0 - %s;\n\n",
+		nameList(roles), g.systemHigh()))
+}
+
+// writeSIDContexts writes the context of each initial SID, in policy order
+func (g *MonolithicGenerator) writeSIDContexts(builder *strings.Builder) {
+	if len(g.policy.InitialSIDs) == 0 {
+		return
+	}
+
+	builder.WriteString("########################################\n")
+	builder.WriteString("# Initial SID Contexts\n")
+	builder.WriteString("########################################\n\n")
+
+	for _, sid := range g.policy.InitialSIDs {
+		builder.WriteString(fmt.Sprintf("sid %s system_u:%s:%s:s0\n", sid.Name, sid.Role(), sid.SELinuxType))
+	}
+	builder.WriteString("\n")
+}
+
+// fsuseRules returns the FilesystemMapper's fs_use rules, sorted by filesystem
+func (g *MonolithicGenerator) fsuseRules() []mapping.FsuseRule {
+	rules := g.fsMapper.GenerateFsuseRules()
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].FSType < rules[j].FSType
+	})
+	return rules
+}
+
+// genfsconRules returns the FilesystemMapper's genfscon rules followed by the
+// policy's own, which replace a default for the same filesystem and path
+func (g *MonolithicGenerator) genfsconRules() []mapping.GenfsconRule {
+	overridden := make(map[string]bool)
+	var own []mapping.GenfsconRule
+	for _, genfs := range g.policy.GenfsContexts {
+		overridden[genfs.FSType+" "+genfs.Path] = true
+		own = append(own, mapping.GenfsconRule{
+			FSType:  genfs.FSType,
+			Path:    genfs.Path,
+			Context: fmt.Sprintf("system_u:object_r:%s:s0", genfs.SELinuxType),
+		})
+	}
+
+	var rules []mapping.GenfsconRule
+	for _, rule := range g.fsMapper.GenerateGenfsconRules() {
+		if !overridden[rule.FSType+" "+rule.Path] {
+			rules = append(rules, rule)
+		}
+	}
+	return append(rules, own...)
+}
+
+// writeFilesystemContexts writes the fs_use statements, then the genfscon statements
+func (g *MonolithicGenerator) writeFilesystemContexts(builder *strings.Builder) {
+	builder.WriteString("########################################\n")
+	builder.WriteString("# Filesystem Contexts\n")
+	builder.WriteString("########################################\n\n")
+
+	for _, rule := range g.fsuseRules() {
+		builder.WriteString(fmt.Sprintf("fs_use_%s %s %s;\n", rule.UseType, rule.FSType, rule.Context))
+	}
+	builder.WriteString("\n")
+	for _, rule := range g.genfsconRules() {
+		builder.WriteString(fmt.Sprintf("genfscon %s %s %s\n", rule.FSType, rule.Path, rule.Context))
+	}
+	builder.WriteString("\n")
+}
+
+// writePortContexts writes a portcon statement per port binding, in policy order
+func (g *MonolithicGenerator) writePortContexts(builder *strings.Builder) {
+	if len(g.policy.PortBindings) == 0 {
+		return
+	}
+
+	builder.WriteString("########################################\n")
+	builder.WriteString("# Port Contexts\n")
+	builder.WriteString("########################################\n\n")
+
+	for _, binding := range g.policy.PortBindings {
+		ports := strconv.Itoa(binding.Port)
+		if binding.PortEnd != 0 {
+			ports = fmt.Sprintf("%d-%d", binding.Port, binding.PortEnd)
+		}
+		builder.WriteString(fmt.Sprintf("portcon %s %s system_u:object_r:%s:s0\n",
+			binding.Protocol, ports, binding.PortType))
+	}
+	builder.WriteString("\n")
+}
+
+// writeNetworkContexts writes the netifcon and nodecon statements, in policy order
+func (g *MonolithicGenerator) writeNetworkContexts(builder *strings.Builder) {
+	if len(g.policy.Netifcons)+len(g.policy.Nodecons) == 0 {
+		return
+	}
+
+	builder.WriteString("########################################\n")
+	builder.WriteString("# Network Contexts\n")
+	builder.WriteString("########################################\n\n")
+
+	for _, netif := range g.policy.Netifcons {
+		context := fmt.Sprintf("system_u:object_r:%s:s0", netif.SELinuxType)
+		builder.WriteString(fmt.Sprintf("netifcon %s %s %s\n", netif.Interface, context, context))
+	}
+	for _, node := range g.policy.Nodecons {
+		builder.WriteString(fmt.Sprintf("nodecon %s %s system_u:object_r:%s:s0\n",
+			node.Address, node.Netmask, node.SELinuxType))
+	}
+	builder.WriteString("\n")
+}
+
+// contextType returns the type field of a user:role:type:level context
+func contextType(context string) string {
+	fields := strings.Split(context, ":")
+	if len(fields) < 3 {
+		return ""
+	}
+	return fields[2]
+}
+
+// containsName reports whether names contains name
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package selinux
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cici0602/pml-to-selinux/models"
+)
+
+func TestMonolithicGenerator_MatchesTESemantics(t *testing.T) {
+	policy := testCILPolicy()
+	policy.InitialSIDs = []models.InitialSID{
+		{Name: "kernel", SELinuxType: "kernel_t"},
+		{Name: "unlabeled", SELinuxType: "unlabeled_t"},
+	}
+	policy.GenfsContexts = []models.GenfsContext{
+		{FSType: "proc", Path: "/", SELinuxType: "httpd_proc_t"},
+	}
+
+	teGenerator := NewTEGenerator(policy)
+	teGenerator.SetBasePolicy(true)
+	te, err := teGenerator.Generate()
+	if err != nil {
+		t.Fatalf("TE Generate() error = %v", err)
+	}
+	conf, err := NewMonolithicGenerator(policy).Generate()
+	if err != nil {
+		t.Fatalf("Monolithic Generate() error = %v", err)
+	}
+
+	// Unconditional allow rules must grant the same accesses as the .te
+	unconditional := func(content string) string {
+		before, _, _ := strings.Cut(content, "# Conditional Rules")
+		return strings.ReplaceAll(before, ":{ dir lnk_file } search;", ":dir search;\nallow httpd_t httpd_log_t:lnk_file search;")
+	}
+	teTuples := allowTuples(teAllowPattern.FindAllStringSubmatch(unconditional(te), -1))
+	confTuples := allowTuples(teAllowPattern.FindAllStringSubmatch(unconditional(conf), -1))
+	if len(teTuples) == 0 || strings.Join(teTuples, "\n") != strings.Join(confTuples, "\n") {
+		t.Errorf("allow semantics differ\nTE:\n%s\npolicy.conf:\n%s", strings.Join(teTuples, "\n"), strings.Join(confTuples, "\n"))
+	}
+
+	for _, unwanted := range []string{"policy_module", "require", "gen_context", "gen_bool", "genfscon proc / system_u:object_r:proc_t:s0"} {
+		if strings.Contains(conf, unwanted) {
+			t.Errorf("policy.conf contains %q:\n%s", unwanted, conf)
+		}
+	}
+
+	for _, want := range []string{
+		"class file\n",
+		"sid kernel\nsid unlabeled\n",
+		"class file { append audit_access create entrypoint execmod execute execute_no_trans",
+		"sensitivity s0;\ndominance { s0 }\ncategory c0;\ncategory c1;\ncategory c2;\ncategory c3;\nlevel s0:c0.c3;\n",
+		"attribute domain;\nattribute exec_type;\nattribute file_type;\n",
+		"type kernel_t;\n",
+		"type unlabeled_t;\n",
+		"type httpd_t, domain;\n",
+		"bool httpd_can_write false;\n",
+		"if (httpd_can_write) {\n\tallow httpd_t httpd_log_t:file { write };\n}\n",
+		"role system_r types { httpd_t kernel_t };\n",
+		"user system_u roles system_r level s0 range s0 - s0:c0.c3;\n",
+		"sid kernel system_u:system_r:kernel_t:s0\nsid unlabeled system_u:object_r:unlabeled_t:s0\n",
+		"fs_use_xattr ext4 system_u:object_r:fs_t:s0;\n",
+		"genfscon proc / system_u:object_r:httpd_proc_t:s0\n",
+	} {
+		if !strings.Contains(conf, want) {
+			t.Errorf("missing %q, got:\n%s", want, conf)
+		}
+	}
+
+	// checkpolicy requires declarations before rules and the labeling statements last
+	order := []string{"# Object Classes", "# MLS Declarations", "# Type Declarations", "# Allow Rules", "# Users", "# Initial SID Contexts", "# Filesystem Contexts"}
+	last := -1
+	for _, section := range order {
+		index := strings.Index(conf, section)
+		if index <= last {
+			t.Errorf("section %q out of order", section)
+		}
+		last = index
+	}
+}
+
+func TestMonolithicGenerator_Unsupported(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*models.SELinuxPolicy)
+	}{
+		{
+			name: "optional rules",
+			modify: func(p *models.SELinuxPolicy) {
+				p.OptionalRules = []models.AllowRule{
+					{SourceType: "httpd_t", TargetType: "mysqld_t", Class: "unix_stream_socket", Permissions: []string{"connectto"}, Optional: "mysql"},
+				}
+			},
+		},
+		{
+			name: "class without permission definition",
+			modify: func(p *models.SELinuxPolicy) {
+				p.Rules = append(p.Rules, models.AllowRule{SourceType: "httpd_t", TargetType: "httpd_t", Class: "netif", Permissions: []string{"ingress"}})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := testCILPolicy()
+			tt.modify(policy)
+			if _, err := NewMonolithicGenerator(policy).Generate(); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,491 @@
+package selinux
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cici0602/pml-to-selinux/models"
+)
+
+// ruleFormatter renders the statements a .te module and a monolithic policy.conf
+// write alike: declarations, roles, type transitions, access vector rules and
+// constraints. Module-only constructs stay with the generators.
+type ruleFormatter struct {
+	policy   *models.SELinuxPolicy
+	annotate bool
+}
+
+// writeTypeDeclarations writes all type declarations
+func (f *ruleFormatter) writeTypeDeclarations(builder *strings.Builder) error {
+	if len(f.policy.Types) == 0 {
+		return nil
+	}
+
+	builder.WriteString("########################################\n")
+	builder.WriteString("# Type Declarations\n")
+	builder.WriteString("########################################\n\n")
+
+	// Attributes come first so the types below can be assigned to them
+	if len(f.policy.Attributes) > 0 {
+		attributes := append([]string(nil), f.policy.Attributes...)
+		sort.Strings(attributes)
+		for _, attr := range attributes {
+			builder.WriteString(fmt.Sprintf("attribute %s;\n", attr))
+		}
+		builder.WriteString("\n")
+	}
+
+	// Sort types for consistent output
+	types := make([]models.TypeDeclaration, len(f.policy.Types))
+	copy(types, f.policy.Types)
+	sort.Slice(types, func(i, j int) bool {
+		return types[i].TypeName < types[j].TypeName
+	})
+
+	for _, typeDecl := range types {
+		if len(typeDecl.Attributes) > 0 {
+			// Type with attributes: type typename, attr1, attr2;
+			builder.WriteString(fmt.Sprintf("type %s, %s;\n",
+				typeDecl.TypeName,
+				strings.Join(typeDecl.Attributes, ", ")))
+		} else {
+			// Simple type declaration: type typename;
+			builder.WriteString(fmt.Sprintf("type %s;\n", typeDecl.TypeName))
+		}
+	}
+
+	builder.WriteString("\n")
+	return nil
+}
+
+// writeAttributeExpansions writes expandattribute statements if any
+func (f *ruleFormatter) writeAttributeExpansions(builder *strings.Builder) {
+	if len(f.policy.Expansions) == 0 {
+		return
+	}
+
+	builder.WriteString("########################################\n")
+	builder.WriteString("# Attribute Expansion\n")
+	builder.WriteString("########################################\n\n")
+
+	expansions := make([]models.AttributeExpansion, len(f.policy.Expansions))
+	copy(expansions, f.policy.Expansions)
+	sort.Slice(expansions, func(i, j int) bool {
+		return expansions[i].Attribute < expansions[j].Attribute
+	})
+	for _, exp := range expansions {
+		builder.WriteString(fmt.Sprintf("expandattribute %s %t;\n", exp.Attribute, exp.Expand))
+	}
+
+	builder.WriteString("\n")
+}
+
+// writeRoles writes role declarations, role allow rules and role-type associations
+func (f *ruleFormatter) writeRoles(builder *strings.Builder) {
+	if len(f.policy.Roles) == 0 && len(f.policy.RoleAllows) == 0 && len(f.policy.RoleTypes) == 0 {
+		return
+	}
+
+	builder.WriteString("########################################\n")
+	builder.WriteString("# Roles\n")
+	builder.WriteString("########################################\n\n")
+
+	roles := append([]string(nil), f.policy.Roles...)
+	sort.Strings(roles)
+	for _, role := range roles {
+		builder.WriteString(fmt.Sprintf("role %s;\n", role))
+	}
+	if len(f.policy.Roles) > 0 && len(f.policy.RoleAllows) > 0 {
+		builder.WriteString("\n")
+	}
+	roleAllows := make([]models.RoleAllow, len(f.policy.RoleAllows))
+	copy(roleAllows, f.policy.RoleAllows)
+	sort.Slice(roleAllows, func(i, j int) bool {
+		if roleAllows[i].FromRole != roleAllows[j].FromRole {
+			return roleAllows[i].FromRole < roleAllows[j].FromRole
+		}
+		return roleAllows[i].ToRole < roleAllows[j].ToRole
+	})
+	for _, ra := range roleAllows {
+		builder.WriteString(fmt.Sprintf("allow %s %s;\n", ra.FromRole, ra.ToRole))
+	}
+	if len(f.policy.RoleTypes) > 0 {
+		builder.WriteString("\n")
+	}
+	for _, rt := range f.policy.RoleTypes {
+		builder.WriteString(fmt.Sprintf("role %s types %s;\n", rt.Role, nameList(rt.Types)))
+	}
+
+	builder.WriteString("\n")
+}
+
+// writeTypeTransitions writes type transition rules if any
+func (f *ruleFormatter) writeTypeTransitions(builder *strings.Builder) error {
+	if len(f.policy.Transitions) == 0 && len(f.policy.NamedTransitions) == 0 {
+		return nil
+	}
+
+	builder.WriteString("########################################\n")
+	builder.WriteString("# Type Transitions\n")
+	builder.WriteString("########################################\n\n")
+
+	// Sort transitions for consistent output
+	transitions := make([]models.TypeTransition, len(f.policy.Transitions))
+	copy(transitions, f.policy.Transitions)
+	sort.Slice(transitions, func(i, j int) bool {
+		if transitions[i].SourceType != transitions[j].SourceType {
+			return transitions[i].SourceType < transitions[j].SourceType
+		}
+		if transitions[i].TargetType != transitions[j].TargetType {
+			return transitions[i].TargetType < transitions[j].TargetType
+		}
+		return transitions[i].Class < transitions[j].Class
+	})
+
+	// Generate domain transitions with supporting rules
+	for _, trans := range transitions {
+		if trans.Class == "process" {
+			// This is a domain transition, generate the complete triplet
+			f.writeDomainTransitionRules(builder, &trans)
+		} else {
+			// Regular type transition
+			builder.WriteString(fmt.Sprintf("type_transition %s %s:%s %s;\n",
+				trans.SourceType, trans.TargetType, trans.Class, trans.NewType))
+		}
+	}
+
+	// Filename transitions follow, each only matching files of that exact name
+	named := make([]models.NamedTransition, len(f.policy.NamedTransitions))
+	copy(named, f.policy.NamedTransitions)
+	sort.Slice(named, func(i, j int) bool {
+		if named[i].SourceType != named[j].SourceType {
+			return named[i].SourceType < named[j].SourceType
+		}
+		if named[i].TargetType != named[j].TargetType {
+			return named[i].TargetType < named[j].TargetType
+		}
+		if named[i].Class != named[j].Class {
+			return named[i].Class < named[j].Class
+		}
+		return named[i].Filename < named[j].Filename
+	})
+	for _, trans := range named {
+		builder.WriteString(fmt.Sprintf("type_transition %s %s:%s %s \"%s\";\n",
+			trans.SourceType, trans.TargetType, trans.Class, trans.NewType, trans.Filename))
+	}
+
+	builder.WriteString("\n")
+	return nil
+}
+
+// writeDomainTransitionRules generates the complete domain transition triplet:
+// 1. type_transition - defines the transition
+// 2. allow source target:file execute - parent can execute child binary
+// 3. allow source target:process transition - parent can transition to child
+// 4. allow target target:file entrypoint - child can use its binary as entrypoint
+func (f *ruleFormatter) writeDomainTransitionRules(builder *strings.Builder, trans *models.TypeTransition) {
+	source := trans.SourceType
+	entrypoint := trans.TargetType
+	target := trans.NewType
+
+	builder.WriteString(fmt.Sprintf("# Domain transition: %s -> %s\n", source, target))
+
+	// 1. Type transition rule
+	builder.WriteString(fmt.Sprintf("type_transition %s %s:process %s;\n",
+		source, entrypoint, target))
+
+	// 2. Allow parent to execute child binary
+	builder.WriteString(fmt.Sprintf("allow %s %s:file execute;\n",
+		source, entrypoint))
+
+	// 3. Allow parent to transition to child domain
+	builder.WriteString(fmt.Sprintf("allow %s %s:process transition;\n",
+		source, target))
+
+	// 4. Allow child to use binary as entrypoint
+	builder.WriteString(fmt.Sprintf("allow %s %s:file entrypoint;\n",
+		target, entrypoint))
+
+	builder.WriteString("\n")
+}
+
+// writeAllowRules writes all allow rules, grouped by source type
+func (f *ruleFormatter) writeAllowRules(builder *strings.Builder) error {
+	if len(f.policy.Rules) == 0 {
+		return nil
+	}
+
+	builder.WriteString("########################################\n")
+	builder.WriteString("# Allow Rules\n")
+	builder.WriteString("########################################\n\n")
+
+	// Group rules by source type, target type, and class
+	ruleGroups := f.groupRules(f.policy.Rules)
+	rationales := f.groupRationales(f.policy.Rules)
+
+	// Sort source types for consistent output
+	sourceTypes := make([]string, 0, len(ruleGroups))
+	for sourceType := range ruleGroups {
+		sourceTypes = append(sourceTypes, sourceType)
+	}
+	sort.Strings(sourceTypes)
+
+	// Write rules for each source type
+	for _, sourceType := range sourceTypes {
+		builder.WriteString(fmt.Sprintf("# Rules for %s\n", sourceType))
+
+		targets := ruleGroups[sourceType]
+		targetKeys := make([]string, 0, len(targets))
+		for key := range targets {
+			targetKeys = append(targetKeys, key)
+		}
+		sort.Strings(targetKeys)
+
+		for _, targetKey := range targetKeys {
+			perms := targets[targetKey]
+			parts := strings.Split(targetKey, ":")
+			targetType := parts[0]
+			class := parts[1]
+
+			// Sort permissions
+			sort.Strings(perms)
+
+			// Write allow rule
+			f.writeRationale(builder, "", rationales[sourceType+" "+targetKey])
+			if len(perms) == 1 {
+				builder.WriteString(fmt.Sprintf("allow %s %s:%s %s;\n",
+					sourceType, targetType, class, perms[0]))
+			} else {
+				builder.WriteString(fmt.Sprintf("allow %s %s:%s { %s };\n",
+					sourceType, targetType, class, strings.Join(perms, " ")))
+			}
+		}
+
+		builder.WriteString("\n")
+	}
+
+	return nil
+}
+
+// writeAuditRules writes auditallow rules for accesses that should be logged when allowed
+func (f *ruleFormatter) writeAuditRules(builder *strings.Builder) {
+	if len(f.policy.AuditRules) > 0 {
+		f.writeRuleSection(builder, "Audit Rules", "auditallow", f.policy.AuditRules)
+	}
+}
+
+// writeXpermRules writes allowxperm rules restricting ioctl commands
+func (f *ruleFormatter) writeXpermRules(builder *strings.Builder) {
+	if len(f.policy.XpermRules) == 0 {
+		return
+	}
+
+	builder.WriteString("########################################\n")
+	builder.WriteString("# Extended Permission Rules\n")
+	builder.WriteString("########################################\n\n")
+
+	rules := make([]models.XpermRule, len(f.policy.XpermRules))
+	copy(rules, f.policy.XpermRules)
+	sort.SliceStable(rules, func(i, j int) bool {
+		if rules[i].SourceType != rules[j].SourceType {
+			return rules[i].SourceType < rules[j].SourceType
+		}
+		if rules[i].TargetType != rules[j].TargetType {
+			return rules[i].TargetType < rules[j].TargetType
+		}
+		return rules[i].Class < rules[j].Class
+	})
+	for _, rule := range rules {
+		builder.WriteString(fmt.Sprintf("allowxperm %s %s:%s %s { %s };\n",
+			rule.SourceType, rule.TargetType, rule.Class, rule.Operation, strings.Join(rule.Ranges, " ")))
+	}
+	builder.WriteString("\n")
+}
+
+// writeDenyRules writes the neverallow and dontaudit rules generated from deny and dontaudit policies
+func (f *ruleFormatter) writeDenyRules(builder *strings.Builder) error {
+	if len(f.policy.NeverallowRules) > 0 {
+		for _, rule := range f.policy.NeverallowRules {
+			if len(rule.Permissions) == 0 {
+				return fmt.Errorf("neverallow %s %s:%s has no permissions", rule.SourceType, rule.TargetType, rule.Class)
+			}
+		}
+		f.writeRuleSection(builder, "Neverallow Rules", "neverallow", neverallowAsAllowRules(f.policy.NeverallowRules))
+	}
+
+	if len(f.policy.DontauditRules) > 0 {
+		f.writeRuleSection(builder, "Dontaudit Rules", "dontaudit", f.policy.DontauditRules)
+	}
+	return nil
+}
+
+// writeRuleSection writes rules as statements of the given kind, grouped and
+// sorted like allow rules, under a section header
+func (f *ruleFormatter) writeRuleSection(builder *strings.Builder, title, statement string, rules []models.AllowRule) {
+	builder.WriteString("########################################\n")
+	builder.WriteString(fmt.Sprintf("# %s\n", title))
+	builder.WriteString("########################################\n\n")
+
+	ruleGroups := f.groupRules(rules)
+	sourceTypes := make([]string, 0, len(ruleGroups))
+	for sourceType := range ruleGroups {
+		sourceTypes = append(sourceTypes, sourceType)
+	}
+	sort.Strings(sourceTypes)
+
+	for _, sourceType := range sourceTypes {
+		targets := ruleGroups[sourceType]
+		targetKeys := make([]string, 0, len(targets))
+		for key := range targets {
+			targetKeys = append(targetKeys, key)
+		}
+		sort.Strings(targetKeys)
+
+		for _, targetKey := range targetKeys {
+			perms := targets[targetKey]
+			sort.Strings(perms)
+			targetType, class, _ := strings.Cut(targetKey, ":")
+			if len(perms) == 1 {
+				builder.WriteString(fmt.Sprintf("%s %s %s:%s %s;\n", statement, sourceType, targetType, class, perms[0]))
+			} else {
+				builder.WriteString(fmt.Sprintf("%s %s %s:%s { %s };\n", statement, sourceType, targetType, class, strings.Join(perms, " ")))
+			}
+		}
+	}
+	builder.WriteString("\n")
+}
+
+// writeConstraints writes constrain, mlsconstrain and validatetrans statements if any
+func (f *ruleFormatter) writeConstraints(builder *strings.Builder) error {
+	if len(f.policy.Constraints) == 0 && len(f.policy.ValidateTrans) == 0 {
+		return nil
+	}
+
+	builder.WriteString("########################################\n")
+	builder.WriteString("# Constraints\n")
+	builder.WriteString("########################################\n\n")
+
+	for _, c := range f.policy.Constraints {
+		if len(c.Classes) == 0 || len(c.Permissions) == 0 || c.Expression == "" {
+			return fmt.Errorf("constraint requires classes, permissions and an expression")
+		}
+		if c.Comment != "" {
+			builder.WriteString(fmt.Sprintf("# %s\n", c.Comment))
+		}
+		statement := "constrain"
+		if c.MLS {
+			statement = "mlsconstrain"
+		}
+		builder.WriteString(fmt.Sprintf("%s %s %s ( %s );\n",
+			statement, nameList(c.Classes), nameList(c.Permissions), c.Expression))
+	}
+
+	for _, vt := range f.policy.ValidateTrans {
+		if len(vt.Classes) == 0 || vt.Expression == "" {
+			return fmt.Errorf("validatetrans requires classes and an expression")
+		}
+		statement := "validatetrans"
+		if vt.MLS {
+			statement = "mlsvalidatetrans"
+		}
+		builder.WriteString(fmt.Sprintf("%s %s ( %s );\n", statement, nameList(vt.Classes), vt.Expression))
+	}
+
+	builder.WriteString("\n")
+	return nil
+}
+
+// writeObjectDefaults writes default_type and default_range statements, in policy order
+func (f *ruleFormatter) writeObjectDefaults(builder *strings.Builder) {
+	if len(f.policy.DefaultTypes) == 0 && len(f.policy.DefaultRanges) == 0 {
+		return
+	}
+
+	builder.WriteString("########################################\n")
+	builder.WriteString("# Object Defaults\n")
+	builder.WriteString("########################################\n\n")
+
+	for _, dt := range f.policy.DefaultTypes {
+		builder.WriteString(fmt.Sprintf("default_type %s %s;\n", nameList(dt.Classes), dt.Default))
+	}
+	for _, dr := range f.policy.DefaultRanges {
+		builder.WriteString(fmt.Sprintf("default_range %s %s %s;\n", nameList(dr.Classes), dr.Default, dr.Range))
+	}
+	builder.WriteString("\n")
+}
+
+// groupRules groups allow rules by source, target, and class to merge permissions
+func (f *ruleFormatter) groupRules(rules []models.AllowRule) map[string]map[string][]string {
+	// Map: sourceType -> "targetType:class" -> []permissions
+	groups := make(map[string]map[string][]string)
+
+	for _, rule := range rules {
+		if _, ok := groups[rule.SourceType]; !ok {
+			groups[rule.SourceType] = make(map[string][]string)
+		}
+
+		key := rule.TargetType + ":" + rule.ClassSpec()
+		groups[rule.SourceType][key] = append(groups[rule.SourceType][key], rule.Permissions...)
+	}
+
+	// Deduplicate permissions
+	for sourceType := range groups {
+		for targetKey := range groups[sourceType] {
+			groups[sourceType][targetKey] = uniqueStrings(groups[sourceType][targetKey])
+		}
+	}
+
+	return groups
+}
+
+// groupRationales collects the rationales of allow rules under the same
+// "source target:class" keys groupRules merges them by, when annotating
+func (f *ruleFormatter) groupRationales(rules []models.AllowRule) map[string][]string {
+	if !f.annotate {
+		return nil
+	}
+	rationales := make(map[string][]string)
+	for _, rule := range rules {
+		key := rule.SourceType + " " + rule.TargetType + ":" + rule.ClassSpec()
+		rationales[key] = uniqueStrings(append(rationales[key], rule.Rationale...))
+	}
+	return rationales
+}
+
+// writeRationale writes one comment line per rationale, indented like the rule
+func (f *ruleFormatter) writeRationale(builder *strings.Builder, indent string, rationales []string) {
+	for _, rationale := range rationales {
+		builder.WriteString(fmt.Sprintf("%s# %s\n", indent, rationale))
+	}
+}
+
+// writeBlockRules writes allow rules indented inside a conditional or optional
+// block, grouped and sorted like top-level allow rules
+func (f *ruleFormatter) writeBlockRules(builder *strings.Builder, rules []models.AllowRule) {
+	ruleGroups := f.groupRules(rules)
+	rationales := f.groupRationales(rules)
+	sourceTypes := make([]string, 0, len(ruleGroups))
+	for sourceType := range ruleGroups {
+		sourceTypes = append(sourceTypes, sourceType)
+	}
+	sort.Strings(sourceTypes)
+
+	for _, sourceType := range sourceTypes {
+		targets := ruleGroups[sourceType]
+		targetKeys := make([]string, 0, len(targets))
+		for key := range targets {
+			targetKeys = append(targetKeys, key)
+		}
+		sort.Strings(targetKeys)
+
+		for _, targetKey := range targetKeys {
+			perms := uniqueStrings(targets[targetKey])
+			sort.Strings(perms)
+			targetType, class, _ := strings.Cut(targetKey, ":")
+			f.writeRationale(builder, "\t", rationales[sourceType+" "+targetKey])
+			builder.WriteString(fmt.Sprintf("\tallow %s %s:%s { %s };\n",
+				sourceType, targetType, class, strings.Join(perms, " ")))
+		}
+	}
+}
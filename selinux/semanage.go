@@ -5,6 +5,7 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/cici0602/pml-to-selinux/mapping"
 	"github.com/cici0602/pml-to-selinux/models"
 )
 
@@ -42,6 +43,12 @@ func (g *SemanageGenerator) GenerateCommands() *SemanageCommands {
 	// Generate file context commands
 	commands.FileContextCommands = g.generateFileContextCommands()
 
+	// Generate port commands for the ports a module cannot label with portcon
+	for _, binding := range g.policy.SemanagePorts {
+		commands.PortCommands = append(commands.PortCommands,
+			fmt.Sprintf("semanage port -a -t %s -p %s %s", binding.PortType, binding.Protocol, binding.Ports()))
+	}
+
 	// Generate module installation commands
 	commands.ModuleCommands = g.generateModuleCommands()
 
@@ -196,6 +203,19 @@ func (g *SemanageGenerator) GenerateDeploymentScript() string {
 		builder.WriteString("\n")
 	}
 
+	// Port configuration
+	if len(commands.PortCommands) > 0 {
+		builder.WriteString("########################################\n")
+		builder.WriteString("# Port Configuration\n")
+		builder.WriteString("########################################\n\n")
+
+		for _, cmd := range commands.PortCommands {
+			builder.WriteString(cmd)
+			builder.WriteString("\n")
+		}
+		builder.WriteString("\n")
+	}
+
 	builder.WriteString("echo \"Deployment completed successfully!\"\n")
 
 	return builder.String()
@@ -235,11 +255,100 @@ func (g *SemanageGenerator) GenerateUninstallScript() string {
 		builder.WriteString("\n")
 	}
 
+	// Remove port labels
+	if len(g.policy.SemanagePorts) > 0 {
+		builder.WriteString("# Remove port labels\n")
+		for _, binding := range g.policy.SemanagePorts {
+			builder.WriteString(fmt.Sprintf("semanage port -d -t %s -p %s %s\n", binding.PortType, binding.Protocol, binding.Ports()))
+		}
+		builder.WriteString("\n")
+	}
+
 	builder.WriteString("echo \"Uninstallation completed successfully!\"\n")
 
 	return builder.String()
 }
 
+// GenerateRelabelScript generates a script that relabels only the directories
+// covered by the policy's file contexts instead of the whole filesystem
+func (g *SemanageGenerator) GenerateRelabelScript() string {
+	var builder strings.Builder
+
+	builder.WriteString("#!/bin/bash\n")
+	builder.WriteString("########################################\n")
+	builder.WriteString(fmt.Sprintf("# SELinux Relabel Script for %s\n", g.policy.ModuleName))
+	builder.WriteString("# Generated by PML-to-SELinux Compiler\n")
+	builder.WriteString("########################################\n\n")
+
+	builder.WriteString("set -e  # Exit on error\n\n")
+
+	builder.WriteString(fmt.Sprintf("echo \"Relabeling files for SELinux policy: %s\"\n\n", g.policy.ModuleName))
+
+	for _, root := range relabelRoots(g.policy.FileContexts) {
+		builder.WriteString(fmt.Sprintf("restorecon -R -v '%s'\n", root))
+	}
+	builder.WriteString("\n")
+
+	builder.WriteString("echo \"Relabeling completed successfully!\"\n")
+
+	return builder.String()
+}
+
+// relabelRoots returns the base paths of the file contexts, keeping only the
+// shallowest roots so no directory is relabeled twice
+func relabelRoots(contexts []models.FileContext) []string {
+	bases := make([]string, 0, len(contexts))
+	for _, fc := range contexts {
+		bases = append(bases, literalBasePath(fc.PathPattern))
+	}
+	sort.Strings(bases)
+
+	roots := make([]string, 0, len(bases))
+	for _, base := range bases {
+		covered := false
+		for _, root := range roots {
+			if root == "/" || base == root || strings.HasPrefix(base, root+"/") {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			roots = append(roots, base)
+		}
+	}
+	return roots
+}
+
+// literalBasePath returns the directory a SELinux path regex is rooted at
+// Examples:
+//
+//	/var/www(/.*)?     →  /var/www
+//	/etc/[^/]+\.conf   →  /etc
+//	/opt/my\-app/bin   →  /opt/my-app/bin
+func literalBasePath(pattern string) string {
+	pattern = strings.TrimSuffix(pattern, "(/.*)?")
+
+	var literal strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		if c == '\\' && i+1 < len(pattern) {
+			i++
+			literal.WriteByte(pattern[i])
+			continue
+		}
+		if strings.IndexByte("([{*?+|^$.", c) != -1 {
+			// Let ExtractBasePath cut back to the directory holding the wildcard
+			return mapping.ExtractBasePath(literal.String() + "*")
+		}
+		literal.WriteByte(c)
+	}
+
+	if literal.Len() == 0 {
+		return "/"
+	}
+	return mapping.ExtractBasePath(literal.String())
+}
+
 // GenerateSemanageCommands is a convenience function to generate semanage commands
 func GenerateSemanageCommands(policy *models.SELinuxPolicy) *SemanageCommands {
 	generator := NewSemanageGenerator(policy)
@@ -0,0 +1,67 @@
+package selinux
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cici0602/pml-to-selinux/models"
+)
+
+func TestSemanageGenerator_RelabelScript(t *testing.T) {
+	policy := &models.SELinuxPolicy{
+		ModuleName: "myapp",
+		FileContexts: []models.FileContext{
+			{PathPattern: "/var/www(/.*)?", SELinuxType: "myapp_var_www_t"},
+			{PathPattern: "/var/www/html/cache(/.*)?", SELinuxType: "myapp_cache_t"},
+			{PathPattern: "/etc/myapp/[^/]+\\.conf", SELinuxType: "myapp_etc_t"},
+			{PathPattern: "/opt/my\\-app/bin", FileType: "--", SELinuxType: "myapp_exec_t"},
+			{PathPattern: "/var/wwwdata(/.*)?", SELinuxType: "myapp_data_t"},
+		},
+	}
+
+	script := NewSemanageGenerator(policy).GenerateRelabelScript()
+
+	expected := []string{
+		"restorecon -R -v '/etc/myapp'\n",
+		"restorecon -R -v '/opt/my-app/bin'\n",
+		"restorecon -R -v '/var/www'\n",
+		"restorecon -R -v '/var/wwwdata'\n",
+	}
+	for _, want := range expected {
+		if !strings.Contains(script, want) {
+			t.Errorf("missing %q in relabel script:\n%s", want, script)
+		}
+	}
+	if strings.Contains(script, "/var/www/html/cache") {
+		t.Error("nested path should be covered by its shallowest root")
+	}
+	if strings.Contains(script, "restorecon -Rv /") {
+		t.Error("relabel script should not relabel the whole filesystem")
+	}
+}
+
+func TestSemanageGenerator_PortCommands(t *testing.T) {
+	policy := &models.SELinuxPolicy{
+		ModuleName: "myapp",
+		SemanagePorts: []models.PortBinding{
+			{Port: 8080, Protocol: "tcp", PortType: "myapp_port_t"},
+			{Port: 9000, PortEnd: 9010, Protocol: "udp", PortType: "myapp_port_t"},
+		},
+	}
+	generator := NewSemanageGenerator(policy)
+
+	deploy := generator.GenerateDeploymentScript()
+	for _, want := range []string{
+		"semanage port -a -t myapp_port_t -p tcp 8080\n",
+		"semanage port -a -t myapp_port_t -p udp 9000-9010\n",
+	} {
+		if !strings.Contains(deploy, want) {
+			t.Errorf("missing %q in deployment script:\n%s", want, deploy)
+		}
+	}
+
+	uninstall := generator.GenerateUninstallScript()
+	if !strings.Contains(uninstall, "semanage port -d -t myapp_port_t -p udp 9000-9010\n") {
+		t.Errorf("missing port removal in uninstall script:\n%s", uninstall)
+	}
+}
@@ -10,49 +10,141 @@ import (
 
 // TEGenerator handles generation of SELinux Type Enforcement (.te) files
 type TEGenerator struct {
-	policy *models.SELinuxPolicy
+	ruleFormatter
+	basePolicy   bool
+	booleanStyle string
 }
 
+// Boolean styles: runtime booleans (gen_bool, if blocks) or refpolicy tunables
+// (gen_tunable, tunable_policy blocks)
+const (
+	BooleanStyleBool    = "bool"
+	BooleanStyleTunable = "tunable"
+)
+
 // NewTEGenerator creates a new TEGenerator instance
 func NewTEGenerator(policy *models.SELinuxPolicy) *TEGenerator {
 	return &TEGenerator{
-		policy: policy,
+		ruleFormatter: ruleFormatter{policy: policy},
+		booleanStyle:  BooleanStyleBool,
 	}
 }
 
-// Generate generates the complete .te file content
+// SetBasePolicy renders a base policy: no policy_module declaration, initial
+// SIDs are declared and given their contexts, and constraints and object
+// defaults are written. Without it a policy holding those fails to generate.
+func (g *TEGenerator) SetBasePolicy(enabled bool) {
+	g.basePolicy = enabled
+}
+
+// SetBooleanStyle selects how booleans and conditional rules are written:
+// BooleanStyleBool (the default) or BooleanStyleTunable
+func (g *TEGenerator) SetBooleanStyle(style string) {
+	g.booleanStyle = style
+}
+
+// SetAnnotate precedes each allow rule with a comment per PML line it was
+// generated from, saying what the line grants
+func (g *TEGenerator) SetAnnotate(enabled bool) {
+	g.annotate = enabled
+}
+
+// Generate generates the complete .te file content. Sections follow the refpolicy
+// module layout so forward references read top-down: requirements, declarations,
+// attribute and role assignments, type transitions, rules, then the policy tail.
+// Each section is sorted; only object defaults, genfs and initial SIDs keep policy order.
 func (g *TEGenerator) Generate() (string, error) {
 	var builder strings.Builder
 
 	// Write header
 	g.writeHeader(&builder)
 
-	// Write policy module declaration
-	g.writePolicyModule(&builder)
+	// Write policy module declaration; a base policy has none
+	if !g.basePolicy {
+		g.writePolicyModule(&builder)
+	}
+
+	// Write require block for types declared by a base module
+	g.writeRequireBlock(&builder)
+
+	// Write boolean declarations
+	g.writeBooleans(&builder)
 
 	// Write type declarations
 	if err := g.writeTypeDeclarations(&builder); err != nil {
 		return "", err
 	}
 
+	// Write userdom calls labeling home directory content
+	g.writeUserHomeContent(&builder)
+
+	// Write role declarations and role allows
+	g.writeRoles(&builder)
+
+	// Write attribute expansion control
+	g.writeAttributeExpansions(&builder)
+
+	// Write type transitions if any
+	if err := g.writeTypeTransitions(&builder); err != nil {
+		return "", err
+	}
+
+	// Write interface calls ahead of the raw allow rules
+	g.writeInterfaceCalls(&builder)
+
 	// Write allow rules
 	if err := g.writeAllowRules(&builder); err != nil {
 		return "", err
 	}
 
+	// Write rules guarded by booleans
+	g.writeConditionalRules(&builder)
+
+	// Write rules depending on optional modules
+	g.writeOptionalRules(&builder)
+
+	// Write auditallow rules
+	g.writeAuditRules(&builder)
+
+	// Write allowxperm rules
+	g.writeXpermRules(&builder)
+
 	// Write deny rules (neverallow)
 	if err := g.writeDenyRules(&builder); err != nil {
 		return "", err
 	}
 
-	// Write type transitions if any
-	if err := g.writeTypeTransitions(&builder); err != nil {
-		return "", err
+	// Write constraints and object context defaults (base policy only)
+	if g.basePolicy {
+		if err := g.writeConstraints(&builder); err != nil {
+			return "", err
+		}
+		g.writeObjectDefaults(&builder)
+	} else if statement := g.policy.BaseOnlyStatement(); statement != "" {
+		return "", fmt.Errorf("%s statements are only valid in a base policy, a module cannot declare them", statement)
+	}
+
+	// Write pseudo-filesystem, port, network interface and node labels and
+	// initial SID contexts (base policy only)
+	if g.basePolicy {
+		g.writeGenfsContexts(&builder)
+		g.writePortContexts(&builder)
+		g.writeNetworkContexts(&builder)
+		g.writeInitialSIDs(&builder)
 	}
 
 	return builder.String(), nil
 }
 
+// GenerateTypes generates only the attribute and type declarations of the policy
+func (g *TEGenerator) GenerateTypes() (string, error) {
+	var builder strings.Builder
+	if err := g.writeTypeDeclarations(&builder); err != nil {
+		return "", err
+	}
+	return builder.String(), nil
+}
+
 // writeHeader writes the file header with comments
 func (g *TEGenerator) writeHeader(builder *strings.Builder) {
 	builder.WriteString("########################################\n")
@@ -69,193 +161,252 @@ func (g *TEGenerator) writePolicyModule(builder *strings.Builder) {
 		g.policy.Version))
 }
 
-// writeTypeDeclarations writes all type declarations
-func (g *TEGenerator) writeTypeDeclarations(builder *strings.Builder) error {
-	if len(g.policy.Types) == 0 {
-		return nil
+// writeUserHomeContent makes home directory content types user home content;
+// the userdom interface labels them under each user's home directory
+func (g *TEGenerator) writeUserHomeContent(builder *strings.Builder) {
+	if len(g.policy.UserHomeContent) == 0 {
+		return
 	}
 
 	builder.WriteString("########################################\n")
-	builder.WriteString("# Type Declarations\n")
+	builder.WriteString("# Home Directory Content\n")
 	builder.WriteString("########################################\n\n")
 
-	// Sort types for consistent output
-	types := make([]models.TypeDeclaration, len(g.policy.Types))
-	copy(types, g.policy.Types)
-	sort.Slice(types, func(i, j int) bool {
-		return types[i].TypeName < types[j].TypeName
-	})
+	types := append([]string(nil), g.policy.UserHomeContent...)
+	sort.Strings(types)
+	for _, typeName := range types {
+		builder.WriteString(fmt.Sprintf("userdom_user_home_content(%s)\n", typeName))
+	}
+	builder.WriteString("\n")
+}
 
-	for _, typeDecl := range types {
-		if len(typeDecl.Attributes) > 0 {
-			// Type with attributes: type typename, attr1, attr2;
-			builder.WriteString(fmt.Sprintf("type %s, %s;\n",
-				typeDecl.TypeName,
-				strings.Join(typeDecl.Attributes, ", ")))
-		} else {
-			// Simple type declaration: type typename;
-			builder.WriteString(fmt.Sprintf("type %s;\n", typeDecl.TypeName))
-		}
+// writeRequireBlock writes the require block when types come from a shared base
+// module or are passed to interfaces without being declared
+func (g *TEGenerator) writeRequireBlock(builder *strings.Builder) {
+	macros := NewMacroGenerator(g.policy)
+	if len(g.policy.BaseTypes) == 0 && !macros.HasExternalInterfaceArgs() {
+		return
 	}
 
+	builder.WriteString("########################################\n")
+	builder.WriteString("# Requirements\n")
+	builder.WriteString("########################################\n\n")
+	builder.WriteString(macros.GenerateRequireBlock())
 	builder.WriteString("\n")
-	return nil
 }
 
-// writeAllowRules writes all allow rules, grouped by source type
-func (g *TEGenerator) writeAllowRules(builder *strings.Builder) error {
-	if len(g.policy.Rules) == 0 {
-		return nil
+// writeInterfaceCalls writes the refpolicy interface calls, in policy order
+func (g *TEGenerator) writeInterfaceCalls(builder *strings.Builder) {
+	if len(g.policy.InterfaceCalls) == 0 {
+		return
 	}
 
 	builder.WriteString("########################################\n")
-	builder.WriteString("# Allow Rules\n")
+	builder.WriteString("# Interface Calls\n")
 	builder.WriteString("########################################\n\n")
 
-	// Group rules by source type, target type, and class
-	ruleGroups := g.groupRules(g.policy.Rules)
+	for _, call := range g.policy.InterfaceCalls {
+		builder.WriteString(fmt.Sprintf("%s(%s)\n", call.Name, strings.Join(call.Args, ", ")))
+	}
+	builder.WriteString("\n")
+}
 
-	// Sort source types for consistent output
-	sourceTypes := make([]string, 0, len(ruleGroups))
-	for sourceType := range ruleGroups {
-		sourceTypes = append(sourceTypes, sourceType)
+// writeBooleans writes a gen_bool or gen_tunable declaration per boolean
+func (g *TEGenerator) writeBooleans(builder *strings.Builder) {
+	if len(g.policy.Booleans) == 0 {
+		return
 	}
-	sort.Strings(sourceTypes)
 
-	// Write rules for each source type
-	for _, sourceType := range sourceTypes {
-		builder.WriteString(fmt.Sprintf("# Rules for %s\n", sourceType))
+	builder.WriteString("########################################\n")
+	builder.WriteString("# Booleans\n")
+	builder.WriteString("########################################\n\n")
 
-		targets := ruleGroups[sourceType]
-		targetKeys := make([]string, 0, len(targets))
-		for key := range targets {
-			targetKeys = append(targetKeys, key)
-		}
-		sort.Strings(targetKeys)
-
-		for _, targetKey := range targetKeys {
-			perms := targets[targetKey]
-			parts := strings.Split(targetKey, ":")
-			targetType := parts[0]
-			class := parts[1]
-
-			// Sort permissions
-			sort.Strings(perms)
-
-			// Write allow rule
-			if len(perms) == 1 {
-				builder.WriteString(fmt.Sprintf("allow %s %s:%s %s;\n",
-					sourceType, targetType, class, perms[0]))
-			} else {
-				builder.WriteString(fmt.Sprintf("allow %s %s:%s { %s };\n",
-					sourceType, targetType, class, strings.Join(perms, " ")))
-			}
-		}
+	macro := "gen_bool"
+	if g.booleanStyle == BooleanStyleTunable {
+		macro = "gen_tunable"
+	}
+	booleans := make([]models.Boolean, len(g.policy.Booleans))
+	copy(booleans, g.policy.Booleans)
+	sort.Slice(booleans, func(i, j int) bool {
+		return booleans[i].Name < booleans[j].Name
+	})
+	for _, b := range booleans {
+		builder.WriteString(fmt.Sprintf("%s(%s, %t)\n", macro, b.Name, b.Default))
+	}
+	builder.WriteString("\n")
+}
 
-		builder.WriteString("\n")
+// writeConditionalRules writes the allow rules of each condition, sorted by
+// condition, inside an if block or a tunable_policy block
+func (g *TEGenerator) writeConditionalRules(builder *strings.Builder) {
+	if len(g.policy.CondRules) == 0 {
+		return
 	}
 
-	return nil
-}
+	builder.WriteString("########################################\n")
+	builder.WriteString("# Conditional Rules\n")
+	builder.WriteString("########################################\n\n")
+
+	var conditions []string
+	byCondition := make(map[string][]models.AllowRule)
+	for _, rule := range g.policy.CondRules {
+		if _, ok := byCondition[rule.Condition]; !ok {
+			conditions = append(conditions, rule.Condition)
+		}
+		byCondition[rule.Condition] = append(byCondition[rule.Condition], rule)
+	}
+	sort.Strings(conditions)
+
+	for _, condition := range conditions {
+		if g.booleanStyle == BooleanStyleTunable {
+			builder.WriteString(fmt.Sprintf("tunable_policy(`%s',`\n", condition))
+		} else {
+			builder.WriteString(fmt.Sprintf("if (%s) {\n", condition))
+		}
 
-// groupRules groups allow rules by source, target, and class to merge permissions
-func (g *TEGenerator) groupRules(rules []models.AllowRule) map[string]map[string][]string {
-	// Map: sourceType -> "targetType:class" -> []permissions
-	groups := make(map[string]map[string][]string)
+		g.writeBlockRules(builder, byCondition[condition])
 
-	for _, rule := range rules {
-		if _, ok := groups[rule.SourceType]; !ok {
-			groups[rule.SourceType] = make(map[string][]string)
+		if g.booleanStyle == BooleanStyleTunable {
+			builder.WriteString("')\n\n")
+		} else {
+			builder.WriteString("}\n\n")
 		}
+	}
+}
 
-		key := rule.TargetType + ":" + rule.Class
-		groups[rule.SourceType][key] = append(groups[rule.SourceType][key], rule.Permissions...)
+// writeOptionalRules writes the allow rules of each optional dependency, sorted
+// by module, inside an optional_policy block that requires the types the module
+// does not declare itself, so the block is skipped when the dependency is missing
+func (g *TEGenerator) writeOptionalRules(builder *strings.Builder) {
+	if len(g.policy.OptionalRules) == 0 {
+		return
 	}
 
-	// Deduplicate permissions
-	for sourceType := range groups {
-		for targetKey := range groups[sourceType] {
-			groups[sourceType][targetKey] = uniqueStrings(groups[sourceType][targetKey])
+	builder.WriteString("########################################\n")
+	builder.WriteString("# Optional Policy\n")
+	builder.WriteString("########################################\n\n")
+
+	var modules []string
+	byModule := make(map[string][]models.AllowRule)
+	for _, rule := range g.policy.OptionalRules {
+		if _, ok := byModule[rule.Optional]; !ok {
+			modules = append(modules, rule.Optional)
 		}
+		byModule[rule.Optional] = append(byModule[rule.Optional], rule)
 	}
+	sort.Strings(modules)
+
+	for _, module := range modules {
+		var required []string
+		for _, rule := range byModule[module] {
+			for _, typeName := range []string{rule.SourceType, rule.TargetType} {
+				if typeName != "self" && !g.policy.HasType(typeName) {
+					required = append(required, typeName)
+				}
+			}
+		}
+		required = uniqueStrings(required)
+		sort.Strings(required)
+
+		builder.WriteString(fmt.Sprintf("# %s\n", module))
+		builder.WriteString("optional_policy(`\n")
+		if len(required) > 0 {
+			builder.WriteString("\tgen_require(`\n")
+			for _, typeName := range required {
+				builder.WriteString(fmt.Sprintf("\t\ttype %s;\n", typeName))
+			}
+			builder.WriteString("\t')\n\n")
+		}
+
+		g.writeBlockRules(builder, byModule[module])
 
-	return groups
+		builder.WriteString("')\n\n")
+	}
 }
 
-// writeDenyRules - Deny rules not supported in simplified version
-func (g *TEGenerator) writeDenyRules(builder *strings.Builder) error {
-	// Deny rules removed in simplified version
-	// For production use, consider using audit_deny or neverallow manually
-	return nil
+// nameList renders a single name as-is and several names as a set: "{ a b }"
+func nameList(names []string) string {
+	if len(names) == 1 {
+		return names[0]
+	}
+	return "{ " + strings.Join(names, " ") + " }"
 }
 
-// writeTypeTransitions writes type transition rules if any
-func (g *TEGenerator) writeTypeTransitions(builder *strings.Builder) error {
-	if len(g.policy.Transitions) == 0 {
-		return nil
+// writeGenfsContexts writes genfscon statements, in policy order
+func (g *TEGenerator) writeGenfsContexts(builder *strings.Builder) {
+	if len(g.policy.GenfsContexts) == 0 {
+		return
 	}
 
 	builder.WriteString("########################################\n")
-	builder.WriteString("# Type Transitions\n")
+	builder.WriteString("# Pseudo-Filesystem Contexts\n")
 	builder.WriteString("########################################\n\n")
 
-	// Sort transitions for consistent output
-	transitions := make([]models.TypeTransition, len(g.policy.Transitions))
-	copy(transitions, g.policy.Transitions)
-	sort.Slice(transitions, func(i, j int) bool {
-		if transitions[i].SourceType != transitions[j].SourceType {
-			return transitions[i].SourceType < transitions[j].SourceType
-		}
-		if transitions[i].TargetType != transitions[j].TargetType {
-			return transitions[i].TargetType < transitions[j].TargetType
-		}
-		return transitions[i].Class < transitions[j].Class
-	})
+	for _, genfs := range g.policy.GenfsContexts {
+		builder.WriteString(fmt.Sprintf("genfscon %s %s gen_context(system_u:object_r:%s:s0)\n",
+			genfs.FSType, genfs.Path, genfs.SELinuxType))
+	}
+	builder.WriteString("\n")
+}
 
-	// Generate domain transitions with supporting rules
-	for _, trans := range transitions {
-		if trans.Class == "process" {
-			// This is a domain transition, generate the complete triplet
-			g.writeDomainTransitionRules(builder, &trans)
-		} else {
-			// Regular type transition
-			builder.WriteString(fmt.Sprintf("type_transition %s %s:%s %s;\n",
-				trans.SourceType, trans.TargetType, trans.Class, trans.NewType))
-		}
+// writePortContexts writes a portcon statement per port binding, in policy order
+func (g *TEGenerator) writePortContexts(builder *strings.Builder) {
+	if len(g.policy.PortBindings) == 0 {
+		return
 	}
 
+	builder.WriteString("########################################\n")
+	builder.WriteString("# Port Contexts\n")
+	builder.WriteString("########################################\n\n")
+
+	for _, binding := range g.policy.PortBindings {
+		builder.WriteString(fmt.Sprintf("portcon %s %s gen_context(system_u:object_r:%s:s0)\n",
+			binding.Protocol, binding.Ports(), binding.PortType))
+	}
 	builder.WriteString("\n")
-	return nil
 }
 
-// writeDomainTransitionRules generates the complete domain transition triplet:
-// 1. type_transition - defines the transition
-// 2. allow source target:file execute - parent can execute child binary
-// 3. allow source target:process transition - parent can transition to child
-// 4. allow target target:file entrypoint - child can use its binary as entrypoint
-func (g *TEGenerator) writeDomainTransitionRules(builder *strings.Builder, trans *models.TypeTransition) {
-	source := trans.SourceType
-	entrypoint := trans.TargetType
-	target := trans.NewType
+// writeNetworkContexts writes the netifcon and nodecon statements, in policy order
+func (g *TEGenerator) writeNetworkContexts(builder *strings.Builder) {
+	if len(g.policy.Netifcons)+len(g.policy.Nodecons) == 0 {
+		return
+	}
 
-	builder.WriteString(fmt.Sprintf("# Domain transition: %s -> %s\n", source, target))
+	builder.WriteString("########################################\n")
+	builder.WriteString("# Network Contexts\n")
+	builder.WriteString("########################################\n\n")
 
-	// 1. Type transition rule
-	builder.WriteString(fmt.Sprintf("type_transition %s %s:process %s;\n",
-		source, entrypoint, target))
+	for _, netif := range g.policy.Netifcons {
+		context := fmt.Sprintf("gen_context(system_u:object_r:%s:s0)", netif.SELinuxType)
+		builder.WriteString(fmt.Sprintf("netifcon %s %s %s\n", netif.Interface, context, context))
+	}
+	for _, node := range g.policy.Nodecons {
+		builder.WriteString(fmt.Sprintf("nodecon %s %s gen_context(system_u:object_r:%s:s0)\n",
+			node.Address, node.Netmask, node.SELinuxType))
+	}
+	builder.WriteString("\n")
+}
 
-	// 2. Allow parent to execute child binary
-	builder.WriteString(fmt.Sprintf("allow %s %s:file execute;\n",
-		source, entrypoint))
+// writeInitialSIDs writes the sid declarations followed by their sid contexts,
+// in policy order since the kernel assigns SIDs by declaration order
+func (g *TEGenerator) writeInitialSIDs(builder *strings.Builder) {
+	if len(g.policy.InitialSIDs) == 0 {
+		return
+	}
 
-	// 3. Allow parent to transition to child domain
-	builder.WriteString(fmt.Sprintf("allow %s %s:process transition;\n",
-		source, target))
+	builder.WriteString("########################################\n")
+	builder.WriteString("# Initial SIDs\n")
+	builder.WriteString("########################################\n\n")
 
-	// 4. Allow child to use binary as entrypoint
-	builder.WriteString(fmt.Sprintf("allow %s %s:file entrypoint;\n",
-		target, entrypoint))
+	for _, sid := range g.policy.InitialSIDs {
+		builder.WriteString(fmt.Sprintf("sid %s\n", sid.Name))
+	}
+	builder.WriteString("\n")
 
+	for _, sid := range g.policy.InitialSIDs {
+		builder.WriteString(fmt.Sprintf("sid %s gen_context(system_u:%s:%s:s0)\n",
+			sid.Name, sid.Role(), sid.SELinuxType))
+	}
 	builder.WriteString("\n")
 }
 
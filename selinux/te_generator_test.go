@@ -68,3 +68,700 @@ func TestTEGenerator_EmptyPolicy(t *testing.T) {
 		t.Error("Missing policy_module declaration")
 	}
 }
+
+func TestTEGenerator_ClassSet(t *testing.T) {
+	policy := &models.SELinuxPolicy{
+		ModuleName: "app",
+		Version:    "1.0.0",
+		Rules: []models.AllowRule{
+			{
+				SourceType:  "app_t",
+				TargetType:  "app_data_t",
+				Class:       "file",
+				Classes:     []string{"file", "lnk_file"},
+				Permissions: []string{"read", "getattr"},
+			},
+		},
+	}
+
+	result, err := NewTEGenerator(policy).Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	if !strings.Contains(result, "allow app_t app_data_t:{ file lnk_file } { getattr read };") {
+		t.Errorf("Missing class-set rule, got:\n%s", result)
+	}
+}
+
+func TestTEGenerator_AuditAllow(t *testing.T) {
+	rule := models.AllowRule{
+		SourceType:  "app_t",
+		TargetType:  "shadow_t",
+		Class:       "file",
+		Permissions: []string{"read", "open"},
+	}
+	policy := &models.SELinuxPolicy{
+		ModuleName: "app",
+		Version:    "1.0.0",
+		Rules:      []models.AllowRule{rule},
+		AuditRules: []models.AllowRule{rule},
+	}
+
+	result, err := NewTEGenerator(policy).Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	if !strings.Contains(result, "allow app_t shadow_t:file { open read };") {
+		t.Error("Missing allow rule")
+	}
+	if !strings.Contains(result, "auditallow app_t shadow_t:file { open read };") {
+		t.Errorf("Missing auditallow rule, got:\n%s", result)
+	}
+}
+
+func TestTEGenerator_Constraints(t *testing.T) {
+	policy := &models.SELinuxPolicy{
+		ModuleName: "app",
+		Version:    "1.0.0",
+		Constraints: []models.Constraint{
+			{
+				Classes:     []string{"process"},
+				Permissions: []string{"transition", "dyntransition"},
+				Expression:  "u2 != staff_u or r2 == staff_r",
+			},
+		},
+	}
+
+	if _, err := NewTEGenerator(policy).Generate(); err == nil || !strings.Contains(err.Error(), "only valid in a base policy") {
+		t.Errorf("a module should reject base-only statements, got %v", err)
+	}
+
+	generator := NewTEGenerator(policy)
+	generator.SetBasePolicy(true)
+	result, err := generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if !strings.Contains(result, "constrain process { transition dyntransition } ( u2 != staff_u or r2 == staff_r );") {
+		t.Errorf("Missing constrain statement, got:\n%s", result)
+	}
+
+	policy.Constraints = append(policy.Constraints, models.Constraint{
+		Classes:     []string{"file"},
+		Permissions: []string{"read"},
+		Expression:  "l1 dom l2",
+		MLS:         true,
+	})
+	result, err = generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if !strings.Contains(result, "mlsconstrain file read ( l1 dom l2 );") {
+		t.Errorf("Missing mlsconstrain statement, got:\n%s", result)
+	}
+
+	policy.Constraints[0].Expression = ""
+	if _, err := generator.Generate(); err == nil {
+		t.Error("expected error for constraint without expression")
+	}
+}
+
+func TestTEGenerator_RequireBaseTypes(t *testing.T) {
+	policy := &models.SELinuxPolicy{
+		ModuleName: "billing",
+		Version:    "1.0.0",
+		Types:      []models.TypeDeclaration{{TypeName: "billing_t"}},
+		Rules: []models.AllowRule{
+			{SourceType: "billing_t", TargetType: "acme_shared_t", Class: "file", Permissions: []string{"read"}},
+		},
+		BaseTypes: []string{"acme_shared_t"},
+	}
+
+	result, err := NewTEGenerator(policy).Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if !strings.Contains(result, "require {\n\ttype acme_shared_t;") {
+		t.Errorf("Missing require block for base type, got:\n%s", result)
+	}
+	if strings.Contains(result, "type acme_shared_t;\n\n") {
+		t.Error("base type should not be declared")
+	}
+}
+
+func TestTEGenerator_Roles(t *testing.T) {
+	policy := &models.SELinuxPolicy{
+		ModuleName: "app",
+		Version:    "1.0.0",
+		Roles:      []string{"user_r", "webadmin_r"},
+		RoleAllows: []models.RoleAllow{{FromRole: "user_r", ToRole: "webadmin_r"}},
+		RoleTypes: []models.RoleType{
+			{Role: "user_r", Types: []string{"user_t"}},
+			{Role: "webadmin_r", Types: []string{"webadm_t", "webadm_cgi_t"}},
+		},
+	}
+
+	result, err := NewTEGenerator(policy).Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	for _, want := range []string{"role user_r;\n", "role webadmin_r;\n", "allow user_r webadmin_r;\n",
+		"role user_r types user_t;\n", "role webadmin_r types { webadm_t webadm_cgi_t };\n"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Missing %q, got:\n%s", want, result)
+		}
+	}
+}
+
+func TestTEGenerator_AttributeExpansion(t *testing.T) {
+	policy := &models.SELinuxPolicy{
+		ModuleName: "app",
+		Version:    "1.0.0",
+		Types:      []models.TypeDeclaration{{TypeName: "app_t", Attributes: []string{"web_domain"}}},
+		Expansions: []models.AttributeExpansion{{Attribute: "web_domain", Expand: true}},
+	}
+
+	result, err := NewTEGenerator(policy).Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if !strings.Contains(result, "expandattribute web_domain true;\n") {
+		t.Errorf("Missing expandattribute statement, got:\n%s", result)
+	}
+}
+
+func TestTEGenerator_GenfsContexts(t *testing.T) {
+	policy := &models.SELinuxPolicy{
+		ModuleName: "app",
+		Version:    "1.0.0",
+		GenfsContexts: []models.GenfsContext{
+			{FSType: "proc", Path: "/sys/kernel/modprobe", SELinuxType: "proc_security_t"},
+		},
+	}
+
+	if _, err := NewTEGenerator(policy).Generate(); err == nil || !strings.Contains(err.Error(), "genfscon") {
+		t.Errorf("a module should reject genfscon, got %v", err)
+	}
+
+	generator := NewTEGenerator(policy)
+	generator.SetBasePolicy(true)
+	result, err := generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if !strings.Contains(result, "genfscon proc /sys/kernel/modprobe gen_context(system_u:object_r:proc_security_t:s0)\n") {
+		t.Errorf("Missing genfscon statement, got:\n%s", result)
+	}
+}
+
+func TestTEGenerator_InitialSIDs(t *testing.T) {
+	policy := &models.SELinuxPolicy{
+		ModuleName: "base",
+		Version:    "1.0.0",
+		InitialSIDs: []models.InitialSID{
+			{Name: "kernel", SELinuxType: "kernel_t"},
+			{Name: "unlabeled", SELinuxType: "unlabeled_t"},
+		},
+	}
+
+	result, err := NewTEGenerator(policy).Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if strings.Contains(result, "sid kernel") {
+		t.Errorf("Initial SIDs rendered outside a base policy, got:\n%s", result)
+	}
+
+	generator := NewTEGenerator(policy)
+	generator.SetBasePolicy(true)
+	result, err = generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if strings.Contains(result, "policy_module(") {
+		t.Errorf("Base policy should not declare a policy module, got:\n%s", result)
+	}
+	for _, want := range []string{
+		"sid kernel\nsid unlabeled\n",
+		"sid kernel gen_context(system_u:system_r:kernel_t:s0)\n",
+		"sid unlabeled gen_context(system_u:object_r:unlabeled_t:s0)\n",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Missing %q, got:\n%s", want, result)
+		}
+	}
+}
+
+func TestTEGenerator_XpermRules(t *testing.T) {
+	policy := &models.SELinuxPolicy{
+		ModuleName: "app",
+		Version:    "1.0.0",
+		XpermRules: []models.XpermRule{
+			{SourceType: "app_t", TargetType: "app_dev_t", Class: "chr_file", Operation: "ioctl", Ranges: []string{"0x1234-0x1240", "0x8910"}},
+		},
+	}
+
+	result, err := NewTEGenerator(policy).Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if !strings.Contains(result, "allowxperm app_t app_dev_t:chr_file ioctl { 0x1234-0x1240 0x8910 };\n") {
+		t.Errorf("Missing allowxperm statement, got:\n%s", result)
+	}
+}
+
+func TestTEGenerator_ConditionalRules(t *testing.T) {
+	policy := &models.SELinuxPolicy{
+		ModuleName: "app",
+		Version:    "1.0.0",
+		Booleans:   []models.Boolean{{Name: "app_use_nfs"}},
+		CondRules: []models.AllowRule{
+			{SourceType: "app_t", TargetType: "nfs_t", Class: "file", Permissions: []string{"read", "open"}, Condition: "app_use_nfs"},
+			{SourceType: "app_t", TargetType: "nfs_t", Class: "file", Permissions: []string{"getattr"}, Condition: "app_use_nfs"},
+			{SourceType: "app_t", TargetType: "tmp_t", Class: "file", Permissions: []string{"write"}, Condition: "!app_use_nfs"},
+		},
+	}
+
+	result, err := NewTEGenerator(policy).Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	for _, want := range []string{
+		"gen_bool(app_use_nfs, false)\n",
+		"if (app_use_nfs) {\n\tallow app_t nfs_t:file { getattr open read };\n}\n",
+		"if (!app_use_nfs) {\n\tallow app_t tmp_t:file { write };\n}\n",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Missing %q, got:\n%s", want, result)
+		}
+	}
+
+	generator := NewTEGenerator(policy)
+	generator.SetBooleanStyle(BooleanStyleTunable)
+	result, err = generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	for _, want := range []string{
+		"gen_tunable(app_use_nfs, false)\n",
+		"tunable_policy(`app_use_nfs',`\n\tallow app_t nfs_t:file { getattr open read };\n')\n",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Missing %q, got:\n%s", want, result)
+		}
+	}
+}
+
+func TestTEGenerator_SectionOrder(t *testing.T) {
+	policy := &models.SELinuxPolicy{
+		ModuleName: "app",
+		Version:    "1.0.0",
+		BaseTypes:  []string{"var_log_t"},
+		Types:      []models.TypeDeclaration{{TypeName: "app_t"}, {TypeName: "app_exec_t"}},
+		Roles:      []string{"web_r", "app_r"},
+		Rules: []models.AllowRule{
+			{SourceType: "app_t", TargetType: "var_log_t", Class: "file", Permissions: []string{"append"}},
+		},
+		Transitions: []models.TypeTransition{
+			{SourceType: "app_t", TargetType: "var_log_t", Class: "file", NewType: "app_log_t"},
+		},
+	}
+
+	result, err := NewTEGenerator(policy).Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	last := -1
+	for _, section := range []string{"# Requirements", "# Type Declarations", "# Roles", "# Type Transitions", "# Allow Rules"} {
+		idx := strings.Index(result, section)
+		if idx < 0 {
+			t.Fatalf("Missing section %q, got:\n%s", section, result)
+		}
+		if idx < last {
+			t.Errorf("Section %q out of order, got:\n%s", section, result)
+		}
+		last = idx
+	}
+	if !strings.Contains(result, "role app_r;\nrole web_r;\n") {
+		t.Errorf("Expected sorted roles, got:\n%s", result)
+	}
+}
+
+func TestTEGenerator_NamedTransitions(t *testing.T) {
+	policy := &models.SELinuxPolicy{
+		ModuleName: "httpd",
+		Version:    "1.0.0",
+		NamedTransitions: []models.NamedTransition{
+			{SourceType: "httpd_t", TargetType: "httpd_var_lib_t", Class: "file", NewType: "httpd_cache_t", Filename: "cache.db"},
+		},
+	}
+
+	result, err := NewTEGenerator(policy).Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if !strings.Contains(result, "type_transition httpd_t httpd_var_lib_t:file httpd_cache_t \"cache.db\";\n") {
+		t.Errorf("Missing named type_transition, got:\n%s", result)
+	}
+}
+
+func TestTEGenerator_AttributeDeclarations(t *testing.T) {
+	policy := &models.SELinuxPolicy{
+		ModuleName: "app",
+		Version:    "1.0.0",
+		Attributes: []string{"app_file_types"},
+		Types: []models.TypeDeclaration{
+			{TypeName: "app_log_t", Attributes: []string{"file_type", "app_file_types"}},
+		},
+	}
+
+	result, err := NewTEGenerator(policy).Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	want := "attribute app_file_types;\n\ntype app_log_t, file_type, app_file_types;\n"
+	if !strings.Contains(result, want) {
+		t.Errorf("Expected attribute before the types, got:\n%s", result)
+	}
+}
+
+func TestTEGenerator_OptionalPolicy(t *testing.T) {
+	policy := &models.SELinuxPolicy{
+		ModuleName: "app",
+		Version:    "1.0.0",
+		Types:      []models.TypeDeclaration{{TypeName: "app_t"}},
+		OptionalRules: []models.AllowRule{
+			{SourceType: "app_t", TargetType: "mysqld_t", Class: "unix_stream_socket", Permissions: []string{"connectto"}, Optional: "mysql"},
+			{SourceType: "app_t", TargetType: "self", Class: "process", Permissions: []string{"signal"}, Optional: "apache"},
+		},
+	}
+
+	result, err := NewTEGenerator(policy).Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	want := "# mysql\noptional_policy(`\n\tgen_require(`\n\t\ttype mysqld_t;\n\t')\n\n\tallow app_t mysqld_t:unix_stream_socket { connectto };\n')\n"
+	if !strings.Contains(result, want) {
+		t.Errorf("Missing mysql block, got:\n%s", result)
+	}
+	if !strings.Contains(result, "# apache\noptional_policy(`\n\tallow app_t self:process { signal };\n')\n") {
+		t.Errorf("Block without foreign types should have no require, got:\n%s", result)
+	}
+	if strings.Index(result, "# apache") > strings.Index(result, "# mysql") {
+		t.Errorf("Optional blocks should be sorted by module, got:\n%s", result)
+	}
+}
+
+func TestTEGenerator_UserHomeContent(t *testing.T) {
+	policy := &models.SELinuxPolicy{
+		ModuleName:      "app",
+		Version:         "1.0.0",
+		Types:           []models.TypeDeclaration{{TypeName: "app_t"}, {TypeName: "app_home_t"}, {TypeName: "app_cache_home_t"}},
+		UserHomeContent: []string{"app_home_t", "app_cache_home_t"},
+	}
+
+	result, err := NewTEGenerator(policy).Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	want := "# Home Directory Content\n########################################\n\nuserdom_user_home_content(app_cache_home_t)\nuserdom_user_home_content(app_home_t)\n"
+	if !strings.Contains(result, want) {
+		t.Errorf("Missing sorted userdom calls, got:\n%s", result)
+	}
+	if strings.Index(result, "type app_home_t;") > strings.Index(result, "userdom_user_home_content(app_home_t)") {
+		t.Errorf("userdom calls should follow the type declarations, got:\n%s", result)
+	}
+}
+
+func TestTEGenerator_ValidateTrans(t *testing.T) {
+	policy := &models.SELinuxPolicy{
+		ModuleName: "app",
+		Version:    "1.0.0",
+		ValidateTrans: []models.ValidateTrans{
+			{Classes: []string{"file", "dir"}, Expression: "t1 == t2 or t3 == app_admin_t"},
+			{Classes: []string{"file"}, Expression: "l1 eq l2", MLS: true},
+		},
+	}
+
+	if _, err := NewTEGenerator(policy).Generate(); err == nil || !strings.Contains(err.Error(), "only valid in a base policy") {
+		t.Errorf("a module should reject base-only statements, got %v", err)
+	}
+
+	generator := NewTEGenerator(policy)
+	generator.SetBasePolicy(true)
+	result, err := generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if !strings.Contains(result, "# Constraints\n") {
+		t.Errorf("validatetrans should get a Constraints section, got:\n%s", result)
+	}
+	if !strings.Contains(result, "validatetrans { file dir } ( t1 == t2 or t3 == app_admin_t );\nmlsvalidatetrans file ( l1 eq l2 );\n") {
+		t.Errorf("Missing validatetrans statements, got:\n%s", result)
+	}
+
+	policy.ValidateTrans[0].Classes = nil
+	if _, err := generator.Generate(); err == nil {
+		t.Error("expected error for validatetrans without classes")
+	}
+}
+
+func TestTEGenerator_GenerateTypes(t *testing.T) {
+	policy := &models.SELinuxPolicy{
+		ModuleName: "app",
+		Version:    "1.0.0",
+		Types:      []models.TypeDeclaration{{TypeName: "app_t", Attributes: []string{"domain"}}},
+		Rules:      []models.AllowRule{{SourceType: "app_t", TargetType: "self", Class: "process", Permissions: []string{"signal"}}},
+	}
+
+	result, err := NewTEGenerator(policy).GenerateTypes()
+	if err != nil {
+		t.Fatalf("GenerateTypes() error = %v", err)
+	}
+	if !strings.Contains(result, "type app_t, domain;\n") {
+		t.Errorf("Missing type declaration, got:\n%s", result)
+	}
+	if strings.Contains(result, "policy_module") || strings.Contains(result, "allow ") {
+		t.Errorf("GenerateTypes() should only contain declarations, got:\n%s", result)
+	}
+}
+
+func TestTEGenerator_Annotate(t *testing.T) {
+	policy := &models.SELinuxPolicy{
+		ModuleName: "httpd",
+		Version:    "1.0.0",
+		Types:      []models.TypeDeclaration{{TypeName: "httpd_t"}, {TypeName: "httpd_content_t"}},
+		Rules: []models.AllowRule{
+			{SourceType: "httpd_t", TargetType: "httpd_content_t", Class: "file", Permissions: []string{"read", "open"},
+				Rationale: []string{"httpd_t may read /var/www/*: serve static content"}},
+			{SourceType: "httpd_t", TargetType: "httpd_content_t", Class: "file", Permissions: []string{"getattr"},
+				Rationale: []string{"httpd_t may stat /var/www/*"}},
+		},
+		CondRules: []models.AllowRule{
+			{SourceType: "httpd_t", TargetType: "httpd_content_t", Class: "file", Permissions: []string{"write"},
+				Condition: "httpd_can_write", Rationale: []string{"httpd_t may write /var/www/*"}},
+		},
+	}
+
+	plain, err := NewTEGenerator(policy).Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if strings.Contains(plain, "# httpd_t may") {
+		t.Errorf("rationales must only be written when annotating, got:\n%s", plain)
+	}
+
+	generator := NewTEGenerator(policy)
+	generator.SetAnnotate(true)
+	result, err := generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	for _, want := range []string{
+		"# httpd_t may read /var/www/*: serve static content\n# httpd_t may stat /var/www/*\nallow httpd_t httpd_content_t:file { getattr open read };\n",
+		"\t# httpd_t may write /var/www/*\n\tallow httpd_t httpd_content_t:file { write };\n",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("missing %q, got:\n%s", want, result)
+		}
+	}
+}
+
+func TestTEGenerator_ObjectDefaults(t *testing.T) {
+	policy := &models.SELinuxPolicy{
+		ModuleName: "app",
+		Version:    "1.0.0",
+		DefaultTypes: []models.DefaultType{
+			{Classes: []string{"file", "dir"}, Default: "target"},
+		},
+		DefaultRanges: []models.DefaultRange{
+			{Classes: []string{"file"}, Default: "target", Range: "low"},
+		},
+	}
+
+	if _, err := NewTEGenerator(policy).Generate(); err == nil || !strings.Contains(err.Error(), "only valid in a base policy") {
+		t.Errorf("a module should reject base-only statements, got %v", err)
+	}
+
+	generator := NewTEGenerator(policy)
+	generator.SetBasePolicy(true)
+	result, err := generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if !strings.Contains(result, "# Object Defaults\n") {
+		t.Errorf("object defaults should get their own section, got:\n%s", result)
+	}
+	if !strings.Contains(result, "default_type { file dir } target;\ndefault_range file target low;\n") {
+		t.Errorf("Missing default_type and default_range statements, got:\n%s", result)
+	}
+}
+
+func TestTEGenerator_DenyRules(t *testing.T) {
+	policy := &models.SELinuxPolicy{
+		ModuleName: "app",
+		Version:    "1.0.0",
+		Rules: []models.AllowRule{
+			{SourceType: "app_t", TargetType: "app_data_t", Class: "file", Permissions: []string{"read"}},
+		},
+		NeverallowRules: []models.NeverallowRule{
+			{SourceType: "app_t", TargetType: "shadow_t", Class: "file", Permissions: []string{"read"}},
+			{SourceType: "app_t", TargetType: "app_exec_t", Class: "file", Permissions: []string{"write", "append"}},
+		},
+		DontauditRules: []models.AllowRule{
+			{SourceType: "app_t", TargetType: "proc_t", Class: "file", Permissions: []string{"read", "open"}},
+		},
+	}
+
+	result, err := NewTEGenerator(policy).Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	for _, want := range []string{
+		"# Neverallow Rules\n########################################\n\nneverallow app_t app_exec_t:file { append write };\nneverallow app_t shadow_t:file read;\n",
+		"# Dontaudit Rules\n########################################\n\ndontaudit app_t proc_t:file { open read };\n",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("missing %q, got:\n%s", want, result)
+		}
+	}
+	if strings.Index(result, "# Allow Rules") > strings.Index(result, "# Neverallow Rules") {
+		t.Errorf("neverallow rules should follow the allow rules, got:\n%s", result)
+	}
+
+	policy.NeverallowRules[0].Permissions = nil
+	if _, err := NewTEGenerator(policy).Generate(); err == nil {
+		t.Error("expected error for a neverallow without permissions")
+	}
+}
+
+func TestTEGenerator_PortContexts(t *testing.T) {
+	policy := models.NewSELinuxPolicy("app", "1.0.0")
+	policy.AddType("app_port_t", "port_type")
+	policy.AddPortBinding(models.PortBinding{Port: 9000, Protocol: "tcp", PortType: "app_port_t"})
+	policy.AddPortBinding(models.PortBinding{Port: 8000, PortEnd: 8100, Protocol: "udp", PortType: "app_port_t"})
+
+	if _, err := NewTEGenerator(policy).Generate(); err == nil || !strings.Contains(err.Error(), "portcon") {
+		t.Errorf("a module should reject portcon, got %v", err)
+	}
+
+	generator := NewTEGenerator(policy)
+	generator.SetBasePolicy(true)
+	content, err := generator.Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	for _, want := range []string{
+		"# Port Contexts\n",
+		"portcon tcp 9000 gen_context(system_u:object_r:app_port_t:s0)\n",
+		"portcon udp 8000-8100 gen_context(system_u:object_r:app_port_t:s0)\n",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("missing %q in:\n%s", want, content)
+		}
+	}
+}
+
+func TestTEGenerator_InterfaceCalls(t *testing.T) {
+	policy := &models.SELinuxPolicy{
+		ModuleName: "myapp",
+		Version:    "1.0.0",
+		Types: []models.TypeDeclaration{
+			{TypeName: "myapp_t", Attributes: []string{"domain"}},
+			{TypeName: "myapp_var_run_t", Attributes: []string{"file_type"}},
+		},
+		Rules: []models.AllowRule{
+			{SourceType: "myapp_t", TargetType: "myapp_var_run_t", Class: "file", Permissions: []string{"read"}},
+		},
+		InterfaceCalls: []models.InterfaceCall{
+			{Name: "apache_read_config", Args: []string{"myapp_t"}},
+			{Name: "files_pid_filetrans", Args: []string{"myapp_t", "myapp_var_run_t", "file"}},
+		},
+	}
+
+	te, err := NewTEGenerator(policy).Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	calls := "apache_read_config(myapp_t)\nfiles_pid_filetrans(myapp_t, myapp_var_run_t, file)\n"
+	if !strings.Contains(te, calls) {
+		t.Errorf("missing interface calls, got:\n%s", te)
+	}
+	if strings.Index(te, "# Interface Calls") > strings.Index(te, "# Allow Rules") {
+		t.Errorf("interface calls should come before the allow rules, got:\n%s", te)
+	}
+	if strings.Contains(te, "require {") {
+		t.Errorf("calls passing only declared types need no require block, got:\n%s", te)
+	}
+
+	// An undeclared type passed to an interface is required, once
+	policy.InterfaceCalls = append(policy.InterfaceCalls, models.InterfaceCall{Name: "domain_entry_file", Args: []string{"httpd_t", "myapp_var_run_t"}})
+	policy.Rules = append(policy.Rules, models.AllowRule{SourceType: "httpd_t", TargetType: "myapp_var_run_t", Class: "file", Permissions: []string{"read"}})
+	te, err = NewTEGenerator(policy).Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if !strings.Contains(te, "require {\n\ttype httpd_t;\n") {
+		t.Errorf("expected httpd_t to be required once, got:\n%s", te)
+	}
+}
+
+func TestTEGenerator_NetworkContexts(t *testing.T) {
+	policy := &models.SELinuxPolicy{
+		ModuleName: "app",
+		Version:    "1.0.0",
+		Nodecons: []models.Nodecon{
+			{Address: "192.168.1.0", Netmask: "255.255.255.0", SELinuxType: "node_internal_t"},
+			{Address: "fd00::", Netmask: "ff00::", SELinuxType: "node_ula_t"},
+		},
+		Netifcons: []models.Netifcon{{Interface: "eth0", SELinuxType: "netif_internal_t"}},
+	}
+
+	if _, err := NewTEGenerator(policy).Generate(); err == nil || !strings.Contains(err.Error(), "netifcon") {
+		t.Errorf("a module should reject netifcon and nodecon, got %v", err)
+	}
+
+	teGenerator := NewTEGenerator(policy)
+	teGenerator.SetBasePolicy(true)
+	te, err := teGenerator.Generate()
+	if err != nil {
+		t.Fatalf("TE Generate() error = %v", err)
+	}
+	conf, err := NewMonolithicGenerator(policy).Generate()
+	if err != nil {
+		t.Fatalf("Monolithic Generate() error = %v", err)
+	}
+	cil, err := NewCILGenerator(policy).Generate()
+	if err != nil {
+		t.Fatalf("CIL Generate() error = %v", err)
+	}
+
+	for _, tt := range []struct {
+		name, output string
+		want         []string
+	}{
+		{"te", te, []string{
+			"netifcon eth0 gen_context(system_u:object_r:netif_internal_t:s0) gen_context(system_u:object_r:netif_internal_t:s0)\n",
+			"nodecon 192.168.1.0 255.255.255.0 gen_context(system_u:object_r:node_internal_t:s0)\n",
+			"nodecon fd00:: ff00:: gen_context(system_u:object_r:node_ula_t:s0)\n",
+		}},
+		{"policy.conf", conf, []string{
+			"netifcon eth0 system_u:object_r:netif_internal_t:s0 system_u:object_r:netif_internal_t:s0\n",
+			"nodecon 192.168.1.0 255.255.255.0 system_u:object_r:node_internal_t:s0\n",
+			"nodecon fd00:: ff00:: system_u:object_r:node_ula_t:s0\n",
+		}},
+		{"cil", cil, []string{
+			"(netifcon eth0 (system_u object_r netif_internal_t ((s0) (s0))) (system_u object_r netif_internal_t ((s0) (s0))))\n",
+			"(nodecon (192.168.1.0) (255.255.255.0) (system_u object_r node_internal_t ((s0) (s0))))\n",
+		}},
+	} {
+		for _, want := range tt.want {
+			if !strings.Contains(tt.output, want) {
+				t.Errorf("%s: missing %q, got:\n%s", tt.name, want, tt.output)
+			}
+		}
+	}
+}